@@ -1,28 +1,55 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"github.com/diegoyosiura/cloud-manager/internal/utils"
 	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/diegoyosiura/cloud-manager/pkg/compute"
+	"github.com/diegoyosiura/cloud-manager/pkg/messaging"
+	"github.com/diegoyosiura/cloud-manager/pkg/storage/bucket"
+	"io"
+	"net/mail"
 	"os"
+	"strings"
+	"text/tabwriter"
 )
 
+// Supported command prefixes. The provider name is appended to each of them (e.g. "list-instances-aws").
+const (
+	cmdAuthenticate  = "authenticate-"
+	cmdListInstances = "list-instances-"
+	cmdListBuckets   = "list-buckets-"
+	cmdSendTestEmail = "send-test-email-"
+)
+
+var commands = []string{cmdAuthenticate, cmdListInstances, cmdListBuckets, cmdSendTestEmail}
+
 func main() {
 	// Validate number of arguments; ensure user provides a command.
 	if len(os.Args) < 2 {
-		fmt.Println("Usage: cloud-manager <authenticate-<provider>>")
-		fmt.Println("Available providers: aws, azure, gcp, oci")
+		printUsage()
 		os.Exit(1)
 	}
 
-	// Retrieve the command (e.g., authenticate-aws, authenticate-azure).
+	// Retrieve the command (e.g., authenticate-aws, list-instances-oci).
 	command := os.Args[1]
 
-	// Extract provider name from the command by removing the "authenticate-" prefix.
-	// Example: command "authenticate-aws" -> provider "aws".
-	provider := extractProviderFromCommand(command)
+	// Split the command into the action and the provider name.
+	// Example: command "list-instances-aws" -> action "list-instances-", provider "aws".
+	action, provider := parseCommand(command)
 	if provider == "" {
-		fmt.Printf("Invalid command. Usage: cloud-manager <authenticate-<provider>>\n")
+		fmt.Printf("Invalid command: %s\n", command)
+		printUsage()
+		os.Exit(1)
+	}
+
+	// Parse the command options.
+	opts, err := parseOptions(command, action, os.Args[2:])
+	if err != nil {
+		fmt.Printf("Invalid arguments for '%s': %v\n", command, err)
+		printUsage()
 		os.Exit(1)
 	}
 
@@ -48,19 +75,236 @@ func main() {
 		os.Exit(1)
 	}
 
-	// If successful, print a success message.
-	fmt.Printf("Authentication successful for provider '%s'.\n", provider)
+	switch action {
+	case cmdAuthenticate:
+		// If successful, print a success message.
+		fmt.Printf("Authentication successful for provider '%s'.\n", provider)
+	case cmdListInstances:
+		err = listInstances(authConfig, opts.json)
+	case cmdListBuckets:
+		err = listBuckets(authConfig, opts.arg(0), opts.json)
+	case cmdSendTestEmail:
+		err = sendTestEmail(authConfig, opts.from, opts.to, opts.json)
+	}
+
+	if err != nil {
+		fmt.Printf("Command '%s' failed: %v\n", command, err)
+		os.Exit(1)
+	}
+}
+
+// printUsage prints the list of supported commands.
+func printUsage() {
+	fmt.Println("Usage: cloud-manager <command> [--json] [arguments]")
+	fmt.Println("Commands:")
+	fmt.Println("  authenticate-<provider>                          Validate and test the provider credentials.")
+	fmt.Println("  list-instances-<provider>                        List all compute instances.")
+	fmt.Println("  list-buckets-<provider> [bucket]                 List the buckets, or the objects of [bucket].")
+	fmt.Println("  send-test-email-<provider> --from <a> --to <b>   Send a test email and report its status.")
+	fmt.Println("Available providers: aws, azure, gcp, oci")
+}
+
+// parseCommand splits the command into its action prefix and provider name.
+// Example: "list-buckets-oci" -> ("list-buckets-", "oci").
+func parseCommand(command string) (string, string) {
+	for _, prefix := range commands {
+		if len(command) > len(prefix) && strings.HasPrefix(command, prefix) {
+			return prefix, command[len(prefix):]
+		}
+	}
+	return "", ""
+}
+
+// cliOptions holds the options and the positional arguments of a command.
+type cliOptions struct {
+	json bool     // Prints results as JSON instead of a table.
+	from string   // Sender address used by send-test-email.
+	to   string   // Recipient address used by send-test-email.
+	args []string // Positional arguments, in order.
 }
 
-// extractProviderFromCommand extracts the provider name from the command.
-// Example: "authenticate-aws" -> "aws".
-func extractProviderFromCommand(command string) string {
-	if len(command) > len("authenticate-") && command[:len("authenticate-")] == "authenticate-" {
-		return command[len("authenticate-"):]
+// arg returns the i-th positional argument, or "" when there is none.
+func (o cliOptions) arg(i int) string {
+	if i < len(o.args) {
+		return o.args[i]
 	}
 	return ""
 }
 
+// maxArgs is the number of positional arguments each action accepts.
+var maxArgs = map[string]int{cmdListBuckets: 1}
+
+// parseOptions parses the arguments of command. Options may come before or after the positional
+// arguments, as the flag package alone stops at the first positional argument and would silently
+// ignore the options that follow it. Everything after "--" is positional.
+func parseOptions(command, action string, arguments []string) (cliOptions, error) {
+	var opts cliOptions
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(io.Discard) // The caller reports the error with the usage of every command.
+	flags.BoolVar(&opts.json, "json", false, "print results as JSON instead of a table")
+	flags.StringVar(&opts.from, "from", "", "sender address used by send-test-email")
+	flags.StringVar(&opts.to, "to", "", "recipient address used by send-test-email")
+
+	for len(arguments) > 0 {
+		if err := flags.Parse(arguments); err != nil {
+			return opts, err
+		}
+		rest := flags.Args()
+		if consumed := len(arguments) - len(rest); consumed > 0 && arguments[consumed-1] == "--" {
+			opts.args = append(opts.args, rest...)
+			break
+		}
+		if len(rest) == 0 {
+			break
+		}
+		opts.args = append(opts.args, rest[0])
+		arguments = rest[1:]
+	}
+
+	if len(opts.args) > maxArgs[action] {
+		return opts, fmt.Errorf("unexpected argument '%s'", opts.args[maxArgs[action]])
+	}
+	return opts, nil
+}
+
+// listInstances prints every compute instance visible to the configured account.
+func listInstances(authConfig *authentication.AuthConfig, jsonOutput bool) error {
+	manager, err := compute.NewVPCManager(authConfig)
+	if err != nil {
+		return err
+	}
+
+	vpcs, err := manager.ListAllVPCs(map[string]interface{}{})
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return printJSON(vpcs)
+	}
+
+	rows := make([][]string, 0, len(vpcs))
+	for _, vpc := range vpcs {
+		rows = append(rows, []string{vpc.ID, vpc.Name, vpc.Region, string(vpc.State), vpc.Description})
+	}
+	return printTable([]string{"ID", "NAME", "REGION", "STATE", "DESCRIPTION"}, rows)
+}
+
+// listBuckets prints the buckets of the account, or the objects stored in bucketName when it is given.
+func listBuckets(authConfig *authentication.AuthConfig, bucketName string, jsonOutput bool) error {
+	manager, err := bucket.NewBucketManager(authConfig)
+	if err != nil {
+		return err
+	}
+
+	if bucketName == "" {
+		buckets, err := manager.ListBuckets()
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			return printJSON(buckets)
+		}
+
+		rows := make([][]string, 0, len(buckets))
+		for _, b := range buckets {
+			rows = append(rows, []string{b})
+		}
+		return printTable([]string{"BUCKET"}, rows)
+	}
+
+	objects, err := manager.List(bucketName)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return printJSON(objects)
+	}
+
+	rows := make([][]string, 0, len(objects))
+	for _, o := range objects {
+		rows = append(rows, []string{o.Key, fmt.Sprint(o.Size), string(o.StorageClass), o.LastModified.Format("2006-01-02 15:04:05")})
+	}
+	return printTable([]string{"KEY", "SIZE", "TIER", "LAST MODIFIED"}, rows)
+}
+
+// sendTestEmail sends a single test message and reports every status change it goes through.
+func sendTestEmail(authConfig *authentication.AuthConfig, from, to string, jsonOutput bool) error {
+	if from == "" || to == "" {
+		return fmt.Errorf("both --from and --to are required")
+	}
+
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address '%s': %w", from, err)
+	}
+
+	manager, err := messaging.NewMessageManager(authConfig)
+	if err != nil {
+		return err
+	}
+
+	manager.AddMessage(messaging.NewMessage(*sender, "cloud-manager test email",
+		"This is a test email sent by cloud-manager.", "text/plain", []string{to}, nil, nil, nil))
+
+	ch, _, err := manager.Send()
+	if err != nil {
+		return err
+	}
+
+	type statusReport struct {
+		Status messaging.MessageStatus `json:"status"`
+		Error  string                  `json:"error,omitempty"`
+	}
+
+	var reports []statusReport
+	for m := range ch {
+		report := statusReport{Status: m.Status}
+		if m.Error != nil {
+			report.Error = m.Error.Error()
+		}
+		reports = append(reports, report)
+	}
+
+	if jsonOutput {
+		if err := printJSON(reports); err != nil {
+			return err
+		}
+	} else {
+		rows := make([][]string, 0, len(reports))
+		for _, r := range reports {
+			rows = append(rows, []string{fmt.Sprint(r.Status), r.Error})
+		}
+		if err := printTable([]string{"STATUS", "ERROR"}, rows); err != nil {
+			return err
+		}
+	}
+
+	if len(reports) > 0 && reports[len(reports)-1].Error != "" {
+		return fmt.Errorf("test email was not sent")
+	}
+	return nil
+}
+
+// printJSON writes v to stdout as indented JSON.
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printTable writes the header and rows to stdout as an aligned table.
+func printTable(header []string, rows [][]string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		_, _ = fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
 // loadEnvVariables loads environment variables into a map based on the provider.
 // It retrieves variables specific to each cloud provider as required.
 func loadEnvVariables(provider string) map[string]string {
@@ -71,16 +315,29 @@ func loadEnvVariables(provider string) map[string]string {
 		envVars["aws_access_key_id"] = utils.GetEnvWithValidation("AWS_KEY")         // Access Key ID.
 		envVars["aws_secret_access_key"] = utils.GetEnvWithValidation("AWS_SECRETE") // Secret Access Key.
 		envVars["aws_region"] = utils.GetEnvWithValidation("AWS_REGION")             // Region.
+		envVars["aws_session_token"] = os.Getenv("AWS_SESSION_TOKEN")                // Session token of temporary keys (optional).
+		envVars["aws_role_arn"] = os.Getenv("AWS_ROLE_ARN")                          // Role to assume (optional).
+		envVars["aws_external_id"] = os.Getenv("AWS_EXTERNAL_ID")                    // External ID of the role (optional).
+		envVars["aws_session_name"] = os.Getenv("AWS_SESSION_NAME")                  // Session name of the role (optional).
 	case "azure":
-		envVars["azure_client_id"] = utils.GetEnvWithValidation("AZURE_CLIENT_KEY")         // Client ID.
-		envVars["azure_client_secret"] = utils.GetEnvWithValidation("AZURE_CLIENT_SECRETE") // Client Secret.
-		envVars["azure_tenant_id"] = utils.GetEnvWithValidation("AZURE_DIRECTORY_ID")       // Tenant ID.
-		envVars["azure_subscription_id"] = utils.GetEnvWithValidation("AZURE_OBJECT_ID")    // Subscription ID.
+		envVars["azure_auth_mode"] = os.Getenv("AZURE_AUTH_MODE") // "client_secret" or "managed_identity" (optional).
+		if envVars["azure_auth_mode"] == "managed_identity" {
+			envVars["azure_client_id"] = os.Getenv("AZURE_CLIENT_KEY") // Client ID of a user-assigned identity (optional).
+		} else {
+			envVars["azure_client_id"] = utils.GetEnvWithValidation("AZURE_CLIENT_KEY")         // Client ID.
+			envVars["azure_client_secret"] = utils.GetEnvWithValidation("AZURE_CLIENT_SECRETE") // Client Secret.
+			envVars["azure_tenant_id"] = utils.GetEnvWithValidation("AZURE_DIRECTORY_ID")       // Tenant ID.
+		}
+		envVars["azure_subscription_id"] = utils.GetEnvWithValidation("AZURE_OBJECT_ID") // Subscription ID.
 	case "gcp":
-		envVars["gcp_project_id"] = utils.GetEnvWithValidation("GCP_KEY_ID")   // Project ID.
-		envVars["gcp_auth_json"] = utils.GetEnvWithValidation("GCP_JSON_INFO") // JSON Credentials.
+		envVars["gcp_project_id"] = os.Getenv("GCP_KEY_ID")                   // Project ID (optional with a key or on Google Cloud).
+		envVars["gcp_auth_json"] = os.Getenv("GCP_JSON_INFO")                 // JSON Credentials (optional: Application Default Credentials otherwise).
+		envVars["gcp_credential_source"] = os.Getenv("GCP_CREDENTIAL_SOURCE") // "json" or "adc" (optional).
 	case "oci":
+		envVars["oci_auth_mode"] = os.Getenv("ORACLE_API_AUTH_MODE")           // "user" or "instance_principal" (optional).
 		envVars["oci_tenancy_id"] = os.Getenv("ORACLE_API_TENANCY")            // Tenancy ID.
+		envVars["oci_compartment_id"] = os.Getenv("ORACLE_API_COMPARTMENT")    // Compartment ID.
+		envVars["oci_namespace"] = os.Getenv("ORACLE_API_NAMESPACE")           // Object Storage namespace (optional).
 		envVars["oci_user_id"] = os.Getenv("ORACLE_API_USER")                  // User ID.
 		envVars["oci_region"] = os.Getenv("ORACLE_API_REGION")                 // Region.
 		envVars["oci_private_key"] = os.Getenv("ORACLE_API_PRIVATE_KEY")       // Private Key.
@@ -92,5 +349,11 @@ func loadEnvVariables(provider string) map[string]string {
 		os.Exit(1)
 	}
 
+	// SMTP settings shared by every provider, used by send-test-email (optional).
+	envVars["email_host"] = os.Getenv("EMAIL_HOST")         // SMTP Host.
+	envVars["email_port"] = os.Getenv("EMAIL_PORT")         // SMTP Port.
+	envVars["email_user"] = os.Getenv("EMAIL_USER")         // SMTP User.
+	envVars["email_password"] = os.Getenv("EMAIL_PASSWORD") // SMTP Password.
+
 	return envVars
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Test splitting commands into their action and provider
+// Verifies the supported prefixes and that unknown or incomplete commands yield no provider.
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		command  string
+		action   string
+		provider string
+	}{
+		{command: "authenticate-aws", action: cmdAuthenticate, provider: "aws"},
+		{command: "list-instances-oci", action: cmdListInstances, provider: "oci"},
+		{command: "list-buckets-gcp", action: cmdListBuckets, provider: "gcp"},
+		{command: "send-test-email-azure", action: cmdSendTestEmail, provider: "azure"},
+		{command: "list-buckets-", action: "", provider: ""},
+		{command: "delete-everything-aws", action: "", provider: ""},
+	}
+
+	for _, tt := range tests {
+		action, provider := parseCommand(tt.command)
+		if action != tt.action || provider != tt.provider {
+			t.Errorf("parseCommand(%q): expected (%q, %q), got (%q, %q)", tt.command, tt.action, tt.provider, action, provider)
+		}
+	}
+}
+
+// Test parsing the options of a command
+// Verifies that options are read wherever they appear and that extra positional arguments are rejected.
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		action    string
+		arguments []string
+		expected  cliOptions
+		wantErr   bool
+	}{
+		{name: "No arguments", action: cmdListInstances, expected: cliOptions{}},
+		{name: "Option before the bucket", action: cmdListBuckets, arguments: []string{"--json", "logs"}, expected: cliOptions{json: true, args: []string{"logs"}}},
+		{name: "Option after the bucket", action: cmdListBuckets, arguments: []string{"logs", "--json"}, expected: cliOptions{json: true, args: []string{"logs"}}},
+		{name: "Bucket after the terminator", action: cmdListBuckets, arguments: []string{"--", "--json"}, expected: cliOptions{args: []string{"--json"}}},
+		{name: "Email addresses", action: cmdSendTestEmail, arguments: []string{"--to", "b@example.com", "--from=a@example.com"}, expected: cliOptions{from: "a@example.com", to: "b@example.com"}},
+		{name: "Extra bucket", action: cmdListBuckets, arguments: []string{"logs", "--json", "data"}, wantErr: true},
+		{name: "Unexpected argument", action: cmdListInstances, arguments: []string{"--json", "extra"}, wantErr: true},
+		{name: "Unknown option", action: cmdListInstances, arguments: []string{"--verbose"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseOptions("command", tt.action, tt.arguments)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(opts, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, opts)
+			}
+		})
+	}
+}
@@ -31,6 +31,9 @@ func IsValidArn(arn string) bool {
 	return strings.HasPrefix(arn, "arn:aws:")
 }
 
+// ConvertToOCIEmailList converts addresses written as "email <name>" (the name being optional) to OCI
+// Email Delivery addresses. Entries that do not look like an email address are passed through as-is,
+// for the service to reject.
 func ConvertToOCIEmailList(l []string) []emaildataplane.EmailAddress {
 	re := regexp.MustCompile(`(?P<email>[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,})\s*(?:<(?P<name>[^>]+)>)?`)
 
@@ -38,6 +41,10 @@ func ConvertToOCIEmailList(l []string) []emaildataplane.EmailAddress {
 
 	for _, e := range l {
 		match := re.FindStringSubmatch(e)
+		if match == nil {
+			r = append(r, emaildataplane.EmailAddress{Email: common.String(strings.TrimSpace(e))})
+			continue
+		}
 		email := match[re.SubexpIndex("email")]
 		name := match[re.SubexpIndex("name")]
 
@@ -1,6 +1,13 @@
 package authentication
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
 
 // AuthConfig is a general configuration structure that holds the provider name and its associated configuration.
 // It uses the Provider interface to abstract provider-specific behavior.
@@ -9,18 +16,66 @@ type AuthConfig struct {
 	Config       Provider // The configuration object for the specific provider implementing the Provider interface.
 }
 
+// providerFieldPrefixes maps the prefix of the credential field names to the provider they belong to.
+var providerFieldPrefixes = map[string]string{
+	"aws_":   "aws",
+	"azure_": "azure",
+	"gcp_":   "gcp",
+	"oci_":   "oci",
+}
+
+// DetectProvider infers the provider name from the prefixes of the non-empty fields (e.g. "aws_region" -> "aws").
+// It returns an error if no field or fields of more than one provider are present.
+func DetectProvider(fields map[string]string) (string, error) {
+	found := map[string]bool{}
+	for key, value := range fields {
+		if value == "" {
+			continue
+		}
+		for prefix, provider := range providerFieldPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				found[provider] = true
+			}
+		}
+	}
+
+	providers := make([]string, 0, len(found))
+	for provider := range found {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	switch len(providers) {
+	case 0:
+		return "", errors.New("unable to detect provider: no provider fields present")
+	case 1:
+		return providers[0], nil
+	default:
+		return "", fmt.Errorf("unable to detect provider: fields of multiple providers present %v", providers)
+	}
+}
+
 // NewAuthConfig initializes a new instance of AuthConfig based on the given provider name and input fields.
 // The function delegates the creation of provider-specific configurations to their respective constructors.
+// When provider is empty, it is inferred from the fields with DetectProvider.
 func NewAuthConfig(provider string, fields map[string]string) (*AuthConfig, error) {
 	var config Provider
 	var err error
 
+	if provider == "" {
+		if provider, err = DetectProvider(fields); err != nil {
+			return nil, err
+		}
+	}
+
 	// Determine the provider and create its associated configuration.
 	switch provider {
 	case "aws":
 		config, err = NewAWSAuthFromAuth(fields) // Initializes AWS-specific configuration.
 	case "azure":
 		config, err = NewAzureAuthFromAuth(fields) // Initializes Azure-specific configuration.
+	case "gcp":
+		config, err = NewGCPAuthFromAuth(fields) // Initializes GCP-specific configuration.
 	case "oci":
 		config, err = NewOCIAuthFromAuth(fields) // Initializes OCI-specific configuration.
 	default:
@@ -59,3 +114,106 @@ func (a *AuthConfig) Authenticate() error {
 	}
 	return a.Config.Authenticate()
 }
+
+// HealthCheck delegates to the provider's HealthCheck, which authenticates first if needed and then
+// performs a lightweight API call, e.g. for the readiness probe of a long-lived service.
+func (a *AuthConfig) HealthCheck(ctx context.Context) error {
+	if a.Config == nil {
+		// Return an error if no configuration has been provided for the specified provider.
+		return errors.New("no configuration provided for provider: " + a.ProviderName)
+	}
+	return a.Config.HealthCheck(ctx)
+}
+
+// ValidateAll validates every configuration of configs, keyed by any name (e.g. the provider name),
+// and returns the outcome of each under the same key: nil when the configuration is valid.
+// Unlike a loop stopping at the first error, every configuration is checked.
+func ValidateAll(configs map[string]*AuthConfig) map[string]error {
+	results := make(map[string]error, len(configs))
+	for name, config := range configs {
+		if config == nil {
+			results[name] = errors.New("no configuration provided for provider: " + name)
+			continue
+		}
+		results[name] = config.Validate()
+	}
+	return results
+}
+
+// AuthenticateAll authenticates every configuration of configs concurrently, performing the live checks
+// of each provider, and returns the outcome of each under the same key: nil when authentication succeeded.
+func AuthenticateAll(configs map[string]*AuthConfig) map[string]error {
+	results := make(map[string]error, len(configs))
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for name, config := range configs {
+		wg.Add(1)
+		go func(name string, config *AuthConfig) {
+			defer wg.Done()
+			var err error
+			if config == nil {
+				err = errors.New("no configuration provided for provider: " + name)
+			} else {
+				err = config.Authenticate()
+			}
+			mu.Lock()
+			results[name] = err
+			mu.Unlock()
+		}(name, config)
+	}
+	wg.Wait()
+	return results
+}
+
+// Clone returns an independent copy of the AuthConfig for use in another goroutine.
+// The credential fields are copied into a fresh provider struct with its own mutex, while the
+// authentication state and cached clients are reset so each copy authenticates on its own.
+// Note that secrets are duplicated in memory for every clone.
+func (a *AuthConfig) Clone() (*AuthConfig, error) {
+	var config Provider
+
+	switch c := a.Config.(type) {
+	case *AWSAuth:
+		config = c.clone()
+	case *AzureAuth:
+		config = c.clone()
+	case *GCPAuth:
+		config = c.clone()
+	case *OCIAuth:
+		config = c.clone()
+	case nil:
+		return nil, errors.New("no configuration provided for provider: " + a.ProviderName)
+	default:
+		return nil, fmt.Errorf("clone not supported for provider configuration %T", a.Config)
+	}
+
+	return &AuthConfig{
+		ProviderName: a.ProviderName,
+		Config:       config,
+	}, nil
+}
+
+// CheckPermissions reports, for each action, whether the authenticated credentials are allowed to perform it.
+// Action names follow each provider's own vocabulary:
+//   - AWS: IAM actions (e.g. "ec2:DescribeInstances", "s3:PutObject"), evaluated with IAM SimulatePrincipalPolicy.
+//   - Azure: RBAC operations (e.g. "Microsoft.Compute/virtualMachines/read"), matched against the subscription permissions.
+//   - OCI: "compute:ListInstances", "objectstorage:ListBuckets" and "email:ListSuppressions", checked with a
+//     read-only dry-run call each, since OCI has no policy simulator.
+//
+// It authenticates first if needed, and returns an error if the permissions cannot be determined.
+func (a *AuthConfig) CheckPermissions(actions []string) (map[string]bool, error) {
+	if err := a.Authenticate(); err != nil {
+		return nil, err
+	}
+
+	switch c := a.Config.(type) {
+	case *AWSAuth:
+		return c.checkPermissions(actions)
+	case *AzureAuth:
+		return c.checkPermissions(actions)
+	case *OCIAuth:
+		return c.checkPermissions(actions)
+	default:
+		return nil, fmt.Errorf("permission check not supported for provider configuration %T", a.Config)
+	}
+}
@@ -1,6 +1,9 @@
 package authentication
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"testing"
 )
 
@@ -98,3 +101,168 @@ func TestNewAuthConfig_MissingOCIFields(t *testing.T) {
 		t.Errorf("mensagem de erro inesperada: esperado %s, mas recebido: %v", expectedErr, err)
 	}
 }
+
+// TestAuthConfig_Clone verifica se o clone é independente do original e seguro para uso concorrente (executar com -race).
+func TestAuthConfig_Clone(t *testing.T) {
+	fields := map[string]string{
+		"aws_access_key_id":     "testAccessKey",
+		"aws_secret_access_key": "testSecretKey",
+		"aws_region":            "us-east-1",
+	}
+
+	original, err := NewAuthConfig("aws", fields)
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	original.Config.(*AWSAuth).Authenticated = true
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			clone, err := original.Clone()
+			if err != nil {
+				t.Errorf("erro inesperado ao clonar: %v", err)
+				return
+			}
+
+			awsClone := clone.Config.(*AWSAuth)
+			if awsClone.Authenticated || awsClone.Session != nil {
+				t.Errorf("esperado clone sem estado de autenticação")
+			}
+
+			// Cada clone altera seus próprios campos sem afetar os demais.
+			awsClone.Region = fmt.Sprintf("region-%d", i)
+			awsClone.AccessKeyID[0] = 'X'
+			if err := clone.Validate(); err != nil {
+				t.Errorf("erro inesperado na validação do clone: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	awsOriginal := original.Config.(*AWSAuth)
+	if awsOriginal.Region != "us-east-1" || string(awsOriginal.AccessKeyID) != "testAccessKey" {
+		t.Errorf("configuração original foi alterada pelos clones: %s/%s", awsOriginal.Region, awsOriginal.AccessKeyID)
+	}
+	if !awsOriginal.Authenticated {
+		t.Errorf("estado de autenticação do original não deveria mudar")
+	}
+}
+
+// TestAuthConfig_Clone_NoConfig garante que clonar sem configuração retorna erro.
+func TestAuthConfig_Clone_NoConfig(t *testing.T) {
+	_, err := (&AuthConfig{ProviderName: "aws"}).Clone()
+	if err == nil {
+		t.Fatalf("esperado erro ao clonar configuração vazia, mas foi recebido nil")
+	}
+}
+
+// TestDetectProvider verifica a detecção do provedor a partir dos prefixos dos campos, incluindo os casos vazio e ambíguo.
+func TestDetectProvider(t *testing.T) {
+	tests := []struct {
+		fields   map[string]string
+		expected string
+	}{
+		{map[string]string{"aws_access_key_id": "key", "aws_region": "us-east-1"}, "aws"},
+		{map[string]string{"azure_client_id": "client", "azure_tenant_id": "tenant"}, "azure"},
+		{map[string]string{"gcp_project_id": "project"}, "gcp"},
+		{map[string]string{"oci_tenancy_id": "tenancy", "oci_region": "sa-saopaulo-1", "azure_client_id": ""}, "oci"},
+	}
+	for _, tt := range tests {
+		provider, err := DetectProvider(tt.fields)
+		if err != nil || provider != tt.expected {
+			t.Errorf("esperado provider '%s', mas foi recebido '%s' (%v)", tt.expected, provider, err)
+		}
+	}
+
+	if _, err := DetectProvider(map[string]string{"irrelevant_field": "value"}); err == nil {
+		t.Error("esperado erro quando nenhum campo de provedor está presente, mas foi recebido nil")
+	}
+	if _, err := DetectProvider(nil); err == nil {
+		t.Error("esperado erro para campos vazios, mas foi recebido nil")
+	}
+
+	_, err := DetectProvider(map[string]string{"aws_region": "us-east-1", "oci_region": "sa-saopaulo-1"})
+	expectedErr := "unable to detect provider: fields of multiple providers present [aws oci]"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("mensagem de erro esperada: %s, mas foi recebido: %v", expectedErr, err)
+	}
+}
+
+// TestNewAuthConfig_DetectedProvider verifica se NewAuthConfig detecta o provedor quando o nome é omitido.
+func TestNewAuthConfig_DetectedProvider(t *testing.T) {
+	fields := map[string]string{
+		"aws_access_key_id":     "testAccessKey",
+		"aws_secret_access_key": "testSecretKey",
+		"aws_region":            "us-east-1",
+	}
+
+	config, err := NewAuthConfig("", fields)
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	if config.ProviderName != "aws" {
+		t.Errorf("esperado provider 'aws', mas foi recebido '%s'", config.ProviderName)
+	}
+
+	if _, err := NewAuthConfig("", map[string]string{}); err == nil {
+		t.Error("esperado erro quando o provedor não pode ser detectado, mas foi recebido nil")
+	}
+}
+
+// TestValidateAll verifica o relatório por provedor para uma mistura de configurações válidas e inválidas.
+func TestValidateAll(t *testing.T) {
+	awsConfig, err := NewAuthConfig("aws", map[string]string{
+		"aws_access_key_id":     "testAccessKey",
+		"aws_secret_access_key": "testSecretKey",
+		"aws_region":            "us-east-1",
+	})
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	configs := map[string]*AuthConfig{
+		"aws":   awsConfig,
+		"azure": {ProviderName: "azure", Config: &AzureAuth{ClientID: "client"}},
+		"oci":   {ProviderName: "oci"},
+		"gcp":   nil,
+	}
+
+	results := ValidateAll(configs)
+	if len(results) != 4 {
+		t.Fatalf("esperado um resultado por provedor, recebido %v", results)
+	}
+	if results["aws"] != nil {
+		t.Errorf("esperado aws válido, recebido erro: %v", results["aws"])
+	}
+	for _, provider := range []string{"azure", "oci", "gcp"} {
+		if results[provider] == nil {
+			t.Errorf("esperado erro para %s, mas nenhum erro foi retornado", provider)
+		}
+	}
+}
+
+// TestAuthenticateAll verifica a autenticação concorrente e o relatório por provedor (executar com -race).
+func TestAuthenticateAll(t *testing.T) {
+	key, _ := newGCPTestKey(t)
+	configs := map[string]*AuthConfig{
+		"gcp":   {ProviderName: "gcp", Config: &GCPAuth{AuthJSON: key}},
+		"azure": {ProviderName: "azure", Config: &AzureAuth{ClientID: "client"}},
+		"oci":   nil,
+	}
+
+	results := AuthenticateAll(configs)
+	if len(results) != 3 || results["gcp"] != nil || results["azure"] == nil || results["oci"] == nil {
+		t.Errorf("esperado gcp autenticado e erros para azure e oci, recebido %v", results)
+	}
+}
+
+// TestAuthConfig_HealthCheck verifica que o health check exige uma configuração de provedor.
+func TestAuthConfig_HealthCheck(t *testing.T) {
+	err := (&AuthConfig{ProviderName: "aws"}).HealthCheck(context.Background())
+	if err == nil || err.Error() != "no configuration provided for provider: aws" {
+		t.Errorf("erro inesperado: %v", err)
+	}
+}
@@ -1,18 +1,29 @@
 package authentication
 
 import (
+	"context"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/diegoyosiura/cloud-manager/internal/utils"
+	"strings"
 	"sync"
+	"time"
 )
 
+// awsRefreshMargin is how long before their expiry EnsureValid refreshes temporary credentials.
+const awsRefreshMargin = 5 * time.Minute
+
 type AWSAuth struct {
 	AccessKeyID     []byte // AWS Access Key ID stored as a byte slice for security
 	SecretAccessKey []byte // AWS Secret Access Key stored as a byte slice for security
+	SessionToken    []byte // Session token of temporary credentials (optional)
+	RoleARN         string // Role assumed with the keys above, re-assumed whenever its credentials expire (optional)
+	ExternalID      string // External ID required by the trust policy of RoleARN, for cross-account roles (optional)
+	SessionName     string // Name of the assumed-role session, shown in CloudTrail (optional; generated when empty)
 	EmailHost       string // SMTP Host
 	EmailPort       string // SMTP Port
 	EmailUser       []byte // SMTP User
@@ -21,6 +32,7 @@ type AWSAuth struct {
 
 	Authenticated bool             // Tracks if authentication was successful
 	Session       *session.Session // AWS Session instance for API interactions
+	ExpiresAt     time.Time        // Expiry of temporary credentials; zero for static keys, which are treated as non-expiring
 
 	mu sync.Mutex
 }
@@ -33,6 +45,10 @@ func NewAWSAuthFromAuth(fields map[string]string) (*AWSAuth, error) {
 		Authenticated:   false,                                   // Authentication starts as false
 		AccessKeyID:     []byte(fields["aws_access_key_id"]),     // Convert key ID to byte slice for security
 		SecretAccessKey: []byte(fields["aws_secret_access_key"]), // Convert secret key to byte slice for security
+		SessionToken:    []byte(fields["aws_session_token"]),     // Session token of temporary keys (optional)
+		RoleARN:         fields["aws_role_arn"],                  // Role to assume (optional)
+		ExternalID:      fields["aws_external_id"],               // External ID of the role (optional)
+		SessionName:     fields["aws_session_name"],              // Session name of the role (optional)
 		Region:          fields["aws_region"],                    // Set the region value
 		EmailHost:       fields["email_host"],                    // SMTP User
 		EmailPort:       fields["email_port"],                    // SMTP User
@@ -48,6 +64,25 @@ func NewAWSAuthFromAuth(fields map[string]string) (*AWSAuth, error) {
 	return config, nil // Return the valid AWSAuth instance
 }
 
+// clone copies the credential fields into a new AWSAuth, leaving the session and authentication state unset.
+func (a *AWSAuth) clone() *AWSAuth {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return &AWSAuth{
+		AccessKeyID:     append([]byte(nil), a.AccessKeyID...),
+		SecretAccessKey: append([]byte(nil), a.SecretAccessKey...),
+		SessionToken:    append([]byte(nil), a.SessionToken...),
+		RoleARN:         a.RoleARN,
+		ExternalID:      a.ExternalID,
+		SessionName:     a.SessionName,
+		EmailHost:       a.EmailHost,
+		EmailPort:       a.EmailPort,
+		EmailUser:       append([]byte(nil), a.EmailUser...),
+		EmailPassword:   append([]byte(nil), a.EmailPassword...),
+		Region:          a.Region,
+	}
+}
+
 // Validate ensures that all required AWS authentication fields are provided and non-empty.
 // Returns an error if any essential fields are missing or invalid.
 func (a *AWSAuth) Validate() error {
@@ -75,14 +110,27 @@ func (a *AWSAuth) Validate() error {
 }
 
 // InitializeSession sets up the AWS session if it is not already initialized.
-// Uses the stored AccessKeyID, SecretAccessKey, and Region for session configuration.
+// Uses the stored AccessKeyID, SecretAccessKey, SessionToken, and Region for session configuration.
+// When RoleARN is set, the session uses the credentials of the assumed role instead, which the SDK
+// re-assumes whenever they expire.
 func (a *AWSAuth) initializeSession() error {
 	// Check if the session is already initialized to avoid duplication
 	if a.Session == nil {
 		// Create a configuration using the provided credentials and region
 		sessionConfig := &aws.Config{
 			Region:      aws.String(a.Region),
-			Credentials: credentials.NewStaticCredentials(string(a.AccessKeyID), string(a.SecretAccessKey), ""), // Static credentials
+			Credentials: credentials.NewStaticCredentials(string(a.AccessKeyID), string(a.SecretAccessKey), string(a.SessionToken)), // Static credentials
+		}
+
+		if a.RoleARN != "" {
+			base, err := session.NewSession(sessionConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create AWS session: %w", err)
+			}
+			sessionConfig = &aws.Config{
+				Region:      aws.String(a.Region),
+				Credentials: stscreds.NewCredentials(base, a.RoleARN, a.assumeRoleOptions), // Assumed-role credentials
+			}
 		}
 
 		// Attempt to create a new AWS session
@@ -98,12 +146,23 @@ func (a *AWSAuth) initializeSession() error {
 	return nil // Session initialized successfully
 }
 
+// assumeRoleOptions applies the external ID and session name of the role to the assume-role provider.
+func (a *AWSAuth) assumeRoleOptions(p *stscreds.AssumeRoleProvider) {
+	if a.ExternalID != "" {
+		p.ExternalID = aws.String(a.ExternalID)
+	}
+	if a.SessionName != "" {
+		p.RoleSessionName = a.SessionName
+	}
+}
+
 // Authenticate establishes a connection to AWS services and validates credentials via STS API.
 // Ensures that the authentication is only performed once unless reauthentication is required.
 func (a *AWSAuth) Authenticate() error {
 	a.mu.Lock()
 	// Skip reauthentication if already authenticated
 	if a.Authenticated {
+		a.mu.Unlock()
 		return nil
 	}
 	a.mu.Unlock()
@@ -119,23 +178,119 @@ func (a *AWSAuth) Authenticate() error {
 		return err // Return error if session initialization fails
 	}
 
+	return a.recordIdentity(verifyIdentity(a.Session, a.RoleARN))
+}
+
+// Refresh discards the current credentials of the session and validates fresh ones via STS, which
+// re-assumes RoleARN when it is set, updating
+// ExpiresAt. Clients created from Session use the new credentials. The STS call is made without holding
+// the lock, so concurrent EnsureValid callers are not blocked behind it.
+func (a *AWSAuth) Refresh() error {
+	a.mu.Lock()
+	sess, roleARN := a.Session, a.RoleARN
+	a.mu.Unlock()
+
+	if sess == nil {
+		return a.Authenticate()
+	}
+
+	sess.Config.Credentials.Expire()
+	expiresAt, err := verifyIdentity(sess, roleARN)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.recordIdentity(expiresAt, err)
+}
+
+// EnsureValid authenticates on first use and refreshes temporary credentials that expire within
+// awsRefreshMargin. Managers call it before their API calls. Static long-lived keys are treated as
+// non-expiring, as are sessions set by the caller, so they are never refreshed.
+func (a *AWSAuth) EnsureValid() error {
+	a.mu.Lock()
+	session, expiresAt := a.Session, a.ExpiresAt
+	a.mu.Unlock()
+
+	if session == nil {
+		return a.Authenticate()
+	}
+	if !expiresAt.IsZero() && time.Until(expiresAt) < awsRefreshMargin {
+		return a.Refresh()
+	}
+	return nil
+}
+
+// HealthCheck verifies that the credentials still work with an STS GetCallerIdentity call, after
+// authenticating or refreshing them if needed (see EnsureValid).
+func (a *AWSAuth) HealthCheck(ctx context.Context) error {
+	if err := a.EnsureValid(); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	session := a.Session
+	a.mu.Unlock()
+
+	if _, err := sts.New(session).GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{}); err != nil {
+		return fmt.Errorf("AWS health check failed: %w", err)
+	}
+	return nil
+}
+
+// recordIdentity stores the outcome of verifyIdentity: the configuration is authenticated and its
+// credentials expire at expiresAt, unless err is set. a.mu must be held.
+func (a *AWSAuth) recordIdentity(expiresAt time.Time, err error) error {
+	if err != nil {
+		a.Authenticated = false
+		return err
+	}
+	a.ExpiresAt = expiresAt
+	a.Authenticated = true
+	return nil
+}
+
+// verifyIdentity validates the credentials of sess with GetCallerIdentity, checking that the identity is a
+// session of roleARN when it is set, and returns when they expire, or the zero time for credentials that
+// do not expire.
+func verifyIdentity(sess *session.Session, roleARN string) (time.Time, error) {
 	// Create an STS (Security Token Service) client using the session
-	stsSvc := sts.New(a.Session)
+	stsSvc := sts.New(sess)
 
 	// Perform a GetCallerIdentity API call to validate credentials
 	identityData, err := stsSvc.GetCallerIdentity(&sts.GetCallerIdentityInput{})
 	if err != nil || identityData == nil {
-		return fmt.Errorf("failed to authenticate with AWS STS: %w", err) // Return error if authentication fails
+		return time.Time{}, fmt.Errorf("failed to authenticate with AWS STS: %w", err) // Return error if authentication fails
 	}
 
 	// Validate the ARN (Amazon Resource Name) returned by STS
-	if !utils.IsValidArn(*identityData.Arn) {
-		return fmt.Errorf("invalid ARN returned from STS: %s", aws.StringValue(identityData.Arn))
+	if !utils.IsValidArn(aws.StringValue(identityData.Arn)) {
+		return time.Time{}, fmt.Errorf("invalid ARN returned from STS: %s", aws.StringValue(identityData.Arn))
 	}
 
-	// If validation is successful, mark as authenticated
-	a.Authenticated = true
-	return nil
+	// With a role, the identity must be a session of that role
+	if roleARN != "" && !isAssumedRoleOf(aws.StringValue(identityData.Arn), roleARN) {
+		return time.Time{}, fmt.Errorf("STS identity %s is not a session of the role %s", aws.StringValue(identityData.Arn), roleARN)
+	}
+
+	// Static credentials do not expire: their provider reports no expiry.
+	expiresAt, err := sess.Config.Credentials.ExpiresAt()
+	if err != nil {
+		return time.Time{}, nil
+	}
+	return expiresAt, nil
+}
+
+// isAssumedRoleOf reports whether identity, an STS caller ARN such as
+// "arn:aws:sts::123456789012:assumed-role/name/session", is a session of the IAM role roleARN, such as
+// "arn:aws:iam::123456789012:role/path/name": same partition and account, and same role name.
+func isAssumedRoleOf(identity, roleARN string) bool {
+	identityParts := strings.SplitN(identity, ":", 6)
+	roleParts := strings.SplitN(roleARN, ":", 6)
+	if len(identityParts) != 6 || len(roleParts) != 6 || identityParts[1] != roleParts[1] || identityParts[4] != roleParts[4] {
+		return false
+	}
+
+	session := strings.Split(identityParts[5], "/")
+	role := strings.Split(roleParts[5], "/")
+	return len(session) == 3 && session[0] == "assumed-role" && len(role) >= 2 && role[0] == "role" && session[1] == role[len(role)-1]
 }
 
 // TestAWSAuth validates the AWSAuth configuration and performs an authentication test.
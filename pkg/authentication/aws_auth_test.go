@@ -1,7 +1,15 @@
 package authentication
 
 import (
+	"context"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestNewAWSAuthFromAuth_Valid verifica se a inicialização de AWSAuth com entradas válidas ocorre sem erros.
@@ -110,3 +118,145 @@ func TestAWSAuth_Authenticate_InvalidConfig(t *testing.T) {
 		t.Errorf("mensagem inesperada de erro: '%v'", err)
 	}
 }
+
+// expiringProvider é um provedor de credenciais temporárias que expiram após validity, contando as renovações.
+type expiringProvider struct {
+	validity  time.Duration
+	expiresAt time.Time
+	retrieved int
+}
+
+func (p *expiringProvider) Retrieve() (credentials.Value, error) {
+	p.retrieved++
+	p.expiresAt = time.Now().Add(p.validity)
+	return credentials.Value{AccessKeyID: "AKID", SecretAccessKey: "SECRET", SessionToken: "TOKEN", ProviderName: "expiring"}, nil
+}
+
+func (p *expiringProvider) IsExpired() bool      { return time.Now().After(p.expiresAt) }
+func (p *expiringProvider) ExpiresAt() time.Time { return p.expiresAt }
+
+// newFakeSTSSession cria uma sessão cujas chamadas ao STS vão para um servidor fake que conta as
+// validações de identidade recebidas.
+func newFakeSTSSession(t *testing.T, creds *credentials.Credentials) (*session.Session, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><GetCallerIdentityResult>` +
+			`<Arn>arn:aws:sts::123456789012:assumed-role/role/session</Arn><UserId>AROA:session</UserId><Account>123456789012</Account>` +
+			`</GetCallerIdentityResult><ResponseMetadata><RequestId>req</RequestId></ResponseMetadata></GetCallerIdentityResponse>`))
+	}))
+	t.Cleanup(server.Close)
+
+	sess, err := session.NewSession(&aws.Config{Region: aws.String("us-east-1"), Endpoint: aws.String(server.URL), Credentials: creds})
+	if err != nil {
+		t.Fatalf("erro inesperado ao criar a sessão: %v", err)
+	}
+	return sess, &calls
+}
+
+// TestAWSAuth_EnsureValid verifica que credenciais temporárias prestes a expirar são renovadas, atualizando
+// ExpiresAt, e que chaves estáticas são tratadas como sem expiração.
+func TestAWSAuth_EnsureValid(t *testing.T) {
+	provider := &expiringProvider{validity: time.Minute}
+	sess, calls := newFakeSTSSession(t, credentials.NewCredentials(provider))
+	auth := &AWSAuth{Session: sess}
+
+	if err := auth.Refresh(); err != nil {
+		t.Fatalf("erro inesperado ao renovar: %v", err)
+	}
+	if !auth.Authenticated || auth.ExpiresAt.IsZero() || time.Until(auth.ExpiresAt) > time.Minute {
+		t.Fatalf("esperada expiração em até 1 minuto, recebido %v (autenticado: %v)", auth.ExpiresAt, auth.Authenticated)
+	}
+
+	// A expiração em menos de awsRefreshMargin força a renovação das credenciais.
+	provider.validity = time.Hour
+	if err := auth.EnsureValid(); err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	if provider.retrieved != 2 || *calls != 2 || time.Until(auth.ExpiresAt) < 50*time.Minute {
+		t.Errorf("esperada uma renovação, recebido %d obtenções, %d chamadas ao STS e expiração em %v", provider.retrieved, *calls, auth.ExpiresAt)
+	}
+
+	// Credenciais ainda válidas não são renovadas.
+	if err := auth.EnsureValid(); err != nil || provider.retrieved != 2 || *calls != 2 {
+		t.Errorf("esperado nenhum acesso ao STS, recebido %d obtenções e %d chamadas (%v)", provider.retrieved, *calls, err)
+	}
+
+	static, staticCalls := newFakeSTSSession(t, credentials.NewStaticCredentials("AKID", "SECRET", ""))
+	staticAuth := &AWSAuth{Session: static}
+	if err := staticAuth.Refresh(); err != nil {
+		t.Fatalf("erro inesperado ao renovar: %v", err)
+	}
+	if !staticAuth.ExpiresAt.IsZero() {
+		t.Errorf("esperado ExpiresAt zerado para chaves estáticas, recebido %v", staticAuth.ExpiresAt)
+	}
+	if err := staticAuth.EnsureValid(); err != nil || *staticCalls != 1 {
+		t.Errorf("esperado nenhum acesso ao STS para chaves estáticas, recebido %d chamadas (%v)", *staticCalls, err)
+	}
+}
+
+// TestAWSAuth_AssumeRole verifica a leitura dos campos da role e que a identidade autenticada deve ser
+// uma sessão da role configurada.
+func TestAWSAuth_AssumeRole(t *testing.T) {
+	auth, err := NewAWSAuthFromAuth(map[string]string{
+		"aws_access_key_id":     "test-access-key-id",
+		"aws_secret_access_key": "test-secret-access-key",
+		"aws_region":            "us-east-1",
+		"aws_role_arn":          "arn:aws:iam::123456789012:role/ops/role",
+		"aws_external_id":       "external",
+		"aws_session_name":      "cloud-manager",
+	})
+	if err != nil {
+		t.Fatalf("erro inesperado ao criar AWSAuth: %v", err)
+	}
+	if auth.RoleARN != "arn:aws:iam::123456789012:role/ops/role" || auth.ExternalID != "external" || auth.SessionName != "cloud-manager" {
+		t.Errorf("campos da role inesperados: %q, %q, %q", auth.RoleARN, auth.ExternalID, auth.SessionName)
+	}
+
+	// O servidor fake responde com uma sessão da role "role" da conta 123456789012.
+	auth.Session, _ = newFakeSTSSession(t, credentials.NewStaticCredentials("AKID", "SECRET", "TOKEN"))
+	if err := auth.Refresh(); err != nil || !auth.Authenticated {
+		t.Errorf("esperada autenticação com a role, recebido %v", err)
+	}
+
+	auth.RoleARN = "arn:aws:iam::999999999999:role/role"
+	if err := auth.Refresh(); err == nil || auth.Authenticated {
+		t.Error("esperado erro para uma identidade de outra conta")
+	}
+
+	tests := map[string]bool{
+		"arn:aws:sts::123456789012:assumed-role/role/session|arn:aws:iam::123456789012:role/role":      true,
+		"arn:aws:sts::123456789012:assumed-role/role/session|arn:aws:iam::123456789012:role/path/role": true,
+		"arn:aws:sts::123456789012:assumed-role/other/session|arn:aws:iam::123456789012:role/role":     false,
+		"arn:aws:iam::123456789012:user/role|arn:aws:iam::123456789012:role/role":                      false,
+		"arn:aws-cn:sts::123456789012:assumed-role/role/session|arn:aws:iam::123456789012:role/role":   false,
+		"arn:aws:sts::123456789012:assumed-role/role/session|invalid":                                  false,
+	}
+	for pair, expected := range tests {
+		parts := strings.Split(pair, "|")
+		if got := isAssumedRoleOf(parts[0], parts[1]); got != expected {
+			t.Errorf("isAssumedRoleOf(%s, %s): esperado %v, recebido %v", parts[0], parts[1], expected, got)
+		}
+	}
+}
+
+// TestAWSAuth_HealthCheck verifica que o health check repete a chamada GetCallerIdentity a cada execução.
+func TestAWSAuth_HealthCheck(t *testing.T) {
+	sess, calls := newFakeSTSSession(t, credentials.NewStaticCredentials("AKID", "SECRET", ""))
+	auth := &AWSAuth{Session: sess}
+
+	for i := 0; i < 2; i++ {
+		if err := auth.HealthCheck(context.Background()); err != nil {
+			t.Fatalf("erro inesperado no health check: %v", err)
+		}
+	}
+	if *calls != 2 {
+		t.Errorf("esperadas 2 chamadas ao STS, recebido %d", *calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := auth.HealthCheck(ctx); err == nil {
+		t.Error("esperado erro com o contexto cancelado")
+	}
+}
@@ -1,27 +1,40 @@
 package authentication
 
 import (
+	"context"
 	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
 	"sync"
 )
 
+// Authentication modes of AzureAuth (see AzureAuth.AuthMode).
+const (
+	AzureAuthModeClientSecret    = "client_secret"    // Client secret of an app registration (the default).
+	AzureAuthModeManagedIdentity = "managed_identity" // System or user-assigned managed identity of the VM or AKS workload.
+)
+
 // AzureAuth represents the configuration and state for authenticating
 // with Microsoft Azure using the Azure SDK for Go.
 type AzureAuth struct {
-	ClientID       string // Azure Client ID (Application ID) used for authentication.
-	ClientSecret   string // Azure Client Secret used for authentication.
-	TenantID       string // Azure Tenant ID that the application belongs to.
+	AuthMode       string // AzureAuthModeClientSecret (default) or AzureAuthModeManagedIdentity.
+	ClientID       string // Azure Client ID (Application ID) used for authentication; selects a user-assigned identity with managed identities (optional).
+	ClientSecret   string // Azure Client Secret used for authentication (not used with managed identities).
+	TenantID       string // Azure Tenant ID that the application belongs to (not used with managed identities).
 	SubscriptionID string // Azure Subscription ID to operate within.
+	StorageAccount string // Azure Storage account whose containers are managed as buckets (optional).
 	EmailHost      string // SMTP Host
 	EmailPort      string // SMTP Port
 	EmailUser      string // SMTP User
 	EmailPassword  string // SMTP PWD
 
-	Authenticated bool                               // Tracks whether authentication was performed successfully.
-	Credential    *azidentity.ClientSecretCredential // Credential object used for authorization with Azure.
-	Client        *armresources.Client               // Azure Resource Manager client for interacting with Azure resources.
+	Authenticated bool                   // Tracks whether authentication was performed successfully.
+	Credential    azcore.TokenCredential // Credential object used for authorization with Azure.
+	Client        *armresources.Client   // Azure Resource Manager client for interacting with Azure resources.
+
+	clientOptions *arm.ClientOptions // Options of the Resource Manager clients (nil for the defaults; replaced in tests).
 
 	mu sync.Mutex
 }
@@ -32,10 +45,12 @@ func NewAzureAuthFromAuth(fields map[string]string) (*AzureAuth, error) {
 	config := &AzureAuth{
 		mu:             sync.Mutex{},
 		Authenticated:  false,                           // Start with unauthenticated state.
+		AuthMode:       fields["azure_auth_mode"],       // Extract the authentication mode from fields (optional).
 		ClientID:       fields["azure_client_id"],       // Extract Azure Client ID from fields.
 		ClientSecret:   fields["azure_client_secret"],   // Extract Azure Client Secret from fields.
 		TenantID:       fields["azure_tenant_id"],       // Extract Azure Tenant ID from fields.
 		SubscriptionID: fields["azure_subscription_id"], // Extract Azure Subscription ID from fields.
+		StorageAccount: fields["azure_storage_account"], // Extract Azure Storage account from fields.
 		EmailHost:      fields["email_host"],            // SMTP User
 		EmailPort:      fields["email_port"],            // SMTP User
 		EmailUser:      fields["email_user"],            // SMTP User
@@ -45,11 +60,47 @@ func NewAzureAuthFromAuth(fields map[string]string) (*AzureAuth, error) {
 	return config, config.Validate()
 }
 
+// clone copies the credential fields into a new AzureAuth, leaving the credential, client and authentication state unset.
+func (a *AzureAuth) clone() *AzureAuth {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return &AzureAuth{
+		AuthMode:       a.AuthMode,
+		ClientID:       a.ClientID,
+		ClientSecret:   a.ClientSecret,
+		TenantID:       a.TenantID,
+		SubscriptionID: a.SubscriptionID,
+		StorageAccount: a.StorageAccount,
+		EmailHost:      a.EmailHost,
+		EmailPort:      a.EmailPort,
+		EmailUser:      a.EmailUser,
+		EmailPassword:  a.EmailPassword,
+	}
+}
+
+// TokenCredential returns the credential created by Authenticate, for clients of Azure services other
+// than the Resource Manager (e.g. Blob Storage). It returns nil before authentication.
+func (a *AzureAuth) TokenCredential() azcore.TokenCredential {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.Credential
+}
+
 // Validate checks if all required Azure authentication fields in the struct are populated.
-// It returns an error if any mandatory fields are missing.
+// It returns an error if any mandatory fields are missing. Managed identities only require SubscriptionID.
 func (a *AzureAuth) Validate() error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
+	switch a.AuthMode {
+	case "", AzureAuthModeClientSecret:
+	case AzureAuthModeManagedIdentity:
+		if a.SubscriptionID == "" {
+			return fmt.Errorf("missing required Azure authentication fields")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported Azure auth mode '%s': use '%s' or '%s'", a.AuthMode, AzureAuthModeClientSecret, AzureAuthModeManagedIdentity)
+	}
 	// Check for empty mandatory fields: ClientID, ClientSecret, TenantID, and SubscriptionID.
 	if a.ClientID == "" || a.ClientSecret == "" || a.TenantID == "" || a.SubscriptionID == "" {
 		return fmt.Errorf("missing required Azure authentication fields")
@@ -58,12 +109,14 @@ func (a *AzureAuth) Validate() error {
 	return nil
 }
 
-// Authenticate performs Azure authentication using the ClientSecretCredential.
+// Authenticate performs Azure authentication using the ClientSecretCredential, or the
+// ManagedIdentityCredential in the managed identity mode.
 // If authentication is successful, it also initializes a resource manager client for further operations.
 func (a *AzureAuth) Authenticate() error {
 	a.mu.Lock()
 	// Avoid reauthentication if already authenticated.
 	if a.Authenticated {
+		a.mu.Unlock()
 		return nil
 	}
 
@@ -77,15 +130,27 @@ func (a *AzureAuth) Authenticate() error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	// Create an Azure client credential object for authentication using ClientID, ClientSecret, and TenantID.
-	a.Credential, err = azidentity.NewClientSecretCredential(a.TenantID, a.ClientID, a.ClientSecret, nil)
-	if err != nil {
-		// Return an error if the credential creation fails, providing more context.
-		return fmt.Errorf("failed to create Azure credentials: %v. Check TenantID, ClientID, ClientSecret", err)
+	if a.AuthMode == AzureAuthModeManagedIdentity {
+		// Use the identity of the hosting VM or AKS workload; ClientID selects a user-assigned identity.
+		options := &azidentity.ManagedIdentityCredentialOptions{}
+		if a.ClientID != "" {
+			options.ID = azidentity.ClientID(a.ClientID)
+		}
+		a.Credential, err = azidentity.NewManagedIdentityCredential(options)
+		if err != nil {
+			return fmt.Errorf("failed to create Azure managed identity credentials: %v. Check ClientID", err)
+		}
+	} else {
+		// Create an Azure client credential object for authentication using ClientID, ClientSecret, and TenantID.
+		a.Credential, err = azidentity.NewClientSecretCredential(a.TenantID, a.ClientID, a.ClientSecret, nil)
+		if err != nil {
+			// Return an error if the credential creation fails, providing more context.
+			return fmt.Errorf("failed to create Azure credentials: %v. Check TenantID, ClientID, ClientSecret", err)
+		}
 	}
 
 	// Initialize a new Resource Manager client for the specified subscription using the created credentials.
-	a.Client, err = armresources.NewClient(a.SubscriptionID, a.Credential, nil)
+	a.Client, err = armresources.NewClient(a.SubscriptionID, a.Credential, a.clientOptions)
 	if err != nil {
 		// Return an error if the resource manager client cannot be created, with possible reasons.
 		return fmt.Errorf("failed to create Azure client: %v. Check SubscriptionID or permissions", err)
@@ -96,6 +161,27 @@ func (a *AzureAuth) Authenticate() error {
 	return nil
 }
 
+// HealthCheck authenticates if needed and verifies that the credential obtains a token and reaches the
+// subscription, by listing at most one resource group.
+func (a *AzureAuth) HealthCheck(ctx context.Context) error {
+	if err := a.Authenticate(); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	credential, subscriptionID, options := a.Credential, a.SubscriptionID, a.clientOptions
+	a.mu.Unlock()
+
+	client, err := armresources.NewResourceGroupsClient(subscriptionID, credential, options)
+	if err != nil {
+		return fmt.Errorf("failed to create Azure resource groups client: %w", err)
+	}
+	top := int32(1)
+	if _, err := client.NewListPager(&armresources.ResourceGroupsClientListOptions{Top: &top}).NextPage(ctx); err != nil {
+		return fmt.Errorf("Azure health check failed: %w", err)
+	}
+	return nil
+}
+
 // TestAzureAuth tests the AzureAuth configuration by validating the input and attempting authentication.
 // It ensures both validation and authentication complete without errors.
 func TestAzureAuth(auth *AzureAuth) error {
@@ -1,7 +1,17 @@
 package authentication
 
 import (
+	"context"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestNewAzureAuthFromAuth_Valid verifica se a inicialização de AzureAuth com entradas válidas ocorre corretamente.
@@ -134,3 +144,67 @@ func TestAzureAuth_Authenticate_Simulated(t *testing.T) {
 		t.Errorf("erro inesperado ao autenticar com configuração simulada: %v", err)
 	}
 }
+
+// TestAzureAuth_ManagedIdentity verifica que o modo de identidade gerenciada dispensa o segredo e o
+// tenant, e cria uma ManagedIdentityCredential.
+func TestAzureAuth_ManagedIdentity(t *testing.T) {
+	auth, err := NewAzureAuthFromAuth(map[string]string{
+		"azure_auth_mode":       AzureAuthModeManagedIdentity,
+		"azure_subscription_id": "test-subscription-id",
+	})
+	if err != nil {
+		t.Fatalf("erro inesperado ao criar AzureAuth: %v", err)
+	}
+	if err := auth.Authenticate(); err != nil {
+		t.Fatalf("erro inesperado ao autenticar: %v", err)
+	}
+	if _, ok := auth.TokenCredential().(*azidentity.ManagedIdentityCredential); !ok {
+		t.Errorf("esperado ManagedIdentityCredential, recebido %T", auth.TokenCredential())
+	}
+
+	if err := (&AzureAuth{AuthMode: AzureAuthModeManagedIdentity}).Validate(); err == nil {
+		t.Error("esperado erro sem SubscriptionID")
+	}
+	if err := (&AzureAuth{AuthMode: "certificate", SubscriptionID: "sub"}).Validate(); err == nil {
+		t.Error("esperado erro para modo de autenticação desconhecido")
+	}
+}
+
+// fakeAzureCredential emite um token fixo, sem acesso ao Microsoft Entra ID.
+type fakeAzureCredential struct{}
+
+func (fakeAzureCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "azure-token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// TestAzureAuth_HealthCheck verifica que o health check lista no máximo um resource group da assinatura.
+func TestAzureAuth_HealthCheck(t *testing.T) {
+	var query string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer azure-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		query = r.URL.RawQuery
+		_, _ = w.Write([]byte(`{"value": []}`))
+	}))
+	defer server.Close()
+
+	auth := &AzureAuth{
+		SubscriptionID: "test-subscription-id",
+		Authenticated:  true,
+		Credential:     fakeAzureCredential{},
+		clientOptions: &arm.ClientOptions{ClientOptions: policy.ClientOptions{
+			Cloud: cloud.Configuration{Services: map[cloud.ServiceName]cloud.ServiceConfiguration{
+				cloud.ResourceManager: {Endpoint: server.URL, Audience: "https://management.azure.com"},
+			}},
+			Transport: server.Client(),
+		}},
+	}
+	if err := auth.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("erro inesperado no health check: %v", err)
+	}
+	if !strings.Contains(query, "top=1") {
+		t.Errorf("esperado $top=1 na consulta, recebido '%s'", query)
+	}
+}
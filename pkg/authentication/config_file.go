@@ -0,0 +1,68 @@
+package authentication
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"sort"
+)
+
+// LoadConfigFile reads a YAML (or JSON) file of named profiles and returns an AuthConfig per profile,
+// keyed by the profile name. Each profile holds an optional "provider" (inferred from the field names
+// when omitted, see DetectProvider) and the same field keys accepted by NewAuthConfig:
+//
+//	production:
+//	  provider: aws
+//	  aws_access_key_id: $AWS_ACCESS_KEY_ID
+//	  aws_secret_access_key: ${AWS_SECRET_ACCESS_KEY}
+//	  aws_region: us-east-1
+//
+// "$VAR" and "${VAR}" in the values are replaced with the environment variables ("$$" for a literal "$").
+// Every profile is validated; the first invalid one, in name order, is reported with its name.
+func LoadConfigFile(path string) (map[string]*AuthConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var profiles map[string]map[string]string
+	if err := yaml.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%s': %w", path, err)
+	}
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("config file '%s' has no profiles", path)
+	}
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	configs := make(map[string]*AuthConfig, len(profiles))
+	for _, name := range names {
+		fields := make(map[string]string, len(profiles[name]))
+		for key, value := range profiles[name] {
+			fields[key] = expandEnv(value)
+		}
+		provider := fields["provider"]
+		delete(fields, "provider")
+
+		config, err := NewAuthConfig(provider, fields)
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile '%s': %w", name, err)
+		}
+		configs[name] = config
+	}
+	return configs, nil
+}
+
+// expandEnv replaces "$VAR" and "${VAR}" in value with the environment variables, keeping "$$" as a literal "$".
+func expandEnv(value string) string {
+	return os.Expand(value, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		return os.Getenv(name)
+	})
+}
@@ -0,0 +1,93 @@
+package authentication
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfigFile grava o conteúdo em um arquivo temporário e retorna o seu caminho.
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("erro inesperado ao gravar o arquivo: %v", err)
+	}
+	return path
+}
+
+// TestLoadConfigFile verifica a leitura de perfis YAML, a detecção do provedor, os valores numéricos
+// e a interpolação de variáveis de ambiente.
+func TestLoadConfigFile(t *testing.T) {
+	t.Setenv("TEST_AWS_SECRET", "secret-from-env")
+	path := writeConfigFile(t, "profiles.yaml", `
+production:
+  provider: aws
+  aws_access_key_id: key
+  aws_secret_access_key: ${TEST_AWS_SECRET}
+  aws_region: us-east-1
+  email_port: 587
+  email_password: pa$$word
+vms:
+  azure_auth_mode: managed_identity
+  azure_subscription_id: $TEST_AWS_SECRET
+`)
+
+	configs, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("erro inesperado ao carregar o arquivo: %v", err)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("esperado 2 perfis, recebido %d", len(configs))
+	}
+
+	aws, ok := configs["production"].Config.(*AWSAuth)
+	if !ok {
+		t.Fatalf("esperado AWSAuth no perfil 'production', recebido %T", configs["production"].Config)
+	}
+	if string(aws.SecretAccessKey) != "secret-from-env" || aws.EmailPort != "587" || string(aws.EmailPassword) != "pa$word" {
+		t.Errorf("valores inesperados: segredo '%s', porta '%s', senha '%s'", aws.SecretAccessKey, aws.EmailPort, aws.EmailPassword)
+	}
+
+	if configs["vms"].ProviderName != "azure" {
+		t.Errorf("esperado provedor 'azure' detectado pelos campos, recebido '%s'", configs["vms"].ProviderName)
+	}
+}
+
+// TestLoadConfigFile_JSON verifica que arquivos JSON também são aceitos.
+func TestLoadConfigFile_JSON(t *testing.T) {
+	path := writeConfigFile(t, "profiles.json", `{"default": {"provider": "gcp", "gcp_credential_source": "adc"}}`)
+
+	configs, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("erro inesperado ao carregar o arquivo: %v", err)
+	}
+	if configs["default"].ProviderName != "gcp" {
+		t.Errorf("esperado provedor 'gcp', recebido '%s'", configs["default"].ProviderName)
+	}
+}
+
+// TestLoadConfigFile_Invalid verifica que o perfil inválido é identificado no erro, assim como
+// arquivos ausentes, malformados ou vazios.
+func TestLoadConfigFile_Invalid(t *testing.T) {
+	path := writeConfigFile(t, "profiles.yaml", `
+valid:
+  provider: gcp
+  gcp_credential_source: adc
+broken:
+  provider: aws
+  aws_region: us-east-1
+`)
+	if _, err := LoadConfigFile(path); err == nil || !strings.Contains(err.Error(), "invalid profile 'broken'") {
+		t.Errorf("esperado erro citando o perfil 'broken', recebido %v", err)
+	}
+
+	for name, content := range map[string]string{"malformed.yaml": "profile: [", "empty.yaml": ""} {
+		if _, err := LoadConfigFile(writeConfigFile(t, name, content)); err == nil {
+			t.Errorf("esperado erro para o arquivo %s", name)
+		}
+	}
+	if _, err := LoadConfigFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("esperado erro para arquivo inexistente")
+	}
+}
@@ -0,0 +1,494 @@
+package authentication
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gcpCloudPlatformScope is the OAuth scope granting access to the Google Cloud APIs.
+const gcpCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// Sources of the GCP credentials (see GCPAuth.CredentialSource).
+const (
+	GCPCredentialSourceJSON = "json" // Service account key or user credentials in AuthJSON.
+	GCPCredentialSourceADC  = "adc"  // Application Default Credentials (see FindDefaultGCPCredential).
+)
+
+// GCPAuth represents the configuration and state for authenticating with Google Cloud
+// using a service account key.
+type GCPAuth struct {
+	ProjectID        string // Google Cloud project to operate within; defaults to the project of the credentials.
+	AuthJSON         string // Service account key, in the JSON format downloaded from the console.
+	CredentialSource string // GCPCredentialSourceJSON or GCPCredentialSourceADC; defaults to "json" when AuthJSON is set, "adc" otherwise.
+
+	EmailHost     string // SMTP Host (e.g. the Google Workspace SMTP relay or SendGrid)
+	EmailPort     string // SMTP Port
+	EmailUser     string // SMTP User
+	EmailPassword string // SMTP PWD
+
+	Authenticated bool           // Tracks whether authentication was performed successfully.
+	Credential    *GCPCredential // Credential issuing the OAuth access tokens of the service account.
+
+	mu sync.Mutex
+}
+
+// NewGCPAuthFromAuth initializes a new GCPAuth object using a map of fields.
+// The function populates the struct with values taken from the fields map and validates it.
+func NewGCPAuthFromAuth(fields map[string]string) (*GCPAuth, error) {
+	config := &GCPAuth{
+		ProjectID:        fields["gcp_project_id"],        // Extract the project ID from fields.
+		AuthJSON:         fields["gcp_auth_json"],         // Extract the service account key from fields.
+		CredentialSource: fields["gcp_credential_source"], // Extract the credential source from fields (optional).
+
+		EmailHost:     fields["email_host"],     // SMTP Host
+		EmailPort:     fields["email_port"],     // SMTP Port
+		EmailUser:     fields["email_user"],     // SMTP User
+		EmailPassword: fields["email_password"], // SMTP PWD
+	}
+	// Return the initialized GCPAuth structure and validate the configuration.
+	return config, config.Validate()
+}
+
+// clone copies the credential fields into a new GCPAuth, leaving the credential and authentication state unset.
+func (a *GCPAuth) clone() *GCPAuth {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return &GCPAuth{
+		ProjectID:        a.ProjectID,
+		AuthJSON:         a.AuthJSON,
+		CredentialSource: a.CredentialSource,
+
+		EmailHost:     a.EmailHost,
+		EmailPort:     a.EmailPort,
+		EmailUser:     a.EmailUser,
+		EmailPassword: a.EmailPassword,
+	}
+}
+
+// Validate checks the credential source and that the service account key is set when it is read from AuthJSON.
+// The project ID is optional, since it can be read from the credentials or the metadata server during authentication.
+func (a *GCPAuth) Validate() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch a.credentialSource() {
+	case GCPCredentialSourceJSON:
+		if a.AuthJSON == "" {
+			return errors.New("missing required GCP authentication fields: [AuthJSON]")
+		}
+	case GCPCredentialSourceADC:
+	default:
+		return fmt.Errorf("unsupported GCP credential source '%s': use '%s' or '%s'", a.CredentialSource, GCPCredentialSourceJSON, GCPCredentialSourceADC)
+	}
+	return nil
+}
+
+// credentialSource returns CredentialSource, defaulting to the key in AuthJSON when it is set and to
+// the Application Default Credentials otherwise.
+func (a *GCPAuth) credentialSource() string {
+	if a.CredentialSource != "" {
+		return a.CredentialSource
+	}
+	if a.AuthJSON != "" {
+		return GCPCredentialSourceJSON
+	}
+	return GCPCredentialSourceADC
+}
+
+// Authenticate loads the credentials, from AuthJSON or the Application Default Credentials, and obtains
+// a first access token to verify them. On success, Credential can be used by the service clients to
+// authorize their requests.
+func (a *GCPAuth) Authenticate() error {
+	if err := a.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Avoid reauthentication if already authenticated.
+	if a.Authenticated {
+		return nil
+	}
+
+	var credential *GCPCredential
+	var projectID string
+	var err error
+	if a.credentialSource() == GCPCredentialSourceADC {
+		credential, projectID, err = FindDefaultGCPCredential(context.Background(), gcpCloudPlatformScope)
+	} else {
+		credential, projectID, err = NewGCPCredentialFromJSON([]byte(a.AuthJSON), gcpCloudPlatformScope)
+	}
+	if err != nil {
+		return err
+	}
+	if a.ProjectID == "" {
+		a.ProjectID = projectID
+	}
+	if a.ProjectID == "" {
+		return errors.New("missing GCP project ID: set gcp_project_id or use credentials with a project")
+	}
+
+	if _, err := credential.Token(context.Background()); err != nil {
+		return fmt.Errorf("failed to authenticate with GCP: %w", err)
+	}
+
+	a.Credential = credential
+	a.Authenticated = true
+	return nil
+}
+
+// gcpBucketsURL is the Cloud Storage endpoint listing the buckets of a project, used by HealthCheck.
+// Replaced in tests.
+var gcpBucketsURL = "https://storage.googleapis.com/storage/v1/b"
+
+// HealthCheck authenticates if needed and verifies that the credentials obtain a token and reach the
+// project, by listing at most one Cloud Storage bucket.
+func (a *GCPAuth) HealthCheck(ctx context.Context) error {
+	if err := a.Authenticate(); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	credential, projectID := a.Credential, a.ProjectID
+	a.mu.Unlock()
+
+	token, err := credential.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("GCP health check failed: %w", err)
+	}
+	query := url.Values{"project": []string{projectID}, "maxResults": []string{"1"}, "fields": []string{"kind"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpBucketsURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	httpClient := credential.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("GCP health check failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("GCP health check failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// GCPCredential issues OAuth access tokens for a service account, using the JWT bearer grant
+// (RFC 7523) signed with the private key of the account. Without a private key, the tokens are
+// obtained with the refresh token of user credentials or, failing that, from the metadata server for
+// the service account attached to the workload (e.g. on GKE or Cloud Run). Tokens are cached until
+// shortly before they expire. It is safe for concurrent use.
+//
+// It implements the grants golang.org/x/oauth2/google would provide so that the module does not
+// depend on the oauth2 packages for the three flows it needs; its assertions are tested against the
+// ones of golang.org/x/oauth2/jws.
+type GCPCredential struct {
+	ClientEmail  string          // Email of the service account (the issuer of the assertions).
+	PrivateKeyID string          // ID of the private key, sent as the "kid" of the assertions.
+	PrivateKey   *rsa.PrivateKey // Private key signing the assertions.
+	TokenURL     string          // OAuth token endpoint (defaults to https://oauth2.googleapis.com/token).
+	Scopes       []string        // OAuth scopes requested for the tokens.
+	HTTPClient   *http.Client    // HTTP client used for the token requests (defaults to http.DefaultClient).
+
+	ClientID     string // OAuth client of user credentials.
+	ClientSecret string // OAuth client secret of user credentials.
+	RefreshToken string // Refresh token of user credentials (e.g. from "gcloud auth application-default login").
+	MetadataHost string // Metadata server issuing the tokens of the attached service account, used without a key or refresh token (defaults to metadata.google.internal).
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// NewGCPCredentialFromJSON creates a GCPCredential from a service account key, or from the user
+// credentials written by "gcloud auth application-default login", in JSON format, requesting the given
+// scopes. It also returns the project ID recorded in the credentials, if any.
+func NewGCPCredentialFromJSON(key []byte, scopes ...string) (*GCPCredential, string, error) {
+	var parsed struct {
+		Type           string `json:"type"`
+		ProjectID      string `json:"project_id"`
+		PrivateKeyID   string `json:"private_key_id"`
+		PrivateKey     string `json:"private_key"`
+		ClientEmail    string `json:"client_email"`
+		TokenURI       string `json:"token_uri"`
+		ClientID       string `json:"client_id"`
+		ClientSecret   string `json:"client_secret"`
+		RefreshToken   string `json:"refresh_token"`
+		QuotaProjectID string `json:"quota_project_id"`
+	}
+	if err := json.Unmarshal(key, &parsed); err != nil {
+		return nil, "", fmt.Errorf("invalid GCP service account key: %w", err)
+	}
+	if parsed.Type == "authorized_user" {
+		if parsed.RefreshToken == "" {
+			return nil, "", errors.New("invalid GCP user credentials: missing refresh_token")
+		}
+		return &GCPCredential{
+			ClientID:     parsed.ClientID,
+			ClientSecret: parsed.ClientSecret,
+			RefreshToken: parsed.RefreshToken,
+			TokenURL:     parsed.TokenURI,
+			Scopes:       scopes,
+		}, parsed.QuotaProjectID, nil
+	}
+	if parsed.Type != "service_account" {
+		return nil, "", fmt.Errorf("unsupported GCP credential type '%s': a service account key is required", parsed.Type)
+	}
+	if parsed.ClientEmail == "" {
+		return nil, "", errors.New("invalid GCP service account key: missing client_email")
+	}
+
+	privateKey, err := parseGCPPrivateKey(parsed.PrivateKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &GCPCredential{
+		ClientEmail:  parsed.ClientEmail,
+		PrivateKeyID: parsed.PrivateKeyID,
+		PrivateKey:   privateKey,
+		TokenURL:     parsed.TokenURI,
+		Scopes:       scopes,
+	}, parsed.ProjectID, nil
+}
+
+// parseGCPPrivateKey decodes the PEM private key of a service account key (PKCS#8, or PKCS#1 for older keys).
+func parseGCPPrivateKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("invalid GCP service account key: private_key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GCP service account key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("invalid GCP service account key: private_key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// FindDefaultGCPCredential looks up the Application Default Credentials, requesting the given scopes:
+// the file named by GOOGLE_APPLICATION_CREDENTIALS, then the file written by
+// "gcloud auth application-default login", then the service account attached to the workload, through
+// the metadata server (GCE_METADATA_HOST overrides its address). It also returns the project ID of the
+// credentials or, on Google Cloud, of the metadata server.
+func FindDefaultGCPCredential(ctx context.Context, scopes ...string) (*GCPCredential, string, error) {
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		key, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read GOOGLE_APPLICATION_CREDENTIALS: %w", err)
+		}
+		return NewGCPCredentialFromJSON(key, scopes...)
+	}
+
+	if path := gcloudCredentialsPath(); path != "" {
+		if key, err := os.ReadFile(path); err == nil {
+			return NewGCPCredentialFromJSON(key, scopes...)
+		}
+	}
+
+	host := os.Getenv("GCE_METADATA_HOST")
+	projectID, err := gcpMetadataProjectID(ctx, host)
+	if err != nil {
+		return nil, "", fmt.Errorf("no GCP credentials found: set gcp_auth_json or GOOGLE_APPLICATION_CREDENTIALS, or run on Google Cloud: %w", err)
+	}
+	return &GCPCredential{MetadataHost: host, Scopes: scopes}, projectID, nil
+}
+
+// gcloudCredentialsPath returns the path of the credentials written by "gcloud auth application-default login".
+func gcloudCredentialsPath() string {
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("APPDATA"); dir != "" {
+			return filepath.Join(dir, "gcloud", "application_default_credentials.json")
+		}
+		return ""
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+}
+
+// gcpMetadataURL returns the URL of path on the metadata server at host (defaults to metadata.google.internal).
+func gcpMetadataURL(host, path string) string {
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	return "http://" + host + "/computeMetadata/v1/" + path
+}
+
+// gcpMetadataProjectID reads the project ID from the metadata server at host, which also tells whether
+// the process runs on Google Cloud.
+func gcpMetadataProjectID(ctx context.Context, host string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataURL(host, "project/project-id"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("metadata server unavailable: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read metadata server response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server returned %s", resp.Status)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// Token returns a valid access token, requesting a new one when the cached token is missing or about to expire.
+func (c *GCPCredential) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Renew a minute early so the token does not expire while a request is in flight.
+	if c.token != "" && time.Now().Add(time.Minute).Before(c.expiry) {
+		return c.token, nil
+	}
+
+	req, err := c.tokenRequest(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request GCP access token: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode GCP token response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || body.AccessToken == "" {
+		return "", fmt.Errorf("GCP token request failed with status %d: %s: %s", resp.StatusCode, body.Error, body.ErrorDescription)
+	}
+
+	c.token = body.AccessToken
+	c.expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	return c.token, nil
+}
+
+// tokenRequest builds the request of a new access token: the JWT bearer grant with a private key, the
+// refresh token grant with user credentials, or else a request to the metadata server.
+func (c *GCPCredential) tokenRequest(ctx context.Context) (*http.Request, error) {
+	if c.PrivateKey == nil && c.RefreshToken == "" {
+		query := url.Values{}
+		if len(c.Scopes) > 0 {
+			query.Set("scopes", strings.Join(c.Scopes, ","))
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataURL(c.MetadataHost, "instance/service-accounts/default/token")+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+		return req, nil
+	}
+
+	tokenURL := c.TokenURL
+	if tokenURL == "" {
+		tokenURL = "https://oauth2.googleapis.com/token"
+	}
+	var form url.Values
+	if c.PrivateKey != nil {
+		assertion, err := c.assertion(tokenURL, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		form = url.Values{
+			"grant_type": []string{"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  []string{assertion},
+		}
+	} else {
+		form = url.Values{
+			"grant_type":    []string{"refresh_token"},
+			"client_id":     []string{c.ClientID},
+			"client_secret": []string{c.ClientSecret},
+			"refresh_token": []string{c.RefreshToken},
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+// assertion builds the RS256-signed JWT exchanged for an access token at the token endpoint.
+func (c *GCPCredential) assertion(audience string, now time.Time) (string, error) {
+	// The fields are in the order of golang.org/x/oauth2/jws, whose assertions the tests compare against
+	header, err := json.Marshal(struct {
+		Algorithm string `json:"alg"`
+		Typ       string `json:"typ"`
+		KeyID     string `json:"kid,omitempty"`
+	}{"RS256", "JWT", c.PrivateKeyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(struct {
+		Iss   string `json:"iss"`
+		Scope string `json:"scope,omitempty"`
+		Aud   string `json:"aud"`
+		Exp   int64  `json:"exp"`
+		Iat   int64  `json:"iat"`
+	}{c.ClientEmail, strings.Join(c.Scopes, " "), audience, now.Add(time.Hour).Unix(), now.Unix()})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, c.PrivateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign GCP token assertion: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,266 @@
+package authentication
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newGCPTestKey gera uma chave de conta de serviço cujo token_uri aponta para um servidor fake
+// que valida a assinatura da asserção e conta as requisições de token recebidas.
+func newGCPTestKey(t *testing.T) (string, *int) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("erro inesperado ao gerar a chave: %v", err)
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_ = r.ParseForm()
+		parts := strings.Split(r.Form.Get("assertion"), ".")
+		if r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || len(parts) != 3 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"bad assertion"}`))
+			return
+		}
+
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&privateKey.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"bad signature"}`))
+			return
+		}
+
+		claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var decoded map[string]interface{}
+		_ = json.Unmarshal(claims, &decoded)
+		if decoded["iss"] != "sa@project.iam.gserviceaccount.com" || decoded["scope"] != gcpCloudPlatformScope {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"bad claims"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"gcp-token","expires_in":3600,"token_type":"Bearer"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("erro inesperado ao codificar a chave: %v", err)
+	}
+	key, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "key-project",
+		"private_key_id": "key-id",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "sa@project.iam.gserviceaccount.com",
+		"token_uri":      server.URL,
+	})
+	return string(key), &requests
+}
+
+// TestNewGCPAuthFromAuth_Invalid verifica se a ausência da chave retorna erro quando a origem "json" é exigida.
+func TestNewGCPAuthFromAuth_Invalid(t *testing.T) {
+	_, err := NewGCPAuthFromAuth(map[string]string{"gcp_project_id": "project", "gcp_credential_source": "json"})
+	expectedErr := "missing required GCP authentication fields: [AuthJSON]"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("esperado erro '%s', recebido '%v'", expectedErr, err)
+	}
+
+	if _, err := NewGCPAuthFromAuth(map[string]string{"gcp_credential_source": "vault"}); err == nil {
+		t.Error("esperado erro para origem de credenciais desconhecida")
+	}
+
+	auth, err := NewGCPAuthFromAuth(map[string]string{"gcp_project_id": "project"})
+	if err != nil || auth.credentialSource() != GCPCredentialSourceADC {
+		t.Errorf("esperado fallback para ADC sem chave, recebido '%v' (%v)", auth, err)
+	}
+}
+
+// TestGCPAuth_Authenticate_Metadata verifica a autenticação por ADC com o servidor de metadados,
+// incluindo o projeto lido dos metadados e o token da conta de serviço anexada.
+func TestGCPAuth_Authenticate_Metadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/computeMetadata/v1/project/project-id":
+			_, _ = w.Write([]byte("metadata-project"))
+		case "/computeMetadata/v1/instance/service-accounts/default/token":
+			if r.URL.Query().Get("scopes") != gcpCloudPlatformScope {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "metadata-token", "expires_in": 3600})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+	auth := &GCPAuth{}
+	if err := auth.Authenticate(); err != nil {
+		t.Fatalf("erro inesperado ao autenticar: %v", err)
+	}
+	if auth.ProjectID != "metadata-project" || auth.Credential.PrivateKey != nil {
+		t.Errorf("esperado projeto 'metadata-project' sem chave privada, recebido '%s'", auth.ProjectID)
+	}
+	if token, err := auth.Credential.Token(context.Background()); err != nil || token != "metadata-token" {
+		t.Errorf("esperado token 'metadata-token', recebido '%s' (%v)", token, err)
+	}
+}
+
+// TestGCPAuth_Authenticate_UserCredentials verifica a autenticação por ADC com as credenciais de
+// usuário indicadas por GOOGLE_APPLICATION_CREDENTIALS (concessão refresh_token).
+func TestGCPAuth_Authenticate_UserCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "refresh_token" || r.FormValue("refresh_token") != "refresh" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "user-token", "expires_in": 3600})
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	key, _ := json.Marshal(map[string]string{
+		"type":             "authorized_user",
+		"client_id":        "client",
+		"client_secret":    "secret",
+		"refresh_token":    "refresh",
+		"quota_project_id": "user-project",
+		"token_uri":        server.URL,
+	})
+	if err := os.WriteFile(path, key, 0o600); err != nil {
+		t.Fatalf("erro inesperado ao gravar as credenciais: %v", err)
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+
+	auth := &GCPAuth{CredentialSource: GCPCredentialSourceADC}
+	if err := auth.Authenticate(); err != nil {
+		t.Fatalf("erro inesperado ao autenticar: %v", err)
+	}
+	if auth.ProjectID != "user-project" {
+		t.Errorf("esperado projeto 'user-project', recebido '%s'", auth.ProjectID)
+	}
+}
+
+// TestGCPAuth_Authenticate verifica a autenticação com a chave, o projeto padrão da chave e o cache do token.
+func TestGCPAuth_Authenticate(t *testing.T) {
+	key, requests := newGCPTestKey(t)
+
+	authConfig, err := NewAuthConfig("", map[string]string{"gcp_auth_json": key})
+	if err != nil {
+		t.Fatalf("erro inesperado ao criar AuthConfig: %v", err)
+	}
+	if err := authConfig.Authenticate(); err != nil {
+		t.Fatalf("erro inesperado ao autenticar: %v", err)
+	}
+
+	auth := authConfig.Config.(*GCPAuth)
+	if !auth.Authenticated || auth.ProjectID != "key-project" {
+		t.Errorf("esperado autenticado com o projeto 'key-project', recebido %v e '%s'", auth.Authenticated, auth.ProjectID)
+	}
+
+	token, err := auth.Credential.Token(context.Background())
+	if err != nil || token != "gcp-token" {
+		t.Errorf("esperado token 'gcp-token', recebido '%s' (%v)", token, err)
+	}
+	if *requests != 1 {
+		t.Errorf("esperado 1 requisição de token graças ao cache, recebido %d", *requests)
+	}
+
+	// Um projeto informado explicitamente tem precedência sobre o da chave.
+	explicit := &GCPAuth{ProjectID: "explicit-project", AuthJSON: key}
+	if err := explicit.Authenticate(); err != nil || explicit.ProjectID != "explicit-project" {
+		t.Errorf("esperado projeto 'explicit-project', recebido '%s' (%v)", explicit.ProjectID, err)
+	}
+}
+
+// TestGCPCredential_Assertion compara a asserção JWT com a gerada pelo pacote golang.org/x/oauth2/jws
+// para os mesmos campos, assinada com a chave inativa dos testes de conformidade do Cloud Storage.
+func TestGCPCredential_Assertion(t *testing.T) {
+	key, err := os.ReadFile("testdata/gcp-service-account.json")
+	if err != nil {
+		t.Fatalf("erro inesperado ao ler a chave: %v", err)
+	}
+	credential, _, err := NewGCPCredentialFromJSON(key, gcpCloudPlatformScope)
+	if err != nil {
+		t.Fatalf("erro inesperado ao ler a chave: %v", err)
+	}
+
+	assertion, err := credential.assertion("https://oauth2.googleapis.com/token", time.Date(2019, 2, 1, 5, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	expected := "eyJhbGciOiJSUzI1NiIsInR5cCI6IkpXVCIsImtpZCI6ImZmZmZmZmZmZmZmZmZmZmZmZmZmZmZmZmZmZmZmZmZmZmZmZmZmZmYifQ." +
+		"eyJpc3MiOiJ0ZXN0LWlhbS1jcmVkZW50aWFsc0BkdW1teS1wcm9qZWN0LWlkLmlhbS5nc2VydmljZWFjY291bnQuY29tIiwic2NvcGUiOiJodHRwczovL3d3dy5nb29nbGVhcGlzLmNvbS9hdXRoL2Nsb3VkLXBsYXRmb3JtIiwiYXVkIjoiaHR0cHM6Ly9vYXV0aDIuZ29vZ2xlYXBpcy5jb20vdG9rZW4iLCJleHAiOjE1NDkwMDA4MDAsImlhdCI6MTU0ODk5NzIwMH0." +
+		"fAFaop_mBGPwO95p2ZRXero1ddg3JGUa3M0rjwqrs7kVP621ugf0QhFoNWhtcxU-KTPeyKbvjQyW79Bw6r2S-yYSEodh5wp86JM9fkm3gSzT07Vu0Fcu1jlgoJiL5tpukfLbnnFqKT1U50YmgAHPm0YZFx92heGtavwtK9pQ9PZvZXelqs2Nw6SWtgqQpUC-1HaO3ISaHVrA80DUL_61buZXEXkCPKkilBn_iLBb-JmgeylGPv6DSJnuv8icxFBZ7G4EDlaT846QuHvrwOKKAPX5XjCRYbtzSH9aPfVpJ7TrFo6kRUbFou3VW_g0mgo4c71JvfY1nDYyZl49Pz0Ryw"
+	if assertion != expected {
+		t.Errorf("asserção inesperada:\n obtida %s\nesperada %s", assertion, expected)
+	}
+}
+
+// TestGCPAuth_Authenticate_InvalidKey verifica se chaves inválidas são rejeitadas.
+func TestGCPAuth_Authenticate_InvalidKey(t *testing.T) {
+	keys := []string{
+		"not json",
+		`{"type":"authorized_user","client_email":"user@example.com"}`,
+		`{"type":"service_account","client_email":"sa@example.com","private_key":"not pem"}`,
+	}
+	for _, key := range keys {
+		auth := &GCPAuth{ProjectID: "project", AuthJSON: key}
+		if err := auth.Authenticate(); err == nil || auth.Authenticated {
+			t.Errorf("esperado erro para a chave %q, mas nenhum erro foi retornado", key)
+		}
+	}
+}
+
+// TestGCPAuth_HealthCheck verifica que o health check lista no máximo um bucket do projeto com o token
+// da conta de serviço e reporta respostas de erro.
+func TestGCPAuth_HealthCheck(t *testing.T) {
+	key, _ := newGCPTestKey(t)
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gcp-token" || r.URL.Query().Get("project") != "key-project" || r.URL.Query().Get("maxResults") != "1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"kind": "storage#buckets"}`))
+	}))
+	defer server.Close()
+	original := gcpBucketsURL
+	gcpBucketsURL = server.URL
+	defer func() { gcpBucketsURL = original }()
+
+	auth := &GCPAuth{AuthJSON: key}
+	if err := auth.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("erro inesperado no health check: %v", err)
+	}
+
+	status = http.StatusForbidden
+	if err := auth.HealthCheck(context.Background()); err == nil || !strings.Contains(err.Error(), "status 403") {
+		t.Errorf("esperado erro com status 403, recebido %v", err)
+	}
+}
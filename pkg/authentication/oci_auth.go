@@ -11,21 +11,62 @@ import (
 	"context"
 	"fmt"
 	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/common/auth"
 	"github.com/oracle/oci-go-sdk/v65/identity"
+	"github.com/oracle/oci-go-sdk/v65/objectstorage"
 	"strings"
 	"sync"
 )
 
+// Authentication modes of OCIAuth (see OCIAuth.AuthMode).
+const (
+	OCIAuthModeUser              = "user"               // API signing key of a user (the default).
+	OCIAuthModeInstancePrincipal = "instance_principal" // Certificates of the compute instance the process runs on.
+)
+
+// ociInstancePrincipalProvider builds the configuration provider of the instance principal mode,
+// discovering the region from the instance metadata when region is empty. Replaced in tests.
+var ociInstancePrincipalProvider = func(region string) (common.ConfigurationProvider, error) {
+	if region == "" {
+		return auth.InstancePrincipalConfigurationProvider()
+	}
+	return auth.InstancePrincipalConfigurationProviderForRegion(common.StringToRegion(region))
+}
+
+// ociListRegions lists the regions with the identity client, the call Authenticate tests the credentials
+// with. Replaced in tests.
+var ociListRegions = func(ctx context.Context, client identity.IdentityClient) (identity.ListRegionsResponse, error) {
+	return client.ListRegions(ctx)
+}
+
+// ociGetNamespace reads the Object Storage namespace of the tenancy with the credentials of provider.
+// Replaced in tests.
+var ociGetNamespace = func(ctx context.Context, provider common.ConfigurationProvider) (string, error) {
+	client, err := objectstorage.NewObjectStorageClientWithConfigurationProvider(provider)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.GetNamespace(ctx, objectstorage.GetNamespaceRequest{})
+	if err != nil {
+		return "", err
+	}
+	if resp.Value == nil {
+		return "", fmt.Errorf("empty namespace returned by Object Storage")
+	}
+	return *resp.Value, nil
+}
+
 // OCIAuth is a struct that encapsulates the configuration and state required
 // to authenticate with Oracle Cloud Infrastructure (OCI) services.
 type OCIAuth struct {
-	Namespace     string // The Namespace of the account.
+	AuthMode      string // OCIAuthModeUser (default) or OCIAuthModeInstancePrincipal.
+	Namespace     string // The Object Storage namespace of the tenancy (optional: see ResolveNamespace).
 	CompartmentID string // The Compartment ID of the account (mandatory).
-	TenancyID     string // The tenancy ID of the account (mandatory).
-	UserID        string // The user ID in the tenancy (mandatory).
-	Region        string // The OCI region where services will be used (mandatory).
-	PrivateKey    string // The private key for authentication (mandatory).
-	Fingerprint   string // Fingerprint of the private key (mandatory).
+	TenancyID     string // The tenancy ID of the account (mandatory; read from the instance certificate for instance principals).
+	UserID        string // The user ID in the tenancy (mandatory for the user mode).
+	Region        string // The OCI region where services will be used (mandatory; discovered from the instance for instance principals).
+	PrivateKey    string // The private key for authentication (mandatory for the user mode).
+	Fingerprint   string // Fingerprint of the private key (mandatory for the user mode).
 	KeyPassphrase string // The passphrase for the private key (optional if the private key doesn't require it).
 	SMTPSecret    string // The passphrase for SMTP Authentication.
 	EmailHost     string // SMTP Host
@@ -53,6 +94,7 @@ func NewOCIAuthFromAuth(fields map[string]string) (*OCIAuth, error) {
 	config := &OCIAuth{
 		mu:            sync.Mutex{},                 // Initializes the mutex for thread safety.
 		Authenticated: false,                        // Authentication is set to "false" by default.
+		AuthMode:      fields["oci_auth_mode"],      // Reads the authentication mode from the input fields (optional).
 		Namespace:     fields["oci_namespace"],      // Reads the namespace from the input fields.
 		CompartmentID: fields["oci_compartment_id"], // Reads the compartment ID from the input fields.
 		TenancyID:     fields["oci_tenancy_id"],     // Reads the tenancy ID from the input fields.
@@ -70,11 +112,35 @@ func NewOCIAuthFromAuth(fields map[string]string) (*OCIAuth, error) {
 	return config, config.Validate()
 }
 
+// clone copies the credential fields into a new OCIAuth, leaving the clients and authentication state unset.
+func (o *OCIAuth) clone() *OCIAuth {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return &OCIAuth{
+		AuthMode:      o.AuthMode,
+		Namespace:     o.Namespace,
+		CompartmentID: o.CompartmentID,
+		TenancyID:     o.TenancyID,
+		UserID:        o.UserID,
+		Region:        o.Region,
+		PrivateKey:    o.PrivateKey,
+		Fingerprint:   o.Fingerprint,
+		KeyPassphrase: o.KeyPassphrase,
+		SMTPSecret:    o.SMTPSecret,
+		EmailHost:     o.EmailHost,
+		EmailPort:     o.EmailPort,
+		EmailUser:     o.EmailUser,
+		EmailPassword: o.EmailPassword,
+	}
+}
+
 // Validate ensures that the OCIAuth struct contains all mandatory fields.
 //
 // Returns:
 // - nil if all required fields are populated.
 // - An error if any of the required fields (TenancyID, UserID, Region, PrivateKey, or Fingerprint) is missing.
+//
+// Instance principals only require the compartment ID, since the other values come from the instance.
 func (o *OCIAuth) Validate() error {
 	// Locks the mutex to ensure thread safety during validation.
 	o.mu.Lock()
@@ -84,6 +150,13 @@ func (o *OCIAuth) Validate() error {
 	if o.CompartmentID == "" {
 		return fmt.Errorf("compartment ID is required")
 	}
+	switch o.AuthMode {
+	case "", OCIAuthModeUser:
+	case OCIAuthModeInstancePrincipal:
+		return nil
+	default:
+		return fmt.Errorf("unsupported auth mode '%s': use '%s' or '%s'", o.AuthMode, OCIAuthModeUser, OCIAuthModeInstancePrincipal)
+	}
 	if o.TenancyID == "" {
 		return fmt.Errorf("tenancy ID is required")
 	}
@@ -128,21 +201,34 @@ func (o *OCIAuth) Authenticate() error {
 	o.mu.Lock()         // Lock again for setup within the struct.
 	defer o.mu.Unlock() // Ensures the mutex is unlocked even if an error occurs.
 
-	// Replace any "\\n" placeholders in the private key with actual newlines ("\n") for proper formatting.
-	o.PrivateKey = strings.Replace(o.PrivateKey, "\\n", "\n", -1)
+	var err error
+	if o.AuthMode == OCIAuthModeInstancePrincipal {
+		// Uses the certificates of the instance, renewed by the provider, instead of an API key.
+		o.privateKeyProvider, err = ociInstancePrincipalProvider(o.Region)
+		if err != nil {
+			return fmt.Errorf("unable to create OCI instance principal provider: %w", err)
+		}
+		if o.TenancyID == "" {
+			if o.TenancyID, err = o.privateKeyProvider.TenancyOCID(); err != nil {
+				return fmt.Errorf("unable to read tenancy from instance principal: %w", err)
+			}
+		}
+	} else {
+		// Replace any "\\n" placeholders in the private key with actual newlines ("\n") for proper formatting.
+		o.PrivateKey = strings.Replace(o.PrivateKey, "\\n", "\n", -1)
 
-	// Creates a new RawConfigurationProvider with the necessary credentials for OCI services.
-	o.privateKeyProvider = common.NewRawConfigurationProvider(
-		o.TenancyID,      // The tenancy ID.
-		o.UserID,         // The user ID.
-		o.Region,         // The OCI region.
-		o.Fingerprint,    // The private key's fingerprint.
-		o.PrivateKey,     // The private key itself.
-		&o.KeyPassphrase, // The private key's passphrase.
-	)
+		// Creates a new RawConfigurationProvider with the necessary credentials for OCI services.
+		o.privateKeyProvider = common.NewRawConfigurationProvider(
+			o.TenancyID,      // The tenancy ID.
+			o.UserID,         // The user ID.
+			o.Region,         // The OCI region.
+			o.Fingerprint,    // The private key's fingerprint.
+			o.PrivateKey,     // The private key itself.
+			&o.KeyPassphrase, // The private key's passphrase.
+		)
+	}
 
 	// Uses the configuration provider to create an OCI identity client.
-	var err error
 	o.Client, err = identity.NewIdentityClientWithConfigurationProvider(o.privateKeyProvider)
 	if err != nil {
 		// Returns an error if the client cannot be created.
@@ -150,7 +236,7 @@ func (o *OCIAuth) Authenticate() error {
 	}
 
 	// Uses the client to retrieve a list of available regions in OCI as a basic test action.
-	response, err := o.Client.ListRegions(context.Background())
+	response, err := ociListRegions(context.Background(), o.Client)
 	if err != nil {
 		// Returns an error if the API call to list regions fails.
 		return fmt.Errorf("error occurred while listing regions: %v", err)
@@ -165,6 +251,22 @@ func (o *OCIAuth) Authenticate() error {
 	return fmt.Errorf("authentication failed: no regions retrieved")
 }
 
+// HealthCheck authenticates if needed and verifies that the OCI Identity service is reachable with the
+// credentials by listing the regions.
+func (o *OCIAuth) HealthCheck(ctx context.Context) error {
+	if err := o.Authenticate(); err != nil {
+		return err
+	}
+	o.mu.Lock()
+	client := o.Client
+	o.mu.Unlock()
+
+	if _, err := client.ListRegions(ctx); err != nil {
+		return fmt.Errorf("OCI health check failed: %w", err)
+	}
+	return nil
+}
+
 func (o *OCIAuth) GetAllRegions() ([]string, error) {
 	response, err := o.Client.ListRegions(context.Background())
 	if err != nil {
@@ -197,8 +299,59 @@ func (o *OCIAuth) GetAllCompartments(request identity.ListCompartmentsRequest) (
 	return regions, nil
 }
 
+// ResolveRegion returns the OCI region, preferring the Region field and falling back to the
+// configuration provider (e.g. for providers that discover the region on their own).
+//
+// Returns:
+// - The region identifier (e.g. "us-ashburn-1").
+// - An error if neither the field nor the configuration provider can supply a region.
+func (o *OCIAuth) ResolveRegion() (string, error) {
+	o.mu.Lock()
+	region := o.Region
+	provider := o.privateKeyProvider
+	o.mu.Unlock()
+
+	if region != "" {
+		return region, nil
+	}
+	if provider == nil {
+		return "", fmt.Errorf("region is not set and no configuration provider is available")
+	}
+
+	region, err := provider.Region()
+	if err != nil {
+		return "", fmt.Errorf("unable to read region from configuration provider: %v", err)
+	}
+	return region, nil
+}
+
 func (o *OCIAuth) GetConfigurationProvider() common.ConfigurationProvider {
 	o.mu.Lock()
 	defer o.mu.Unlock()
 	return o.privateKeyProvider
 }
+
+// ResolveNamespace returns the Object Storage namespace of the tenancy. When Namespace is empty, it is
+// read once from Object Storage with the authenticated credentials and cached in Namespace.
+//
+// Returns:
+// - The namespace (e.g. "axaxnpcrorw5").
+// - An error if the auth is not authenticated yet or the namespace cannot be retrieved.
+func (o *OCIAuth) ResolveNamespace(ctx context.Context) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.Namespace != "" {
+		return o.Namespace, nil
+	}
+	if o.privateKeyProvider == nil {
+		return "", fmt.Errorf("namespace is not set and no configuration provider is available; authenticate first")
+	}
+
+	namespace, err := ociGetNamespace(ctx, o.privateKeyProvider)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve Object Storage namespace: %w", err)
+	}
+	o.Namespace = namespace
+	return namespace, nil
+}
@@ -1,7 +1,15 @@
 package authentication
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/identity"
+	"strings"
 	"testing"
 )
 
@@ -35,6 +43,22 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			name: "Instance Principal Without Key",
+			fields: &OCIAuth{
+				AuthMode:      OCIAuthModeInstancePrincipal,
+				CompartmentID: "ocid1.compartment.oc1...",
+			},
+			wantErr: nil,
+		},
+		{
+			name: "Unsupported AuthMode",
+			fields: &OCIAuth{
+				AuthMode:      "session",
+				CompartmentID: "ocid1.compartment.oc1...",
+			},
+			wantErr: errors.New("unsupported auth mode 'session': use 'user' or 'instance_principal'"),
+		},
 		{
 			name: "Missing PrivateKey",
 			fields: &OCIAuth{
@@ -82,3 +106,118 @@ func TestNewOCIAuth(t *testing.T) {
 		t.Errorf("Validate() unexpectedly failed: %v", err)
 	}
 }
+
+// TestResolveRegion checks that the region is read from the field or, when it is empty, from the configuration provider.
+func TestResolveRegion(t *testing.T) {
+	// Region field set (API key authentication).
+	withField := &OCIAuth{Region: "us-ashburn-1"}
+	region, err := withField.ResolveRegion()
+	if err != nil || region != "us-ashburn-1" {
+		t.Errorf("expected 'us-ashburn-1', got '%s' (error: %v)", region, err)
+	}
+
+	// Empty Region field: the region comes from the configuration provider.
+	withProvider := &OCIAuth{
+		privateKeyProvider: common.NewRawConfigurationProvider("tenancy", "user", "sa-saopaulo-1", "fingerprint", "key", nil),
+	}
+	region, err = withProvider.ResolveRegion()
+	if err != nil || region != "sa-saopaulo-1" {
+		t.Errorf("expected 'sa-saopaulo-1', got '%s' (error: %v)", region, err)
+	}
+
+	// Without a field or a provider, an error must be returned.
+	if _, err := (&OCIAuth{}).ResolveRegion(); err == nil {
+		t.Errorf("expected an error without a configured region, got nil")
+	}
+}
+
+// TestAuthenticateInstancePrincipal checks that the instance principal mode uses the instance provider,
+// reads the tenancy from it, authenticates once the regions are listed and reports provider errors.
+func TestAuthenticateInstancePrincipal(t *testing.T) {
+	original, originalListRegions := ociInstancePrincipalProvider, ociListRegions
+	defer func() { ociInstancePrincipalProvider, ociListRegions = original, originalListRegions }()
+
+	ociInstancePrincipalProvider = func(region string) (common.ConfigurationProvider, error) {
+		return nil, errors.New("not running on OCI")
+	}
+	auth, err := NewOCIAuthFromAuth(map[string]string{
+		"oci_auth_mode":      OCIAuthModeInstancePrincipal,
+		"oci_compartment_id": "ocid1.compartment.oc1...",
+	})
+	if err != nil {
+		t.Fatalf("failed to create OCIAuth: %v", err)
+	}
+	if err := auth.Authenticate(); err == nil || !strings.Contains(err.Error(), "not running on OCI") {
+		t.Errorf("expected the provider error, got %v", err)
+	}
+
+	// The identity client needs a complete configuration; the instance provider signs with its own key.
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate the key: %v", err)
+	}
+	privateKey := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+
+	var gotRegion string
+	ociInstancePrincipalProvider = func(region string) (common.ConfigurationProvider, error) {
+		gotRegion = region
+		return common.NewRawConfigurationProvider("ocid1.tenancy.oc1..instance", "ocid1.instance.oc1..test", "sa-saopaulo-1", "aa:bb", privateKey, nil), nil
+	}
+	ociListRegions = func(ctx context.Context, client identity.IdentityClient) (identity.ListRegionsResponse, error) {
+		return identity.ListRegionsResponse{Items: []identity.Region{{Name: common.String("sa-saopaulo-1")}}}, nil
+	}
+	auth = &OCIAuth{AuthMode: OCIAuthModeInstancePrincipal, CompartmentID: "ocid1.compartment.oc1...", Region: "sa-saopaulo-1"}
+	if err := auth.Authenticate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotRegion != "sa-saopaulo-1" || auth.TenancyID != "ocid1.tenancy.oc1..instance" {
+		t.Errorf("expected region 'sa-saopaulo-1' and the instance tenancy, got '%s' and '%s'", gotRegion, auth.TenancyID)
+	}
+	if !auth.Authenticated {
+		t.Error("expected the instance principal to be authenticated")
+	}
+
+	ociListRegions = func(ctx context.Context, client identity.IdentityClient) (identity.ListRegionsResponse, error) {
+		return identity.ListRegionsResponse{}, errors.New("not authorized")
+	}
+	auth = &OCIAuth{AuthMode: OCIAuthModeInstancePrincipal, CompartmentID: "ocid1.compartment.oc1...", Region: "sa-saopaulo-1"}
+	if err := auth.Authenticate(); err == nil || !strings.Contains(err.Error(), "not authorized") || auth.Authenticated {
+		t.Errorf("expected the listing error, got %v", err)
+	}
+}
+
+// TestResolveNamespace checks that the namespace is read from Object Storage once and cached.
+func TestResolveNamespace(t *testing.T) {
+	original := ociGetNamespace
+	defer func() { ociGetNamespace = original }()
+
+	calls := 0
+	ociGetNamespace = func(ctx context.Context, provider common.ConfigurationProvider) (string, error) {
+		calls++
+		return "tenancy-namespace", nil
+	}
+
+	// Without a provider (not authenticated), the namespace cannot be resolved.
+	if _, err := (&OCIAuth{}).ResolveNamespace(context.Background()); err == nil {
+		t.Errorf("expected an error without a configuration provider, got nil")
+	}
+
+	auth := &OCIAuth{
+		privateKeyProvider: common.NewRawConfigurationProvider("tenancy", "user", "sa-saopaulo-1", "fingerprint", "key", nil),
+	}
+	for i := 0; i < 2; i++ {
+		namespace, err := auth.ResolveNamespace(context.Background())
+		if err != nil || namespace != "tenancy-namespace" {
+			t.Errorf("expected 'tenancy-namespace', got '%s' (error: %v)", namespace, err)
+		}
+	}
+	if calls != 1 || auth.Namespace != "tenancy-namespace" {
+		t.Errorf("expected 1 lookup cached in Namespace, got %d lookups and '%s'", calls, auth.Namespace)
+	}
+
+	// An explicit namespace is never looked up.
+	explicit := &OCIAuth{Namespace: "configured"}
+	if namespace, err := explicit.ResolveNamespace(context.Background()); err != nil || namespace != "configured" {
+		t.Errorf("expected 'configured', got '%s' (error: %v)", namespace, err)
+	}
+}
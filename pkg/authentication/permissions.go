@@ -0,0 +1,222 @@
+package authentication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/oracle/oci-go-sdk/v65/email"
+	"github.com/oracle/oci-go-sdk/v65/objectstorage"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// checkPermissions simulates the actions (e.g. "ec2:DescribeInstances") against the IAM policies
+// attached to the authenticated principal, using IAM SimulatePrincipalPolicy.
+func (a *AWSAuth) checkPermissions(actions []string) (map[string]bool, error) {
+	a.mu.Lock()
+	sess := a.Session
+	a.mu.Unlock()
+
+	// Resolve the principal whose policies are simulated
+	identityData, err := sts.New(sess).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve AWS caller identity: %w", err)
+	}
+	principal := iamPrincipalArn(aws.StringValue(identityData.Arn))
+
+	result := make(map[string]bool, len(actions))
+	for _, action := range actions {
+		result[action] = false
+	}
+
+	err = iam.New(sess).SimulatePrincipalPolicyPages(&iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: aws.String(principal),
+		ActionNames:     aws.StringSlice(actions),
+	}, func(page *iam.SimulatePolicyResponse, lastPage bool) bool {
+		for _, r := range page.EvaluationResults {
+			result[aws.StringValue(r.EvalActionName)] = aws.StringValue(r.EvalDecision) == iam.PolicyEvaluationDecisionTypeAllowed
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate AWS policies for '%s': %w", principal, err)
+	}
+
+	return result, nil
+}
+
+// iamPrincipalArn converts an STS assumed-role ARN into the ARN of its IAM role, which is what
+// SimulatePrincipalPolicy accepts. Other ARNs (users, roles) are returned unchanged.
+// Example: "arn:aws:sts::123456789012:assumed-role/Admin/session" -> "arn:aws:iam::123456789012:role/Admin".
+func iamPrincipalArn(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[2] != "sts" || !strings.HasPrefix(parts[5], "assumed-role/") {
+		return arn
+	}
+
+	role := strings.SplitN(strings.TrimPrefix(parts[5], "assumed-role/"), "/", 2)[0]
+	return fmt.Sprintf("arn:%s:iam::%s:role/%s", parts[1], parts[4], role)
+}
+
+// ociPermissionChecks maps the OCI actions supported by CheckPermissions to a harmless, read-only
+// call exercising them. OCI has no policy simulator, so the outcome of the call is the answer.
+var ociPermissionChecks = map[string]func(ctx context.Context, o *OCIAuth) error{
+	"compute:ListInstances": func(ctx context.Context, o *OCIAuth) error {
+		client, err := core.NewComputeClientWithConfigurationProvider(o.GetConfigurationProvider())
+		if err != nil {
+			return err
+		}
+		_, err = client.ListInstances(ctx, core.ListInstancesRequest{CompartmentId: &o.CompartmentID, Limit: common.Int(1)})
+		return err
+	},
+	"objectstorage:ListBuckets": func(ctx context.Context, o *OCIAuth) error {
+		client, err := objectstorage.NewObjectStorageClientWithConfigurationProvider(o.GetConfigurationProvider())
+		if err != nil {
+			return err
+		}
+		namespace, err := o.ResolveNamespace(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = client.ListBuckets(ctx, objectstorage.ListBucketsRequest{NamespaceName: &namespace, CompartmentId: &o.CompartmentID, Limit: common.Int(1)})
+		return err
+	},
+	"email:ListSuppressions": func(ctx context.Context, o *OCIAuth) error {
+		client, err := email.NewEmailClientWithConfigurationProvider(o.GetConfigurationProvider())
+		if err != nil {
+			return err
+		}
+		_, err = client.ListSuppressions(ctx, email.ListSuppressionsRequest{CompartmentId: &o.TenancyID, Limit: common.Int(1)})
+		return err
+	},
+}
+
+// checkPermissions runs the dry-run call of every action (see ociPermissionChecks). A call rejected
+// with 401, 403 or 404 (OCI answers NotAuthorizedOrNotFound when a policy is missing) reports the
+// action as denied; any other failure is returned as an error.
+func (o *OCIAuth) checkPermissions(actions []string) (map[string]bool, error) {
+	result := make(map[string]bool, len(actions))
+	for _, action := range actions {
+		check, ok := ociPermissionChecks[action]
+		if !ok {
+			return nil, fmt.Errorf("unsupported OCI action '%s'", action)
+		}
+
+		err := check(context.Background(), o)
+		if err == nil {
+			result[action] = true
+			continue
+		}
+
+		serviceErr, ok := common.IsServiceError(err)
+		if !ok {
+			return nil, fmt.Errorf("failed to check OCI action '%s': %w", action, err)
+		}
+		switch serviceErr.GetHTTPStatusCode() {
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+			result[action] = false
+		default:
+			return nil, fmt.Errorf("failed to check OCI action '%s': %w", action, err)
+		}
+	}
+	return result, nil
+}
+
+// azureManagementEndpoint is the Azure Resource Manager endpoint queried for permissions.
+var azureManagementEndpoint = "https://management.azure.com"
+
+// azurePermission is an entry of the Microsoft.Authorization permissions list.
+type azurePermission struct {
+	Actions    []string `json:"actions"`
+	NotActions []string `json:"notActions"`
+}
+
+// checkPermissions matches the actions (e.g. "Microsoft.Compute/virtualMachines/read") against the
+// permissions the principal is granted on the subscription by its role assignments.
+func (a *AzureAuth) checkPermissions(actions []string) (map[string]bool, error) {
+	a.mu.Lock()
+	credential := a.Credential
+	subscriptionID := a.SubscriptionID
+	a.mu.Unlock()
+
+	permissions, err := listAzurePermissions(context.Background(), credential, azureManagementEndpoint, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]bool, len(actions))
+	for _, action := range actions {
+		result[action] = azureAllows(permissions, action)
+	}
+	return result, nil
+}
+
+// listAzurePermissions returns every permission the principal holds on the subscription.
+func listAzurePermissions(ctx context.Context, credential azcore.TokenCredential, endpoint, subscriptionID string) ([]azurePermission, error) {
+	token, err := credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Azure token: %w", err)
+	}
+
+	var permissions []azurePermission
+	next := fmt.Sprintf("%s/subscriptions/%s/providers/Microsoft.Authorization/permissions?api-version=2022-04-01", endpoint, subscriptionID)
+	for next != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Azure permissions: %w", err)
+		}
+
+		var page struct {
+			Value    []azurePermission `json:"value"`
+			NextLink string            `json:"nextLink"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to list Azure permissions: unexpected status %s", resp.Status)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode Azure permissions: %w", err)
+		}
+
+		permissions = append(permissions, page.Value...)
+		next = page.NextLink
+	}
+	return permissions, nil
+}
+
+// azureAllows reports whether an entry grants the action without excluding it through its notActions.
+func azureAllows(permissions []azurePermission, action string) bool {
+	for _, p := range permissions {
+		if matchesAzureAction(p.Actions, action) && !matchesAzureAction(p.NotActions, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAzureAction reports whether any pattern matches the action. Patterns are case-insensitive
+// and "*" matches any sequence of characters (e.g. "Microsoft.Compute/*/read").
+func matchesAzureAction(patterns []string, action string) bool {
+	for _, pattern := range patterns {
+		expr := "(?i)^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+		if ok, _ := regexp.MatchString(expr, action); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package authentication
+
+import (
+	"context"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestAuthConfig_CheckPermissions_AWS verifica o resultado misto (permitido/negado) de um simulador IAM falso.
+func TestAuthConfig_CheckPermissions_AWS(t *testing.T) {
+	var simulatedArn string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		w.Header().Set("Content-Type", "text/xml")
+
+		switch r.Form.Get("Action") {
+		case "GetCallerIdentity":
+			_, _ = fmt.Fprint(w, `<GetCallerIdentityResponse><GetCallerIdentityResult>`+
+				`<Arn>arn:aws:sts::123456789012:assumed-role/Deployer/job-42</Arn><Account>123456789012</Account>`+
+				`</GetCallerIdentityResult></GetCallerIdentityResponse>`)
+		case "SimulatePrincipalPolicy":
+			simulatedArn = r.Form.Get("PolicySourceArn")
+			_, _ = fmt.Fprint(w, `<SimulatePrincipalPolicyResponse><SimulatePrincipalPolicyResult><IsTruncated>false</IsTruncated><EvaluationResults>`+
+				`<member><EvalActionName>ec2:DescribeInstances</EvalActionName><EvalDecision>allowed</EvalDecision></member>`+
+				`<member><EvalActionName>s3:PutObject</EvalActionName><EvalDecision>implicitDeny</EvalDecision></member>`+
+				`<member><EvalActionName>s3:DeleteBucket</EvalActionName><EvalDecision>explicitDeny</EvalDecision></member>`+
+				`</EvaluationResults></SimulatePrincipalPolicyResult></SimulatePrincipalPolicyResponse>`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(server.URL),
+		Credentials: credentials.NewStaticCredentials("test-key", "test-secret", ""),
+	})
+	if err != nil {
+		t.Fatalf("erro inesperado ao criar a sessão: %v", err)
+	}
+
+	authConfig := &AuthConfig{
+		ProviderName: "aws",
+		Config: &AWSAuth{
+			AccessKeyID:     []byte("test-key"),
+			SecretAccessKey: []byte("test-secret"),
+			Region:          "us-east-1",
+			Session:         sess,
+		},
+	}
+
+	allowed, err := authConfig.CheckPermissions([]string{"ec2:DescribeInstances", "s3:PutObject", "s3:DeleteBucket"})
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+
+	expected := map[string]bool{"ec2:DescribeInstances": true, "s3:PutObject": false, "s3:DeleteBucket": false}
+	for action, want := range expected {
+		if allowed[action] != want {
+			t.Errorf("ação '%s': esperado %v, recebido %v", action, want, allowed[action])
+		}
+	}
+
+	// O ARN do assumed-role deve ser convertido no ARN da role IAM.
+	if simulatedArn != "arn:aws:iam::123456789012:role/Deployer" {
+		t.Errorf("esperado PolicySourceArn 'arn:aws:iam::123456789012:role/Deployer', recebido '%s'", simulatedArn)
+	}
+}
+
+// fakeOCIServiceError implementa common.ServiceError para simular respostas da OCI.
+type fakeOCIServiceError struct {
+	status int
+}
+
+func (e fakeOCIServiceError) Error() string           { return fmt.Sprintf("service error %d", e.status) }
+func (e fakeOCIServiceError) GetHTTPStatusCode() int  { return e.status }
+func (e fakeOCIServiceError) GetMessage() string      { return "" }
+func (e fakeOCIServiceError) GetCode() string         { return "NotAuthorizedOrNotFound" }
+func (e fakeOCIServiceError) GetOpcRequestID() string { return "" }
+
+// TestAuthConfig_CheckPermissions_OCI verifica que as chamadas de teste negadas são reportadas como não permitidas.
+func TestAuthConfig_CheckPermissions_OCI(t *testing.T) {
+	original := ociPermissionChecks
+	defer func() { ociPermissionChecks = original }()
+
+	ociPermissionChecks = map[string]func(ctx context.Context, o *OCIAuth) error{
+		"compute:ListInstances":     func(context.Context, *OCIAuth) error { return nil },
+		"objectstorage:ListBuckets": func(context.Context, *OCIAuth) error { return fakeOCIServiceError{status: http.StatusNotFound} },
+		"email:ListSuppressions": func(context.Context, *OCIAuth) error {
+			return fakeOCIServiceError{status: http.StatusInternalServerError}
+		},
+	}
+
+	authConfig := &AuthConfig{ProviderName: "oci", Config: &OCIAuth{Authenticated: true}}
+
+	allowed, err := authConfig.CheckPermissions([]string{"compute:ListInstances", "objectstorage:ListBuckets"})
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	if !allowed["compute:ListInstances"] || allowed["objectstorage:ListBuckets"] {
+		t.Errorf("esperado compute permitido e objectstorage negado, recebido %v", allowed)
+	}
+
+	// Falhas que não indicam falta de permissão devem retornar erro.
+	if _, err := authConfig.CheckPermissions([]string{"email:ListSuppressions"}); err == nil {
+		t.Error("esperado erro para falha do serviço, mas nenhum erro foi retornado")
+	}
+	if _, err := authConfig.CheckPermissions([]string{"identity:ListUsers"}); err == nil {
+		t.Error("esperado erro para ação não suportada, mas nenhum erro foi retornado")
+	}
+}
+
+// fakeTokenCredential implementa azcore.TokenCredential com um token fixo.
+type fakeTokenCredential struct{}
+
+func (fakeTokenCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "test-token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// TestAzurePermissions verifica a leitura paginada das permissões e a avaliação de actions/notActions.
+func TestAzurePermissions(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = fmt.Fprint(w, `{"value":[{"actions":["Microsoft.Storage/storageAccounts/read"],"notActions":[]}]}`)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"value":[{"actions":["Microsoft.Compute/*"],"notActions":["Microsoft.Compute/virtualMachines/delete"]}],"nextLink":"%s/next?page=2"}`, server.URL)
+	}))
+	defer server.Close()
+
+	permissions, err := listAzurePermissions(context.Background(), fakeTokenCredential{}, server.URL, "sub-id")
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	if len(permissions) != 2 {
+		t.Fatalf("esperado 2 permissões, recebido %d", len(permissions))
+	}
+
+	expected := map[string]bool{
+		"Microsoft.Compute/virtualMachines/read":   true,
+		"microsoft.compute/virtualmachines/start":  true,
+		"Microsoft.Compute/virtualMachines/delete": false,
+		"Microsoft.Storage/storageAccounts/read":   true,
+		"Microsoft.Storage/storageAccounts/write":  false,
+	}
+	for action, want := range expected {
+		if got := azureAllows(permissions, action); got != want {
+			t.Errorf("ação '%s': esperado %v, recebido %v", action, want, got)
+		}
+	}
+}
+
+// TestIamPrincipalArn verifica a conversão de ARNs de assumed-role.
+func TestIamPrincipalArn(t *testing.T) {
+	tests := map[string]string{
+		"arn:aws:sts::123456789012:assumed-role/Admin/session": "arn:aws:iam::123456789012:role/Admin",
+		"arn:aws:iam::123456789012:user/alice":                 "arn:aws:iam::123456789012:user/alice",
+	}
+	for arn, want := range tests {
+		if got := iamPrincipalArn(arn); got != want {
+			t.Errorf("ARN '%s': esperado '%s', recebido '%s'", arn, want, got)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package authentication
 
+import "context"
+
 // Provider is an interface that defines the contract for provider-specific authentication configurations.
-// Each provider must implement its own Validate and Authenticate logic.
+// Each provider must implement its own Validate, Authenticate and HealthCheck logic.
 type Provider interface {
-	Validate() error     // Ensures all required fields are properly set for the provider.
-	Authenticate() error // Handles the provider-specific authentication logic.
+	Validate() error                       // Ensures all required fields are properly set for the provider.
+	Authenticate() error                   // Handles the provider-specific authentication logic.
+	HealthCheck(ctx context.Context) error // Re-verifies the credentials and cloud reachability with a lightweight call.
 }
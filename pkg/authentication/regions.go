@@ -0,0 +1,182 @@
+package authentication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+// ListRegions returns the identifiers of the regions available to the authenticated credentials,
+// sorted alphabetically and named the way each provider's APIs expect them:
+//   - AWS: region names from EC2 DescribeRegions (e.g. "us-east-1"), limited to the regions enabled for the account.
+//   - Azure: location names of the subscription (e.g. "eastus").
+//   - GCP: Compute Engine region names of the project (e.g. "southamerica-east1").
+//   - OCI: region names from the identity service (e.g. "sa-saopaulo-1").
+//
+// It authenticates first if needed.
+func (a *AuthConfig) ListRegions() ([]string, error) {
+	if err := a.Authenticate(); err != nil {
+		return nil, err
+	}
+
+	var regions []string
+	var err error
+	switch c := a.Config.(type) {
+	case *AWSAuth:
+		regions, err = c.listRegions()
+	case *AzureAuth:
+		regions, err = c.listRegions()
+	case *GCPAuth:
+		regions, err = c.listRegions()
+	case *OCIAuth:
+		regions, err = c.GetAllRegions()
+		if err != nil {
+			err = fmt.Errorf("failed to list OCI regions: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("region listing not supported for provider configuration %T", a.Config)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(regions)
+	return regions, nil
+}
+
+// listRegions returns the regions enabled for the account using EC2 DescribeRegions.
+func (a *AWSAuth) listRegions() ([]string, error) {
+	a.mu.Lock()
+	sess := a.Session
+	a.mu.Unlock()
+
+	output, err := ec2.New(sess).DescribeRegions(&ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list AWS regions: %w", err)
+	}
+
+	regions := make([]string, 0, len(output.Regions))
+	for _, region := range output.Regions {
+		regions = append(regions, aws.StringValue(region.RegionName))
+	}
+	return regions, nil
+}
+
+// listRegions returns the locations available to the subscription.
+func (a *AzureAuth) listRegions() ([]string, error) {
+	a.mu.Lock()
+	credential := a.Credential
+	subscriptionID := a.SubscriptionID
+	a.mu.Unlock()
+
+	return listAzureLocations(context.Background(), credential, azureManagementEndpoint, subscriptionID)
+}
+
+// listAzureLocations returns the names of the locations of the subscription (Subscriptions - List Locations),
+// following the nextLink of every page.
+func listAzureLocations(ctx context.Context, credential azcore.TokenCredential, endpoint, subscriptionID string) ([]string, error) {
+	token, err := credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Azure token: %w", err)
+	}
+
+	var locations []string
+	next := fmt.Sprintf("%s/subscriptions/%s/locations?api-version=2022-12-01", endpoint, subscriptionID)
+	for next != "" {
+		var page struct {
+			Value []struct {
+				Name string `json:"name"`
+			} `json:"value"`
+			NextLink string `json:"nextLink"`
+		}
+		if err := getRegionsPage(ctx, http.DefaultClient, next, token.Token, "Azure locations", &page); err != nil {
+			return nil, err
+		}
+		for _, location := range page.Value {
+			locations = append(locations, location.Name)
+		}
+		next = page.NextLink
+	}
+	return locations, nil
+}
+
+// gcpComputeEndpoint is the Compute Engine API endpoint queried for the regions of a project. Replaced in tests.
+var gcpComputeEndpoint = "https://compute.googleapis.com/compute/v1"
+
+// listRegions returns the Compute Engine regions of the project.
+func (a *GCPAuth) listRegions() ([]string, error) {
+	a.mu.Lock()
+	credential, projectID := a.Credential, a.ProjectID
+	a.mu.Unlock()
+
+	return listGCPRegions(context.Background(), credential, gcpComputeEndpoint, projectID)
+}
+
+// listGCPRegions returns the names of the Compute Engine regions of the project (regions.list),
+// following the nextPageToken of every page.
+func listGCPRegions(ctx context.Context, credential *GCPCredential, endpoint, projectID string) ([]string, error) {
+	token, err := credential.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GCP token: %w", err)
+	}
+	httpClient := credential.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	var regions []string
+	pageToken := ""
+	for {
+		query := url.Values{"fields": []string{"items/name,nextPageToken"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		var page struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		address := fmt.Sprintf("%s/projects/%s/regions?%s", endpoint, url.PathEscape(projectID), query.Encode())
+		if err := getRegionsPage(ctx, httpClient, address, token, "GCP regions", &page); err != nil {
+			return nil, err
+		}
+		for _, region := range page.Items {
+			regions = append(regions, region.Name)
+		}
+		if page.NextPageToken == "" {
+			return regions, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// getRegionsPage decodes into page the JSON answer of an authorized GET of address; what names the
+// listing in error messages.
+func getRegionsPage(ctx context.Context, httpClient *http.Client, address, token, what string, page any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", what, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to list %s: unexpected status %s", what, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(page); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", what, err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package authentication
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/identity"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestAuthConfig_ListRegions_AWS verifica a listagem ordenada das regiões retornadas por um EC2 falso.
+func TestAuthConfig_ListRegions_AWS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		w.Header().Set("Content-Type", "text/xml")
+
+		switch r.Form.Get("Action") {
+		case "GetCallerIdentity":
+			_, _ = fmt.Fprint(w, `<GetCallerIdentityResponse><GetCallerIdentityResult>`+
+				`<Arn>arn:aws:iam::123456789012:user/test</Arn><Account>123456789012</Account>`+
+				`</GetCallerIdentityResult></GetCallerIdentityResponse>`)
+		case "DescribeRegions":
+			_, _ = fmt.Fprint(w, `<DescribeRegionsResponse><regionInfo>`+
+				`<item><regionName>us-east-1</regionName><regionEndpoint>ec2.us-east-1.amazonaws.com</regionEndpoint></item>`+
+				`<item><regionName>sa-east-1</regionName><regionEndpoint>ec2.sa-east-1.amazonaws.com</regionEndpoint></item>`+
+				`</regionInfo></DescribeRegionsResponse>`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(server.URL),
+		Credentials: credentials.NewStaticCredentials("test-key", "test-secret", ""),
+	})
+	if err != nil {
+		t.Fatalf("erro inesperado ao criar a sessão: %v", err)
+	}
+
+	authConfig := &AuthConfig{
+		ProviderName: "aws",
+		Config: &AWSAuth{
+			AccessKeyID:     []byte("test-key"),
+			SecretAccessKey: []byte("test-secret"),
+			Region:          "us-east-1",
+			Session:         sess,
+		},
+	}
+
+	regions, err := authConfig.ListRegions()
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	if expected := []string{"sa-east-1", "us-east-1"}; !reflect.DeepEqual(regions, expected) {
+		t.Errorf("esperado %v, recebido %v", expected, regions)
+	}
+}
+
+// TestAuthConfig_ListRegions_OCI verifica que os nomes das regiões do serviço de identidade falso são retornados.
+func TestAuthConfig_ListRegions_OCI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `[{"key":"IAD","name":"us-ashburn-1"},{"key":"GRU","name":"sa-saopaulo-1"}]`)
+	}))
+	defer server.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("erro inesperado ao gerar a chave: %v", err)
+	}
+	privateKey := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+
+	provider := common.NewRawConfigurationProvider("ocid1.tenancy.test", "ocid1.user.test", "sa-saopaulo-1", "aa:bb", privateKey, nil)
+	client, err := identity.NewIdentityClientWithConfigurationProvider(provider)
+	if err != nil {
+		t.Fatalf("erro inesperado ao criar o cliente: %v", err)
+	}
+	client.Host = server.URL
+
+	authConfig := &AuthConfig{ProviderName: "oci", Config: &OCIAuth{Authenticated: true, Client: client}}
+
+	regions, err := authConfig.ListRegions()
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	if expected := []string{"sa-saopaulo-1", "us-ashburn-1"}; !reflect.DeepEqual(regions, expected) {
+		t.Errorf("esperado %v, recebido %v", expected, regions)
+	}
+}
+
+// TestAzureLocations verifica a leitura das localizações da assinatura em um Resource Manager falso,
+// seguindo o nextLink até a última página.
+func TestAzureLocations(t *testing.T) {
+	var path string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = fmt.Fprint(w, `{"value":[{"name":"westeurope","displayName":"West Europe"}]}`)
+			return
+		}
+		path = r.URL.Path
+		_, _ = fmt.Fprintf(w, `{"value":[{"name":"eastus","displayName":"East US"},{"name":"brazilsouth","displayName":"Brazil South"}],`+
+			`"nextLink":"%s/subscriptions/sub-id/locations?api-version=2022-12-01&page=2"}`, server.URL)
+	}))
+	defer server.Close()
+
+	locations, err := listAzureLocations(context.Background(), fakeTokenCredential{}, server.URL, "sub-id")
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	if expected := []string{"eastus", "brazilsouth", "westeurope"}; !reflect.DeepEqual(locations, expected) {
+		t.Errorf("esperado %v, recebido %v", expected, locations)
+	}
+	if path != "/subscriptions/sub-id/locations" {
+		t.Errorf("caminho inesperado: %s", path)
+	}
+}
+
+// TestAuthConfig_ListRegions_GCP verifica a listagem ordenada das regiões de um Compute Engine falso,
+// seguindo o nextPageToken até a última página.
+func TestAuthConfig_ListRegions_GCP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" || r.URL.Path != "/projects/my-project/regions" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("pageToken") == "page-2" {
+			_, _ = fmt.Fprint(w, `{"items":[{"name":"europe-west1"}]}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"items":[{"name":"us-central1"},{"name":"southamerica-east1"}],"nextPageToken":"page-2"}`)
+	}))
+	defer server.Close()
+
+	original := gcpComputeEndpoint
+	gcpComputeEndpoint = server.URL
+	defer func() { gcpComputeEndpoint = original }()
+
+	credential := &GCPCredential{token: "test-token", expiry: time.Now().Add(time.Hour)}
+	authConfig := &AuthConfig{
+		ProviderName: "gcp",
+		Config:       &GCPAuth{ProjectID: "my-project", CredentialSource: GCPCredentialSourceADC, Authenticated: true, Credential: credential},
+	}
+
+	regions, err := authConfig.ListRegions()
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	if expected := []string{"europe-west1", "southamerica-east1", "us-central1"}; !reflect.DeepEqual(regions, expected) {
+		t.Errorf("esperado %v, recebido %v", expected, regions)
+	}
+}
+
+// TestAuthConfig_ListRegions_Unsupported garante que configurações sem provedor implementado retornam erro.
+func TestAuthConfig_ListRegions_Unsupported(t *testing.T) {
+	authConfig := &AuthConfig{ProviderName: "unknown"}
+	if _, err := authConfig.ListRegions(); err == nil {
+		t.Error("esperado erro para provedor sem configuração, mas nenhum erro foi retornado")
+	}
+}
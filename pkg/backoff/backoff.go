@@ -0,0 +1,135 @@
+package backoff
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff controls how an operation is retried: how long to wait after each failed attempt
+// and how many attempts are made in total.
+type Backoff interface {
+	// NextDelay returns the wait after the given failed attempt (1 for the first attempt).
+	NextDelay(attempt int) time.Duration
+	// MaxAttempts returns the total number of attempts, including the first one; zero or less means unlimited.
+	MaxAttempts() int
+}
+
+// ExponentialBackoff multiplies the delay by Multiplier after every attempt, starting at Initial and capped at Max.
+type ExponentialBackoff struct {
+	Initial    time.Duration // Delay after the first failed attempt (defaults to 100ms).
+	Max        time.Duration // Upper bound of any delay, jitter included (no bound when zero).
+	Multiplier float64       // Growth factor between attempts (defaults to 2).
+	Jitter     float64       // Randomizes each delay by up to ±Jitter of its value (0 to 1).
+	Attempts   int           // Total number of attempts (unlimited when zero).
+}
+
+func (b ExponentialBackoff) NextDelay(attempt int) time.Duration {
+	initial := b.Initial
+	if initial <= 0 {
+		initial = 100 * time.Millisecond
+	}
+	multiplier := b.Multiplier
+	if multiplier <= 1 {
+		multiplier = 2
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	delay = jitter(delay, b.Jitter)
+	if b.Max > 0 && delay > float64(b.Max) {
+		return b.Max
+	}
+	if delay > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
+}
+
+func (b ExponentialBackoff) MaxAttempts() int {
+	return b.Attempts
+}
+
+// ConstantBackoff waits the same Delay between every attempt.
+type ConstantBackoff struct {
+	Delay    time.Duration // Delay between attempts.
+	Jitter   float64       // Randomizes each delay by up to ±Jitter of its value (0 to 1).
+	Attempts int           // Total number of attempts (unlimited when zero).
+}
+
+func (b ConstantBackoff) NextDelay(int) time.Duration {
+	return time.Duration(jitter(float64(b.Delay), b.Jitter))
+}
+
+func (b ConstantBackoff) MaxAttempts() int {
+	return b.Attempts
+}
+
+// jitter scales the delay by a random factor in [1-fraction, 1+fraction], with fraction clamped to [0, 1].
+func jitter(delay float64, fraction float64) float64 {
+	if fraction <= 0 {
+		return delay
+	}
+	fraction = math.Min(fraction, 1)
+	return delay * (1 - fraction + 2*fraction*rand.Float64())
+}
+
+// sleep is replaced in tests to avoid waiting.
+var sleep = time.Sleep
+
+// Retry runs op until it succeeds, returns an error that retryable rejects, or the attempts of b
+// are exhausted; the last error is returned. A nil retryable retries every error, and a nil b
+// runs op once.
+func Retry(b Backoff, retryable func(error) bool, op func() error) error {
+	return retry(b, retryable, op, nil, func(d time.Duration) error {
+		sleep(d)
+		return nil
+	})
+}
+
+// sleepContext waits for d or until ctx is done, returning ctx.Err() in that case. It is replaced in tests.
+var sleepContext = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RetryNotify behaves like Retry, but calls notify, when not nil, with the error, the failed attempt and
+// the delay before every retry, and stops waiting with ctx.Err() once ctx is done.
+func RetryNotify(ctx context.Context, b Backoff, retryable func(error) bool, op func() error, notify func(err error, attempt int, delay time.Duration)) error {
+	return retry(b, retryable, op, notify, func(d time.Duration) error {
+		return sleepContext(ctx, d)
+	})
+}
+
+// retry is the loop shared by Retry and RetryNotify; wait returning an error stops it with that error.
+func retry(b Backoff, retryable func(error) bool, op func() error, notify func(err error, attempt int, delay time.Duration), wait func(time.Duration) error) error {
+	if b == nil {
+		return op()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || (retryable != nil && !retryable(err)) {
+			return err
+		}
+		if limit := b.MaxAttempts(); limit > 0 && attempt >= limit {
+			return err
+		}
+		delay := b.NextDelay(attempt)
+		if notify != nil {
+			notify(err, attempt, delay)
+		}
+		if err := wait(delay); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestExponentialBackoff verifies the growth of the delay, the cap, and that jittered delays stay within bounds.
+func TestExponentialBackoff(t *testing.T) {
+	b := ExponentialBackoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2, Attempts: 5}
+
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, want := range expected {
+		if got := b.NextDelay(i + 1); got != want {
+			t.Errorf("attempt %d: expected %v, got %v", i+1, want, got)
+		}
+	}
+	if b.MaxAttempts() != 5 {
+		t.Errorf("expected 5 attempts, got %d", b.MaxAttempts())
+	}
+
+	if got := (ExponentialBackoff{}).NextDelay(3); got != 400*time.Millisecond {
+		t.Errorf("expected the defaults to give 400ms on the third attempt, got %v", got)
+	}
+
+	b.Jitter = 0.5
+	for i := 0; i < 1000; i++ {
+		if got := b.NextDelay(2); got < 100*time.Millisecond || got > 300*time.Millisecond {
+			t.Fatalf("jittered delay %v outside [100ms, 300ms]", got)
+		}
+		if got := b.NextDelay(4); got < 400*time.Millisecond || got > time.Second {
+			t.Fatalf("jittered delay %v outside [400ms, 1s]", got)
+		}
+	}
+}
+
+// TestConstantBackoff verifies the fixed delay and the jitter bounds.
+func TestConstantBackoff(t *testing.T) {
+	b := ConstantBackoff{Delay: time.Second, Attempts: 3}
+	for attempt := 1; attempt <= 5; attempt++ {
+		if got := b.NextDelay(attempt); got != time.Second {
+			t.Errorf("attempt %d: expected 1s, got %v", attempt, got)
+		}
+	}
+
+	b.Jitter = 2 // clamped to 1
+	seen := map[bool]bool{}
+	for i := 0; i < 1000; i++ {
+		got := b.NextDelay(1)
+		if got < 0 || got > 2*time.Second {
+			t.Fatalf("jittered delay %v outside [0, 2s]", got)
+		}
+		seen[got > time.Second] = true
+	}
+	if !seen[true] || !seen[false] {
+		t.Error("expected jittered delays on both sides of the base delay")
+	}
+}
+
+// TestRetry verifies the number of attempts, the delays waited and that non-retryable errors stop immediately.
+func TestRetry(t *testing.T) {
+	var waited []time.Duration
+	sleep = func(d time.Duration) { waited = append(waited, d) }
+	defer func() { sleep = time.Sleep }()
+
+	errTransient := errors.New("transient")
+	errPermanent := errors.New("permanent")
+	retryable := func(err error) bool { return errors.Is(err, errTransient) }
+
+	calls := 0
+	err := Retry(ConstantBackoff{Delay: time.Millisecond, Attempts: 3}, retryable, func() error {
+		calls++
+		return errTransient
+	})
+	if !errors.Is(err, errTransient) || calls != 3 || len(waited) != 2 {
+		t.Errorf("expected 3 calls and 2 waits ending in the transient error, got %d calls, %d waits, %v", calls, len(waited), err)
+	}
+
+	calls = 0
+	err = Retry(ConstantBackoff{Delay: time.Millisecond}, retryable, func() error {
+		calls++
+		if calls < 4 {
+			return errTransient
+		}
+		return nil
+	})
+	if err != nil || calls != 4 {
+		t.Errorf("expected success on the fourth call, got %d calls, %v", calls, err)
+	}
+
+	calls = 0
+	err = Retry(ConstantBackoff{Delay: time.Millisecond, Attempts: 3}, retryable, func() error {
+		calls++
+		return errPermanent
+	})
+	if !errors.Is(err, errPermanent) || calls != 1 {
+		t.Errorf("expected a single call for a permanent error, got %d calls, %v", calls, err)
+	}
+
+	calls = 0
+	if err := Retry(nil, nil, func() error { calls++; return errTransient }); !errors.Is(err, errTransient) || calls != 1 {
+		t.Errorf("expected a single call without a backoff, got %d calls, %v", calls, err)
+	}
+}
+
+// TestRetryNotify verifies that every retry is notified with its delay and that a done context stops the wait.
+func TestRetryNotify(t *testing.T) {
+	errTransient := errors.New("transient")
+	var notified []int
+	calls := 0
+	err := RetryNotify(context.Background(), ConstantBackoff{Delay: time.Millisecond, Attempts: 3}, nil, func() error {
+		calls++
+		return errTransient
+	}, func(err error, attempt int, delay time.Duration) {
+		if !errors.Is(err, errTransient) || delay != time.Millisecond {
+			t.Errorf("unexpected notification: %v, %v", err, delay)
+		}
+		notified = append(notified, attempt)
+	})
+	if !errors.Is(err, errTransient) || calls != 3 || fmt.Sprint(notified) != "[1 2]" {
+		t.Errorf("expected 3 calls and 2 notifications, got %d calls, %v, %v", calls, notified, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls = 0
+	start := time.Now()
+	err = RetryNotify(ctx, ConstantBackoff{Delay: time.Hour}, nil, func() error {
+		calls++
+		return errTransient
+	}, func(error, int, time.Duration) { cancel() })
+	if !errors.Is(err, context.Canceled) || calls != 1 || time.Since(start) > time.Second {
+		t.Errorf("expected the cancelled wait to stop the retries, got %d calls, %v", calls, err)
+	}
+}
@@ -1,33 +1,88 @@
 package compute
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/diegoyosiura/cloud-manager/pkg/backoff"
+	"github.com/diegoyosiura/cloud-manager/pkg/observer"
 	"github.com/oracle/oci-go-sdk/v65/common"
+	"sync"
 )
 
 // AWSManager provides functionality for managing AWS VPCs and their lifecycle states.
 // It abstracts AWS SDK interactions, enabling listing, creating, deleting, and retrieving VPCs.
 type AWSManager struct {
 	Auth   *authentication.AWSAuth // Stores AWS authentication and session configurations.
-	Ec2Svc *ec2.EC2                // AWS EC2 Service client for managing VPCs.
+	Ec2Svc *ec2.EC2                // EC2 client for the default region (optional); built from Auth.Session when nil.
+	Pricer Pricer                  // Populates VPC.CostEstimate when set (optional).
+
+	Backoff  backoff.Backoff   // Retries Start, Stop and Restart on throttling or server errors when set (optional).
+	Observer observer.Observer // Receives the API call, duration and retry metrics when set (optional).
+
+	clients sync.Map // Region -> *regionClient, built from Auth.Session on first use.
+}
+
+// regionClient lazily holds the EC2 client of one region, created exactly once.
+type regionClient struct {
+	once sync.Once
+	svc  *ec2.EC2
+}
+
+// client returns the EC2 client for region, creating it from the shared session on first use.
+// An empty region (or Auth.Region) selects the default region, served by Ec2Svc when it is set.
+// It is safe for concurrent use.
+func (m *AWSManager) client(region string) *ec2.EC2 {
+	if region == "" || region == m.Auth.Region {
+		if m.Ec2Svc != nil {
+			return m.Ec2Svc
+		}
+		region = m.Auth.Region
+	}
+
+	value, _ := m.clients.LoadOrStore(region, &regionClient{})
+	c := value.(*regionClient)
+	c.once.Do(func() {
+		config := aws.NewConfig()
+		if region != "" {
+			config = config.WithRegion(region)
+		}
+		c.svc = ec2.New(m.Auth.Session, config)
+	})
+	return c.svc
+}
+
+// requestRegion returns the region requested through fields["aws_region"], or an empty string for the default region.
+func requestRegion(fields map[string]interface{}) string {
+	region, _ := fields["aws_region"].(string)
+	return region
 }
 
-// ListVPCs retrieves a list of VPCs filtered by lifecycle state and additional custom parameters.
+// WithPricing sets the Pricer used to populate the cost estimate of the VPCs returned by ListVPCs and GetVPC.
+func (m *AWSManager) WithPricing(p Pricer) {
+	m.Pricer = p
+}
+
+// ListVPCsCtx retrieves a list of VPCs filtered by lifecycle state and additional custom parameters.
 // Parameters:
 //   - fields: A map (`map[string]interface{}`) containing optional filters for the request.
 //   - instanceStateCode: A string representing the lifecycle state of instances (e.g., "running", "stopped").
 //
+// Every page is read unless fields["max_results"] (an int) caps the number of instances collected.
+// fields["aws_region"] (a string) lists another region than the one of the session.
+//
 // Returns:
 //   - A slice of `VPC` objects that match the inputs.
-//   - An error if the operation fails.
-func (m *AWSManager) ListVPCs(fields map[string]interface{}, instanceStateCode string) ([]VPC, error) {
-	// Lazily initialize Ec2Svc if not already set
-	if m.Ec2Svc == nil {
-		m.Ec2Svc = ec2.New(m.Auth.Session)
+//   - An error if the operation fails, or ErrTruncated with the capped slice when more instances exist.
+func (m *AWSManager) ListVPCsCtx(ctx context.Context, fields map[string]interface{}, instanceStateCode string) ([]VPC, error) {
+	if err := m.Auth.EnsureValid(); err != nil {
+		return nil, err
 	}
+	svc := m.client(requestRegion(fields))
 
 	// Convert the fields map to AWS DescribeInstancesInput
 	input := convertMapDescribeInstancesInput(fields)
@@ -40,18 +95,38 @@ func (m *AWSManager) ListVPCs(fields map[string]interface{}, instanceStateCode s
 		})
 	}
 
-	// Describe instances through AWS SDK
-	result, err := m.Ec2Svc.DescribeInstances(input)
+	// Describe instances through AWS SDK, page by page, converting them into custom VPC objects
+	// until the optional "max_results" cap is reached
+	limit := maxResults(fields)
+	truncated := false
+	var response []VPC
+	err := observer.Call(m.Observer, "aws", "DescribeInstances", func() error {
+		return svc.DescribeInstancesPagesWithContext(ctx, input, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, reservation := range page.Reservations {
+				for _, instance := range reservation.Instances {
+					if limit > 0 && len(response) == limit {
+						truncated = true
+						return false
+					}
+					response = append(response, AWSInstanceToVPC(instance))
+				}
+			}
+			if limit > 0 && len(response) == limit && !lastPage {
+				truncated = true
+				return false
+			}
+			return true
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert AWS instance data into custom VPC objects
-	var response []VPC
-	for _, reservation := range result.Reservations {
-		for _, instance := range reservation.Instances {
-			response = append(response, AWSInstanceToVPC(instance))
-		}
+	if err := applyPricing(m.Pricer, response); err != nil {
+		return nil, err
+	}
+	if truncated {
+		return response, ErrTruncated
 	}
 	return response, nil
 }
@@ -72,130 +147,305 @@ func convertMapDescribeInstancesInput(fields map[string]interface{}) *ec2.Descri
 	return &ec2.DescribeInstancesInput{}
 }
 
-// ListRunningVPCs retrieves a list of VPCs with instances in the "running" state.
+// ListRunningVPCsCtx retrieves a list of VPCs with instances in the "running" state.
 // Parameters:
 //   - fields: A map (`map[string]interface{}`) containing optional filters for the request.
 //
 // Returns:
 //   - A slice of `VPC` objects.
 //   - An error if the operation fails.
-func (m *AWSManager) ListRunningVPCs(fields map[string]interface{}) ([]VPC, error) {
-	return m.ListVPCs(fields, "running")
+func (m *AWSManager) ListRunningVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, "running")
 }
 
-// ListStartingVPCs retrieves a list of VPCs with instances in the "pending" (starting) state.
+// ListStartingVPCsCtx retrieves a list of VPCs with instances in the "pending" (starting) state.
 // Parameters:
 //   - fields: A map (`map[string]interface{}`) containing optional filters for the request.
 //
 // Returns:
 //   - A slice of `VPC` objects.
 //   - An error if the operation fails.
-func (m *AWSManager) ListStartingVPCs(fields map[string]interface{}) ([]VPC, error) {
-	return m.ListVPCs(fields, "pending")
+func (m *AWSManager) ListStartingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, "pending")
 }
 
-// ListStoppingVPCs retrieves a list of VPCs with instances in the "stopping" state.
+// ListStoppingVPCsCtx retrieves a list of VPCs with instances in the "stopping" state.
 // Parameters:
 //   - fields: A map (`map[string]interface{}`) containing optional filters for the request.
 //
 // Returns:
 //   - A slice of `VPC` objects.
 //   - An error if the operation fails.
-func (m *AWSManager) ListStoppingVPCs(fields map[string]interface{}) ([]VPC, error) {
-	return m.ListVPCs(fields, "stopping")
+func (m *AWSManager) ListStoppingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, "stopping")
 }
 
-// ListStoppedVPCs retrieves a list of VPCs with instances in the "stopped" state.
+// ListStoppedVPCsCtx retrieves a list of VPCs with instances in the "stopped" state.
 // Parameters:
 //   - fields: A map (`map[string]interface{}`) containing optional filters for the request.
 //
 // Returns:
 //   - A slice of `VPC` objects.
 //   - An error if the operation fails.
-func (m *AWSManager) ListStoppedVPCs(fields map[string]interface{}) ([]VPC, error) {
-	return m.ListVPCs(fields, "stopped")
+func (m *AWSManager) ListStoppedVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, "stopped")
 }
 
-// ListCreatingVPCs retrieves a list of VPCs with instances in the "pending" (creating) state.
+// ListCreatingVPCsCtx retrieves a list of VPCs with instances in the "pending" (creating) state.
 // Parameters:
 //   - fields: A map (`map[string]interface{}`) containing optional filters for the request.
 //
 // Returns:
 //   - A slice of `VPC` objects.
 //   - An error if the operation fails.
-func (m *AWSManager) ListCreatingVPCs(fields map[string]interface{}) ([]VPC, error) {
-	return m.ListVPCs(fields, "pending")
+func (m *AWSManager) ListCreatingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, "pending")
 }
 
-// ListDeletingVPCs retrieves a list of VPCs with instances in the "pending" (deleting) state.
+// ListDeletingVPCsCtx retrieves a list of VPCs with instances in the "pending" (deleting) state.
 // Parameters:
 //   - fields: A map (`map[string]interface{}`) containing optional filters for the request.
 //
 // Returns:
 //   - A slice of `VPC` objects.
 //   - An error if the operation fails.
-func (m *AWSManager) ListDeletingVPCs(fields map[string]interface{}) ([]VPC, error) {
-	return m.ListVPCs(fields, "pending")
+func (m *AWSManager) ListDeletingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, "pending")
 }
 
-// ListDeletedVPCs retrieves a list of VPCs with instances in the "terminated" (deleted) state.
+// ListDeletedVPCsCtx retrieves a list of VPCs with instances in the "terminated" (deleted) state.
 // Parameters:
 //   - fields: A map (`map[string]interface{}`) containing optional filters for the request.
 //
 // Returns:
 //   - A slice of `VPC` objects.
 //   - An error if the operation fails.
-func (m *AWSManager) ListDeletedVPCs(fields map[string]interface{}) ([]VPC, error) {
-	return m.ListVPCs(fields, "terminated")
+func (m *AWSManager) ListDeletedVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, "terminated")
 }
 
-// ListAllVPCs retrieves a list of all VPCs, regardless of lifecycle state.
+// ListAllVPCsCtx retrieves a list of all VPCs, regardless of lifecycle state.
 // Parameters:
 //   - fields: A map (`map[string]interface{}`) containing optional filters for the request.
 //
 // Returns:
 //   - A slice of `VPC` objects.
 //   - An error if the operation fails.
-func (m *AWSManager) ListAllVPCs(fields map[string]interface{}) ([]VPC, error) {
-	return m.ListVPCs(fields, "")
+func (m *AWSManager) ListAllVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, "")
 }
 
-// CreateVPC creates a new VPC with the specified name and CIDR block.
+// ListByShapeCtx retrieves the VPCs whose instance type matches shape (e.g., "t3.micro"),
+// using a server-side "instance-type" filter.
 // Parameters:
-//   - name: The name of the VPC to create.
-//   - cidr: The CIDR block for the new VPC.
+//   - shape: The EC2 instance type to match.
+//   - fields: A map (`map[string]interface{}`) containing optional filters for the request.
 //
 // Returns:
-//   - A `VPC` object representing the created VPC (placeholder).
+//   - A slice of `VPC` objects of the given instance type.
 //   - An error if the operation fails.
-func (m *AWSManager) CreateVPC(name, cidr string) (*VPC, error) {
-	return &VPC{}, nil
+func (m *AWSManager) ListByShapeCtx(ctx context.Context, shape string, fields map[string]interface{}) ([]VPC, error) {
+	// Copy the caller's input so the extra filter does not leak into it
+	input := *convertMapDescribeInstancesInput(fields)
+	input.Filters = append(append([]*ec2.Filter{}, input.Filters...), &ec2.Filter{
+		Name:   aws.String("instance-type"),
+		Values: []*string{aws.String(shape)},
+	})
+
+	listFields := map[string]interface{}{"aws_describe_instances_input": &input}
+	for _, key := range []string{"max_results", "aws_region"} {
+		if value, ok := fields[key]; ok {
+			listFields[key] = value
+		}
+	}
+	return m.ListVPCsCtx(ctx, listFields, "")
 }
 
-// DeleteVPC deletes a VPC with the specified ID.
+// CreateVPCCtx creates a new VPC with the specified name and CIDR block.
+// The VPC is created in the default region and tagged with the name ("Name" tag).
+// Parameters:
+//   - name: The name of the VPC to create.
+//   - cidr: The IPv4 CIDR block for the new VPC (e.g., "10.0.0.0/16").
+//
+// Returns:
+//   - A `VPC` object representing the created VPC, with its ID, CIDR block, region and state.
+//   - An error if the operation fails, wrapping the AWS error (e.g., an invalid or overlapping CIDR block).
+func (m *AWSManager) CreateVPCCtx(ctx context.Context, name, cidr string) (*VPC, error) {
+	if err := m.Auth.EnsureValid(); err != nil {
+		return nil, err
+	}
+	svc := m.client("")
+
+	var output *ec2.CreateVpcOutput
+	err := observer.Call(m.Observer, "aws", "CreateVpc", func() (err error) {
+		output, err = svc.CreateVpcWithContext(ctx, &ec2.CreateVpcInput{CidrBlock: aws.String(cidr)})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS VPC '%s' with CIDR block '%s': %w", name, cidr, err)
+	}
+
+	// Tag the VPC with its name; the VPC is returned along with the error if tagging fails, so it can be cleaned up
+	vpc := AWSVpcToVPC(output.Vpc, name, aws.StringValue(svc.Config.Region))
+	_, err = svc.CreateTagsWithContext(ctx, &ec2.CreateTagsInput{
+		Resources: []*string{output.Vpc.VpcId},
+		Tags:      []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String(name)}},
+	})
+	if err != nil {
+		return &vpc, fmt.Errorf("failed to tag AWS VPC '%s' with name '%s': %w", vpc.ID, name, err)
+	}
+
+	return &vpc, nil
+}
+
+// VPCDependencyError is returned when a VPC cannot be deleted because resources still depend on it.
+// It matches ErrVPCHasDependencies with errors.Is and unwraps to the provider error.
+type VPCDependencyError struct {
+	VPCID             string   // ID of the VPC that could not be deleted.
+	Subnets           []string // IDs of the subnets of the VPC.
+	NetworkInterfaces []string // IDs of the network interfaces in the VPC.
+	Err               error    // Error returned by the provider.
+}
+
+func (e *VPCDependencyError) Error() string {
+	return fmt.Sprintf("VPC '%s' has dependencies (subnets %v, network interfaces %v): %v", e.VPCID, e.Subnets, e.NetworkInterfaces, e.Err)
+}
+
+func (e *VPCDependencyError) Is(target error) bool {
+	return target == ErrVPCHasDependencies
+}
+
+func (e *VPCDependencyError) Unwrap() error {
+	return e.Err
+}
+
+// DeleteVPCCtx deletes a VPC with the specified ID, such as one returned by CreateVPC.
 // Parameters:
 //   - id: The ID of the VPC to delete.
 //
 // Returns:
-//   - An error if the operation fails (placeholder implementation).
-func (m *AWSManager) DeleteVPC(id string) error {
+//   - An error if the operation fails; a *VPCDependencyError (matching ErrVPCHasDependencies) listing the
+//     subnets and network interfaces of the VPC when AWS refuses the deletion because of its dependencies.
+func (m *AWSManager) DeleteVPCCtx(ctx context.Context, id string) error {
+	return m.DeleteVPCWithDependenciesCtx(ctx, id, false)
+}
+
+// TerminateInstanceCtx terminates the instance with the specified ID, the kind of ID GetVPC, Start and
+// Stop take.
+// Parameters:
+//   - id: The ID of the instance to terminate.
+//
+// Returns:
+//   - An error if the operation fails, wrapping the AWS error (e.g. InvalidInstanceID.NotFound).
+func (m *AWSManager) TerminateInstanceCtx(ctx context.Context, id string) error {
+	if err := m.Auth.EnsureValid(); err != nil {
+		return err
+	}
+	err := observer.Call(m.Observer, "aws", "TerminateInstances", func() error {
+		_, err := m.client("").TerminateInstancesWithContext(ctx, &ec2.TerminateInstancesInput{InstanceIds: []*string{aws.String(id)}})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to terminate AWS instance '%s': %w", id, err)
+	}
 	return nil
 }
 
-// GetVPC retrieves the details of a VPC with the specified ID.
+// DeleteVPCWithDependenciesCtx deletes a VPC with the specified ID, first deleting its network interfaces
+// and subnets when force is set. Other dependencies (instances, internet gateways, non-default security
+// groups, ...) are not removed: their network interfaces cannot be deleted, so the deletion still fails.
 // Parameters:
-//   - id: The ID of the VPC to retrieve.
+//   - id: The ID of the VPC to delete.
+//   - force: Whether to delete the network interfaces and subnets of the VPC first.
 //
 // Returns:
-//   - A `VPC` object representing the retrieved VPC (placeholder).
-//   - An error if the operation fails.
-func (m *AWSManager) GetVPC(id string) (*VPC, error) {
-	// Lazily initialize Ec2Svc if not already set
-	if m.Ec2Svc == nil {
-		m.Ec2Svc = ec2.New(m.Auth.Session)
+//   - An error if the operation fails, as for DeleteVPC.
+func (m *AWSManager) DeleteVPCWithDependenciesCtx(ctx context.Context, id string, force bool) error {
+	if err := m.Auth.EnsureValid(); err != nil {
+		return err
+	}
+	svc := m.client("")
+
+	if force {
+		subnets, interfaces, err := vpcDependencies(ctx, svc, id)
+		if err != nil {
+			return err
+		}
+		for _, eni := range interfaces {
+			if _, err := svc.DeleteNetworkInterfaceWithContext(ctx, &ec2.DeleteNetworkInterfaceInput{NetworkInterfaceId: aws.String(eni)}); err != nil {
+				return fmt.Errorf("failed to delete network interface '%s' of AWS VPC '%s': %w", eni, id, err)
+			}
+		}
+		for _, subnet := range subnets {
+			if _, err := svc.DeleteSubnetWithContext(ctx, &ec2.DeleteSubnetInput{SubnetId: aws.String(subnet)}); err != nil {
+				return fmt.Errorf("failed to delete subnet '%s' of AWS VPC '%s': %w", subnet, id, err)
+			}
+		}
+	}
+
+	err := observer.Call(m.Observer, "aws", "DeleteVpc", func() error {
+		_, err := svc.DeleteVpcWithContext(ctx, &ec2.DeleteVpcInput{VpcId: aws.String(id)})
+		return err
+	})
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == "DependencyViolation" {
+		subnets, interfaces, listErr := vpcDependencies(ctx, svc, id)
+		if listErr != nil {
+			return fmt.Errorf("failed to delete AWS VPC '%s': %w (listing its dependencies also failed: %v)", id, err, listErr)
+		}
+		return &VPCDependencyError{VPCID: id, Subnets: subnets, NetworkInterfaces: interfaces, Err: err}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete AWS VPC '%s': %w", id, err)
+	}
+	return nil
+}
+
+// vpcDependencies returns the IDs of the subnets and network interfaces of the VPC.
+func vpcDependencies(ctx context.Context, svc *ec2.EC2, id string) ([]string, []string, error) {
+	filters := []*ec2.Filter{{Name: aws.String("vpc-id"), Values: []*string{aws.String(id)}}}
+
+	var subnets []string
+	err := svc.DescribeSubnetsPagesWithContext(ctx, &ec2.DescribeSubnetsInput{Filters: filters}, func(page *ec2.DescribeSubnetsOutput, lastPage bool) bool {
+		for _, subnet := range page.Subnets {
+			subnets = append(subnets, aws.StringValue(subnet.SubnetId))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list subnets of AWS VPC '%s': %w", id, err)
 	}
 
-	result, err := m.Ec2Svc.DescribeInstances(&ec2.DescribeInstancesInput{InstanceIds: []*string{&id}})
+	var interfaces []string
+	err = svc.DescribeNetworkInterfacesPagesWithContext(ctx, &ec2.DescribeNetworkInterfacesInput{Filters: filters}, func(page *ec2.DescribeNetworkInterfacesOutput, lastPage bool) bool {
+		for _, eni := range page.NetworkInterfaces {
+			interfaces = append(interfaces, aws.StringValue(eni.NetworkInterfaceId))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list network interfaces of AWS VPC '%s': %w", id, err)
+	}
+
+	return subnets, interfaces, nil
+}
+
+// GetVPCCtx retrieves the details of the instance with the specified ID.
+// Parameters:
+//   - id: The ID of the instance to retrieve.
+//
+// Returns:
+//   - A `VPC` object representing the instance, priced by the Pricer when set.
+//   - An error if the operation fails, or if DescribeInstances does not return exactly one instance.
+func (m *AWSManager) GetVPCCtx(ctx context.Context, id string) (*VPC, error) {
+	if err := m.Auth.EnsureValid(); err != nil {
+		return nil, err
+	}
+	var result *ec2.DescribeInstancesOutput
+	err := observer.Call(m.Observer, "aws", "DescribeInstances", func() (err error) {
+		result, err = m.client("").DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{InstanceIds: []*string{&id}})
+		return err
+	})
 
 	if err != nil {
 		return nil, err
@@ -211,31 +461,186 @@ func (m *AWSManager) GetVPC(id string) (*VPC, error) {
 	if len(response) != 1 {
 		return nil, errors.New("invalid instance count")
 	}
+	if err := applyPricing(m.Pricer, response); err != nil {
+		return nil, err
+	}
 	return &response[0], nil
 }
-func (m *AWSManager) Start(id string) (*VPC, error) {
-	request, _ := m.Ec2Svc.StartInstancesRequest(&ec2.StartInstancesInput{InstanceIds: []*string{&id}})
-	err := request.Send()
+func (m *AWSManager) StartCtx(ctx context.Context, id string) (*VPC, error) {
+	err := backoff.Retry(m.Backoff, retryable(ctx), observer.Retrying(m.Observer, "aws", "StartInstances", func() error {
+		if err := m.Auth.EnsureValid(); err != nil {
+			return err
+		}
+		request, _ := m.client("").StartInstancesRequest(&ec2.StartInstancesInput{InstanceIds: []*string{&id}})
+		request.SetContext(ctx)
+		return request.Send()
+	}))
 	if err != nil {
 		return nil, err
 	}
-	return m.GetVPC(id)
+	return m.GetVPCCtx(ctx, id)
 }
 
-func (m *AWSManager) Stop(id string) (*VPC, error) {
-	request, _ := m.Ec2Svc.StopInstancesRequest(&ec2.StopInstancesInput{InstanceIds: []*string{&id}})
-	err := request.Send()
+func (m *AWSManager) StopCtx(ctx context.Context, id string) (*VPC, error) {
+	err := backoff.Retry(m.Backoff, retryable(ctx), observer.Retrying(m.Observer, "aws", "StopInstances", func() error {
+		if err := m.Auth.EnsureValid(); err != nil {
+			return err
+		}
+		request, _ := m.client("").StopInstancesRequest(&ec2.StopInstancesInput{InstanceIds: []*string{&id}})
+		request.SetContext(ctx)
+		return request.Send()
+	}))
 	if err != nil {
 		return nil, err
 	}
-	return m.GetVPC(id)
+	return m.GetVPCCtx(ctx, id)
 }
 
-func (m *AWSManager) Restart(id string) (*VPC, error) {
-	request, _ := m.Ec2Svc.RebootInstancesRequest(&ec2.RebootInstancesInput{InstanceIds: []*string{&id}})
-	err := request.Send()
+func (m *AWSManager) RestartCtx(ctx context.Context, id string) (*VPC, error) {
+	err := backoff.Retry(m.Backoff, retryable(ctx), observer.Retrying(m.Observer, "aws", "RebootInstances", func() error {
+		if err := m.Auth.EnsureValid(); err != nil {
+			return err
+		}
+		request, _ := m.client("").RebootInstancesRequest(&ec2.RebootInstancesInput{InstanceIds: []*string{&id}})
+		request.SetContext(ctx)
+		return request.Send()
+	}))
 	if err != nil {
 		return nil, err
 	}
-	return m.GetVPC(id)
+	return m.GetVPCCtx(ctx, id)
+}
+
+// ResizeCtx changes the instance type of the instance to newType and returns its refreshed state.
+// EC2 only changes the type of stopped instances, so a running instance is stopped, waited for until
+// it is stopped, modified and started again. When EC2 rejects the new type (e.g. another architecture,
+// or one not offered in the availability zone), its error is returned and a stopped instance that was
+// running is started again with its original type.
+func (m *AWSManager) ResizeCtx(ctx context.Context, id, newType string) (*VPC, error) {
+	if newType == "" {
+		return nil, errors.New("instance type is required")
+	}
+	vpc, err := m.GetVPCCtx(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	state := ""
+	if instance, ok := vpc.ProviderSpecific.(*ec2.Instance); ok && instance.State != nil {
+		state = aws.StringValue(instance.State.Name)
+	}
+
+	running := state == ec2.InstanceStateNameRunning || state == ec2.InstanceStateNamePending
+	if running {
+		if _, err := m.StopCtx(ctx, id); err != nil {
+			return nil, fmt.Errorf("failed to stop instance %s before resizing: %w", id, err)
+		}
+	}
+	if running || state == ec2.InstanceStateNameStopping {
+		err := m.client("").WaitUntilInstanceStoppedWithContext(ctx, &ec2.DescribeInstancesInput{InstanceIds: []*string{&id}})
+		if err != nil {
+			return nil, fmt.Errorf("failed waiting for instance %s to stop: %w", id, err)
+		}
+	}
+
+	err = backoff.Retry(m.Backoff, retryable(ctx), observer.Retrying(m.Observer, "aws", "ModifyInstanceAttribute", func() error {
+		if err := m.Auth.EnsureValid(); err != nil {
+			return err
+		}
+		_, err := m.client("").ModifyInstanceAttributeWithContext(ctx, &ec2.ModifyInstanceAttributeInput{
+			InstanceId:   &id,
+			InstanceType: &ec2.AttributeValue{Value: &newType},
+		})
+		return err
+	}))
+	if err != nil {
+		err = fmt.Errorf("failed to change the type of instance %s to %s: %w", id, newType, err)
+		if running {
+			if _, startErr := m.StartCtx(ctx, id); startErr != nil {
+				return nil, errors.Join(err, fmt.Errorf("failed to restart instance %s: %w", id, startErr))
+			}
+		}
+		return nil, err
+	}
+
+	if running {
+		return m.StartCtx(ctx, id)
+	}
+	return m.GetVPCCtx(ctx, id)
+}
+
+// The methods below run their Ctx variant with context.Background(), for callers that cannot cancel them.
+func (m *AWSManager) ListVPCs(fields map[string]interface{}, instanceStateCode string) ([]VPC, error) {
+	return m.ListVPCsCtx(context.Background(), fields, instanceStateCode)
+}
+
+func (m *AWSManager) DeleteVPCWithDependencies(id string, force bool) error {
+	return m.DeleteVPCWithDependenciesCtx(context.Background(), id, force)
+}
+
+func (m *AWSManager) TerminateInstance(id string) error {
+	return m.TerminateInstanceCtx(context.Background(), id)
+}
+
+func (m *AWSManager) ListRunningVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListRunningVPCsCtx(context.Background(), fields)
+}
+
+func (m *AWSManager) ListStartingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListStartingVPCsCtx(context.Background(), fields)
+}
+
+func (m *AWSManager) ListStoppingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListStoppingVPCsCtx(context.Background(), fields)
+}
+
+func (m *AWSManager) ListStoppedVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListStoppedVPCsCtx(context.Background(), fields)
+}
+
+func (m *AWSManager) ListCreatingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListCreatingVPCsCtx(context.Background(), fields)
+}
+
+func (m *AWSManager) ListDeletingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListDeletingVPCsCtx(context.Background(), fields)
+}
+
+func (m *AWSManager) ListDeletedVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListDeletedVPCsCtx(context.Background(), fields)
+}
+
+func (m *AWSManager) ListAllVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListAllVPCsCtx(context.Background(), fields)
+}
+
+func (m *AWSManager) ListByShape(shape string, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListByShapeCtx(context.Background(), shape, fields)
+}
+
+func (m *AWSManager) CreateVPC(name, cidr string) (*VPC, error) {
+	return m.CreateVPCCtx(context.Background(), name, cidr)
+}
+
+func (m *AWSManager) DeleteVPC(id string) error {
+	return m.DeleteVPCCtx(context.Background(), id)
+}
+
+func (m *AWSManager) GetVPC(id string) (*VPC, error) {
+	return m.GetVPCCtx(context.Background(), id)
+}
+
+func (m *AWSManager) Start(id string) (*VPC, error) {
+	return m.StartCtx(context.Background(), id)
+}
+
+func (m *AWSManager) Stop(id string) (*VPC, error) {
+	return m.StopCtx(context.Background(), id)
+}
+
+func (m *AWSManager) Restart(id string) (*VPC, error) {
+	return m.RestartCtx(context.Background(), id)
+}
+
+func (m *AWSManager) Resize(id, newType string) (*VPC, error) {
+	return m.ResizeCtx(context.Background(), id, newType)
 }
@@ -0,0 +1,528 @@
+package compute
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/diegoyosiura/cloud-manager/pkg/backoff"
+	"github.com/diegoyosiura/cloud-manager/pkg/observer"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEC2Instance is an instance served by the fake EC2 endpoint.
+type fakeEC2Instance struct {
+	ID    string
+	Type  string
+	State string
+	Tags  map[string]string
+}
+
+// newTestAWSManager returns an AWSManager whose EC2 client talks to the given fake endpoint.
+func newTestAWSManager(t *testing.T, endpoint string) *AWSManager {
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(endpoint),
+		Credentials: credentials.NewStaticCredentials("test-key", "test-secret", ""),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating session: %v", err)
+	}
+
+	return &AWSManager{Auth: &authentication.AWSAuth{Session: sess, Region: "us-east-1"}}
+}
+
+// fakeEC2Handler serves DescribeInstances, honoring the "instance-type" filter.
+func fakeEC2Handler(instances []fakeEC2Instance) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+
+		instanceType := ""
+		for key, values := range r.Form {
+			if strings.HasPrefix(key, "Filter.") && strings.HasSuffix(key, ".Name") && values[0] == "instance-type" {
+				instanceType = r.Form.Get(strings.TrimSuffix(key, ".Name") + ".Value.1")
+			}
+		}
+
+		var items strings.Builder
+		for _, i := range instances {
+			if instanceType != "" && i.Type != instanceType {
+				continue
+			}
+			var tags strings.Builder
+			for key, value := range i.Tags {
+				_, _ = fmt.Fprintf(&tags, `<item><key>%s</key><value>%s</value></item>`, key, value)
+			}
+			_, _ = fmt.Fprintf(&items, `<item><instanceId>%s</instanceId><instanceType>%s</instanceType><keyName>key</keyName>`+
+				`<placement><availabilityZone>us-east-1a</availabilityZone></placement>`+
+				`<cpuOptions><coreCount>1</coreCount><threadsPerCore>2</threadsPerCore></cpuOptions>`+
+				`<hypervisor>xen</hypervisor><instanceState><code>16</code><name>%s</name></instanceState>`+
+				`<tagSet>%s</tagSet></item>`, i.ID, i.Type, i.State, tags.String())
+		}
+
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = fmt.Fprintf(w, `<DescribeInstancesResponse><reservationSet><item><instancesSet>%s</instancesSet></item></reservationSet></DescribeInstancesResponse>`, items.String())
+	}
+}
+
+// TestAWSManager_ListByShape verifies that only instances of the requested type are returned.
+func TestAWSManager_ListByShape(t *testing.T) {
+	server := httptest.NewServer(fakeEC2Handler([]fakeEC2Instance{
+		{ID: "i-1", Type: "t3.micro", State: "running"},
+		{ID: "i-2", Type: "m5.large", State: "running"},
+		{ID: "i-3", Type: "t3.micro", State: "stopped"},
+	}))
+	defer server.Close()
+
+	vpcs, err := newTestAWSManager(t, server.URL).ListByShape("t3.micro", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(vpcs) != 2 {
+		t.Fatalf("expected 2 instances, got %d", len(vpcs))
+	}
+	for _, vpc := range vpcs {
+		if vpc.Description != "t3.micro" {
+			t.Errorf("expected only 't3.micro' instances, got '%s' (%s)", vpc.Description, vpc.ID)
+		}
+	}
+}
+
+// TestAWSManager_ListVPCs_MaxResults verifies that pagination follows NextToken and stops at the "max_results" cap.
+func TestAWSManager_ListVPCs_MaxResults(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		requests++
+
+		// Three pages of two instances each, chained by NextToken.
+		page := 1
+		if token := r.Form.Get("NextToken"); token != "" {
+			_, _ = fmt.Sscanf(token, "page-%d", &page)
+		}
+		var items strings.Builder
+		for i := 1; i <= 2; i++ {
+			_, _ = fmt.Fprintf(&items, `<item><instanceId>i-%d-%d</instanceId><instanceType>t3.micro</instanceType><keyName>key</keyName>`+
+				`<placement><availabilityZone>us-east-1a</availabilityZone></placement>`+
+				`<cpuOptions><coreCount>1</coreCount><threadsPerCore>2</threadsPerCore></cpuOptions>`+
+				`<hypervisor>xen</hypervisor><instanceState><code>16</code><name>running</name></instanceState></item>`, page, i)
+		}
+		nextToken := ""
+		if page < 3 {
+			nextToken = fmt.Sprintf("<nextToken>page-%d</nextToken>", page+1)
+		}
+
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = fmt.Fprintf(w, `<DescribeInstancesResponse><reservationSet><item><instancesSet>%s</instancesSet></item></reservationSet>%s</DescribeInstancesResponse>`, items.String(), nextToken)
+	}))
+	defer server.Close()
+
+	manager := newTestAWSManager(t, server.URL)
+
+	vpcs, err := manager.ListAllVPCs(map[string]interface{}{})
+	if err != nil || len(vpcs) != 6 || requests != 3 {
+		t.Errorf("expected 6 instances in 3 requests without a cap, got %d in %d (%v)", len(vpcs), requests, err)
+	}
+
+	requests = 0
+	vpcs, err = manager.ListByShape("t3.micro", map[string]interface{}{"max_results": 3})
+	if !errors.Is(err, ErrTruncated) || len(vpcs) != 3 || requests != 2 {
+		t.Errorf("expected 3 instances in 2 requests and ErrTruncated, got %d in %d (%v)", len(vpcs), requests, err)
+	}
+}
+
+// TestAWSManager_ConcurrentClients hammers ListVPCs and GetVPC concurrently (run with -race) and
+// verifies that each region gets its own client, signing its requests for that region.
+func TestAWSManager_ConcurrentClients(t *testing.T) {
+	handler := fakeEC2Handler([]fakeEC2Instance{{ID: "i-1", Type: "t3.micro", State: "running"}})
+	regions := sync.Map{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The credential scope of the signature is <key>/<date>/<region>/ec2/aws4_request.
+		if scope := strings.Split(r.Header.Get("Authorization"), "/"); len(scope) > 2 {
+			regions.Store(scope[2], true)
+		}
+		handler(w, r)
+	}))
+	defer server.Close()
+
+	manager := newTestAWSManager(t, server.URL)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 60)
+	for i := 0; i < 20; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			_, err := manager.ListAllVPCs(map[string]interface{}{})
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := manager.ListAllVPCs(map[string]interface{}{"aws_region": "eu-west-1"})
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := manager.GetVPC("i-1")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if manager.client("") != manager.client("us-east-1") || manager.client("") == manager.client("eu-west-1") {
+		t.Error("expected one client per region, shared by the default region")
+	}
+	for _, region := range []string{"us-east-1", "eu-west-1"} {
+		if _, ok := regions.Load(region); !ok {
+			t.Errorf("expected requests signed for %s", region)
+		}
+	}
+}
+
+// TestAWSManager_CreateVPC verifies that the VPC is created with the CIDR block, tagged with its name,
+// and that an invalid CIDR block surfaces the AWS error.
+func TestAWSManager_CreateVPC(t *testing.T) {
+	var actions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		actions = append(actions, r.Form.Get("Action"))
+		w.Header().Set("Content-Type", "text/xml")
+
+		switch r.Form.Get("Action") {
+		case "CreateVpc":
+			if r.Form.Get("CidrBlock") != "10.0.0.0/16" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = fmt.Fprintf(w, `<Response><Errors><Error><Code>InvalidVpc.Range</Code><Message>The CIDR '%s' is invalid.</Message></Error></Errors></Response>`, r.Form.Get("CidrBlock"))
+				return
+			}
+			_, _ = fmt.Fprint(w, `<CreateVpcResponse><vpc><vpcId>vpc-123</vpcId><state>pending</state><cidrBlock>10.0.0.0/16</cidrBlock></vpc></CreateVpcResponse>`)
+		case "CreateTags":
+			if r.Form.Get("ResourceId.1") != "vpc-123" || r.Form.Get("Tag.1.Key") != "Name" || r.Form.Get("Tag.1.Value") != "main" {
+				t.Errorf("unexpected CreateTags request: %v", r.Form)
+			}
+			_, _ = fmt.Fprint(w, `<CreateTagsResponse><return>true</return></CreateTagsResponse>`)
+		}
+	}))
+	defer server.Close()
+
+	manager := newTestAWSManager(t, server.URL)
+
+	vpc, err := manager.CreateVPC("main", "10.0.0.0/16")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vpc.ID != "vpc-123" || vpc.Name != "main" || vpc.CidrBlock != "10.0.0.0/16" || vpc.Region != "us-east-1" || vpc.State != VPCStateCreating {
+		t.Errorf("unexpected VPC: %+v", vpc)
+	}
+	if strings.Join(actions, ",") != "CreateVpc,CreateTags" {
+		t.Errorf("expected CreateVpc then CreateTags, got %v", actions)
+	}
+
+	_, err = manager.CreateVPC("main", "10.0.0.0/8")
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) || awsErr.Code() != "InvalidVpc.Range" {
+		t.Errorf("expected the InvalidVpc.Range error, got %v", err)
+	}
+}
+
+// TestAWSManager_DeleteVPC verifies that a dependency violation reports the blocking subnets and network
+// interfaces, and that the forced variant deletes them before the VPC.
+func TestAWSManager_DeleteVPC(t *testing.T) {
+	var mu sync.Mutex
+	var actions []string
+	deleted := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		mu.Lock()
+		defer mu.Unlock()
+		action := r.Form.Get("Action")
+		actions = append(actions, action)
+		w.Header().Set("Content-Type", "text/xml")
+
+		switch action {
+		case "DeleteVpc":
+			if !deleted["subnet-1"] || !deleted["eni-1"] {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = fmt.Fprint(w, `<Response><Errors><Error><Code>DependencyViolation</Code><Message>The vpc 'vpc-1' has dependencies and cannot be deleted.</Message></Error></Errors></Response>`)
+				return
+			}
+			_, _ = fmt.Fprint(w, `<DeleteVpcResponse><return>true</return></DeleteVpcResponse>`)
+		case "DescribeSubnets":
+			if r.Form.Get("Filter.1.Name") != "vpc-id" || r.Form.Get("Filter.1.Value.1") != "vpc-1" {
+				t.Errorf("expected a vpc-id filter, got %v", r.Form)
+			}
+			_, _ = fmt.Fprint(w, `<DescribeSubnetsResponse><subnetSet><item><subnetId>subnet-1</subnetId></item></subnetSet></DescribeSubnetsResponse>`)
+		case "DescribeNetworkInterfaces":
+			_, _ = fmt.Fprint(w, `<DescribeNetworkInterfacesResponse><networkInterfaceSet><item><networkInterfaceId>eni-1</networkInterfaceId></item></networkInterfaceSet></DescribeNetworkInterfacesResponse>`)
+		case "DeleteSubnet":
+			deleted[r.Form.Get("SubnetId")] = true
+			_, _ = fmt.Fprint(w, `<DeleteSubnetResponse><return>true</return></DeleteSubnetResponse>`)
+		case "DeleteNetworkInterface":
+			deleted[r.Form.Get("NetworkInterfaceId")] = true
+			_, _ = fmt.Fprint(w, `<DeleteNetworkInterfaceResponse><return>true</return></DeleteNetworkInterfaceResponse>`)
+		}
+	}))
+	defer server.Close()
+
+	manager := newTestAWSManager(t, server.URL)
+
+	err := manager.DeleteVPC("vpc-1")
+	var depErr *VPCDependencyError
+	if !errors.Is(err, ErrVPCHasDependencies) || !errors.As(err, &depErr) {
+		t.Fatalf("expected a VPCDependencyError, got %v", err)
+	}
+	if fmt.Sprint(depErr.Subnets) != "[subnet-1]" || fmt.Sprint(depErr.NetworkInterfaces) != "[eni-1]" {
+		t.Errorf("unexpected dependencies: %+v", depErr)
+	}
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) || awsErr.Code() != "DependencyViolation" {
+		t.Errorf("expected the error to wrap the AWS error, got %v", err)
+	}
+
+	actions = nil
+	if err := manager.DeleteVPCWithDependencies("vpc-1", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "DescribeSubnets,DescribeNetworkInterfaces,DeleteNetworkInterface,DeleteSubnet,DeleteVpc"
+	if got := strings.Join(actions, ","); got != expected {
+		t.Errorf("expected actions %s, got %s", expected, got)
+	}
+}
+
+// TestAWSManager_TerminateInstance verifies that TerminateInstance terminates the instance with the given ID.
+func TestAWSManager_TerminateInstance(t *testing.T) {
+	var actions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		actions = append(actions, r.Form.Get("Action"))
+		if id := r.Form.Get("InstanceId.1"); id != "i-1" {
+			t.Errorf("expected instance i-1, got %q", id)
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = fmt.Fprint(w, `<TerminateInstancesResponse><instancesSet><item><instanceId>i-1</instanceId></item></instancesSet></TerminateInstancesResponse>`)
+	}))
+	defer server.Close()
+
+	manager := newTestAWSManager(t, server.URL)
+	if err := manager.TerminateInstance("i-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(actions, ","); got != "TerminateInstances" {
+		t.Errorf("expected a single TerminateInstances call, got %s", got)
+	}
+}
+
+// TestAWSManager_Ctx verifies that a done context stops a blocked listing and the retries of Start.
+func TestAWSManager_Ctx(t *testing.T) {
+	release := make(chan struct{})
+	blocked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer blocked.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := newTestAWSManager(t, blocked.URL).ListAllVPCsCtx(ctx, map[string]interface{}{})
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) || awsErr.Code() != request.CanceledErrorCode {
+		t.Errorf("expected a %s error, got %v", request.CanceledErrorCode, err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the listing to stop with the context, took %v", elapsed)
+	}
+
+	// Every attempt is throttled, so Start retries until the context is done.
+	attempts, mu := 0, &sync.Mutex{}
+	throttled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = fmt.Fprint(w, `<Response><Errors><Error><Code>Unavailable</Code><Message>try again</Message></Error></Errors></Response>`)
+	}))
+	defer throttled.Close()
+
+	manager := newTestAWSManager(t, throttled.URL)
+	manager.Auth.Session.Config.MaxRetries = aws.Int(0)
+	manager.Backoff = backoff.ConstantBackoff{Delay: 10 * time.Millisecond}
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := manager.StartCtx(ctx, "i-1"); err == nil {
+		t.Fatal("expected an error once the context is done")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts < 2 {
+		t.Errorf("expected Start to be retried before the context is done, got %d attempts", attempts)
+	}
+}
+
+// TestAWSManager_Observer verifies the observations emitted for a listing.
+func TestAWSManager_Observer(t *testing.T) {
+	server := httptest.NewServer(fakeEC2Handler([]fakeEC2Instance{{ID: "i-1", Type: "t3.micro", State: "running"}}))
+	defer server.Close()
+
+	memory := observer.NewMemory()
+	manager := newTestAWSManager(t, server.URL)
+	manager.Observer = memory
+	if _, err := manager.ListAllVPCs(map[string]interface{}{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	labels := map[string]string{"provider": "aws", "operation": "DescribeInstances"}
+	if got := memory.Counter(observer.APICalls, map[string]string{"provider": "aws", "operation": "DescribeInstances", "outcome": "success"}); got != 1 {
+		t.Errorf("expected 1 successful DescribeInstances call, got %d", got)
+	}
+	if got := len(memory.Durations(observer.APICallDuration, labels)); got != 1 {
+		t.Errorf("expected 1 duration, got %d", got)
+	}
+	if got := memory.Counter(observer.Retries, labels); got != 0 {
+		t.Errorf("expected no retries, got %d", got)
+	}
+}
+
+// TestAWSManager_Resize verifies that a running instance is stopped, modified and started again, and
+// that a rejected type is reported after restarting the instance with its original type.
+func TestAWSManager_Resize(t *testing.T) {
+	var mu sync.Mutex
+	var actions []string
+	state, reject := "running", false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		mu.Lock()
+		defer mu.Unlock()
+		action := r.Form.Get("Action")
+		actions = append(actions, action)
+		w.Header().Set("Content-Type", "text/xml")
+
+		switch action {
+		case "DescribeInstances":
+			fakeEC2Handler([]fakeEC2Instance{{ID: "i-1", Type: "t3.micro", State: state}})(w, r)
+		case "StopInstances":
+			state = "stopped"
+			_, _ = fmt.Fprint(w, `<StopInstancesResponse><instancesSet/></StopInstancesResponse>`)
+		case "StartInstances":
+			state = "running"
+			_, _ = fmt.Fprint(w, `<StartInstancesResponse><instancesSet/></StartInstancesResponse>`)
+		case "ModifyInstanceAttribute":
+			if r.Form.Get("InstanceType.Value") != "t3.large" {
+				t.Errorf("expected the t3.large type, got %v", r.Form)
+			}
+			if reject {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = fmt.Fprint(w, `<Response><Errors><Error><Code>InvalidParameterCombination</Code><Message>incompatible architecture</Message></Error></Errors></Response>`)
+				return
+			}
+			_, _ = fmt.Fprint(w, `<ModifyInstanceAttributeResponse><return>true</return></ModifyInstanceAttributeResponse>`)
+		}
+	}))
+	defer server.Close()
+
+	manager := newTestAWSManager(t, server.URL)
+	if _, err := manager.Resize("i-1", "t3.large"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "DescribeInstances,StopInstances,DescribeInstances,DescribeInstances,ModifyInstanceAttribute,StartInstances,DescribeInstances"
+	if got := strings.Join(actions, ","); got != expected {
+		t.Errorf("expected actions %s, got %s", expected, got)
+	}
+
+	actions, reject = nil, true
+	_, err := manager.Resize("i-1", "t3.large")
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) || awsErr.Code() != "InvalidParameterCombination" {
+		t.Fatalf("expected the InvalidParameterCombination error, got %v", err)
+	}
+	if state != "running" || actions[len(actions)-2] != "StartInstances" {
+		t.Errorf("expected the instance to be started again, got actions %v", actions)
+	}
+}
+
+// TestAWSInstanceToVPC_Tags verifies that the instance tags are exposed on the VPC and survive a JSON
+// round-trip, and that an instance without tags has nil Tags.
+func TestAWSInstanceToVPC_Tags(t *testing.T) {
+	server := httptest.NewServer(fakeEC2Handler([]fakeEC2Instance{
+		{ID: "i-1", Type: "t3.micro", State: "running", Tags: map[string]string{"team": "billing", "Name": "api"}},
+		{ID: "i-2", Type: "t3.micro", State: "running"},
+	}))
+	defer server.Close()
+
+	vpcs, err := newTestAWSManager(t, server.URL).ListByShape("t3.micro", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(vpcs) != 2 {
+		t.Fatalf("expected 2 instances, got %d", len(vpcs))
+	}
+	if vpcs[0].Tags["team"] != "billing" || vpcs[0].Tags["Name"] != "api" || len(vpcs[0].Tags) != 2 {
+		t.Errorf("unexpected tags: %v", vpcs[0].Tags)
+	}
+	if vpcs[1].Tags != nil {
+		t.Errorf("expected nil tags, got %v", vpcs[1].Tags)
+	}
+
+	data, err := json.Marshal(vpcs[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded struct {
+		Tags map[string]string `json:"tags"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil || !reflect.DeepEqual(decoded.Tags, vpcs[0].Tags) {
+		t.Errorf("expected tags %v after the round-trip, got %v (%v)", vpcs[0].Tags, decoded.Tags, err)
+	}
+
+	if vpc := AWSInstanceToVPC(&ec2.Instance{
+		InstanceId: aws.String("i-3"), KeyName: aws.String("key"), InstanceType: aws.String("t3.micro"),
+		Placement:  &ec2.Placement{AvailabilityZone: aws.String("us-east-1a")},
+		CpuOptions: &ec2.CpuOptions{CoreCount: aws.Int64(1), ThreadsPerCore: aws.Int64(1)},
+		Hypervisor: aws.String("xen"), State: &ec2.InstanceState{Name: aws.String("running")},
+		Tags: []*ec2.Tag{nil, {Value: aws.String("no key")}, {Key: aws.String("empty")}},
+	}); !reflect.DeepEqual(vpc.Tags, map[string]string{"empty": ""}) {
+		t.Errorf("expected only the keyed tag, got %v", vpc.Tags)
+	}
+}
+
+// TestAWSInstanceToVPC_Name verifies that the VPC name is the "Name" tag of the instance, falling back
+// to the instance ID, and never the key pair name.
+func TestAWSInstanceToVPC_Name(t *testing.T) {
+	server := httptest.NewServer(fakeEC2Handler([]fakeEC2Instance{
+		{ID: "i-1", Type: "t3.micro", State: "running", Tags: map[string]string{"Name": "api"}},
+		{ID: "i-2", Type: "t3.micro", State: "running", Tags: map[string]string{"team": "billing"}},
+	}))
+	defer server.Close()
+
+	vpcs, err := newTestAWSManager(t, server.URL).ListByShape("t3.micro", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(vpcs) != 2 || vpcs[0].Name != "api" || vpcs[1].Name != "i-2" {
+		t.Errorf("expected the names api and i-2, got %+v", vpcs)
+	}
+}
@@ -1,7 +1,9 @@
 package compute
 
 import (
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"strings"
 )
 
 // AWSInstanceToVPC converts an AWS EC2 Instance object into a generic VPC structure.
@@ -28,13 +30,27 @@ func AWSInstanceToVPC(instance *ec2.Instance) VPC {
 		publicIP = *instance.PublicIpAddress
 	}
 
+	// The region is the availability zone without its letter suffix (e.g. "us-east-1a" -> "us-east-1")
+	availabilityZone := *instance.Placement.AvailabilityZone
+	region := strings.TrimRight(availabilityZone, "abcdefghijklmnopqrstuvwxyz")
+
+	// The instance name is its "Name" tag; instances without one are named after their ID.
+	// The key pair name is still available through ProviderSpecific.
+	tags := awsTagsToMap(instance.Tags)
+	name := tags["Name"]
+	if name == "" {
+		name = aws.StringValue(instance.InstanceId)
+	}
+
 	// Constructing the VPC object
 	vpc := VPC{
-		ID:          *instance.InstanceId,                 // Instance ID
-		Name:        *instance.KeyName,                    // Key name (possibly representing the instance)
-		Region:      *instance.Placement.AvailabilityZone, // The availability zone of the instance
-		Provider:    "aws",                                // Static value "aws" for provider
-		Description: *instance.InstanceType,               // Instance type for its description
+		ID:          *instance.InstanceId,   // Instance ID
+		Name:        name,                   // Value of the "Name" tag, or the instance ID
+		Region:      region,                 // The region of the instance
+		Provider:    "aws",                  // Static value "aws" for provider
+		Description: *instance.InstanceType, // Instance type for its description
+
+		AvailabilityZone: availabilityZone, // The availability zone of the instance
 
 		CPUCount: *instance.CpuOptions.CoreCount, // Number of CPU cores
 		VirtualCPUCount: *instance.CpuOptions.CoreCount * // Total virtual CPUs based on cores and threads per core
@@ -47,6 +63,8 @@ func AWSInstanceToVPC(instance *ec2.Instance) VPC {
 		PrivateIP: privateIP, // Resolved private IP address
 		PublicIP:  publicIP,  // Resolved public IP address
 
+		Tags: tags, // Instance tags keyed by their names
+
 		ProviderSpecific: instance,                                         // Store the original AWS Instance object
 		State:            mapInstanceStateToVPCState(*instance.State.Name), // Map AWS instance state to VPC state
 	}
@@ -54,6 +72,21 @@ func AWSInstanceToVPC(instance *ec2.Instance) VPC {
 	return vpc
 }
 
+// awsTagsToMap converts EC2 tags into a map keyed by the tag name, returning nil when there are no tags.
+func awsTagsToMap(tags []*ec2.Tag) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		if tag == nil || tag.Key == nil {
+			continue
+		}
+		result[*tag.Key] = aws.StringValue(tag.Value)
+	}
+	return result
+}
+
 // mapInstanceStateToVPCState maps the state of an AWS EC2 instance to a generic VPC state.
 // Parameters:
 //   - state: A string representing the state of the AWS instance (e.g., "running", "stopped").
@@ -74,3 +107,36 @@ func mapInstanceStateToVPCState(state string) VPCStateEnum {
 		return VPCStateUnavailable // Default to unavailable for unknown states
 	}
 }
+
+// AWSVpcToVPC converts an AWS EC2 Vpc object into a generic VPC structure.
+// Parameters:
+//   - vpc: A pointer to an AWS EC2 Vpc object.
+//   - name: The name of the VPC (its "Name" tag).
+//   - region: The region the VPC was created in.
+//
+// Returns:
+//   - A VPC object populated with details from the AWS VPC.
+func AWSVpcToVPC(vpc *ec2.Vpc, name, region string) VPC {
+	return VPC{
+		ID:        aws.StringValue(vpc.VpcId),     // VPC ID (e.g., "vpc-0123456789abcdef0")
+		Name:      name,                           // Value of the "Name" tag
+		Region:    region,                         // The region of the VPC
+		Provider:  "aws",                          // Static value "aws" for provider
+		CidrBlock: aws.StringValue(vpc.CidrBlock), // Primary IPv4 CIDR block
+
+		ProviderSpecific: vpc,                                               // Store the original AWS Vpc object
+		State:            mapVpcStateToVPCState(aws.StringValue(vpc.State)), // Map AWS VPC state to VPC state
+	}
+}
+
+// mapVpcStateToVPCState maps the state of an AWS VPC ("pending" or "available") to a generic VPC state.
+func mapVpcStateToVPCState(state string) VPCStateEnum {
+	switch state {
+	case ec2.VpcStatePending:
+		return VPCStateCreating
+	case ec2.VpcStateAvailable:
+		return VPCStateAvailable
+	default:
+		return VPCStateUnavailable
+	}
+}
@@ -0,0 +1,535 @@
+package compute
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/diegoyosiura/cloud-manager/pkg/backoff"
+	"github.com/diegoyosiura/cloud-manager/pkg/observer"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// azureComputeAPIVersion is the Microsoft.Compute API version used by the AzureVirtualMachinesClient.
+const azureComputeAPIVersion = "2024-07-01"
+
+// azureResponseError is an error response of the Azure Resource Manager API.
+type azureResponseError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *azureResponseError) Error() string {
+	return fmt.Sprintf("azure request failed with status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// AzureVirtualMachinesClient calls the Microsoft.Compute virtual machine operations of the Azure
+// Resource Manager REST API for one subscription. It is a thin REST client rather than the armcompute
+// module: the handful of operations used here do not justify that module and its generated models,
+// and the requests are authorized with the azcore credential that AzureAuth already holds.
+type AzureVirtualMachinesClient struct {
+	Credential     azcore.TokenCredential // Credential used to obtain the Resource Manager tokens.
+	SubscriptionID string                 // Subscription whose virtual machines are managed.
+	Endpoint       string                 // Resource Manager endpoint (defaults to https://management.azure.com).
+	HTTPClient     *http.Client           // HTTP client used for the requests (defaults to http.DefaultClient).
+}
+
+// do sends a request to the Resource Manager, with in encoded as the JSON body when not nil, and decodes
+// the JSON response into out, when not nil. target is either a path relative to the endpoint or an
+// absolute URL (e.g., a nextLink).
+func (c *AzureVirtualMachinesClient) do(ctx context.Context, method, target string, query url.Values, in, out interface{}) error {
+	token, err := c.Credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
+	if err != nil {
+		return fmt.Errorf("failed to get Azure token: %w", err)
+	}
+
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		endpoint := c.Endpoint
+		if endpoint == "" {
+			endpoint = "https://management.azure.com"
+		}
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("api-version", azureComputeAPIVersion)
+		target = strings.TrimRight(endpoint, "/") + target + "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var body struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return &azureResponseError{StatusCode: resp.StatusCode, Code: body.Error.Code, Message: body.Error.Message}
+	}
+
+	if out == nil || resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode Azure response: %w", err)
+	}
+	return nil
+}
+
+// ListPages lists the virtual machines of the subscription, or of resourceGroup when not empty,
+// with their power state, calling fn for every page until it returns false.
+func (c *AzureVirtualMachinesClient) ListPages(ctx context.Context, resourceGroup string, fn func(vms []AzureVirtualMachine) bool) error {
+	next := fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Compute/virtualMachines", url.PathEscape(c.SubscriptionID))
+	if resourceGroup != "" {
+		next = fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachines",
+			url.PathEscape(c.SubscriptionID), url.PathEscape(resourceGroup))
+	}
+	query := url.Values{"statusOnly": []string{"true"}}
+
+	for next != "" {
+		var page struct {
+			Value    []AzureVirtualMachine `json:"value"`
+			NextLink string                `json:"nextLink"`
+		}
+		if err := c.do(ctx, http.MethodGet, next, query, nil, &page); err != nil {
+			return err
+		}
+		if !fn(page.Value) {
+			return nil
+		}
+		next, query = page.NextLink, nil
+	}
+	return nil
+}
+
+// Get returns the virtual machine with the given resource ID, including its instance view.
+func (c *AzureVirtualMachinesClient) Get(ctx context.Context, id string) (AzureVirtualMachine, error) {
+	var vm AzureVirtualMachine
+	err := c.do(ctx, http.MethodGet, id, url.Values{"$expand": []string{"instanceView"}}, nil, &vm)
+	return vm, err
+}
+
+// Start starts the virtual machine. The operation is asynchronous and returns once accepted.
+func (c *AzureVirtualMachinesClient) Start(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, id+"/start", nil, nil, nil)
+}
+
+// Deallocate stops the virtual machine and releases its compute resources, which stops their billing.
+func (c *AzureVirtualMachinesClient) Deallocate(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, id+"/deallocate", nil, nil, nil)
+}
+
+// Restart restarts the virtual machine.
+func (c *AzureVirtualMachinesClient) Restart(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, id+"/restart", nil, nil, nil)
+}
+
+// Delete deletes the virtual machine. Its disks and network interfaces are kept unless configured otherwise.
+func (c *AzureVirtualMachinesClient) Delete(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, id, nil, nil, nil)
+}
+
+// Resize changes the size of the virtual machine. Azure restarts a running virtual machine to apply it,
+// and rejects sizes that are not available on its hardware cluster until the machine is deallocated.
+func (c *AzureVirtualMachinesClient) Resize(ctx context.Context, id, size string) error {
+	body := map[string]interface{}{"properties": map[string]interface{}{"hardwareProfile": map[string]string{"vmSize": size}}}
+	return c.do(ctx, http.MethodPatch, id, nil, body, nil)
+}
+
+// ListSizes lists the VM sizes available in the location.
+func (c *AzureVirtualMachinesClient) ListSizes(ctx context.Context, location string) ([]AzureVMSize, error) {
+	var page struct {
+		Value []AzureVMSize `json:"value"`
+	}
+	path := fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Compute/locations/%s/vmSizes", url.PathEscape(c.SubscriptionID), url.PathEscape(location))
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, &page); err != nil {
+		return nil, err
+	}
+	return page.Value, nil
+}
+
+// AzureManager manages Azure virtual machines through the Manager interface.
+type AzureManager struct {
+	Auth   *authentication.AzureAuth   // Azure authentication details.
+	Client *AzureVirtualMachinesClient // Virtual machines client, created from Auth on first use.
+	Pricer Pricer                      // Populates VPC.CostEstimate when set (optional).
+
+	Backoff  backoff.Backoff   // Retries Start, Stop and Restart on throttling or server errors when set (optional).
+	Observer observer.Observer // Receives the API call, duration and retry metrics when set (optional).
+
+	mu    sync.Mutex                        // Guards Client creation in setup and sizes.
+	sizes map[string]map[string]AzureVMSize // VM sizes by location and name, loaded on demand.
+}
+
+// WithPricing sets the Pricer used to populate the cost estimate of the VPCs returned by ListVPCs and GetVPC.
+func (m *AzureManager) WithPricing(p Pricer) {
+	m.Pricer = p
+}
+
+// setup creates the virtual machines client from the authenticated credentials when it is not set.
+func (m *AzureManager) setup() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Client != nil {
+		return nil
+	}
+	if m.Auth == nil || m.Auth.Credential == nil {
+		return errors.New("azure credentials are not initialized; authenticate first")
+	}
+	m.Client = &AzureVirtualMachinesClient{Credential: m.Auth.Credential, SubscriptionID: m.Auth.SubscriptionID}
+	return nil
+}
+
+// vmSize returns the description of the VM size in the location, loading the sizes of the location on first use.
+// It returns nil when the size is not listed for the location.
+func (m *AzureManager) vmSize(ctx context.Context, location, name string) (*AzureVMSize, error) {
+	m.mu.Lock()
+	sizes, ok := m.sizes[location]
+	m.mu.Unlock()
+	if !ok {
+		// The sizes are listed without holding the lock; concurrent first uses of a location may list it twice.
+		list, err := m.Client.ListSizes(ctx, location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list VM sizes of '%s': %w", location, err)
+		}
+		sizes = make(map[string]AzureVMSize, len(list))
+		for _, size := range list {
+			sizes[strings.ToLower(size.Name)] = size
+		}
+		m.mu.Lock()
+		if m.sizes == nil {
+			m.sizes = map[string]map[string]AzureVMSize{}
+		}
+		m.sizes[location] = sizes
+		m.mu.Unlock()
+	}
+
+	if size, ok := sizes[strings.ToLower(name)]; ok {
+		return &size, nil
+	}
+	return nil, nil
+}
+
+// toVPCs converts the virtual machines, filling CPU and memory from their VM sizes, and applies the pricing.
+func (m *AzureManager) toVPCs(ctx context.Context, vms []AzureVirtualMachine) ([]VPC, error) {
+	var response []VPC
+	for _, vm := range vms {
+		size, err := m.vmSize(ctx, vm.Location, vm.Properties.HardwareProfile.VMSize)
+		if err != nil {
+			return nil, err
+		}
+		response = append(response, AzureInstanceToVPC(vm, size))
+	}
+
+	if err := applyPricing(m.Pricer, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// ListVPCsCtx lists the virtual machines accepted by match (every one when match is nil).
+// Parameters:
+//   - fields: A map (`map[string]interface{}`) of optional filters; "azure_resource_group" (a string)
+//     limits the listing to a resource group.
+//   - match: Filter applied to each virtual machine, since Azure cannot filter by state server-side.
+//
+// Every page is read unless fields["max_results"] (an int) caps the number of virtual machines collected.
+//
+// Returns:
+//   - A slice of `VPC` objects that match the inputs.
+//   - An error if the operation fails, or ErrTruncated with the capped slice when more virtual machines match.
+func (m *AzureManager) ListVPCsCtx(ctx context.Context, fields map[string]interface{}, match func(vm AzureVirtualMachine) bool) ([]VPC, error) {
+	if err := m.setup(); err != nil {
+		return nil, err
+	}
+
+	resourceGroup, _ := fields["azure_resource_group"].(string)
+	limit := maxResults(fields)
+	truncated := false
+	var vms []AzureVirtualMachine
+	err := observer.Call(m.Observer, "azure", "ListVirtualMachines", func() error {
+		return m.Client.ListPages(ctx, resourceGroup, func(page []AzureVirtualMachine) bool {
+			for _, vm := range page {
+				if match != nil && !match(vm) {
+					continue
+				}
+				if limit > 0 && len(vms) == limit {
+					truncated = true
+					return false
+				}
+				vms = append(vms, vm)
+			}
+			return true
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := m.toVPCs(ctx, vms)
+	if err != nil {
+		return nil, err
+	}
+	if truncated {
+		return response, ErrTruncated
+	}
+	return response, nil
+}
+
+// powerStateIn returns a filter accepting the virtual machines in any of the power states.
+func powerStateIn(states ...string) func(vm AzureVirtualMachine) bool {
+	return func(vm AzureVirtualMachine) bool {
+		powerState := vm.PowerState()
+		for _, state := range states {
+			if powerState == state {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// provisioningStateIs returns a filter accepting the virtual machines in the provisioning state.
+func provisioningStateIs(state string) func(vm AzureVirtualMachine) bool {
+	return func(vm AzureVirtualMachine) bool {
+		return vm.ProvisioningState() == state
+	}
+}
+
+// ListRunningVPCsCtx lists the virtual machines in the "PowerState/running" state.
+func (m *AzureManager) ListRunningVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, powerStateIn("running"))
+}
+
+// ListStartingVPCsCtx lists the virtual machines in the "PowerState/starting" state.
+func (m *AzureManager) ListStartingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, powerStateIn("starting"))
+}
+
+// ListStoppingVPCsCtx lists the virtual machines being stopped or deallocated.
+func (m *AzureManager) ListStoppingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, powerStateIn("stopping", "deallocating"))
+}
+
+// ListStoppedVPCsCtx lists the virtual machines that are stopped or deallocated.
+func (m *AzureManager) ListStoppedVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, powerStateIn("stopped", "deallocated"))
+}
+
+// ListCreatingVPCsCtx lists the virtual machines being provisioned.
+func (m *AzureManager) ListCreatingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, provisioningStateIs("creating"))
+}
+
+// ListDeletingVPCsCtx lists the virtual machines being deleted.
+func (m *AzureManager) ListDeletingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, provisioningStateIs("deleting"))
+}
+
+// ListDeletedVPCsCtx returns no virtual machines: Azure removes deleted virtual machines from the listings.
+func (m *AzureManager) ListDeletedVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return nil, nil
+}
+
+// ListAllVPCsCtx lists every virtual machine, regardless of its state.
+func (m *AzureManager) ListAllVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, nil)
+}
+
+// ListByShapeCtx lists the virtual machines of the given VM size (e.g., "Standard_D2s_v3").
+// The Resource Manager cannot filter by size, so a "max_results" cap applies to the matching virtual machines.
+func (m *AzureManager) ListByShapeCtx(ctx context.Context, shape string, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, func(vm AzureVirtualMachine) bool {
+		return strings.EqualFold(vm.Properties.HardwareProfile.VMSize, shape)
+	})
+}
+
+// CreateVPCCtx is not supported: a virtual machine cannot be created from a name and a CIDR block alone.
+// The error matches ErrNotSupported.
+func (m *AzureManager) CreateVPCCtx(ctx context.Context, name, cidr string) (*VPC, error) {
+	return nil, fmt.Errorf("azure: cannot create virtual machine '%s': %w", name, ErrNotSupported)
+}
+
+// DeleteVPCCtx deletes the virtual machine with the given resource ID. The deletion is asynchronous.
+func (m *AzureManager) DeleteVPCCtx(ctx context.Context, id string) error {
+	if err := m.setup(); err != nil {
+		return err
+	}
+	return observer.Call(m.Observer, "azure", "DeleteVirtualMachine", func() error {
+		return m.Client.Delete(ctx, id)
+	})
+}
+
+// GetVPCCtx retrieves the virtual machine with the given resource ID.
+func (m *AzureManager) GetVPCCtx(ctx context.Context, id string) (*VPC, error) {
+	if err := m.setup(); err != nil {
+		return nil, err
+	}
+
+	var vm AzureVirtualMachine
+	err := observer.Call(m.Observer, "azure", "GetVirtualMachine", func() (err error) {
+		vm, err = m.Client.Get(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	vpcs, err := m.toVPCs(ctx, []AzureVirtualMachine{vm})
+	if err != nil {
+		return nil, err
+	}
+	return &vpcs[0], nil
+}
+
+// StartCtx starts the virtual machine and returns its state once the operation is accepted.
+func (m *AzureManager) StartCtx(ctx context.Context, id string) (*VPC, error) {
+	return m.action(ctx, id, "StartVirtualMachine", (*AzureVirtualMachinesClient).Start)
+}
+
+// StopCtx deallocates the virtual machine, so that its compute resources are no longer billed.
+func (m *AzureManager) StopCtx(ctx context.Context, id string) (*VPC, error) {
+	return m.action(ctx, id, "DeallocateVirtualMachine", (*AzureVirtualMachinesClient).Deallocate)
+}
+
+// RestartCtx restarts the virtual machine.
+func (m *AzureManager) RestartCtx(ctx context.Context, id string) (*VPC, error) {
+	return m.action(ctx, id, "RestartVirtualMachine", (*AzureVirtualMachinesClient).Restart)
+}
+
+// ResizeCtx changes the size of the virtual machine (e.g., "Standard_D4s_v5") and returns its state.
+// A running machine is restarted by Azure; when the size is not available on its current hardware
+// cluster, Azure's error is returned and the machine must be stopped (deallocated) first.
+func (m *AzureManager) ResizeCtx(ctx context.Context, id, newType string) (*VPC, error) {
+	if newType == "" {
+		return nil, errors.New("VM size is required")
+	}
+	vpc, err := m.action(ctx, id, "UpdateVirtualMachine", func(c *AzureVirtualMachinesClient, ctx context.Context, id string) error {
+		return c.Resize(ctx, id, newType)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to change the size of virtual machine %s to %s: %w", id, newType, err)
+	}
+	return vpc, nil
+}
+
+// action runs a power operation of the client on the virtual machine, observed as the API call name,
+// retrying transient failures, and returns its state.
+func (m *AzureManager) action(ctx context.Context, id, name string, operation func(c *AzureVirtualMachinesClient, ctx context.Context, id string) error) (*VPC, error) {
+	if err := m.setup(); err != nil {
+		return nil, err
+	}
+
+	err := backoff.Retry(m.Backoff, retryable(ctx), observer.Retrying(m.Observer, "azure", name, func() error {
+		return operation(m.Client, ctx, id)
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return m.GetVPCCtx(ctx, id)
+}
+
+// The methods below run their Ctx variant with context.Background(), for callers that cannot cancel them.
+func (m *AzureManager) ListVPCs(fields map[string]interface{}, match func(vm AzureVirtualMachine) bool) ([]VPC, error) {
+	return m.ListVPCsCtx(context.Background(), fields, match)
+}
+
+func (m *AzureManager) ListRunningVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListRunningVPCsCtx(context.Background(), fields)
+}
+
+func (m *AzureManager) ListStartingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListStartingVPCsCtx(context.Background(), fields)
+}
+
+func (m *AzureManager) ListStoppingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListStoppingVPCsCtx(context.Background(), fields)
+}
+
+func (m *AzureManager) ListStoppedVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListStoppedVPCsCtx(context.Background(), fields)
+}
+
+func (m *AzureManager) ListCreatingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListCreatingVPCsCtx(context.Background(), fields)
+}
+
+func (m *AzureManager) ListDeletingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListDeletingVPCsCtx(context.Background(), fields)
+}
+
+func (m *AzureManager) ListDeletedVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListDeletedVPCsCtx(context.Background(), fields)
+}
+
+func (m *AzureManager) ListAllVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListAllVPCsCtx(context.Background(), fields)
+}
+
+func (m *AzureManager) ListByShape(shape string, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListByShapeCtx(context.Background(), shape, fields)
+}
+
+func (m *AzureManager) CreateVPC(name, cidr string) (*VPC, error) {
+	return m.CreateVPCCtx(context.Background(), name, cidr)
+}
+
+func (m *AzureManager) DeleteVPC(id string) error {
+	return m.DeleteVPCCtx(context.Background(), id)
+}
+
+func (m *AzureManager) GetVPC(id string) (*VPC, error) {
+	return m.GetVPCCtx(context.Background(), id)
+}
+
+func (m *AzureManager) Start(id string) (*VPC, error) {
+	return m.StartCtx(context.Background(), id)
+}
+
+func (m *AzureManager) Stop(id string) (*VPC, error) {
+	return m.StopCtx(context.Background(), id)
+}
+
+func (m *AzureManager) Restart(id string) (*VPC, error) {
+	return m.RestartCtx(context.Background(), id)
+}
+
+func (m *AzureManager) Resize(id, newType string) (*VPC, error) {
+	return m.ResizeCtx(context.Background(), id, newType)
+}
@@ -0,0 +1,231 @@
+package compute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAzureCredential is an azcore.TokenCredential returning a fixed token.
+type fakeAzureCredential struct{}
+
+func (fakeAzureCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "test-token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// azureVMJSON renders a virtual machine as returned by the Resource Manager with its instance view.
+func azureVMJSON(name, size, provisioningState, powerState string) string {
+	return fmt.Sprintf(`{"id":"/subscriptions/sub-id/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/%s","name":"%s",`+
+		`"location":"eastus","zones":["2"],"properties":{"hardwareProfile":{"vmSize":"%s"},"provisioningState":"%s",`+
+		`"instanceView":{"statuses":[{"code":"ProvisioningState/%s"},{"code":"PowerState/%s"}]}}}`,
+		name, name, size, provisioningState, strings.ToLower(provisioningState), powerState)
+}
+
+// fakeAzureComputeHandler serves a two-page virtual machine listing, the VM sizes of eastus,
+// the "vm-1" virtual machine and its power operations, recording the operations received.
+func fakeAzureComputeHandler(t *testing.T, operations *[]string) http.HandlerFunc {
+	mu := &sync.Mutex{}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		server := "http://" + r.Host
+		w.Header().Set("Content-Type", "application/json")
+
+		vmPath := "/subscriptions/sub-id/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm-1"
+		switch {
+		case r.URL.Path == "/subscriptions/sub-id/providers/Microsoft.Compute/virtualMachines" && r.URL.Query().Get("page") == "":
+			if r.URL.Query().Get("statusOnly") != "true" {
+				t.Errorf("expected the listing to request the power state")
+			}
+			_, _ = fmt.Fprintf(w, `{"value":[%s,%s],"nextLink":"%s%s?page=2"}`,
+				azureVMJSON("vm-1", "Standard_D2s_v3", "Succeeded", "running"),
+				azureVMJSON("vm-2", "Standard_B1s", "Succeeded", "deallocated"),
+				server, r.URL.Path)
+		case r.URL.Path == "/subscriptions/sub-id/providers/Microsoft.Compute/virtualMachines":
+			_, _ = fmt.Fprintf(w, `{"value":[%s,%s]}`,
+				azureVMJSON("vm-3", "Standard_D2s_v3", "Creating", "starting"),
+				azureVMJSON("vm-4", "Standard_D2s_v3", "Succeeded", "deallocating"))
+		case r.URL.Path == "/subscriptions/sub-id/providers/Microsoft.Compute/locations/eastus/vmSizes":
+			_, _ = fmt.Fprint(w, `{"value":[{"name":"Standard_D2s_v3","numberOfCores":2,"memoryInMB":8192},{"name":"Standard_B1s","numberOfCores":1,"memoryInMB":1024}]}`)
+		case r.URL.Path == vmPath && r.Method == http.MethodGet:
+			_, _ = fmt.Fprint(w, azureVMJSON("vm-1", "Standard_D2s_v3", "Succeeded", "running"))
+		case strings.HasPrefix(r.URL.Path, vmPath) && r.Method != http.MethodGet:
+			mu.Lock()
+			*operations = append(*operations, r.Method+" "+strings.TrimPrefix(r.URL.Path, vmPath))
+			mu.Unlock()
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"error":{"code":"ResourceNotFound","message":"not found"}}`)
+		}
+	}
+}
+
+// newTestAzureManager returns an AzureManager whose client talks to the given fake endpoint.
+func newTestAzureManager(endpoint string) *AzureManager {
+	return &AzureManager{Client: &AzureVirtualMachinesClient{
+		Credential:     fakeAzureCredential{},
+		SubscriptionID: "sub-id",
+		Endpoint:       endpoint,
+	}}
+}
+
+// TestAzureManager_ListVPCs verifies the paging, the power state mapping, the size lookup and the state filters.
+func TestAzureManager_ListVPCs(t *testing.T) {
+	var operations []string
+	server := httptest.NewServer(fakeAzureComputeHandler(t, &operations))
+	defer server.Close()
+
+	manager := newTestAzureManager(server.URL)
+
+	vpcs, err := manager.ListAllVPCs(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(vpcs) != 4 {
+		t.Fatalf("expected 4 VPCs across both pages, got %d", len(vpcs))
+	}
+
+	expectedStates := map[string]VPCStateEnum{
+		"vm-1": VPCStateAvailable, "vm-2": VPCStateUnavailable, "vm-3": VPCStateCreating, "vm-4": VPCStateModifying,
+	}
+	for _, vpc := range vpcs {
+		if vpc.State != expectedStates[vpc.Name] {
+			t.Errorf("%s: expected state %s, got %s", vpc.Name, expectedStates[vpc.Name], vpc.State)
+		}
+	}
+
+	vm1 := vpcs[0]
+	if vm1.Provider != "azure" || vm1.Region != "eastus" || vm1.AvailabilityZone != "2" || vm1.Description != "Standard_D2s_v3" {
+		t.Errorf("unexpected VPC: %+v", vm1)
+	}
+	if vm1.VirtualCPUCount != 2 || vm1.MemoryGB != 8 {
+		t.Errorf("expected 2 vCPUs and 8 GB from the VM size, got %d and %d", vm1.VirtualCPUCount, vm1.MemoryGB)
+	}
+
+	filters := []struct {
+		name     string
+		list     func(map[string]interface{}) ([]VPC, error)
+		expected []string
+	}{
+		{"running", manager.ListRunningVPCs, []string{"vm-1"}},
+		{"starting", manager.ListStartingVPCs, []string{"vm-3"}},
+		{"stopping", manager.ListStoppingVPCs, []string{"vm-4"}},
+		{"stopped", manager.ListStoppedVPCs, []string{"vm-2"}},
+		{"creating", manager.ListCreatingVPCs, []string{"vm-3"}},
+		{"deleted", manager.ListDeletedVPCs, nil},
+	}
+	for _, filter := range filters {
+		vpcs, err := filter.list(map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", filter.name, err)
+		}
+		var names []string
+		for _, vpc := range vpcs {
+			names = append(names, vpc.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(filter.expected, ",") {
+			t.Errorf("%s: expected %v, got %v", filter.name, filter.expected, names)
+		}
+	}
+
+	bySize, err := manager.ListByShape("standard_d2s_v3", map[string]interface{}{"max_results": 2})
+	if !errors.Is(err, ErrTruncated) || len(bySize) != 2 {
+		t.Errorf("expected 2 VMs of the size and ErrTruncated, got %d (%v)", len(bySize), err)
+	}
+}
+
+// TestAzureManager_PowerOperations verifies that Start, Stop, Restart and DeleteVPC call the matching operations.
+func TestAzureManager_PowerOperations(t *testing.T) {
+	var operations []string
+	server := httptest.NewServer(fakeAzureComputeHandler(t, &operations))
+	defer server.Close()
+
+	manager := newTestAzureManager(server.URL)
+	id := "/subscriptions/sub-id/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm-1"
+
+	for _, operation := range []func(string) (*VPC, error){manager.Start, manager.Stop, manager.Restart} {
+		vpc, err := operation(id)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if vpc.ID != id || vpc.State != VPCStateAvailable {
+			t.Errorf("unexpected VPC returned: %+v", vpc)
+		}
+	}
+	if err := manager.DeleteVPC(id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "POST /start,POST /deallocate,POST /restart,DELETE "
+	if got := strings.Join(operations, ","); got != expected {
+		t.Errorf("expected operations %q, got %q", expected, got)
+	}
+
+	_, err := manager.GetVPC("/subscriptions/sub-id/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/missing")
+	var azureErr *azureResponseError
+	if !errors.As(err, &azureErr) || azureErr.StatusCode != http.StatusNotFound || azureErr.Code != "ResourceNotFound" {
+		t.Errorf("expected a ResourceNotFound error, got %v", err)
+	}
+}
+
+// TestAzureManager_Resize verifies that the new size is sent in a PATCH of the virtual machine.
+func TestAzureManager_Resize(t *testing.T) {
+	var operations []string
+	server := httptest.NewServer(fakeAzureComputeHandler(t, &operations))
+	defer server.Close()
+
+	manager := newTestAzureManager(server.URL)
+	id := "/subscriptions/sub-id/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm-1"
+	if _, err := manager.Resize(id, "Standard_D4s_v5"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(operations, ","); got != "PATCH " {
+		t.Errorf("expected a PATCH of the virtual machine, got %q", got)
+	}
+	if _, err := manager.Resize(id, ""); err == nil {
+		t.Error("expected an error without a size")
+	}
+}
+
+// TestAzureManager_ConcurrentUse verifies that concurrent calls on a manager share the client created by
+// setup and the VM size cache safely (run with -race), and that CreateVPC reports ErrNotSupported.
+func TestAzureManager_ConcurrentUse(t *testing.T) {
+	var operations []string
+	server := httptest.NewServer(fakeAzureComputeHandler(t, &operations))
+	defer server.Close()
+
+	manager := newTestAzureManager(server.URL)
+	id := "/subscriptions/sub-id/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm-1"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vpc, err := manager.GetVPC(id)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if vpc.VirtualCPUCount != 2 {
+				t.Errorf("expected the vCPUs of the VM size, got %d", vpc.VirtualCPUCount)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := manager.CreateVPC("vm-5", "10.0.0.0/16"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package compute
+
+import (
+	"math"
+	"strings"
+)
+
+// AzureVirtualMachine is the subset of an Azure Resource Manager virtual machine used by the AzureManager.
+type AzureVirtualMachine struct {
+	ID         string                        `json:"id"`       // Full resource ID (/subscriptions/.../virtualMachines/name).
+	Name       string                        `json:"name"`     // Name of the virtual machine.
+	Location   string                        `json:"location"` // Azure region (e.g., "eastus").
+	Zones      []string                      `json:"zones"`    // Availability zones the VM is pinned to, if any.
+	Properties AzureVirtualMachineProperties `json:"properties"`
+}
+
+// AzureVirtualMachineProperties holds the hardware profile and status of an Azure virtual machine.
+type AzureVirtualMachineProperties struct {
+	VMID              string `json:"vmId"`
+	ProvisioningState string `json:"provisioningState"` // e.g., "Creating", "Succeeded", "Deleting", "Failed".
+	HardwareProfile   struct {
+		VMSize string `json:"vmSize"` // VM size (e.g., "Standard_D2s_v3").
+	} `json:"hardwareProfile"`
+	InstanceView *AzureInstanceView `json:"instanceView,omitempty"` // Present when listed with statusOnly or fetched with $expand=instanceView.
+}
+
+// AzureInstanceView holds the runtime statuses of an Azure virtual machine.
+type AzureInstanceView struct {
+	Statuses []AzureInstanceViewStatus `json:"statuses"`
+}
+
+// AzureInstanceViewStatus is a status of the instance view (e.g., "PowerState/running").
+type AzureInstanceViewStatus struct {
+	Code          string `json:"code"`
+	DisplayStatus string `json:"displayStatus"`
+}
+
+// AzureVMSize describes the hardware of an Azure VM size, as listed for a location.
+type AzureVMSize struct {
+	Name          string `json:"name"`          // VM size name (e.g., "Standard_D2s_v3").
+	NumberOfCores int64  `json:"numberOfCores"` // Number of vCPUs.
+	MemoryInMB    int64  `json:"memoryInMB"`    // Memory in MB.
+}
+
+// status returns the value of the instance view status with the given prefix (e.g., "PowerState/"
+// -> "running"), lowercased, or an empty string when the VM has no such status.
+func (vm AzureVirtualMachine) status(prefix string) string {
+	if vm.Properties.InstanceView == nil {
+		return ""
+	}
+	for _, status := range vm.Properties.InstanceView.Statuses {
+		if strings.HasPrefix(status.Code, prefix) {
+			return strings.ToLower(strings.TrimPrefix(status.Code, prefix))
+		}
+	}
+	return ""
+}
+
+// PowerState returns the power state of the VM (e.g., "running", "deallocated"), or an empty string when unknown.
+func (vm AzureVirtualMachine) PowerState() string {
+	return vm.status("PowerState/")
+}
+
+// ProvisioningState returns the provisioning state of the VM (e.g., "creating", "succeeded"), lowercased.
+func (vm AzureVirtualMachine) ProvisioningState() string {
+	if state := vm.status("ProvisioningState/"); state != "" {
+		return state
+	}
+	return strings.ToLower(vm.Properties.ProvisioningState)
+}
+
+// AzureInstanceToVPC converts an Azure virtual machine into a generic VPC structure.
+// The CPU and memory come from size, the description of the VM size; they are left at zero when it is nil.
+func AzureInstanceToVPC(vm AzureVirtualMachine, size *AzureVMSize) VPC {
+	availabilityZone := ""
+	if len(vm.Zones) > 0 {
+		availabilityZone = vm.Zones[0]
+	}
+
+	vpc := VPC{
+		ID:               vm.ID,
+		Name:             vm.Name,
+		Region:           vm.Location,
+		AvailabilityZone: availabilityZone,
+		Provider:         "azure",
+		Description:      vm.Properties.HardwareProfile.VMSize,
+
+		ProviderSpecific: vm,
+		State:            mapAzureStateToVPCState(vm.ProvisioningState(), vm.PowerState()),
+	}
+
+	if size != nil {
+		// Azure reports vCPUs only, so they are used for both counts.
+		vpc.CPUCount = size.NumberOfCores
+		vpc.VirtualCPUCount = size.NumberOfCores
+		vpc.MemoryGB = int64(math.Round(float64(size.MemoryInMB) / 1024))
+	}
+
+	return vpc
+}
+
+// mapAzureStateToVPCState maps the provisioning and power states of an Azure VM to a generic VPC state.
+// Provisioning operations (creating, deleting, failed) take precedence over the power state.
+func mapAzureStateToVPCState(provisioningState, powerState string) VPCStateEnum {
+	switch provisioningState {
+	case "creating":
+		return VPCStateCreating
+	case "deleting":
+		return VPCStateDeleting
+	case "failed":
+		return VPCStateFailed
+	}
+
+	switch powerState {
+	case "running":
+		return VPCStateAvailable
+	case "starting", "stopping", "deallocating":
+		return VPCStateModifying
+	case "stopped", "deallocated":
+		return VPCStateUnavailable
+	default:
+		return VPCStateUnavailable
+	}
+}
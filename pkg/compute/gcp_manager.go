@@ -0,0 +1,624 @@
+package compute
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/diegoyosiura/cloud-manager/pkg/backoff"
+	"github.com/diegoyosiura/cloud-manager/pkg/observer"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// gcpResponseError is an error response of the Compute Engine API.
+type gcpResponseError struct {
+	StatusCode int
+	Reason     string
+	Message    string
+}
+
+func (e *gcpResponseError) Error() string {
+	return fmt.Sprintf("gcp request failed with status %d: %s: %s", e.StatusCode, e.Reason, e.Message)
+}
+
+// GCPTokenSource provides the OAuth access tokens authorizing the Compute Engine requests.
+// It is implemented by authentication.GCPCredential.
+type GCPTokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// GCPOperation is a zonal operation of the Compute Engine API, returned by the instance operations.
+type GCPOperation struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`   // "PENDING", "RUNNING" or "DONE".
+	SelfLink string `json:"selfLink"` // Full URL of the operation.
+	Error    *struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"error,omitempty"` // Set when the operation failed.
+}
+
+// GCPInstancesClient calls the instance operations of the Compute Engine REST API for one project. It is
+// a thin REST client rather than the cloud.google.com/go/compute module: the few operations used here
+// do not justify that module and its gRPC dependencies, and the requests are authorized with the
+// tokens of the GCPCredential that GCPAuth already holds.
+type GCPInstancesClient struct {
+	Credential GCPTokenSource // Source of the access tokens.
+	ProjectID  string         // Project whose instances are managed.
+	Endpoint   string         // API endpoint (defaults to https://compute.googleapis.com/compute/v1).
+	HTTPClient *http.Client   // HTTP client used for the requests (defaults to http.DefaultClient).
+}
+
+// gcpResourcePath converts an instance or operation URL into its path relative to the API endpoint
+// ("projects/..."). Paths are returned unchanged.
+func gcpResourcePath(resource string) string {
+	if index := strings.Index(resource, "/projects/"); index >= 0 && !strings.HasPrefix(resource, "projects/") {
+		return resource[index+1:]
+	}
+	return strings.TrimPrefix(resource, "/")
+}
+
+// do sends a request to the Compute Engine API, with in encoded as the JSON body when not nil, and decodes
+// the JSON response into out, when not nil. path is relative to the endpoint (e.g., "projects/p/zones/z/instances/n").
+func (c *GCPInstancesClient) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	token, err := c.Credential.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get GCP token: %w", err)
+	}
+
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = "https://compute.googleapis.com/compute/v1"
+	}
+	target := strings.TrimRight(endpoint, "/") + "/" + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var body struct {
+			Error struct {
+				Message string `json:"message"`
+				Errors  []struct {
+					Reason string `json:"reason"`
+				} `json:"errors"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		respErr := &gcpResponseError{StatusCode: resp.StatusCode, Message: body.Error.Message}
+		if len(body.Error.Errors) > 0 {
+			respErr.Reason = body.Error.Errors[0].Reason
+		}
+		return respErr
+	}
+
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode GCP response: %w", err)
+	}
+	return nil
+}
+
+// AggregatedListPages lists the instances of every zone of the project matching filter (a Compute Engine
+// filter expression, e.g. `status = "RUNNING"`, or empty for every instance), calling fn for every page
+// until it returns false. The instances of a page are ordered by zone.
+func (c *GCPInstancesClient) AggregatedListPages(ctx context.Context, filter string, fn func(instances []GCPInstance) bool) error {
+	path := fmt.Sprintf("projects/%s/aggregated/instances", url.PathEscape(c.ProjectID))
+	query := url.Values{}
+	if filter != "" {
+		query.Set("filter", filter)
+	}
+
+	for {
+		var page struct {
+			Items map[string]struct {
+				Instances []GCPInstance `json:"instances"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := c.do(ctx, http.MethodGet, path, query, nil, &page); err != nil {
+			return err
+		}
+
+		zones := make([]string, 0, len(page.Items))
+		for zone := range page.Items {
+			zones = append(zones, zone)
+		}
+		sort.Strings(zones)
+		var instances []GCPInstance
+		for _, zone := range zones {
+			instances = append(instances, page.Items[zone].Instances...)
+		}
+
+		if !fn(instances) || page.NextPageToken == "" {
+			return nil
+		}
+		query.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// Get returns the instance with the given path or URL.
+func (c *GCPInstancesClient) Get(ctx context.Context, instance string) (GCPInstance, error) {
+	var result GCPInstance
+	err := c.do(ctx, http.MethodGet, gcpResourcePath(instance), nil, nil, &result)
+	return result, err
+}
+
+// GetMachineType returns the machine type of the zone with the given name.
+func (c *GCPInstancesClient) GetMachineType(ctx context.Context, zone, name string) (GCPMachineType, error) {
+	var result GCPMachineType
+	path := fmt.Sprintf("projects/%s/zones/%s/machineTypes/%s", url.PathEscape(c.ProjectID), url.PathEscape(zone), url.PathEscape(name))
+	err := c.do(ctx, http.MethodGet, path, nil, nil, &result)
+	return result, err
+}
+
+// operation starts an operation on the instance (e.g., "start") and returns the zonal operation tracking it.
+func (c *GCPInstancesClient) operation(ctx context.Context, method, instance, verb string) (*GCPOperation, error) {
+	path := gcpResourcePath(instance)
+	if verb != "" {
+		path += "/" + verb
+	}
+	var op GCPOperation
+	if err := c.do(ctx, method, path, nil, nil, &op); err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
+// Start starts a stopped instance.
+func (c *GCPInstancesClient) Start(ctx context.Context, instance string) (*GCPOperation, error) {
+	return c.operation(ctx, http.MethodPost, instance, "start")
+}
+
+// Stop stops the instance; a stopped instance is no longer billed for its vCPUs and memory.
+func (c *GCPInstancesClient) Stop(ctx context.Context, instance string) (*GCPOperation, error) {
+	return c.operation(ctx, http.MethodPost, instance, "stop")
+}
+
+// Reset performs a hard reset of the instance.
+func (c *GCPInstancesClient) Reset(ctx context.Context, instance string) (*GCPOperation, error) {
+	return c.operation(ctx, http.MethodPost, instance, "reset")
+}
+
+// SetMachineType changes the machine type of a stopped instance to the machine type of its zone with
+// the given name.
+func (c *GCPInstancesClient) SetMachineType(ctx context.Context, instance, zone, machineType string) (*GCPOperation, error) {
+	body := map[string]string{"machineType": fmt.Sprintf("zones/%s/machineTypes/%s", zone, machineType)}
+	var op GCPOperation
+	if err := c.do(ctx, http.MethodPost, gcpResourcePath(instance)+"/setMachineType", nil, body, &op); err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
+// Delete deletes the instance.
+func (c *GCPInstancesClient) Delete(ctx context.Context, instance string) (*GCPOperation, error) {
+	return c.operation(ctx, http.MethodDelete, instance, "")
+}
+
+// Wait blocks until the operation is done, using the operations "wait" method, which returns
+// after at most two minutes, as many times as needed. It returns an error if the operation failed.
+func (c *GCPInstancesClient) Wait(ctx context.Context, op *GCPOperation) error {
+	for op.Status != "DONE" {
+		next := &GCPOperation{}
+		if err := c.do(ctx, http.MethodPost, gcpResourcePath(op.SelfLink)+"/wait", nil, nil, next); err != nil {
+			return err
+		}
+		op = next
+	}
+
+	if op.Error != nil && len(op.Error.Errors) > 0 {
+		return fmt.Errorf("gcp operation %s failed: %s: %s", op.Name, op.Error.Errors[0].Code, op.Error.Errors[0].Message)
+	}
+	return nil
+}
+
+// GCPManager manages Compute Engine instances through the Manager interface.
+type GCPManager struct {
+	Auth   *authentication.GCPAuth // GCP authentication details.
+	Client *GCPInstancesClient     // Instances client, created from Auth on first use.
+	Pricer Pricer                  // Populates VPC.CostEstimate when set (optional).
+
+	Backoff  backoff.Backoff   // Retries Start, Stop and Restart on throttling or server errors when set (optional).
+	Observer observer.Observer // Receives the API call, duration and retry metrics when set (optional).
+
+	mu           sync.Mutex                           // Guards Client creation in setup and machineTypes.
+	machineTypes map[string]map[string]GCPMachineType // Machine types by zone and name, loaded on demand.
+}
+
+// WithPricing sets the Pricer used to populate the cost estimate of the VPCs returned by ListVPCs and GetVPC.
+func (m *GCPManager) WithPricing(p Pricer) {
+	m.Pricer = p
+}
+
+// setup creates the instances client from the authenticated credentials when it is not set.
+func (m *GCPManager) setup() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Client != nil {
+		return nil
+	}
+	if m.Auth == nil || m.Auth.Credential == nil {
+		return errors.New("gcp credentials are not initialized; authenticate first")
+	}
+	m.Client = &GCPInstancesClient{Credential: m.Auth.Credential, ProjectID: m.Auth.ProjectID}
+	return nil
+}
+
+// machineType returns the description of the machine type in the zone, fetching it on first use.
+func (m *GCPManager) machineType(ctx context.Context, zone, name string) (*GCPMachineType, error) {
+	m.mu.Lock()
+	machineType, ok := m.machineTypes[zone][name]
+	m.mu.Unlock()
+	if ok {
+		return &machineType, nil
+	}
+
+	// The machine type is fetched without holding the lock; concurrent first uses may fetch it twice.
+	machineType, err := m.Client.GetMachineType(ctx, zone, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get machine type '%s' of '%s': %w", name, zone, err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.machineTypes == nil {
+		m.machineTypes = map[string]map[string]GCPMachineType{}
+	}
+	if m.machineTypes[zone] == nil {
+		m.machineTypes[zone] = map[string]GCPMachineType{}
+	}
+	m.machineTypes[zone][name] = machineType
+	return &machineType, nil
+}
+
+// toVPCs converts the instances, filling CPU and memory from their machine types, and applies the pricing.
+func (m *GCPManager) toVPCs(ctx context.Context, instances []GCPInstance) ([]VPC, error) {
+	var response []VPC
+	for _, instance := range instances {
+		machineType, err := m.machineType(ctx, instance.ZoneName(), instance.MachineTypeName())
+		if err != nil {
+			return nil, err
+		}
+		response = append(response, GCPInstanceToVPC(instance, machineType))
+	}
+
+	if err := applyPricing(m.Pricer, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// ListVPCsCtx lists the instances of every zone of the project in any of the statuses (every instance when none is given).
+// Parameters:
+//   - fields: A map (`map[string]interface{}`) of optional filters.
+//   - statuses: Compute Engine instance statuses (e.g., "RUNNING", "TERMINATED"), filtered server-side.
+//
+// Every page is read unless fields["max_results"] (an int) caps the number of instances collected.
+//
+// Returns:
+//   - A slice of `VPC` objects that match the inputs.
+//   - An error if the operation fails, or ErrTruncated with the capped slice when more instances exist.
+func (m *GCPManager) ListVPCsCtx(ctx context.Context, fields map[string]interface{}, statuses ...string) ([]VPC, error) {
+	if err := m.setup(); err != nil {
+		return nil, err
+	}
+
+	var conditions []string
+	for _, status := range statuses {
+		conditions = append(conditions, fmt.Sprintf(`(status = "%s")`, status))
+	}
+
+	limit := maxResults(fields)
+	truncated := false
+	var instances []GCPInstance
+	err := observer.Call(m.Observer, "gcp", "AggregatedListInstances", func() error {
+		return m.Client.AggregatedListPages(ctx, strings.Join(conditions, " OR "), func(page []GCPInstance) bool {
+			for _, instance := range page {
+				if limit > 0 && len(instances) == limit {
+					truncated = true
+					return false
+				}
+				instances = append(instances, instance)
+			}
+			return true
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := m.toVPCs(ctx, instances)
+	if err != nil {
+		return nil, err
+	}
+	if truncated {
+		return response, ErrTruncated
+	}
+	return response, nil
+}
+
+// ListRunningVPCsCtx lists the instances in the "RUNNING" status.
+func (m *GCPManager) ListRunningVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, "RUNNING")
+}
+
+// ListStartingVPCsCtx lists the instances being provisioned or staged, which is how Compute Engine starts them.
+func (m *GCPManager) ListStartingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, "PROVISIONING", "STAGING")
+}
+
+// ListStoppingVPCsCtx lists the instances being stopped or suspended.
+func (m *GCPManager) ListStoppingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, "STOPPING", "SUSPENDING")
+}
+
+// ListStoppedVPCsCtx lists the instances that are stopped ("TERMINATED" in Compute Engine) or suspended.
+func (m *GCPManager) ListStoppedVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, "STOPPED", "TERMINATED", "SUSPENDED")
+}
+
+// ListCreatingVPCsCtx lists the instances being provisioned or staged.
+func (m *GCPManager) ListCreatingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, "PROVISIONING", "STAGING")
+}
+
+// ListDeletingVPCsCtx returns no instances: Compute Engine has no status for instances being deleted.
+func (m *GCPManager) ListDeletingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return nil, nil
+}
+
+// ListDeletedVPCsCtx returns no instances: Compute Engine removes deleted instances from the listings.
+func (m *GCPManager) ListDeletedVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return nil, nil
+}
+
+// ListAllVPCsCtx lists every instance, regardless of its status.
+func (m *GCPManager) ListAllVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields)
+}
+
+// ListByShapeCtx lists the instances of the given machine type (e.g., "e2-medium").
+// The machine type is a URL the listing cannot filter on exactly, so the instances are filtered after
+// being fetched; a "max_results" cap therefore applies to the instances fetched, before filtering.
+func (m *GCPManager) ListByShapeCtx(ctx context.Context, shape string, fields map[string]interface{}) ([]VPC, error) {
+	vpcs, err := m.ListAllVPCsCtx(ctx, fields)
+	if err != nil && !errors.Is(err, ErrTruncated) {
+		return nil, err
+	}
+
+	var response []VPC
+	for _, vpc := range vpcs {
+		// Description holds the machine type (see GCPInstanceToVPC).
+		if vpc.Description == shape {
+			response = append(response, vpc)
+		}
+	}
+	return response, err
+}
+
+// CreateVPCCtx is not supported: an instance cannot be created from a name and a CIDR block alone.
+// The error matches ErrNotSupported.
+func (m *GCPManager) CreateVPCCtx(ctx context.Context, name, cidr string) (*VPC, error) {
+	return nil, fmt.Errorf("gcp: cannot create instance '%s': %w", name, ErrNotSupported)
+}
+
+// DeleteVPCCtx deletes the instance with the given path ("projects/{project}/zones/{zone}/instances/{name}")
+// and waits for the deletion to complete.
+func (m *GCPManager) DeleteVPCCtx(ctx context.Context, id string) error {
+	if err := m.setup(); err != nil {
+		return err
+	}
+
+	var op *GCPOperation
+	err := observer.Call(m.Observer, "gcp", "DeleteInstance", func() (err error) {
+		op, err = m.Client.Delete(ctx, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return m.Client.Wait(ctx, op)
+}
+
+// GetVPCCtx retrieves the instance with the given path ("projects/{project}/zones/{zone}/instances/{name}").
+func (m *GCPManager) GetVPCCtx(ctx context.Context, id string) (*VPC, error) {
+	if err := m.setup(); err != nil {
+		return nil, err
+	}
+
+	var instance GCPInstance
+	err := observer.Call(m.Observer, "gcp", "GetInstance", func() (err error) {
+		instance, err = m.Client.Get(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	vpcs, err := m.toVPCs(ctx, []GCPInstance{instance})
+	if err != nil {
+		return nil, err
+	}
+	return &vpcs[0], nil
+}
+
+// StartCtx starts the instance and returns its state once the operation is done.
+func (m *GCPManager) StartCtx(ctx context.Context, id string) (*VPC, error) {
+	return m.action(ctx, id, "StartInstance", (*GCPInstancesClient).Start)
+}
+
+// StopCtx stops the instance and returns its state once the operation is done.
+func (m *GCPManager) StopCtx(ctx context.Context, id string) (*VPC, error) {
+	return m.action(ctx, id, "StopInstance", (*GCPInstancesClient).Stop)
+}
+
+// RestartCtx resets the instance and returns its state once the operation is done.
+func (m *GCPManager) RestartCtx(ctx context.Context, id string) (*VPC, error) {
+	return m.action(ctx, id, "ResetInstance", (*GCPInstancesClient).Reset)
+}
+
+// ResizeCtx changes the machine type of the instance (e.g., "e2-standard-4") and returns its state.
+// Compute Engine only changes the machine type of stopped instances, so a running instance is stopped,
+// modified and started again. Machine types not offered in the zone of the instance are rejected and
+// the error is returned, leaving the instance stopped.
+func (m *GCPManager) ResizeCtx(ctx context.Context, id, newType string) (*VPC, error) {
+	if newType == "" {
+		return nil, errors.New("machine type is required")
+	}
+	if err := m.setup(); err != nil {
+		return nil, err
+	}
+	instance, err := m.Client.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	running := instance.Status != "TERMINATED" && instance.Status != "STOPPED"
+	if running {
+		if _, err := m.StopCtx(ctx, id); err != nil {
+			return nil, fmt.Errorf("failed to stop instance %s before resizing: %w", id, err)
+		}
+	}
+
+	zone := path.Base(instance.Zone)
+	if _, err := m.action(ctx, id, "SetMachineType", func(c *GCPInstancesClient, ctx context.Context, name string) (*GCPOperation, error) {
+		return c.SetMachineType(ctx, name, zone, newType)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to change the machine type of instance %s to %s: %w", id, newType, err)
+	}
+
+	if running {
+		return m.StartCtx(ctx, id)
+	}
+	return m.GetVPCCtx(ctx, id)
+}
+
+// action runs an instance operation of the client, observed as the API call name, retrying transient failures,
+// waits for it to complete and returns the instance state.
+func (m *GCPManager) action(ctx context.Context, id, name string, operation func(c *GCPInstancesClient, ctx context.Context, instance string) (*GCPOperation, error)) (*VPC, error) {
+	if err := m.setup(); err != nil {
+		return nil, err
+	}
+
+	var op *GCPOperation
+	err := backoff.Retry(m.Backoff, retryable(ctx), observer.Retrying(m.Observer, "gcp", name, func() (err error) {
+		op, err = operation(m.Client, ctx, id)
+		return err
+	}))
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Client.Wait(ctx, op); err != nil {
+		return nil, err
+	}
+	return m.GetVPCCtx(ctx, id)
+}
+
+// The methods below run their Ctx variant with context.Background(), for callers that cannot cancel them.
+func (m *GCPManager) ListVPCs(fields map[string]interface{}, statuses ...string) ([]VPC, error) {
+	return m.ListVPCsCtx(context.Background(), fields, statuses...)
+}
+
+func (m *GCPManager) ListRunningVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListRunningVPCsCtx(context.Background(), fields)
+}
+
+func (m *GCPManager) ListStartingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListStartingVPCsCtx(context.Background(), fields)
+}
+
+func (m *GCPManager) ListStoppingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListStoppingVPCsCtx(context.Background(), fields)
+}
+
+func (m *GCPManager) ListStoppedVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListStoppedVPCsCtx(context.Background(), fields)
+}
+
+func (m *GCPManager) ListCreatingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListCreatingVPCsCtx(context.Background(), fields)
+}
+
+func (m *GCPManager) ListDeletingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListDeletingVPCsCtx(context.Background(), fields)
+}
+
+func (m *GCPManager) ListDeletedVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListDeletedVPCsCtx(context.Background(), fields)
+}
+
+func (m *GCPManager) ListAllVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListAllVPCsCtx(context.Background(), fields)
+}
+
+func (m *GCPManager) ListByShape(shape string, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListByShapeCtx(context.Background(), shape, fields)
+}
+
+func (m *GCPManager) CreateVPC(name, cidr string) (*VPC, error) {
+	return m.CreateVPCCtx(context.Background(), name, cidr)
+}
+
+func (m *GCPManager) DeleteVPC(id string) error {
+	return m.DeleteVPCCtx(context.Background(), id)
+}
+
+func (m *GCPManager) GetVPC(id string) (*VPC, error) {
+	return m.GetVPCCtx(context.Background(), id)
+}
+
+func (m *GCPManager) Start(id string) (*VPC, error) {
+	return m.StartCtx(context.Background(), id)
+}
+
+func (m *GCPManager) Stop(id string) (*VPC, error) {
+	return m.StopCtx(context.Background(), id)
+}
+
+func (m *GCPManager) Restart(id string) (*VPC, error) {
+	return m.RestartCtx(context.Background(), id)
+}
+
+func (m *GCPManager) Resize(id, newType string) (*VPC, error) {
+	return m.ResizeCtx(context.Background(), id, newType)
+}
@@ -0,0 +1,239 @@
+package compute
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeGCPTokenSource is a GCPTokenSource returning a fixed token.
+type fakeGCPTokenSource struct{}
+
+func (fakeGCPTokenSource) Token(context.Context) (string, error) {
+	return "test-token", nil
+}
+
+// gcpInstanceJSON renders a Compute Engine instance as returned by the API.
+func gcpInstanceJSON(server, zone, name, machineType, status string) string {
+	return fmt.Sprintf(`{"id":"1","name":"%[3]s","zone":"%[1]s/projects/proj/zones/%[2]s",`+
+		`"machineType":"%[1]s/projects/proj/zones/%[2]s/machineTypes/%[4]s","status":"%[5]s",`+
+		`"selfLink":"%[1]s/projects/proj/zones/%[2]s/instances/%[3]s",`+
+		`"networkInterfaces":[{"networkIP":"10.0.0.2","accessConfigs":[{"natIP":"34.1.2.3"}]}]}`,
+		server, zone, name, machineType, status)
+}
+
+// fakeGCPComputeHandler serves a two-page aggregated listing, the e2-medium and n2-standard-4 machine
+// types, the "vm-1" instance and its operations, recording the operations received and the listing filters.
+func fakeGCPComputeHandler(operations *[]string, filters *[]string) http.HandlerFunc {
+	mu := &sync.Mutex{}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		server := "http://" + r.Host
+		w.Header().Set("Content-Type", "application/json")
+
+		vmPath := "/projects/proj/zones/us-central1-a/instances/vm-1"
+		path := r.URL.Path
+		switch {
+		case path == "/projects/proj/aggregated/instances" && r.URL.Query().Get("pageToken") == "":
+			mu.Lock()
+			*filters = append(*filters, r.URL.Query().Get("filter"))
+			mu.Unlock()
+			_, _ = fmt.Fprintf(w, `{"items":{"zones/us-central1-b":{"instances":[%s]},"zones/us-central1-a":{"instances":[%s]},`+
+				`"zones/europe-west1-b":{"warning":{"code":"NO_RESULTS_ON_PAGE"}}},"nextPageToken":"page-2"}`,
+				gcpInstanceJSON(server, "us-central1-b", "vm-2", "n2-standard-4", "TERMINATED"),
+				gcpInstanceJSON(server, "us-central1-a", "vm-1", "e2-medium", "RUNNING"))
+		case path == "/projects/proj/aggregated/instances":
+			_, _ = fmt.Fprintf(w, `{"items":{"zones/us-central1-a":{"instances":[%s,%s]}}}`,
+				gcpInstanceJSON(server, "us-central1-a", "vm-3", "e2-medium", "STAGING"),
+				gcpInstanceJSON(server, "us-central1-a", "vm-4", "e2-medium", "STOPPING"))
+		case strings.HasSuffix(path, "/machineTypes/e2-medium"):
+			_, _ = fmt.Fprint(w, `{"name":"e2-medium","guestCpus":2,"memoryMb":4096}`)
+		case strings.HasSuffix(path, "/machineTypes/n2-standard-4"):
+			_, _ = fmt.Fprint(w, `{"name":"n2-standard-4","guestCpus":4,"memoryMb":16384}`)
+		case path == vmPath && r.Method == http.MethodGet:
+			_, _ = fmt.Fprint(w, gcpInstanceJSON(server, "us-central1-a", "vm-1", "e2-medium", "RUNNING"))
+		case strings.HasPrefix(path, vmPath):
+			mu.Lock()
+			*operations = append(*operations, r.Method+" "+strings.TrimPrefix(path, vmPath))
+			mu.Unlock()
+			_, _ = fmt.Fprintf(w, `{"name":"op-1","status":"RUNNING","selfLink":"%s/projects/proj/zones/us-central1-a/operations/op-1"}`, server)
+		case path == "/projects/proj/zones/us-central1-a/operations/op-1/wait":
+			mu.Lock()
+			*operations = append(*operations, "wait")
+			mu.Unlock()
+			_, _ = fmt.Fprint(w, `{"name":"op-1","status":"DONE"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"error":{"code":404,"message":"not found","errors":[{"reason":"notFound"}]}}`)
+		}
+	}
+}
+
+// newTestGCPManager returns a GCPManager whose client talks to the given fake endpoint.
+func newTestGCPManager(endpoint string) *GCPManager {
+	return &GCPManager{Client: &GCPInstancesClient{Credential: fakeGCPTokenSource{}, ProjectID: "proj", Endpoint: endpoint}}
+}
+
+// TestGCPManager_ListVPCs verifies the paging across zones, the status filters and the machine type lookup.
+func TestGCPManager_ListVPCs(t *testing.T) {
+	var operations, filters []string
+	server := httptest.NewServer(fakeGCPComputeHandler(&operations, &filters))
+	defer server.Close()
+
+	manager := newTestGCPManager(server.URL)
+
+	vpcs, err := manager.ListAllVPCs(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, vpc := range vpcs {
+		names = append(names, vpc.Name)
+	}
+	if strings.Join(names, ",") != "vm-1,vm-2,vm-3,vm-4" {
+		t.Fatalf("expected the instances of both pages ordered by zone, got %v", names)
+	}
+
+	expected := VPC{
+		ID: "projects/proj/zones/us-central1-a/instances/vm-1", Name: "vm-1", Region: "us-central1", AvailabilityZone: "us-central1-a",
+		Provider: "gcp", Description: "e2-medium", PrivateIP: "10.0.0.2", PublicIP: "34.1.2.3",
+		State: VPCStateAvailable, CPUCount: 2, VirtualCPUCount: 2, MemoryGB: 4,
+	}
+	got := vpcs[0]
+	got.ProviderSpecific = nil
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+	states := []VPCStateEnum{VPCStateAvailable, VPCStateUnavailable, VPCStateCreating, VPCStateModifying}
+	for i, vpc := range vpcs {
+		if vpc.State != states[i] {
+			t.Errorf("%s: expected state %s, got %s", vpc.Name, states[i], vpc.State)
+		}
+	}
+
+	if _, err := manager.ListStoppedVPCs(map[string]interface{}{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filters[0] != "" || filters[1] != `(status = "STOPPED") OR (status = "TERMINATED") OR (status = "SUSPENDED")` {
+		t.Errorf("unexpected listing filters: %q", filters)
+	}
+
+	byShape, err := manager.ListByShape("e2-medium", map[string]interface{}{"max_results": 2})
+	if !errors.Is(err, ErrTruncated) || len(byShape) != 1 || byShape[0].Name != "vm-1" {
+		t.Errorf("expected vm-1 among the 2 instances fetched and ErrTruncated, got %d (%v)", len(byShape), err)
+	}
+}
+
+// TestGCPManager_Operations verifies that Start, Stop, Restart and DeleteVPC wait for their operations.
+func TestGCPManager_Operations(t *testing.T) {
+	var operations, filters []string
+	server := httptest.NewServer(fakeGCPComputeHandler(&operations, &filters))
+	defer server.Close()
+
+	manager := newTestGCPManager(server.URL)
+	id := "projects/proj/zones/us-central1-a/instances/vm-1"
+
+	for _, operation := range []func(string) (*VPC, error){manager.Start, manager.Stop, manager.Restart} {
+		vpc, err := operation(id)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if vpc.ID != id || vpc.State != VPCStateAvailable {
+			t.Errorf("unexpected VPC returned: %+v", vpc)
+		}
+	}
+	if err := manager.DeleteVPC(server.URL + "/" + id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "POST /start,wait,POST /stop,wait,POST /reset,wait,DELETE ,wait"
+	if got := strings.Join(operations, ","); got != expected {
+		t.Errorf("expected operations %q, got %q", expected, got)
+	}
+
+	_, err := manager.GetVPC("projects/proj/zones/us-central1-a/instances/missing")
+	var gcpErr *gcpResponseError
+	if !errors.As(err, &gcpErr) || gcpErr.StatusCode != http.StatusNotFound || gcpErr.Reason != "notFound" {
+		t.Errorf("expected a notFound error, got %v", err)
+	}
+}
+
+// TestGCPInstancesClient_WaitFailure verifies that a failed operation is reported as an error.
+func TestGCPInstancesClient_WaitFailure(t *testing.T) {
+	client := &GCPInstancesClient{Credential: fakeGCPTokenSource{}, ProjectID: "proj"}
+	op := &GCPOperation{Name: "op-1", Status: "DONE"}
+	if err := client.Wait(context.Background(), op); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	failed := `{"name":"op-1","status":"DONE","error":{"errors":[{"code":"ZONE_RESOURCE_POOL_EXHAUSTED","message":"no capacity"}]}}`
+	if err := json.Unmarshal([]byte(failed), op); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.Wait(context.Background(), op); err == nil || !strings.Contains(err.Error(), "ZONE_RESOURCE_POOL_EXHAUSTED") {
+		t.Errorf("expected the operation error, got %v", err)
+	}
+}
+
+// TestGCPManager_Resize verifies that a running instance is stopped, modified and started again.
+func TestGCPManager_Resize(t *testing.T) {
+	var operations, filters []string
+	server := httptest.NewServer(fakeGCPComputeHandler(&operations, &filters))
+	defer server.Close()
+
+	manager := newTestGCPManager(server.URL)
+	vpc, err := manager.Resize("projects/proj/zones/us-central1-a/instances/vm-1", "e2-standard-4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vpc.State != VPCStateAvailable {
+		t.Errorf("unexpected VPC returned: %+v", vpc)
+	}
+
+	expected := "POST /stop,wait,POST /setMachineType,wait,POST /start,wait"
+	if got := strings.Join(operations, ","); got != expected {
+		t.Errorf("expected operations %q, got %q", expected, got)
+	}
+}
+
+// TestGCPManager_ConcurrentUse verifies that concurrent calls on a manager share the client created by
+// setup and the machine type cache safely (run with -race), and that CreateVPC reports ErrNotSupported.
+func TestGCPManager_ConcurrentUse(t *testing.T) {
+	var operations, filters []string
+	server := httptest.NewServer(fakeGCPComputeHandler(&operations, &filters))
+	defer server.Close()
+
+	manager := newTestGCPManager(server.URL)
+	id := "projects/proj/zones/us-central1-a/instances/vm-1"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vpc, err := manager.GetVPC(id)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if vpc.VirtualCPUCount != 2 {
+				t.Errorf("expected the vCPUs of the machine type, got %d", vpc.VirtualCPUCount)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := manager.CreateVPC("vm-5", "10.0.0.0/16"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
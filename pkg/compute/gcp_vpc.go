@@ -0,0 +1,114 @@
+package compute
+
+import (
+	"math"
+	"strings"
+)
+
+// GCPInstance is the subset of a Compute Engine instance used by the GCPManager.
+type GCPInstance struct {
+	ID                string                `json:"id"`                // Numeric identifier of the instance, as a string.
+	Name              string                `json:"name"`              // Name of the instance, unique within its zone.
+	Zone              string                `json:"zone"`              // URL of the zone (e.g., ".../zones/us-central1-a").
+	MachineType       string                `json:"machineType"`       // URL of the machine type (e.g., ".../machineTypes/e2-medium").
+	Status            string                `json:"status"`            // e.g., "PROVISIONING", "RUNNING", "TERMINATED".
+	SelfLink          string                `json:"selfLink"`          // Full URL of the instance.
+	CPUPlatform       string                `json:"cpuPlatform"`       // e.g., "Intel Broadwell".
+	NetworkInterfaces []GCPNetworkInterface `json:"networkInterfaces"` // Network interfaces, the first being the primary one.
+}
+
+// GCPNetworkInterface is a network interface of a Compute Engine instance.
+type GCPNetworkInterface struct {
+	NetworkIP     string `json:"networkIP"` // Internal IP address.
+	AccessConfigs []struct {
+		NatIP string `json:"natIP"` // External IP address, if any.
+	} `json:"accessConfigs"`
+}
+
+// GCPMachineType describes the hardware of a Compute Engine machine type.
+type GCPMachineType struct {
+	Name      string `json:"name"`      // Machine type name (e.g., "e2-medium").
+	GuestCPUs int64  `json:"guestCpus"` // Number of vCPUs.
+	MemoryMB  int64  `json:"memoryMb"`  // Memory in MB.
+}
+
+// lastPathSegment returns the part of a Compute Engine URL after its last slash (e.g., the zone name of a zone URL).
+func lastPathSegment(resourceURL string) string {
+	return resourceURL[strings.LastIndex(resourceURL, "/")+1:]
+}
+
+// ZoneName returns the name of the zone of the instance (e.g., "us-central1-a").
+func (i GCPInstance) ZoneName() string {
+	return lastPathSegment(i.Zone)
+}
+
+// MachineTypeName returns the name of the machine type of the instance (e.g., "e2-medium").
+func (i GCPInstance) MachineTypeName() string {
+	return lastPathSegment(i.MachineType)
+}
+
+// ResourcePath returns the path identifying the instance within the Compute Engine API
+// ("projects/{project}/zones/{zone}/instances/{name}"), used as the VPC ID.
+func (i GCPInstance) ResourcePath() string {
+	return gcpResourcePath(i.SelfLink)
+}
+
+// GCPInstanceToVPC converts a Compute Engine instance into a generic VPC structure.
+// The CPU and memory come from machineType, the description of the machine type; they are left at zero when it is nil.
+func GCPInstanceToVPC(instance GCPInstance, machineType *GCPMachineType) VPC {
+	zone := instance.ZoneName()
+	// The region is the zone without its suffix (e.g. "us-central1-a" -> "us-central1")
+	region := zone
+	if index := strings.LastIndex(zone, "-"); index > 0 {
+		region = zone[:index]
+	}
+
+	privateIP, publicIP := "", ""
+	if len(instance.NetworkInterfaces) > 0 {
+		privateIP = instance.NetworkInterfaces[0].NetworkIP
+		if len(instance.NetworkInterfaces[0].AccessConfigs) > 0 {
+			publicIP = instance.NetworkInterfaces[0].AccessConfigs[0].NatIP
+		}
+	}
+
+	vpc := VPC{
+		ID:               instance.ResourcePath(),
+		Name:             instance.Name,
+		Region:           region,
+		AvailabilityZone: zone,
+		Provider:         "gcp",
+		Description:      instance.MachineTypeName(),
+		CPUDescription:   instance.CPUPlatform,
+
+		PrivateIP: privateIP,
+		PublicIP:  publicIP,
+
+		ProviderSpecific: instance,
+		State:            mapGCPStatusToVPCState(instance.Status),
+	}
+
+	if machineType != nil {
+		// Compute Engine reports vCPUs only, so they are used for both counts.
+		vpc.CPUCount = machineType.GuestCPUs
+		vpc.VirtualCPUCount = machineType.GuestCPUs
+		vpc.MemoryGB = int64(math.Round(float64(machineType.MemoryMB) / 1024))
+	}
+
+	return vpc
+}
+
+// mapGCPStatusToVPCState maps the status of a Compute Engine instance to a generic VPC state.
+func mapGCPStatusToVPCState(status string) VPCStateEnum {
+	switch status {
+	case "PROVISIONING", "STAGING":
+		return VPCStateCreating
+	case "RUNNING":
+		return VPCStateAvailable
+	case "STOPPING", "SUSPENDING", "REPAIRING":
+		return VPCStateModifying
+	case "STOPPED", "SUSPENDED", "TERMINATED":
+		return VPCStateUnavailable
+	default:
+		return VPCStateUnavailable
+	}
+}
@@ -0,0 +1,283 @@
+package compute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+)
+
+// MockManager is an in-memory Manager for testing code that depends on Manager without cloud
+// credentials. Its VPCs change state immediately: Start and Restart make them VPCStateAvailable,
+// Stop makes them VPCStateUnavailable, CreateVPC adds an available VPC and DeleteVPC removes it.
+// It is safe for concurrent use, and the VPCs it returns are copies of the ones it stores.
+type MockManager struct {
+	mu     sync.Mutex
+	vpcs   []VPC
+	nextID int
+	pricer Pricer
+}
+
+// NewMockManager returns a MockManager holding a copy of the initial VPCs.
+func NewMockManager(initial []VPC) *MockManager {
+	m := &MockManager{vpcs: make([]VPC, 0, len(initial))}
+	for _, vpc := range initial {
+		m.vpcs = append(m.vpcs, cloneVPC(vpc))
+	}
+	return m
+}
+
+// WithPricing sets the Pricer used to populate the cost estimate of the VPCs returned.
+func (m *MockManager) WithPricing(p Pricer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pricer = p
+}
+
+// cloneVPC copies the VPC so its tags and cost estimate are not shared with the caller. ProviderSpecific
+// is kept as is, since its type is up to whoever set it.
+func cloneVPC(vpc VPC) VPC {
+	vpc.Tags = maps.Clone(vpc.Tags)
+	if vpc.CostEstimate != nil {
+		estimate := *vpc.CostEstimate
+		vpc.CostEstimate = &estimate
+	}
+	return vpc
+}
+
+// list returns the VPCs accepted by match, capped at fields["max_results"] like the providers do.
+func (m *MockManager) list(ctx context.Context, fields map[string]interface{}, match func(VPC) bool) ([]VPC, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	var response []VPC
+	for _, vpc := range m.vpcs {
+		if match(vpc) {
+			response = append(response, cloneVPC(vpc))
+		}
+	}
+	pricer := m.pricer
+	m.mu.Unlock()
+
+	var err error
+	if limit := maxResults(fields); limit > 0 && len(response) > limit {
+		response, err = response[:limit], ErrTruncated
+	}
+	if pricingErr := applyPricing(pricer, response); pricingErr != nil {
+		return nil, pricingErr
+	}
+	return response, err
+}
+
+// listState returns the VPCs in one of the given states.
+func (m *MockManager) listState(ctx context.Context, fields map[string]interface{}, states ...VPCStateEnum) ([]VPC, error) {
+	return m.list(ctx, fields, func(vpc VPC) bool {
+		for _, state := range states {
+			if vpc.State == state {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// update applies change to the stored VPC and returns a copy of the result.
+func (m *MockManager) update(ctx context.Context, id string, change func(*VPC)) (*VPC, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.vpcs {
+		if m.vpcs[i].ID == id {
+			change(&m.vpcs[i])
+			vpcs := []VPC{cloneVPC(m.vpcs[i])}
+			if err := applyPricing(m.pricer, vpcs); err != nil {
+				return nil, err
+			}
+			return &vpcs[0], nil
+		}
+	}
+	return nil, fmt.Errorf("VPC %s not found", id)
+}
+
+// ListRunningVPCsCtx returns the VPCs in VPCStateAvailable.
+func (m *MockManager) ListRunningVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.listState(ctx, fields, VPCStateAvailable)
+}
+
+// ListStartingVPCsCtx returns the VPCs in VPCStateModifying, as the providers map starting instances to it.
+func (m *MockManager) ListStartingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.listState(ctx, fields, VPCStateModifying)
+}
+
+// ListStoppingVPCsCtx returns the VPCs in VPCStateModifying, as the providers map stopping instances to it.
+func (m *MockManager) ListStoppingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.listState(ctx, fields, VPCStateModifying)
+}
+
+// ListStoppedVPCsCtx returns the VPCs in VPCStateUnavailable.
+func (m *MockManager) ListStoppedVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.listState(ctx, fields, VPCStateUnavailable)
+}
+
+// ListCreatingVPCsCtx returns the VPCs in VPCStateCreating.
+func (m *MockManager) ListCreatingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.listState(ctx, fields, VPCStateCreating)
+}
+
+// ListDeletingVPCsCtx returns the VPCs in VPCStateDeleting.
+func (m *MockManager) ListDeletingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.listState(ctx, fields, VPCStateDeleting)
+}
+
+// ListDeletedVPCsCtx returns the VPCs in VPCStateDeleted. DeleteVPC removes VPCs instead of marking
+// them deleted, so only initial VPCs in that state are listed.
+func (m *MockManager) ListDeletedVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.listState(ctx, fields, VPCStateDeleted)
+}
+
+// ListAllVPCsCtx returns every VPC, regardless of state.
+func (m *MockManager) ListAllVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.list(ctx, fields, func(VPC) bool { return true })
+}
+
+// ListByShapeCtx returns the VPCs whose Description, which holds the shape, matches shape.
+func (m *MockManager) ListByShapeCtx(ctx context.Context, shape string, fields map[string]interface{}) ([]VPC, error) {
+	return m.list(ctx, fields, func(vpc VPC) bool { return vpc.Description == shape })
+}
+
+// CreateVPCCtx adds an available VPC with the name and CIDR block, identified as "mock-vpc-<n>", and
+// returns a copy of it priced by the Pricer when set.
+func (m *MockManager) CreateVPCCtx(ctx context.Context, name, cidr string) (*VPC, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	vpc := VPC{
+		ID:        fmt.Sprintf("mock-vpc-%d", m.nextID),
+		Name:      name,
+		Provider:  "mock",
+		CidrBlock: cidr,
+		State:     VPCStateAvailable,
+	}
+	m.vpcs = append(m.vpcs, vpc)
+	vpcs := []VPC{cloneVPC(vpc)}
+	if err := applyPricing(m.pricer, vpcs); err != nil {
+		return nil, err
+	}
+	return &vpcs[0], nil
+}
+
+// DeleteVPCCtx removes the VPC.
+func (m *MockManager) DeleteVPCCtx(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.vpcs {
+		if m.vpcs[i].ID == id {
+			m.vpcs = append(m.vpcs[:i], m.vpcs[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("VPC %s not found", id)
+}
+
+func (m *MockManager) GetVPCCtx(ctx context.Context, id string) (*VPC, error) {
+	return m.update(ctx, id, func(*VPC) {})
+}
+
+func (m *MockManager) StartCtx(ctx context.Context, id string) (*VPC, error) {
+	return m.update(ctx, id, func(vpc *VPC) { vpc.State = VPCStateAvailable })
+}
+
+func (m *MockManager) StopCtx(ctx context.Context, id string) (*VPC, error) {
+	return m.update(ctx, id, func(vpc *VPC) { vpc.State = VPCStateUnavailable })
+}
+
+func (m *MockManager) RestartCtx(ctx context.Context, id string) (*VPC, error) {
+	return m.update(ctx, id, func(vpc *VPC) { vpc.State = VPCStateAvailable })
+}
+
+// ResizeCtx sets the Description, which holds the shape, to newType.
+func (m *MockManager) ResizeCtx(ctx context.Context, id, newType string) (*VPC, error) {
+	if newType == "" {
+		return nil, errors.New("instance type is required")
+	}
+	return m.update(ctx, id, func(vpc *VPC) { vpc.Description = newType })
+}
+
+// The methods below run their Ctx variant with context.Background(), for callers that cannot cancel them.
+func (m *MockManager) ListRunningVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListRunningVPCsCtx(context.Background(), fields)
+}
+
+func (m *MockManager) ListStartingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListStartingVPCsCtx(context.Background(), fields)
+}
+
+func (m *MockManager) ListStoppingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListStoppingVPCsCtx(context.Background(), fields)
+}
+
+func (m *MockManager) ListStoppedVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListStoppedVPCsCtx(context.Background(), fields)
+}
+
+func (m *MockManager) ListCreatingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListCreatingVPCsCtx(context.Background(), fields)
+}
+
+func (m *MockManager) ListDeletingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListDeletingVPCsCtx(context.Background(), fields)
+}
+
+func (m *MockManager) ListDeletedVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListDeletedVPCsCtx(context.Background(), fields)
+}
+
+func (m *MockManager) ListAllVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListAllVPCsCtx(context.Background(), fields)
+}
+
+func (m *MockManager) ListByShape(shape string, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListByShapeCtx(context.Background(), shape, fields)
+}
+
+func (m *MockManager) CreateVPC(name, cidr string) (*VPC, error) {
+	return m.CreateVPCCtx(context.Background(), name, cidr)
+}
+
+func (m *MockManager) DeleteVPC(id string) error {
+	return m.DeleteVPCCtx(context.Background(), id)
+}
+
+func (m *MockManager) GetVPC(id string) (*VPC, error) {
+	return m.GetVPCCtx(context.Background(), id)
+}
+
+func (m *MockManager) Start(id string) (*VPC, error) {
+	return m.StartCtx(context.Background(), id)
+}
+
+func (m *MockManager) Stop(id string) (*VPC, error) {
+	return m.StopCtx(context.Background(), id)
+}
+
+func (m *MockManager) Restart(id string) (*VPC, error) {
+	return m.RestartCtx(context.Background(), id)
+}
+
+func (m *MockManager) Resize(id, newType string) (*VPC, error) {
+	return m.ResizeCtx(context.Background(), id, newType)
+}
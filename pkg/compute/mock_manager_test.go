@@ -0,0 +1,116 @@
+package compute
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestMockManager verifies that the in-memory manager filters by state and applies Start, Stop,
+// Resize, CreateVPC and DeleteVPC to the VPCs it lists.
+func TestMockManager(t *testing.T) {
+	var manager Manager = NewMockManager([]VPC{
+		{ID: "vpc-1", Description: "t3.micro", State: VPCStateAvailable, Tags: map[string]string{"env": "dev"}},
+		{ID: "vpc-2", Description: "t3.large", State: VPCStateUnavailable},
+	})
+	ids := func(vpcs []VPC, err error) []string {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var response []string
+		for _, vpc := range vpcs {
+			response = append(response, vpc.ID)
+		}
+		return response
+	}
+	expect := func(name string, got []string, want ...string) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s: expected %v, got %v", name, want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("%s: expected %v, got %v", name, want, got)
+			}
+		}
+	}
+
+	expect("running", ids(manager.ListRunningVPCs(nil)), "vpc-1")
+	expect("stopped", ids(manager.ListStoppedVPCs(nil)), "vpc-2")
+
+	if _, err := manager.Stop("vpc-1"); err != nil {
+		t.Fatalf("unexpected error stopping: %v", err)
+	}
+	if vpc, err := manager.Start("vpc-2"); err != nil || vpc.State != VPCStateAvailable {
+		t.Fatalf("expected vpc-2 to be started, got %+v (%v)", vpc, err)
+	}
+	expect("running after start and stop", ids(manager.ListRunningVPCs(nil)), "vpc-2")
+	expect("stopped after start and stop", ids(manager.ListStoppedVPCs(nil)), "vpc-1")
+
+	if _, err := manager.Resize("vpc-1", "t3.large"); err != nil {
+		t.Fatalf("unexpected error resizing: %v", err)
+	}
+	expect("by shape", ids(manager.ListByShape("t3.large", nil)), "vpc-1", "vpc-2")
+
+	created, err := manager.CreateVPC("new", "10.0.0.0/16")
+	if err != nil {
+		t.Fatalf("unexpected error creating: %v", err)
+	}
+	if err := manager.DeleteVPC("vpc-2"); err != nil {
+		t.Fatalf("unexpected error deleting: %v", err)
+	}
+	expect("all", ids(manager.ListAllVPCs(nil)), "vpc-1", created.ID)
+
+	vpcs, err := manager.ListAllVPCs(map[string]interface{}{"max_results": 1})
+	if !errors.Is(err, ErrTruncated) || len(vpcs) != 1 {
+		t.Errorf("expected 1 VPC with ErrTruncated, got %d (%v)", len(vpcs), err)
+	}
+
+	// The returned VPCs are copies: changing their tags does not change the stored VPC
+	vpc, err := manager.GetVPC("vpc-1")
+	if err != nil {
+		t.Fatalf("unexpected error getting: %v", err)
+	}
+	vpc.Tags["env"] = "prod"
+	if vpc, _ := manager.GetVPC("vpc-1"); vpc.Tags["env"] != "dev" {
+		t.Errorf("expected the stored tags to be unchanged, got %v", vpc.Tags)
+	}
+
+	if _, err := manager.GetVPC("missing"); err == nil {
+		t.Error("expected an error for a missing VPC")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := manager.StartCtx(ctx, "vpc-1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// TestMockManager_Pricing verifies that the Pricer prices the VPCs returned by GetVPC and CreateVPC, and
+// that their cost estimates are copies of the stored ones.
+func TestMockManager_Pricing(t *testing.T) {
+	manager := NewMockManager([]VPC{
+		{ID: "vpc-1", Description: "t3.micro", State: VPCStateAvailable},
+		{ID: "vpc-2", Description: "t3.large", CostEstimate: &CostEstimate{HourlyUSD: 0.0832, Source: "static-table"}},
+	})
+
+	vpc, err := manager.GetVPC("vpc-2")
+	if err != nil {
+		t.Fatalf("unexpected error getting: %v", err)
+	}
+	vpc.CostEstimate.HourlyUSD = 1
+	if vpc, _ := manager.GetVPC("vpc-2"); vpc.CostEstimate.HourlyUSD != 0.0832 {
+		t.Errorf("expected the stored estimate to be unchanged, got %+v", vpc.CostEstimate)
+	}
+
+	manager.WithPricing(&stubPricer{prices: map[string]float64{"t3.micro": 0.0104}})
+	if vpc, err := manager.GetVPC("vpc-1"); err != nil || vpc.CostEstimate == nil || vpc.CostEstimate.HourlyUSD != 0.0104 {
+		t.Errorf("expected vpc-1 to be priced, got %+v (%v)", vpc, err)
+	}
+
+	errPricing := errors.New("pricing unavailable")
+	manager.WithPricing(&stubPricer{err: errPricing})
+	if _, err := manager.CreateVPC("new", "10.0.0.0/16"); !errors.Is(err, errPricing) {
+		t.Errorf("expected CreateVPC to apply the Pricer, got %v", err)
+	}
+}
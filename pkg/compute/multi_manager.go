@@ -0,0 +1,92 @@
+package compute
+
+import (
+	"errors"
+	"fmt"
+	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"sync"
+)
+
+// DefaultMaxConcurrentProviders is the number of providers queried simultaneously when
+// MultiManager.MaxConcurrentProviders is not set.
+const DefaultMaxConcurrentProviders = 8
+
+// MultiManager aggregates the inventory of several Manager instances (e.g. one per account),
+// querying them concurrently and merging their results.
+type MultiManager struct {
+	Managers               []Manager // Managers queried by the aggregated operations.
+	MaxConcurrentProviders int       // Maximum number of managers queried at once (defaults to DefaultMaxConcurrentProviders).
+}
+
+// NewMultiManager builds a MultiManager with one Manager per authentication configuration.
+// It returns an error if any of the managers cannot be created.
+func NewMultiManager(authConfigs []*authentication.AuthConfig) (*MultiManager, error) {
+	managers := make([]Manager, 0, len(authConfigs))
+	for _, authConfig := range authConfigs {
+		manager, err := NewVPCManager(authConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create manager for provider '%s': %w", authConfig.ProviderName, err)
+		}
+		managers = append(managers, manager)
+	}
+
+	return &MultiManager{Managers: managers, MaxConcurrentProviders: DefaultMaxConcurrentProviders}, nil
+}
+
+// ListAllVPCs lists the VPCs of every manager, querying at most MaxConcurrentProviders at once.
+// Parameters:
+//   - fields: A map (`map[string]interface{}`) of optional filters passed to every manager.
+//
+// Returns:
+//   - The merged slice of `VPC` objects from the managers that succeeded.
+//   - The errors of the managers that failed, joined together, or nil.
+func (m *MultiManager) ListAllVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.fanOut(func(manager Manager) ([]VPC, error) {
+		return manager.ListAllVPCs(fields)
+	})
+}
+
+// ListRunningVPCs lists the running VPCs of every manager, querying at most MaxConcurrentProviders at once.
+func (m *MultiManager) ListRunningVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.fanOut(func(manager Manager) ([]VPC, error) {
+		return manager.ListRunningVPCs(fields)
+	})
+}
+
+// fanOut runs list against every manager, bounding the concurrency with a semaphore,
+// and merges the results while aggregating per-manager errors.
+func (m *MultiManager) fanOut(list func(Manager) ([]VPC, error)) ([]VPC, error) {
+	limit := m.MaxConcurrentProviders
+	if limit <= 0 {
+		limit = DefaultMaxConcurrentProviders
+	}
+
+	semaphore := make(chan struct{}, limit)
+	results := make([][]VPC, len(m.Managers))
+	errs := make([]error, len(m.Managers))
+
+	wg := &sync.WaitGroup{}
+	for i, manager := range m.Managers {
+		wg.Add(1)
+		go func(i int, manager Manager) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			// Partial results (e.g. along with ErrTruncated) are kept next to the error.
+			vpcs, err := list(manager)
+			results[i] = vpcs
+			if err != nil {
+				errs[i] = fmt.Errorf("provider %d: %w", i, err)
+			}
+		}(i, manager)
+	}
+	wg.Wait()
+
+	// Merge in manager order so the output is deterministic.
+	var response []VPC
+	for _, vpcs := range results {
+		response = append(response, vpcs...)
+	}
+	return response, errors.Join(errs...)
+}
@@ -0,0 +1,97 @@
+package compute
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeManager implements Manager for tests. Only ListAllVPCs is overridden; calling any other
+// method panics through the embedded nil interface.
+type fakeManager struct {
+	Manager
+	id       string
+	err      error
+	inFlight *int
+	peak     *int
+	mu       *sync.Mutex
+}
+
+func (f *fakeManager) ListAllVPCs(map[string]interface{}) ([]VPC, error) {
+	f.mu.Lock()
+	*f.inFlight++
+	if *f.inFlight > *f.peak {
+		*f.peak = *f.inFlight
+	}
+	f.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	f.mu.Lock()
+	*f.inFlight--
+	f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []VPC{{ID: f.id}}, nil
+}
+
+// TestMultiManager_ListAllVPCs_BoundedConcurrency verifies that no more than MaxConcurrentProviders
+// managers are queried at once, and that results are merged and errors aggregated.
+func TestMultiManager_ListAllVPCs_BoundedConcurrency(t *testing.T) {
+	var inFlight, peak int
+	mu := &sync.Mutex{}
+	errFailed := errors.New("listing failed")
+
+	multi := &MultiManager{MaxConcurrentProviders: 3}
+	for i := 0; i < 20; i++ {
+		manager := &fakeManager{id: fmt.Sprintf("vpc-%d", i), inFlight: &inFlight, peak: &peak, mu: mu}
+		if i == 5 {
+			manager.err = errFailed
+		}
+		multi.Managers = append(multi.Managers, manager)
+	}
+
+	vpcs, err := multi.ListAllVPCs(map[string]interface{}{})
+
+	if peak > 3 {
+		t.Errorf("expected at most 3 providers in flight, got %d", peak)
+	}
+	if len(vpcs) != 19 {
+		t.Errorf("expected 19 merged VPCs, got %d", len(vpcs))
+	}
+	if !errors.Is(err, errFailed) {
+		t.Errorf("expected aggregated error to contain the provider failure, got %v", err)
+	}
+}
+
+// TestMultiManager_ListAllVPCs_JoinedErrors verifies that every provider failure is wrapped with its
+// provider index and can be reached through the aggregated error.
+func TestMultiManager_ListAllVPCs_JoinedErrors(t *testing.T) {
+	var inFlight, peak int
+	mu := &sync.Mutex{}
+	errDenied := errors.New("access denied")
+	errThrottled := errors.New("throttled")
+
+	multi := &MultiManager{Managers: []Manager{
+		&fakeManager{id: "vpc-0", err: errDenied, inFlight: &inFlight, peak: &peak, mu: mu},
+		&fakeManager{id: "vpc-1", inFlight: &inFlight, peak: &peak, mu: mu},
+		&fakeManager{id: "vpc-2", err: errThrottled, inFlight: &inFlight, peak: &peak, mu: mu},
+	}}
+
+	vpcs, err := multi.ListAllVPCs(map[string]interface{}{})
+
+	if len(vpcs) != 1 || vpcs[0].ID != "vpc-1" {
+		t.Errorf("expected only the VPC of the healthy provider, got %v", vpcs)
+	}
+	if !errors.Is(err, errDenied) || !errors.Is(err, errThrottled) {
+		t.Fatalf("expected aggregated error to contain every provider failure, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "provider 0: access denied") || !strings.Contains(err.Error(), "provider 2: throttled") {
+		t.Errorf("expected each failure to carry its provider index, got %v", err)
+	}
+}
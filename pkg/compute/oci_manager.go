@@ -2,9 +2,15 @@ package compute
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/diegoyosiura/cloud-manager/pkg/backoff"
+	"github.com/diegoyosiura/cloud-manager/pkg/observer"
 	"github.com/oracle/oci-go-sdk/v65/common"
 	"github.com/oracle/oci-go-sdk/v65/core"
+	"strconv"
+	"strings"
 )
 
 // OCIManager manages VPC-related operations in Oracle Cloud Infrastructure (OCI).
@@ -12,14 +18,24 @@ import (
 type OCIManager struct {
 	Auth   *authentication.OCIAuth // OCI authentication details.
 	Client *core.ComputeClient     // OCI Compute Client for interacting with OCI services.
+	Pricer Pricer                  // Populates VPC.CostEstimate when set (optional).
+
+	Backoff  backoff.Backoff   // Retries Start, Stop and Restart on throttling or server errors when set (optional).
+	Observer observer.Observer // Receives the API call, duration and retry metrics when set (optional).
+}
+
+// WithPricing sets the Pricer used to populate the cost estimate of the VPCs returned by ListVPCs and GetVPC.
+func (m *OCIManager) WithPricing(p Pricer) {
+	m.Pricer = p
 }
 
-// ListVPCs filters VPCs based on a lifecycle state and additional fields.
+// ListVPCsCtx filters VPCs based on a lifecycle state and additional fields.
 // Parameters:
 // - fields: A generic map where keys (e.g., "oci_compartment_id") provide filtering options.
 // - enum: The lifecycle state to filter VPCs (e.g., Running, Stopped).
-// Returns: A list of filtered VPCs or an error if the request fails.
-func (m *OCIManager) ListVPCs(fields map[string]interface{}, enum *core.InstanceLifecycleStateEnum) ([]VPC, error) {
+// Every page is read unless fields["max_results"] (an int) caps the number of instances collected.
+// Returns: A list of filtered VPCs or an error if the request fails, or ErrTruncated with the capped list when more instances exist.
+func (m *OCIManager) ListVPCsCtx(ctx context.Context, fields map[string]interface{}, enum *core.InstanceLifecycleStateEnum) ([]VPC, error) {
 	if m.Client == nil {
 		cl, err := core.NewComputeClientWithConfigurationProvider(m.Auth.GetConfigurationProvider())
 		if err != nil {
@@ -35,15 +51,43 @@ func (m *OCIManager) ListVPCs(fields map[string]interface{}, enum *core.Instance
 		request.LifecycleState = *enum
 	}
 
-	resp, err := m.Client.ListInstances(context.Background(), request)
+	limit := maxResults(fields)
+	truncated := false
+	var response []VPC
+	for {
+		var resp core.ListInstancesResponse
+		err := observer.Call(m.Observer, "oci", "ListInstances", func() (err error) {
+			resp, err = m.Client.ListInstances(ctx, request)
+			return err
+		})
 
-	if err != nil {
-		return nil, err
+		if err != nil {
+			return nil, err
+		}
+
+		for _, vpc := range resp.Items {
+			if limit > 0 && len(response) == limit {
+				truncated = true
+				break
+			}
+			response = append(response, OCIInstanceToVPC(vpc))
+		}
+
+		if truncated || resp.OpcNextPage == nil {
+			break
+		}
+		if limit > 0 && len(response) == limit {
+			truncated = true
+			break
+		}
+		request.Page = resp.OpcNextPage
 	}
 
-	var response []VPC
-	for _, vpc := range resp.Items {
-		response = append(response, OCIInstanceToVPC(vpc))
+	if err := applyPricing(m.Pricer, response); err != nil {
+		return nil, err
+	}
+	if truncated {
+		return response, ErrTruncated
 	}
 	return response, nil
 }
@@ -72,51 +116,91 @@ func convertMapInstanceRequest(fields map[string]interface{}) core.ListInstances
 // - ListDeletedVPCs: Lists VPCs in the "Deleted" state.
 // - ListAllVPCs: Aggregates all VPCs from any lifecycle state.
 
-func (m *OCIManager) ListRunningVPCs(fields map[string]interface{}) ([]VPC, error) {
+func (m *OCIManager) ListRunningVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
 	ils := core.InstanceLifecycleStateRunning
-	return m.ListVPCs(fields, &ils)
+	return m.ListVPCsCtx(ctx, fields, &ils)
 }
 
-func (m *OCIManager) ListStartingVPCs(fields map[string]interface{}) ([]VPC, error) {
+func (m *OCIManager) ListStartingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
 	ils := core.InstanceLifecycleStateStarting
-	return m.ListVPCs(fields, &ils)
+	return m.ListVPCsCtx(ctx, fields, &ils)
 }
 
-func (m *OCIManager) ListStoppingVPCs(fields map[string]interface{}) ([]VPC, error) {
+func (m *OCIManager) ListStoppingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
 	ils := core.InstanceLifecycleStateStopping
-	return m.ListVPCs(fields, &ils)
+	return m.ListVPCsCtx(ctx, fields, &ils)
 }
-func (m *OCIManager) ListStoppedVPCs(fields map[string]interface{}) ([]VPC, error) {
+func (m *OCIManager) ListStoppedVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
 	ils := core.InstanceLifecycleStateStopped
-	return m.ListVPCs(fields, &ils)
+	return m.ListVPCsCtx(ctx, fields, &ils)
 }
 
-func (m *OCIManager) ListCreatingVPCs(fields map[string]interface{}) ([]VPC, error) {
+func (m *OCIManager) ListCreatingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
 	ils := core.InstanceLifecycleStateProvisioning
-	return m.ListVPCs(fields, &ils)
+	return m.ListVPCsCtx(ctx, fields, &ils)
 }
 
-func (m *OCIManager) ListDeletingVPCs(fields map[string]interface{}) ([]VPC, error) {
+func (m *OCIManager) ListDeletingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
 	ils := core.InstanceLifecycleStateTerminating
-	return m.ListVPCs(fields, &ils)
+	return m.ListVPCsCtx(ctx, fields, &ils)
 }
 
-func (m *OCIManager) ListDeletedVPCs(fields map[string]interface{}) ([]VPC, error) {
+func (m *OCIManager) ListDeletedVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
 	ils := core.InstanceLifecycleStateTerminated
-	return m.ListVPCs(fields, &ils)
+	return m.ListVPCsCtx(ctx, fields, &ils)
 }
 
-func (m *OCIManager) ListAllVPCs(fields map[string]interface{}) ([]VPC, error) {
-	return m.ListVPCs(fields, nil)
+func (m *OCIManager) ListAllVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListVPCsCtx(ctx, fields, nil)
 }
-func (m *OCIManager) CreateVPC(name, cidr string) (*VPC, error) {
-	return &VPC{}, nil
+
+// ListByShapeCtx lists the VPCs whose shape matches shape (e.g., "VM.Standard.E4.Flex").
+// ListInstances cannot filter by shape, so the instances are filtered after being fetched;
+// a "max_results" cap therefore applies to the instances fetched, before filtering.
+func (m *OCIManager) ListByShapeCtx(ctx context.Context, shape string, fields map[string]interface{}) ([]VPC, error) {
+	vpcs, err := m.ListAllVPCsCtx(ctx, fields)
+	if err != nil && !errors.Is(err, ErrTruncated) {
+		return nil, err
+	}
+
+	var response []VPC
+	for _, vpc := range vpcs {
+		// Description holds the instance shape (see OCIInstanceToVPC).
+		if vpc.Description == shape {
+			response = append(response, vpc)
+		}
+	}
+	return response, err
 }
-func (m *OCIManager) DeleteVPC(id string) error {
+
+// CreateVPCCtx is not supported: an instance cannot be launched from a name and a CIDR block alone.
+// The error matches ErrNotSupported.
+func (m *OCIManager) CreateVPCCtx(ctx context.Context, name, cidr string) (*VPC, error) {
+	return nil, fmt.Errorf("oci: cannot create instance '%s': %w", name, ErrNotSupported)
+}
+
+// DeleteVPCCtx terminates the instance with the given OCID, as the Azure and GCP managers delete theirs.
+// Its boot volume is deleted along with it.
+func (m *OCIManager) DeleteVPCCtx(ctx context.Context, id string) error {
+	if m.Client == nil {
+		cl, err := core.NewComputeClientWithConfigurationProvider(m.Auth.GetConfigurationProvider())
+		if err != nil {
+			return err
+		}
+		m.Client = &cl
+	}
+
+	err := observer.Call(m.Observer, "oci", "TerminateInstance", func() error {
+		_, err := m.Client.TerminateInstance(ctx, core.TerminateInstanceRequest{InstanceId: &id})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to terminate OCI instance '%s': %w", id, err)
+	}
 	return nil
 }
 
-func (m *OCIManager) GetVPC(id string) (*VPC, error) {
+func (m *OCIManager) GetVPCCtx(ctx context.Context, id string) (*VPC, error) {
 	if m.Client == nil {
 		cl, err := core.NewComputeClientWithConfigurationProvider(m.Auth.GetConfigurationProvider())
 		if err != nil {
@@ -126,17 +210,24 @@ func (m *OCIManager) GetVPC(id string) (*VPC, error) {
 	}
 
 	request := core.GetInstanceRequest{InstanceId: &id}
-	response, err := m.Client.GetInstance(context.Background(), request)
+	var response core.GetInstanceResponse
+	err := observer.Call(m.Observer, "oci", "GetInstance", func() (err error) {
+		response, err = m.Client.GetInstance(ctx, request)
+		return err
+	})
 
 	if err != nil {
 		return nil, err
 	}
-	vpc := OCIInstanceToVPC(response.Instance)
+	vpcs := []VPC{OCIInstanceToVPC(response.Instance)}
+	if err := applyPricing(m.Pricer, vpcs); err != nil {
+		return nil, err
+	}
 
-	return &vpc, err
+	return &vpcs[0], nil
 }
 
-func (m *OCIManager) Start(id string) (*VPC, error) {
+func (m *OCIManager) StartCtx(ctx context.Context, id string) (*VPC, error) {
 	if m.Client == nil {
 		cl, err := core.NewComputeClientWithConfigurationProvider(m.Auth.GetConfigurationProvider())
 		if err != nil {
@@ -149,7 +240,11 @@ func (m *OCIManager) Start(id string) (*VPC, error) {
 		InstanceId: &id,
 		Action:     core.InstanceActionActionStart,
 	}
-	response, err := m.Client.InstanceAction(context.Background(), request)
+	var response core.InstanceActionResponse
+	err := backoff.Retry(m.Backoff, retryable(ctx), observer.Retrying(m.Observer, "oci", "InstanceAction", func() (err error) {
+		response, err = m.Client.InstanceAction(ctx, request)
+		return err
+	}))
 
 	if err != nil {
 		return nil, err
@@ -160,7 +255,7 @@ func (m *OCIManager) Start(id string) (*VPC, error) {
 	return &vpc, err
 }
 
-func (m *OCIManager) Stop(id string) (*VPC, error) {
+func (m *OCIManager) StopCtx(ctx context.Context, id string) (*VPC, error) {
 	if m.Client == nil {
 		cl, err := core.NewComputeClientWithConfigurationProvider(m.Auth.GetConfigurationProvider())
 		if err != nil {
@@ -173,7 +268,11 @@ func (m *OCIManager) Stop(id string) (*VPC, error) {
 		InstanceId: &id,
 		Action:     core.InstanceActionActionStop,
 	}
-	response, err := m.Client.InstanceAction(context.Background(), request)
+	var response core.InstanceActionResponse
+	err := backoff.Retry(m.Backoff, retryable(ctx), observer.Retrying(m.Observer, "oci", "InstanceAction", func() (err error) {
+		response, err = m.Client.InstanceAction(ctx, request)
+		return err
+	}))
 
 	if err != nil {
 		return nil, err
@@ -184,7 +283,7 @@ func (m *OCIManager) Stop(id string) (*VPC, error) {
 	return &vpc, err
 }
 
-func (m *OCIManager) Restart(id string) (*VPC, error) {
+func (m *OCIManager) RestartCtx(ctx context.Context, id string) (*VPC, error) {
 	if m.Client == nil {
 		cl, err := core.NewComputeClientWithConfigurationProvider(m.Auth.GetConfigurationProvider())
 		if err != nil {
@@ -197,7 +296,11 @@ func (m *OCIManager) Restart(id string) (*VPC, error) {
 		InstanceId: &id,
 		Action:     core.InstanceActionActionReset,
 	}
-	response, err := m.Client.InstanceAction(context.Background(), request)
+	var response core.InstanceActionResponse
+	err := backoff.Retry(m.Backoff, retryable(ctx), observer.Retrying(m.Observer, "oci", "InstanceAction", func() (err error) {
+		response, err = m.Client.InstanceAction(ctx, request)
+		return err
+	}))
 
 	if err != nil {
 		return nil, err
@@ -207,3 +310,136 @@ func (m *OCIManager) Restart(id string) (*VPC, error) {
 
 	return &vpc, err
 }
+
+// ResizeCtx changes the shape of the instance with UpdateInstance and returns its updated state.
+// newType is a shape name (e.g., "VM.Standard.E4.Flex"), optionally followed by the OCPUs and the memory
+// in GB of flexible shapes: "VM.Standard.E4.Flex:2:16". OCI reboots a running instance to apply the new
+// shape, so it does not need to be stopped first. Shapes incompatible with the image or the availability
+// domain are rejected by OCI and its error is returned.
+func (m *OCIManager) ResizeCtx(ctx context.Context, id, newType string) (*VPC, error) {
+	shape, shapeConfig, err := parseOCIShape(newType)
+	if err != nil {
+		return nil, err
+	}
+	if m.Client == nil {
+		cl, err := core.NewComputeClientWithConfigurationProvider(m.Auth.GetConfigurationProvider())
+		if err != nil {
+			return nil, err
+		}
+		m.Client = &cl
+	}
+
+	request := core.UpdateInstanceRequest{
+		InstanceId:            &id,
+		UpdateInstanceDetails: core.UpdateInstanceDetails{Shape: &shape, ShapeConfig: shapeConfig},
+	}
+	var response core.UpdateInstanceResponse
+	err = backoff.Retry(m.Backoff, retryable(ctx), observer.Retrying(m.Observer, "oci", "UpdateInstance", func() (err error) {
+		response, err = m.Client.UpdateInstance(ctx, request)
+		return err
+	}))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to change the shape of instance %s to %s: %w", id, newType, err)
+	}
+
+	vpc := OCIInstanceToVPC(response.Instance)
+
+	return &vpc, nil
+}
+
+// parseOCIShape splits a "shape[:ocpus[:memoryGB]]" value into the shape name and the configuration of
+// a flexible shape, which is nil when neither OCPUs nor memory are given.
+func parseOCIShape(value string) (string, *core.UpdateInstanceShapeConfigDetails, error) {
+	parts := strings.Split(value, ":")
+	if parts[0] == "" || len(parts) > 3 {
+		return "", nil, fmt.Errorf("invalid shape '%s': expected shape[:ocpus[:memoryGB]]", value)
+	}
+	if len(parts) == 1 {
+		return parts[0], nil, nil
+	}
+
+	config := &core.UpdateInstanceShapeConfigDetails{}
+	ocpus, err := strconv.ParseFloat(parts[1], 32)
+	if err != nil || ocpus <= 0 {
+		return "", nil, fmt.Errorf("invalid OCPU count '%s' in shape '%s'", parts[1], value)
+	}
+	config.Ocpus = common.Float32(float32(ocpus))
+	if len(parts) == 3 {
+		memory, err := strconv.ParseFloat(parts[2], 32)
+		if err != nil || memory <= 0 {
+			return "", nil, fmt.Errorf("invalid memory '%s' in shape '%s'", parts[2], value)
+		}
+		config.MemoryInGBs = common.Float32(float32(memory))
+	}
+	return parts[0], config, nil
+}
+
+// The methods below run their Ctx variant with context.Background(), for callers that cannot cancel them.
+func (m *OCIManager) ListVPCs(fields map[string]interface{}, enum *core.InstanceLifecycleStateEnum) ([]VPC, error) {
+	return m.ListVPCsCtx(context.Background(), fields, enum)
+}
+
+func (m *OCIManager) ListRunningVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListRunningVPCsCtx(context.Background(), fields)
+}
+
+func (m *OCIManager) ListStartingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListStartingVPCsCtx(context.Background(), fields)
+}
+
+func (m *OCIManager) ListStoppingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListStoppingVPCsCtx(context.Background(), fields)
+}
+
+func (m *OCIManager) ListStoppedVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListStoppedVPCsCtx(context.Background(), fields)
+}
+
+func (m *OCIManager) ListCreatingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListCreatingVPCsCtx(context.Background(), fields)
+}
+
+func (m *OCIManager) ListDeletingVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListDeletingVPCsCtx(context.Background(), fields)
+}
+
+func (m *OCIManager) ListDeletedVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListDeletedVPCsCtx(context.Background(), fields)
+}
+
+func (m *OCIManager) ListAllVPCs(fields map[string]interface{}) ([]VPC, error) {
+	return m.ListAllVPCsCtx(context.Background(), fields)
+}
+
+func (m *OCIManager) ListByShape(shape string, fields map[string]interface{}) ([]VPC, error) {
+	return m.ListByShapeCtx(context.Background(), shape, fields)
+}
+
+func (m *OCIManager) CreateVPC(name, cidr string) (*VPC, error) {
+	return m.CreateVPCCtx(context.Background(), name, cidr)
+}
+
+func (m *OCIManager) DeleteVPC(id string) error {
+	return m.DeleteVPCCtx(context.Background(), id)
+}
+
+func (m *OCIManager) GetVPC(id string) (*VPC, error) {
+	return m.GetVPCCtx(context.Background(), id)
+}
+
+func (m *OCIManager) Start(id string) (*VPC, error) {
+	return m.StartCtx(context.Background(), id)
+}
+
+func (m *OCIManager) Stop(id string) (*VPC, error) {
+	return m.StopCtx(context.Background(), id)
+}
+
+func (m *OCIManager) Restart(id string) (*VPC, error) {
+	return m.RestartCtx(context.Background(), id)
+}
+
+func (m *OCIManager) Resize(id, newType string) (*VPC, error) {
+	return m.ResizeCtx(context.Background(), id, newType)
+}
@@ -0,0 +1,294 @@
+package compute
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestOCIManager returns an OCIManager whose compute client talks to the given fake endpoint.
+// Requests are signed with a throwaway key, which the fake endpoint does not verify.
+func newTestOCIManager(t *testing.T, endpoint string) *OCIManager {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	provider := common.NewRawConfigurationProvider("ocid1.tenancy", "ocid1.user", "us-ashburn-1", "aa:bb", string(privateKey), nil)
+	client, err := core.NewComputeClientWithConfigurationProvider(provider)
+	if err != nil {
+		t.Fatalf("unexpected error creating client: %v", err)
+	}
+	client.Host = endpoint
+
+	return &OCIManager{
+		Auth:   &authentication.OCIAuth{CompartmentID: "ocid1.compartment", Region: "us-ashburn-1"},
+		Client: &client,
+	}
+}
+
+// fakeOCIInstance returns an instance as served by the fake compute endpoint.
+func fakeOCIInstance(id, shape string) core.Instance {
+	return core.Instance{
+		Id:                 common.String(id),
+		DisplayName:        common.String(id),
+		AvailabilityDomain: common.String("AD-1"),
+		CompartmentId:      common.String("ocid1.compartment"),
+		Region:             common.String("us-ashburn-1"),
+		Shape:              common.String(shape),
+		LifecycleState:     core.InstanceLifecycleStateRunning,
+		TimeCreated:        &common.SDKTime{},
+		ShapeConfig:        &core.InstanceShapeConfig{},
+	}
+}
+
+// fakeOCIComputeHandler serves ListInstances with the given instances.
+func fakeOCIComputeHandler(instances []core.Instance) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(instances)
+	}
+}
+
+// TestOCIManager_ListByShape verifies that only instances of the requested shape are returned.
+func TestOCIManager_ListByShape(t *testing.T) {
+	server := httptest.NewServer(fakeOCIComputeHandler([]core.Instance{
+		fakeOCIInstance("ocid1.instance.1", "VM.Standard.E4.Flex"),
+		fakeOCIInstance("ocid1.instance.2", "VM.Standard2.1"),
+		fakeOCIInstance("ocid1.instance.3", "VM.Standard.E4.Flex"),
+	}))
+	defer server.Close()
+
+	vpcs, err := newTestOCIManager(t, server.URL).ListByShape("VM.Standard.E4.Flex", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(vpcs) != 2 {
+		t.Fatalf("expected 2 instances, got %d", len(vpcs))
+	}
+	for _, vpc := range vpcs {
+		if vpc.Description != "VM.Standard.E4.Flex" {
+			t.Errorf("expected only 'VM.Standard.E4.Flex' instances, got '%s' (%s)", vpc.Description, vpc.ID)
+		}
+	}
+}
+
+// TestOCIInstanceToVPC_AvailabilityDomain verifies that the availability domain is split into region and AD name.
+func TestOCIInstanceToVPC_AvailabilityDomain(t *testing.T) {
+	instance := fakeOCIInstance("ocid1.instance.1", "VM.Standard.E4.Flex")
+	instance.AvailabilityDomain = common.String("Uocm:PHX-AD-1")
+	instance.Region = common.String("phx")
+
+	vpc := OCIInstanceToVPC(instance)
+	if vpc.Region != "us-phoenix-1" || vpc.AvailabilityZone != "PHX-AD-1" {
+		t.Errorf("expected region us-phoenix-1 and AD PHX-AD-1, got %q and %q", vpc.Region, vpc.AvailabilityZone)
+	}
+
+	tests := []struct {
+		availabilityDomain, instanceRegion string
+		region, availabilityZone           string
+	}{
+		{"Uocm:PHX-AD-1", "", "us-phoenix-1", "PHX-AD-1"},
+		{"qIZq:US-ASHBURN-AD-2", "us-ashburn-1", "us-ashburn-1", "US-ASHBURN-AD-2"},
+		{"EXAMPLE:SA-SAOPAULO-1-AD-1", "", "sa-saopaulo-1", "SA-SAOPAULO-1-AD-1"},
+		{"AD-1", "", "", "AD-1"},
+	}
+	for _, tt := range tests {
+		region, availabilityZone := parseOCIAvailabilityDomain(tt.availabilityDomain, tt.instanceRegion)
+		if region != tt.region || availabilityZone != tt.availabilityZone {
+			t.Errorf("%q: expected (%q, %q), got (%q, %q)", tt.availabilityDomain, tt.region, tt.availabilityZone, region, availabilityZone)
+		}
+	}
+}
+
+// TestOCIInstanceToVPC_Tags verifies that freeform and defined tags are merged, the latter keyed as
+// "namespace.key", and that an instance without tags has nil Tags.
+func TestOCIInstanceToVPC_Tags(t *testing.T) {
+	instance := fakeOCIInstance("ocid1.instance.1", "VM.Standard.E4.Flex")
+	if vpc := OCIInstanceToVPC(instance); vpc.Tags != nil {
+		t.Errorf("expected nil tags, got %v", vpc.Tags)
+	}
+
+	instance.FreeformTags = map[string]string{"team": "billing"}
+	instance.DefinedTags = map[string]map[string]interface{}{
+		"Operations": {"CostCenter": 42, "Owner": "ops", "Empty": nil},
+	}
+	expected := map[string]string{
+		"team":                  "billing",
+		"Operations.CostCenter": "42",
+		"Operations.Owner":      "ops",
+		"Operations.Empty":      "",
+	}
+	if vpc := OCIInstanceToVPC(instance); !reflect.DeepEqual(vpc.Tags, expected) {
+		t.Errorf("expected tags %v, got %v", expected, vpc.Tags)
+	}
+
+	instance.FreeformTags = nil
+	if vpc := OCIInstanceToVPC(instance); len(vpc.Tags) != 3 {
+		t.Errorf("expected the defined tags alone, got %v", vpc.Tags)
+	}
+}
+
+// TestOCIManager_ListVPCs_MaxResults verifies that every page is read by default and that pagination
+// stops at the "max_results" cap, reporting ErrTruncated only when more instances exist.
+func TestOCIManager_ListVPCs_MaxResults(t *testing.T) {
+	pages := map[string][]core.Instance{
+		"":  {fakeOCIInstance("ocid1.instance.1", "VM.Standard2.1"), fakeOCIInstance("ocid1.instance.2", "VM.Standard2.1")},
+		"2": {fakeOCIInstance("ocid1.instance.3", "VM.Standard2.1"), fakeOCIInstance("ocid1.instance.4", "VM.Standard2.1")},
+		"3": {fakeOCIInstance("ocid1.instance.5", "VM.Standard2.1"), fakeOCIInstance("ocid1.instance.6", "VM.Standard2.1")},
+	}
+	next := map[string]string{"": "2", "2": "3"}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		page := r.URL.Query().Get("page")
+		if next[page] != "" {
+			w.Header().Set("opc-next-page", next[page])
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(pages[page])
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		maxResults int
+		count      int
+		requests   int
+		truncated  bool
+	}{
+		{maxResults: 0, count: 6, requests: 3},
+		{maxResults: 3, count: 3, requests: 2, truncated: true},
+		{maxResults: 4, count: 4, requests: 2, truncated: true},
+		{maxResults: 6, count: 6, requests: 3},
+	}
+	for _, tt := range tests {
+		requests = 0
+		fields := map[string]interface{}{}
+		if tt.maxResults > 0 {
+			fields["max_results"] = tt.maxResults
+		}
+
+		vpcs, err := newTestOCIManager(t, server.URL).ListAllVPCs(fields)
+		if tt.truncated != errors.Is(err, ErrTruncated) || (err != nil && !tt.truncated) {
+			t.Errorf("max_results %d: unexpected error %v", tt.maxResults, err)
+		}
+		if len(vpcs) != tt.count || requests != tt.requests {
+			t.Errorf("max_results %d: expected %d instances in %d requests, got %d in %d", tt.maxResults, tt.count, tt.requests, len(vpcs), requests)
+		}
+	}
+}
+
+// TestOCIManager_ListVPCs_FilterOnEveryPage verifies that the lifecycle filter and the default page size
+// are sent with every page request, not only the first one.
+func TestOCIManager_ListVPCs_FilterOnEveryPage(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		queries = append(queries, query.Get("page")+"|"+query.Get("lifecycleState")+"|"+query.Get("limit"))
+		instances := []core.Instance{fakeOCIInstance("ocid1.instance.1", "VM.Standard2.1")}
+		if query.Get("page") == "" {
+			w.Header().Set("opc-next-page", "2")
+		} else {
+			instances = []core.Instance{fakeOCIInstance("ocid1.instance.2", "VM.Standard2.1")}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(instances)
+	}))
+	defer server.Close()
+
+	vpcs, err := newTestOCIManager(t, server.URL).ListRunningVPCs(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(vpcs) != 2 || vpcs[1].ID != "ocid1.instance.2" {
+		t.Errorf("expected the instances of both pages, got %+v", vpcs)
+	}
+	expected := []string{"|RUNNING|100", "2|RUNNING|100"}
+	if strings.Join(queries, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected page requests %v, got %v", expected, queries)
+	}
+}
+
+// TestOCIManager_Resize verifies that UpdateInstance receives the shape and the flexible shape configuration.
+func TestOCIManager_Resize(t *testing.T) {
+	var details core.UpdateInstanceDetails
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || !strings.HasSuffix(r.URL.Path, "/instances/ocid1.instance") {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&details)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(fakeOCIInstance("ocid1.instance", *details.Shape))
+	}))
+	defer server.Close()
+
+	manager := newTestOCIManager(t, server.URL)
+	vpc, err := manager.Resize("ocid1.instance", "VM.Standard.E4.Flex:2:16")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vpc.Description != "VM.Standard.E4.Flex" {
+		t.Errorf("expected the new shape, got %q", vpc.Description)
+	}
+	if details.ShapeConfig == nil || *details.ShapeConfig.Ocpus != 2 || *details.ShapeConfig.MemoryInGBs != 16 {
+		t.Errorf("expected 2 OCPUs and 16 GB, got %+v", details.ShapeConfig)
+	}
+
+	for _, invalid := range []string{"", "VM.Standard.E4.Flex:x", "VM.Standard.E4.Flex:2:0", "a:1:2:3"} {
+		if _, err := manager.Resize("ocid1.instance", invalid); err == nil {
+			t.Errorf("expected an error for shape %q", invalid)
+		}
+	}
+}
+
+// TestOCIManager_DeleteVPC verifies that DeleteVPC terminates the instance and reports the failures.
+func TestOCIManager_DeleteVPC(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if !strings.HasSuffix(r.URL.Path, "/instances/ocid1.instance") {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":"NotAuthorizedOrNotFound","message":"instance not found"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	manager := newTestOCIManager(t, server.URL)
+	if err := manager.DeleteVPC("ocid1.instance"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requests) != 1 || !strings.HasPrefix(requests[0], http.MethodDelete+" ") {
+		t.Errorf("expected a single DELETE of the instance, got %v", requests)
+	}
+
+	err := manager.DeleteVPC("ocid1.missing")
+	var serviceErr common.ServiceError
+	if !errors.As(err, &serviceErr) || serviceErr.GetHTTPStatusCode() != http.StatusNotFound {
+		t.Errorf("expected the 404 of TerminateInstance, got %v", err)
+	}
+}
+
+// TestOCIManager_CreateVPC verifies that CreateVPC reports ErrNotSupported instead of an empty VPC.
+func TestOCIManager_CreateVPC(t *testing.T) {
+	vpc, err := (&OCIManager{}).CreateVPC("instance", "10.0.0.0/16")
+	if !errors.Is(err, ErrNotSupported) || vpc != nil {
+		t.Errorf("expected ErrNotSupported and no VPC, got %+v and %v", vpc, err)
+	}
+}
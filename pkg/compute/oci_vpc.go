@@ -1,8 +1,11 @@
 package compute
 
 import (
+	"fmt"
+	"github.com/oracle/oci-go-sdk/v65/common"
 	"github.com/oracle/oci-go-sdk/v65/core"
 	"math"
+	"strings"
 )
 
 // OCIInstanceToVPC converts an OCI Instance object into a generic VPC structure.
@@ -35,12 +38,19 @@ func OCIInstanceToVPC(instance core.Instance) VPC {
 		GPUDescription = *instance.ShapeConfig.GpuDescription
 	}
 
+	instanceRegion := ""
+	if instance.Region != nil {
+		instanceRegion = *instance.Region
+	}
+	region, availabilityZone := parseOCIAvailabilityDomain(*instance.AvailabilityDomain, instanceRegion)
+
 	vpc := VPC{
-		ID:          *instance.Id,
-		Name:        *instance.DisplayName,
-		Region:      *instance.AvailabilityDomain,
-		Provider:    "oci",
-		Description: *instance.Shape,
+		ID:               *instance.Id,
+		Name:             *instance.DisplayName,
+		Region:           region,
+		AvailabilityZone: availabilityZone,
+		Provider:         "oci",
+		Description:      *instance.Shape,
 
 		CPUCount:        CPUCount,
 		VirtualCPUCount: VirtualCPUCount,
@@ -49,6 +59,8 @@ func OCIInstanceToVPC(instance core.Instance) VPC {
 		GPUDescription:  GPUDescription,
 		MemoryGB:        MemoryGB,
 
+		Tags: ociTagsToMap(instance.FreeformTags, instance.DefinedTags),
+
 		ProviderSpecific: instance,
 	}
 
@@ -83,3 +95,48 @@ func OCIInstanceToVPC(instance core.Instance) VPC {
 	}
 	return vpc
 }
+
+// ociTagsToMap merges the freeform tags with the defined tags, the latter keyed as "namespace.key" and
+// with their values converted to strings. It returns nil when the instance has no tags.
+func ociTagsToMap(freeform map[string]string, defined map[string]map[string]interface{}) map[string]string {
+	if len(freeform) == 0 && len(defined) == 0 {
+		return nil
+	}
+	tags := make(map[string]string, len(freeform))
+	for key, value := range freeform {
+		tags[key] = value
+	}
+	for namespace, values := range defined {
+		for key, value := range values {
+			if value == nil {
+				tags[namespace+"."+key] = ""
+				continue
+			}
+			tags[namespace+"."+key] = fmt.Sprint(value)
+		}
+	}
+	return tags
+}
+
+// parseOCIAvailabilityDomain splits an availability domain such as "Uocm:PHX-AD-1" into the region
+// ("us-phoenix-1") and the AD name without the tenancy-specific prefix ("PHX-AD-1").
+// The instance region, when known, takes precedence over the region key of the AD name; both may be
+// short codes ("phx", "iad") and are normalized to the full region name.
+func parseOCIAvailabilityDomain(availabilityDomain string, instanceRegion string) (string, string) {
+	availabilityZone := availabilityDomain
+	if i := strings.LastIndex(availabilityZone, ":"); i >= 0 {
+		availabilityZone = availabilityZone[i+1:]
+	}
+
+	regionKey := instanceRegion
+	if regionKey == "" {
+		if i := strings.LastIndex(strings.ToUpper(availabilityZone), "-AD-"); i > 0 {
+			regionKey = availabilityZone[:i]
+		}
+	}
+	if regionKey == "" {
+		return "", availabilityZone
+	}
+
+	return string(common.StringToRegion(regionKey)), availabilityZone
+}
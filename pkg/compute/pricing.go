@@ -0,0 +1,32 @@
+package compute
+
+import "fmt"
+
+// CostEstimate is the estimated cost of running an instance, attached to VPC.CostEstimate when a Pricer is configured.
+type CostEstimate struct {
+	HourlyUSD float64 `json:"hourly_usd"` // Estimated cost per hour in US dollars.
+	Source    string  `json:"source"`     // Origin of the price (e.g., "aws-pricing-api", "static-table").
+}
+
+// Pricer estimates the hourly cost of instances from their normalized representation
+// (provider, region, and the instance type/shape held in VPC.Description).
+// Implementations return a nil estimate, and no error, for instances they cannot price.
+type Pricer interface {
+	EstimateCost(vpc VPC) (*CostEstimate, error)
+}
+
+// applyPricing sets the cost estimate of every VPC using the pricer. It does nothing when the pricer is nil.
+func applyPricing(pricer Pricer, vpcs []VPC) error {
+	if pricer == nil {
+		return nil
+	}
+
+	for i := range vpcs {
+		estimate, err := pricer.EstimateCost(vpcs[i])
+		if err != nil {
+			return fmt.Errorf("failed to estimate cost of instance '%s': %w", vpcs[i].ID, err)
+		}
+		vpcs[i].CostEstimate = estimate
+	}
+	return nil
+}
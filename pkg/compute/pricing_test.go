@@ -0,0 +1,95 @@
+package compute
+
+import (
+	"errors"
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"net/http/httptest"
+	"testing"
+)
+
+// stubPricer prices instances from a fixed table keyed by instance type/shape.
+type stubPricer struct {
+	prices map[string]float64
+	err    error
+}
+
+func (p *stubPricer) EstimateCost(vpc VPC) (*CostEstimate, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	price, ok := p.prices[vpc.Description]
+	if !ok {
+		return nil, nil
+	}
+	return &CostEstimate{HourlyUSD: price, Source: "stub"}, nil
+}
+
+// TestAWSManager_WithPricing verifies that the estimate is attached to every listed VPC and left nil for unknown types.
+func TestAWSManager_WithPricing(t *testing.T) {
+	server := httptest.NewServer(fakeEC2Handler([]fakeEC2Instance{
+		{ID: "i-1", Type: "t3.micro", State: "running"},
+		{ID: "i-2", Type: "m5.large", State: "running"},
+		{ID: "i-3", Type: "x1.32xlarge", State: "running"},
+	}))
+	defer server.Close()
+
+	manager := newTestAWSManager(t, server.URL)
+
+	vpcs, err := manager.ListAllVPCs(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, vpc := range vpcs {
+		if vpc.CostEstimate != nil {
+			t.Errorf("expected no estimate without a pricer, got %+v for %s", vpc.CostEstimate, vpc.ID)
+		}
+	}
+
+	manager.WithPricing(&stubPricer{prices: map[string]float64{"t3.micro": 0.0104, "m5.large": 0.096}})
+
+	vpcs, err = manager.ListAllVPCs(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]float64{"i-1": 0.0104, "i-2": 0.096}
+	for _, vpc := range vpcs {
+		price, priced := expected[vpc.ID]
+		switch {
+		case !priced && vpc.CostEstimate != nil:
+			t.Errorf("expected no estimate for %s, got %+v", vpc.ID, vpc.CostEstimate)
+		case priced && (vpc.CostEstimate == nil || vpc.CostEstimate.HourlyUSD != price || vpc.CostEstimate.Source != "stub"):
+			t.Errorf("expected estimate %.4f for %s, got %+v", price, vpc.ID, vpc.CostEstimate)
+		}
+	}
+
+	errUnavailable := errors.New("price list unavailable")
+	manager.WithPricing(&stubPricer{err: errUnavailable})
+	if _, err := manager.ListAllVPCs(map[string]interface{}{}); !errors.Is(err, errUnavailable) {
+		t.Errorf("expected the pricer error, got %v", err)
+	}
+}
+
+// TestOCIManager_WithPricing verifies that the estimate is attached to every listed OCI instance.
+func TestOCIManager_WithPricing(t *testing.T) {
+	server := httptest.NewServer(fakeOCIComputeHandler([]core.Instance{
+		fakeOCIInstance("ocid1.instance.1", "VM.Standard.E4.Flex"),
+		fakeOCIInstance("ocid1.instance.2", "VM.Standard2.1"),
+	}))
+	defer server.Close()
+
+	manager := newTestOCIManager(t, server.URL)
+	manager.WithPricing(&stubPricer{prices: map[string]float64{"VM.Standard.E4.Flex": 0.025, "VM.Standard2.1": 0.0638}})
+
+	vpcs, err := manager.ListAllVPCs(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(vpcs) != 2 {
+		t.Fatalf("expected 2 instances, got %d", len(vpcs))
+	}
+	for _, vpc := range vpcs {
+		if vpc.CostEstimate == nil || vpc.CostEstimate.HourlyUSD == 0 {
+			t.Errorf("expected an estimate for %s, got %+v", vpc.ID, vpc.CostEstimate)
+		}
+	}
+}
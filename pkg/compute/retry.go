@@ -0,0 +1,38 @@
+package compute
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"net/http"
+)
+
+// isTransient reports whether a provider error is worth retrying: throttling and server-side failures.
+func isTransient(err error) bool {
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) {
+		return reqErr.StatusCode() == http.StatusTooManyRequests || reqErr.StatusCode() >= http.StatusInternalServerError ||
+			reqErr.Code() == "RequestLimitExceeded" || reqErr.Code() == "Throttling"
+	}
+	var azureErr *azureResponseError
+	if errors.As(err, &azureErr) {
+		return azureErr.StatusCode == http.StatusTooManyRequests || azureErr.StatusCode >= http.StatusInternalServerError
+	}
+	var gcpErr *gcpResponseError
+	if errors.As(err, &gcpErr) {
+		return gcpErr.StatusCode == http.StatusTooManyRequests || gcpErr.StatusCode >= http.StatusInternalServerError
+	}
+	if serviceErr, ok := common.IsServiceError(err); ok {
+		return serviceErr.GetHTTPStatusCode() == http.StatusTooManyRequests || serviceErr.GetHTTPStatusCode() >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// retryable returns the retry predicate of the operations run with ctx: transient errors are retried
+// until ctx is done, so a cancelled caller does not wait for the remaining attempts.
+func retryable(ctx context.Context) func(error) bool {
+	return func(err error) bool {
+		return ctx.Err() == nil && isTransient(err)
+	}
+}
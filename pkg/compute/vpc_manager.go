@@ -1,27 +1,71 @@
 package compute
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
 )
 
+// ErrTruncated is returned, together with the VPCs collected so far, when a listing stops at the
+// "max_results" cap of the fields map while more instances exist.
+var ErrTruncated = errors.New("listing truncated at max_results")
+
+// ErrVPCHasDependencies is matched by the error returned when a VPC cannot be deleted because resources
+// (subnets, network interfaces, ...) still depend on it; see VPCDependencyError.
+var ErrVPCHasDependencies = errors.New("VPC has dependencies")
+
+// ErrNotSupported is matched by the error of an operation the provider's manager cannot perform, such as
+// CreateVPC where a VPC is an instance that cannot be created from a name and a CIDR block alone.
+var ErrNotSupported = errors.New("operation not supported by the provider")
+
+// maxResults returns the "max_results" cap (an int) of the fields map, or 0 (unlimited) when it is not set.
+func maxResults(fields map[string]interface{}) int {
+	if value, ok := fields["max_results"].(int); ok && value > 0 {
+		return value
+	}
+	return 0
+}
+
 // Manager is a generic interface for managing VPCs across cloud providers.
 // It includes methods for listing, creating, and deleting VPCs in various states.
 type Manager interface {
-	ListRunningVPCs(map[string]interface{}) ([]VPC, error)  // Lists VPCs in "Running" state.
-	ListStartingVPCs(map[string]interface{}) ([]VPC, error) // Lists VPCs in "Starting" state.
-	ListStoppingVPCs(map[string]interface{}) ([]VPC, error) // Lists VPCs in "Stopping" state.
-	ListStoppedVPCs(map[string]interface{}) ([]VPC, error)  // Lists VPCs in "Stopped" state.
-	ListCreatingVPCs(map[string]interface{}) ([]VPC, error) // Lists VPCs in "Creating" state.
-	ListDeletingVPCs(map[string]interface{}) ([]VPC, error) // Lists VPCs in "Deleting" state.
-	ListDeletedVPCs(map[string]interface{}) ([]VPC, error)  // Lists VPCs in "Deleted" state.
-	ListAllVPCs(map[string]interface{}) ([]VPC, error)      // Lists VPCs across all states.
-	CreateVPC(name, cidr string) (*VPC, error)              // Creates a new VPC.
-	DeleteVPC(id string) error                              // Deletes a VPC by ID.
-	GetVPC(id string) (*VPC, error)                         // Retrieves a specific VPC by ID.
-	Start(id string) (*VPC, error)                          // Start a VPC by ID.
-	Stop(id string) (*VPC, error)                           // Stop a VPC by ID.
-	Restart(id string) (*VPC, error)                        // Reboot a VPC by ID.
+	ListRunningVPCs(map[string]interface{}) ([]VPC, error)                  // Lists VPCs in "Running" state.
+	ListStartingVPCs(map[string]interface{}) ([]VPC, error)                 // Lists VPCs in "Starting" state.
+	ListStoppingVPCs(map[string]interface{}) ([]VPC, error)                 // Lists VPCs in "Stopping" state.
+	ListStoppedVPCs(map[string]interface{}) ([]VPC, error)                  // Lists VPCs in "Stopped" state.
+	ListCreatingVPCs(map[string]interface{}) ([]VPC, error)                 // Lists VPCs in "Creating" state.
+	ListDeletingVPCs(map[string]interface{}) ([]VPC, error)                 // Lists VPCs in "Deleting" state.
+	ListDeletedVPCs(map[string]interface{}) ([]VPC, error)                  // Lists VPCs in "Deleted" state.
+	ListAllVPCs(map[string]interface{}) ([]VPC, error)                      // Lists VPCs across all states.
+	ListByShape(shape string, fields map[string]interface{}) ([]VPC, error) // Lists VPCs of a given instance type/shape.
+	CreateVPC(name, cidr string) (*VPC, error)                              // Creates a new VPC.
+	DeleteVPC(id string) error                                              // Deletes a VPC by ID.
+	GetVPC(id string) (*VPC, error)                                         // Retrieves a specific VPC by ID.
+	Start(id string) (*VPC, error)                                          // Start a VPC by ID.
+	Stop(id string) (*VPC, error)                                           // Stop a VPC by ID.
+	Restart(id string) (*VPC, error)                                        // Reboot a VPC by ID.
+	Resize(id, newType string) (*VPC, error)                                // Change the instance type/shape of a VPC by ID.
+	WithPricing(p Pricer)                                                   // Sets the Pricer used to populate VPC.CostEstimate.
+
+	// The Ctx variants stop waiting on the provider when ctx is done, returning its error;
+	// the methods above call them with context.Background().
+	ListRunningVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error)
+	ListStartingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error)
+	ListStoppingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error)
+	ListStoppedVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error)
+	ListCreatingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error)
+	ListDeletingVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error)
+	ListDeletedVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error)
+	ListAllVPCsCtx(ctx context.Context, fields map[string]interface{}) ([]VPC, error)
+	ListByShapeCtx(ctx context.Context, shape string, fields map[string]interface{}) ([]VPC, error)
+	CreateVPCCtx(ctx context.Context, name, cidr string) (*VPC, error)
+	DeleteVPCCtx(ctx context.Context, id string) error
+	GetVPCCtx(ctx context.Context, id string) (*VPC, error)
+	StartCtx(ctx context.Context, id string) (*VPC, error)
+	StopCtx(ctx context.Context, id string) (*VPC, error)
+	RestartCtx(ctx context.Context, id string) (*VPC, error)
+	ResizeCtx(ctx context.Context, id, newType string) (*VPC, error)
 }
 
 // NewVPCManager is a factory function that returns a Manager implementation based on the cloud provider.
@@ -47,6 +91,20 @@ func NewVPCManager(authConfig *authentication.AuthConfig) (Manager, error) {
 			return nil, fmt.Errorf("invalid OCI authentication config")
 		}
 		return &AWSManager{Auth: awsConfig}, nil
+	case "azure":
+		// Returns an Azure-specific manager implementation.
+		azureConfig, ok := authConfig.Config.(*authentication.AzureAuth)
+		if !ok {
+			return nil, fmt.Errorf("invalid Azure authentication config")
+		}
+		return &AzureManager{Auth: azureConfig}, nil
+	case "gcp":
+		// Returns a GCP-specific manager implementation.
+		gcpConfig, ok := authConfig.Config.(*authentication.GCPAuth)
+		if !ok {
+			return nil, fmt.Errorf("invalid GCP authentication config")
+		}
+		return &GCPManager{Auth: gcpConfig}, nil
 
 	default:
 		// Returns an error if the cloud provider is unsupported.
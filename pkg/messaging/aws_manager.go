@@ -1,134 +1,126 @@
 package messaging
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/diegoyosiura/cloud-manager/pkg/backoff"
+	"github.com/diegoyosiura/cloud-manager/pkg/observer"
 	"net/smtp"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
-	"time"
 )
 
+// sesTagRegex matches the characters SES accepts in message tag names/values.
+var sesTagRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,256}$`)
+
+// sesConfigurationSetRegex matches the characters SES accepts in configuration set names, which are
+// shorter than the tags.
+var sesConfigurationSetRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
 type AWSManager struct {
 	Auth   *authentication.AWSAuth // AWS authentication details.
 	Client smtp.Auth
 
+	SESConfigurationSet string            // SES configuration set applied to every message (optional).
+	SESMessageTags      map[string]string // SES message tags applied to every message (optional).
+	UseSESAPI           bool              // Sends messages through the SES v2 API with the IAM credentials of Auth.Session instead of SMTP (no SMTP credentials needed).
+	sesClient           sesSendClient     // SES v2 client, created on first use.
+	sesClientMu         sync.Mutex        // Guards the creation of sesClient by concurrent sends.
+	Backoff             backoff.Backoff   // Retry policy of transient failures, e.g. 4xx SMTP replies (defaults to DefaultSendBackoff, 3 attempts).
+	Observer            observer.Observer // Receives the SMTP call, retry and message metrics when set (optional).
+	TLSMode             TLSMode           // Encryption of the SMTP connection (defaults to TLSModeNone).
+	TLSConfig           *tls.Config       // TLS settings of the SMTP connection (optional).
+
+	MaxMessagesPerSecond float64 // Maximum dispatch rate of a Send batch, retries included (0 means unlimited).
+	ChannelBuffer        int     // Capacity of the status channel returned by Send (0 means MaxOCIMessages).
+
 	Messages   []Message
 	MessagesMT *sync.RWMutex
-}
 
-func (a *AWSManager) setup() (bool, error) {
-	a.Client = smtp.PlainAuth("", string(a.Auth.EmailUser), string(a.Auth.EmailPassword), a.Auth.EmailHost)
-	return true, nil
+	smtpBatchSender // Queue and send methods shared with the other managers, bound by NewMessageManager.
 }
 
-func (a *AWSManager) AddMessage(m Message) {
-	a.MessagesMT.Lock()
-	defer a.MessagesMT.Unlock()
-	a.Messages = append(a.Messages, m)
-}
-
-func (a *AWSManager) AddMessages(m []Message) {
-	a.MessagesMT.Lock()
-	defer a.MessagesMT.Unlock()
-	a.Messages = append(a.Messages, m...)
+// sendConfig returns the queue and the delivery settings used by the shared send loop.
+func (a *AWSManager) sendConfig() sendConfig {
+	return sendConfig{
+		provider:             "aws",
+		setup:                a.setup,
+		messages:             &a.Messages,
+		mt:                   a.MessagesMT,
+		backoff:              a.Backoff,
+		observer:             a.Observer,
+		tlsMode:              a.TLSMode,
+		tlsConfig:            a.TLSConfig,
+		maxMessagesPerSecond: a.MaxMessagesPerSecond,
+		channelBuffer:        a.ChannelBuffer,
+		endpoint: func() (string, smtp.Auth) {
+			return fmt.Sprintf(`%s:%s`, a.Auth.EmailHost, a.Auth.EmailPort), a.Client
+		},
+		batch: func() prepareFunc { return a.prepare },
+	}
 }
-func (a *AWSManager) CancelSend() (bool, error) {
-	ready, err := a.setup()
 
-	if !ready {
+func (a *AWSManager) setup() (bool, error) {
+	if err := a.validateSESOptions(); err != nil {
 		return false, err
 	}
-	panic("implement me")
+	a.Client = smtp.PlainAuth("", string(a.Auth.EmailUser), string(a.Auth.EmailPassword), a.Auth.EmailHost)
+	return true, nil
 }
 
-func (a *AWSManager) Send() (chan Message, bool, error) {
-	ready, err := a.setup()
-
-	if !ready {
-		return nil, false, err
+// validateSESOptions checks the configuration set and message tags against the characters SES allows.
+func (a *AWSManager) validateSESOptions() error {
+	if a.SESConfigurationSet != "" && !sesConfigurationSetRegex.MatchString(a.SESConfigurationSet) {
+		return fmt.Errorf("invalid SES configuration set name '%s'", a.SESConfigurationSet)
 	}
-
-	ch := a.sendMessage()
-
-	return ch, true, nil
+	for name, value := range a.SESMessageTags {
+		if !sesTagRegex.MatchString(name) {
+			return fmt.Errorf("invalid SES message tag name '%s'", name)
+		}
+		if !sesTagRegex.MatchString(value) {
+			return fmt.Errorf("invalid SES message tag value '%s' for tag '%s'", value, name)
+		}
+	}
+	return nil
 }
 
-func (a *AWSManager) SendStatus() (float64, error) {
-	ready, err := a.setup()
-
-	if !ready {
-		return 0.0, err
+// applySESHeaders adds the SES configuration set and message tag headers to the message.
+func (a *AWSManager) applySESHeaders(m *Message) {
+	if a.SESConfigurationSet != "" {
+		m.AddHeader("X-SES-CONFIGURATION-SET", a.SESConfigurationSet)
 	}
+	if len(a.SESMessageTags) > 0 {
+		names := make([]string, 0, len(a.SESMessageTags))
+		for name := range a.SESMessageTags {
+			names = append(names, name)
+		}
+		sort.Strings(names)
 
-	sent := 0.0
-	a.MessagesMT.Lock()
-	defer a.MessagesMT.Unlock()
-	for _, msg := range a.Messages {
-		if msg.Status == Sent {
-			sent++
+		tags := make([]string, 0, len(names))
+		for _, name := range names {
+			tags = append(tags, name+"="+a.SESMessageTags[name])
 		}
+		m.AddHeader("X-SES-MESSAGE-TAGS", strings.Join(tags, ", "))
 	}
-
-	return sent / float64(len(a.Messages)), nil
 }
 
-func (a *AWSManager) sendMessage() chan Message {
-	ch := make(chan Message, MaxOCIMessages)
-
-	go func() {
-		defer close(ch)
-		wg := &sync.WaitGroup{}
+// prepare adds the SES headers to m and renders it for either the SES API or the SMTP endpoint.
+func (a *AWSManager) prepare(ctx context.Context, m *Message, list []string) (string, func() error, error) {
+	// Copy the headers so the SES headers are not appended to the caller's slice.
+	m.Headers = append([]Header(nil), m.Headers...)
+	a.applySESHeaders(m)
 
-		tm := len(a.Messages)
-		for i := 0; i < tm; i++ {
-			a.MessagesMT.Lock()
-			m := a.Messages[i]
-			a.MessagesMT.Unlock()
-			m.Status = Queued
-			ch <- m
-			wg.Add(1)
-			go a.send(ch, m, wg)
+	if a.UseSESAPI {
+		data, err := m.Bytes()
+		if err != nil {
+			return "", nil, err
 		}
-
-		wg.Wait()
-	}()
-	return ch
-}
-
-func (a *AWSManager) send(ch chan Message, m Message, wg *sync.WaitGroup) {
-	defer wg.Done()
-	m.Status = Sending
-	ch <- m
-
-	list, err := m.Tolist()
-	if err != nil {
-		m.Status = SendError
-		m.DateStatus = time.Now()
-		m.Error = err
-		ch <- m
-		return
-	}
-
-	data, err := m.Bytes()
-	if err != nil {
-		m.Status = SendError
-		m.DateStatus = time.Now()
-		m.Error = err
-		ch <- m
-		return
+		input := sesSendEmailInput(m, list, data)
+		return "SendEmail", func() error { return a.sendSES(ctx, m, input) }, nil
 	}
-
-	err = smtp.SendMail(fmt.Sprintf(`%s:%s`, a.Auth.EmailHost, a.Auth.EmailPort), a.Client, m.From.Address, list, data)
-
-	if err != nil {
-		m.Status = SendError
-		m.DateStatus = time.Now()
-		m.Error = err
-		ch <- m
-		return
-	}
-
-	m.Status = Sent
-	m.DateStatus = time.Now()
-	ch <- m
+	return a.sendConfig().smtpDelivery(ctx, m, list)
 }
@@ -0,0 +1,189 @@
+package messaging
+
+import (
+	"bytes"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sesv2"
+	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/diegoyosiura/cloud-manager/pkg/backoff"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Test applying SES configuration-set and message-tag headers
+// Verifies that the AWS manager injects the SES headers while plain rendering (as used by OCI) does not.
+func TestAWSManagerSESHeaders(t *testing.T) {
+	manager := boundManager(&AWSManager{
+		MessagesMT:          &sync.RWMutex{},
+		SESConfigurationSet: "analytics",
+		SESMessageTags:      map[string]string{"campaign": "launch", "env": "prod"},
+	})
+
+	if err := manager.validateSESOptions(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	msg := generateSampleMessage()
+	manager.applySESHeaders(&msg)
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Contains(data, []byte("X-Ses-Configuration-Set: analytics\r\n")) {
+		t.Error("missing 'X-SES-CONFIGURATION-SET' header")
+	}
+	if !bytes.Contains(data, []byte("X-Ses-Message-Tags: campaign=launch, env=prod\r\n")) {
+		t.Error("missing or invalid 'X-SES-MESSAGE-TAGS' header")
+	}
+
+	// The OCI manager renders the message as-is, without any SES headers.
+	ociMsg := generateSampleMessage()
+	ociData, err := ociMsg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bytes.Contains(bytes.ToUpper(ociData), []byte("X-SES-")) {
+		t.Error("SES headers must not be present outside the AWS manager")
+	}
+}
+
+// Test validating SES options
+// Verifies that names and values outside the SES character set are rejected.
+func TestAWSManagerSESOptionsValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		manager *AWSManager
+	}{
+		{name: "Invalid configuration set", manager: &AWSManager{SESConfigurationSet: "bad set"}},
+		{name: "Configuration set too long", manager: &AWSManager{SESConfigurationSet: strings.Repeat("a", 65)}},
+		{name: "Invalid tag name", manager: &AWSManager{SESMessageTags: map[string]string{"bad:name": "value"}}},
+		{name: "Invalid tag value", manager: &AWSManager{SESMessageTags: map[string]string{"name": "bad value"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.manager.validateSESOptions(); err == nil {
+				t.Error("expected validation error, got nil")
+			}
+		})
+	}
+}
+
+// Test carrying metadata through the send channel
+// Verifies that Metadata reaches every event emitted by Send() and is never rendered into the email.
+func TestAWSManagerMetadataRoundTrip(t *testing.T) {
+	manager := boundManager(&AWSManager{MessagesMT: &sync.RWMutex{}})
+
+	msg := generateSampleMessage()
+	msg.MailTo = []string{"not-an-address"} // Fails before any network access.
+	msg.Metadata = map[string]string{"order_id": "1234", "campaign": "launch"}
+	manager.AddMessage(msg)
+
+	events := 0
+	for event := range manager.sendMessage(0) {
+		events++
+		if event.Metadata["order_id"] != "1234" || event.Metadata["campaign"] != "launch" {
+			t.Errorf("metadata lost in event with status %d: %v", event.Status, event.Metadata)
+		}
+	}
+	if events == 0 {
+		t.Fatal("expected events from the send channel")
+	}
+
+	data, err := generateSampleMessageWithMetadata().Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bytes.Contains(data, []byte("order_id")) || bytes.Contains(data, []byte("1234")) {
+		t.Error("metadata must not be rendered into the message")
+	}
+}
+
+// generateSampleMessageWithMetadata returns the sample message with metadata attached.
+func generateSampleMessageWithMetadata() *Message {
+	msg := generateSampleMessage()
+	msg.Metadata = map[string]string{"order_id": "1234"}
+	return &msg
+}
+
+// fakeSESClient answers SendEmail with the queued errors, then successfully, recording every request.
+type fakeSESClient struct {
+	mu     sync.Mutex
+	errs   []error
+	inputs []*sesv2.SendEmailInput
+}
+
+func (f *fakeSESClient) SendEmailWithContext(_ aws.Context, input *sesv2.SendEmailInput, _ ...request.Option) (*sesv2.SendEmailOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inputs = append(f.inputs, input)
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return &sesv2.SendEmailOutput{MessageId: aws.String("ses-message-id")}, nil
+}
+
+// Test sending through the SES v2 API
+// Verifies the raw message and envelope of the request, that throttling is retried and that rejections end in SendError.
+func TestAWSManagerSESAPI(t *testing.T) {
+	throttled := awserr.NewRequestFailure(awserr.New(sesv2.ErrCodeTooManyRequestsException, "Maximum sending rate exceeded", nil), 429, "req-1")
+	rejected := awserr.NewRequestFailure(awserr.New(sesv2.ErrCodeMessageRejected, "Email address is not verified", nil), 400, "req-2")
+
+	tests := []struct {
+		name     string
+		errs     []error
+		status   MessageStatus
+		attempts int
+	}{
+		{name: "Sent after throttling", errs: []error{throttled}, status: Sent, attempts: 2},
+		{name: "Rejected", errs: []error{rejected}, status: SendError, attempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeSESClient{errs: tt.errs}
+			manager := boundManager(&AWSManager{
+				Auth:       &authentication.AWSAuth{Authenticated: true},
+				MessagesMT: &sync.RWMutex{},
+				Backoff:    backoff.ConstantBackoff{Delay: time.Millisecond, Attempts: 3},
+				UseSESAPI:  true,
+				sesClient:  client,
+			})
+			manager.AddMessage(generateSampleMessage())
+
+			ch, _, err := manager.Send()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var last Message
+			for m := range ch {
+				last = m
+			}
+
+			if last.Status != tt.status || last.Attempts != tt.attempts {
+				t.Fatalf("expected status %d after %d attempts, got status %d after %d attempts (%v)", tt.status, tt.attempts, last.Status, last.Attempts, last.Error)
+			}
+			if tt.status == SendError && !strings.Contains(last.Error.Error(), "req-2") {
+				t.Errorf("expected the error to carry the request ID, got %v", last.Error)
+			}
+			if tt.status == Sent && last.ProviderMessageID != "ses-message-id" {
+				t.Errorf("expected the SES message ID, got %q", last.ProviderMessageID)
+			}
+
+			input := client.inputs[0]
+			if aws.StringValue(input.FromEmailAddress) != "from@email.com" || len(input.Destination.ToAddresses) != 3 {
+				t.Errorf("unexpected envelope: %s -> %v", aws.StringValue(input.FromEmailAddress), aws.StringValueSlice(input.Destination.ToAddresses))
+			}
+			if raw := input.Content.Raw; raw == nil || !bytes.Contains(raw.Data, []byte("Subject: ")) || bytes.Contains(raw.Data, []byte("Bcc:")) {
+				t.Errorf("expected the raw message without a Bcc header, got %q", raw)
+			}
+		})
+	}
+}
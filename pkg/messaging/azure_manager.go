@@ -0,0 +1,56 @@
+package messaging
+
+import (
+	"crypto/tls"
+	"fmt"
+	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/diegoyosiura/cloud-manager/pkg/backoff"
+	"github.com/diegoyosiura/cloud-manager/pkg/observer"
+	"net/smtp"
+	"sync"
+)
+
+// AzureManager sends messages through the SMTP endpoint of Azure Communication Services Email
+// (smtp.azurecomm.net, port 587 with TLSModeStartTLS). The SMTP user is the one configured for the
+// Communication Services resource and the password the secret of its Entra application.
+type AzureManager struct {
+	Auth   *authentication.AzureAuth // Azure authentication details.
+	Client smtp.Auth
+
+	Backoff   backoff.Backoff   // Retry policy of transient failures, e.g. 4xx SMTP replies (defaults to DefaultSendBackoff, 3 attempts).
+	Observer  observer.Observer // Receives the SMTP call, retry and message metrics when set (optional).
+	TLSMode   TLSMode           // Encryption of the SMTP connection (defaults to TLSModeNone; Azure's port 587 expects TLSModeStartTLS).
+	TLSConfig *tls.Config       // TLS settings of the SMTP connection (optional).
+
+	MaxMessagesPerSecond float64 // Maximum dispatch rate of a Send batch, retries included (0 means unlimited).
+	ChannelBuffer        int     // Capacity of the status channel returned by Send (0 means MaxOCIMessages).
+
+	Messages   []Message
+	MessagesMT *sync.RWMutex
+
+	smtpBatchSender // Queue and send methods shared with the other managers, bound by NewMessageManager.
+}
+
+// sendConfig returns the queue and the delivery settings used by the shared send loop.
+func (a *AzureManager) sendConfig() sendConfig {
+	return sendConfig{
+		provider:             "azure",
+		setup:                a.setup,
+		messages:             &a.Messages,
+		mt:                   a.MessagesMT,
+		backoff:              a.Backoff,
+		observer:             a.Observer,
+		tlsMode:              a.TLSMode,
+		tlsConfig:            a.TLSConfig,
+		maxMessagesPerSecond: a.MaxMessagesPerSecond,
+		channelBuffer:        a.ChannelBuffer,
+		endpoint: func() (string, smtp.Auth) {
+			return fmt.Sprintf(`%s:%s`, a.Auth.EmailHost, a.Auth.EmailPort), a.Client
+		},
+	}
+}
+
+func (a *AzureManager) setup() (bool, error) {
+	a.Client = smtp.PlainAuth("", a.Auth.EmailUser, a.Auth.EmailPassword, a.Auth.EmailHost)
+	return true, nil
+}
@@ -0,0 +1,359 @@
+package messaging
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/diegoyosiura/cloud-manager/pkg/backoff"
+	"github.com/diegoyosiura/cloud-manager/pkg/observer"
+	"golang.org/x/time/rate"
+	"net/mail"
+	"net/smtp"
+	"sync"
+	"time"
+)
+
+// maxConcurrentSends bounds the messages of a batch that are being delivered at once, so a large queue
+// does not start one goroutine, and one SMTP connection, per message. MaxMessagesPerSecond further
+// spaces the deliveries of these goroutines.
+const maxConcurrentSends = 16
+
+// smtpBatchSender implements the queue and send methods shared by the provider managers, which embed it.
+// The queue and the delivery settings stay fields of each manager, which supplies them through its
+// sendConfig; the sender itself only keeps the state of the running batches.
+type smtpBatchSender struct {
+	source      sendSource      // Manager embedding the sender (see bind).
+	mu          sync.Mutex      // Guards sendContext, which WithContext may set while a batch starts.
+	sendContext context.Context // Context of the Send batches (see WithContext).
+	batches     batches         // Running Send batches, cancelled by CancelSend.
+}
+
+// sendSource is implemented by the managers embedding a smtpBatchSender.
+type sendSource interface {
+	sendConfig() sendConfig
+}
+
+// sendConfig is the queue and the delivery settings of a manager, as used by its smtpBatchSender.
+type sendConfig struct {
+	provider string // Labels the metrics of the Observer.
+
+	// setup readies the manager before a Send, e.g. creating its SMTP credentials.
+	setup func() (bool, error)
+
+	messages *[]Message
+	mt       *sync.RWMutex
+
+	backoff              backoff.Backoff
+	observer             observer.Observer
+	tlsMode              TLSMode
+	tlsConfig            *tls.Config
+	maxMessagesPerSecond float64
+	channelBuffer        int
+
+	// endpoint returns the address of the SMTP server and the credentials to log in to it.
+	endpoint func() (addr string, auth smtp.Auth)
+	// batch, when set, is called once a batch starts and returns how its messages are delivered.
+	// Otherwise every message is rendered and sent to the SMTP endpoint (see smtpDelivery).
+	batch func() prepareFunc
+}
+
+// prepareFunc readies the delivery of m, addressed to the envelope recipients in list. It returns the
+// name of the operation reported to the Observer and the function delivering m once, which is retried
+// according to the Backoff of the manager.
+type prepareFunc func(ctx context.Context, m *Message, list []string) (operation string, deliver func() error, err error)
+
+// bind sets the manager whose sendConfig drives the sender. The managers created by NewMessageManager
+// are bound; the others must be passed to boundManager before use.
+func (b *smtpBatchSender) bind(source sendSource) {
+	b.source = source
+}
+
+// boundManager binds the smtpBatchSender embedded in m to m, and returns m.
+func boundManager[M interface {
+	sendSource
+	bind(sendSource)
+}](m M) M {
+	m.bind(m)
+	return m
+}
+
+// config returns the sendConfig of the bound manager.
+func (b *smtpBatchSender) config() sendConfig {
+	if b.source == nil {
+		panic("messaging: manager used without NewMessageManager")
+	}
+	return b.source.sendConfig()
+}
+
+// WithContext sets the context of the Send batches. Once it is done, queued messages are no longer
+// dispatched and status updates are dropped, so the send goroutines return even if the consumer
+// stopped reading the channel. It is safe to call concurrently with Send, and applies to the batches
+// started afterwards.
+func (b *smtpBatchSender) WithContext(ctx context.Context) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sendContext = ctx
+}
+
+// context returns the context of the Send batches, defaulting to context.Background.
+func (b *smtpBatchSender) context() context.Context {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.sendContext == nil {
+		return context.Background()
+	}
+	return b.sendContext
+}
+
+// CancelSend cancels the running Send batches: queued messages are no longer dispatched and are marked
+// Cancelled, and in-flight messages are not retried. It returns once every batch has stopped.
+func (b *smtpBatchSender) CancelSend() (bool, error) {
+	b.batches.cancelAll()
+	return true, nil
+}
+
+// channelBufferSize returns the capacity of the status channel, defaulting to MaxOCIMessages.
+func (c sendConfig) channelBufferSize() int {
+	if c.channelBuffer <= 0 {
+		return MaxOCIMessages
+	}
+	return c.channelBuffer
+}
+
+// smtpDelivery renders m and returns the SendMail operation delivering it to the SMTP endpoint.
+func (c sendConfig) smtpDelivery(_ context.Context, m *Message, list []string) (string, func() error, error) {
+	data, err := m.Bytes()
+	if err != nil {
+		return "", nil, err
+	}
+	addr, auth := c.endpoint()
+	return "SendMail", func() error {
+		return sendMail(addr, auth, c.tlsMode, c.tlsConfig, m, list, data)
+	}, nil
+}
+
+func (b *smtpBatchSender) AddMessage(m Message) {
+	b.addMessages(b.config(), m)
+}
+
+func (b *smtpBatchSender) AddMessages(m []Message) {
+	b.addMessages(b.config(), m...)
+}
+
+// AddMessagesValidated enqueues only the messages that pass Message.Validate and returns how many were
+// accepted, together with the reason each rejected message (keyed by its index in m) was left out.
+func (b *smtpBatchSender) AddMessagesValidated(m []Message) (accepted int, rejected map[int]error) {
+	valid, rejected := validateMessages(m)
+	b.addMessages(b.config(), valid...)
+	return len(valid), rejected
+}
+
+// addMessages appends m to the queue.
+func (b *smtpBatchSender) addMessages(c sendConfig, m ...Message) {
+	c.mt.Lock()
+	defer c.mt.Unlock()
+	*c.messages = append(*c.messages, m...)
+}
+
+func (b *smtpBatchSender) Send() (chan Message, bool, error) {
+	c := b.config()
+	ready, err := c.setup()
+
+	if !ready {
+		return nil, false, err
+	}
+
+	ch := b.sendBatch(c, 0)
+
+	return ch, true, nil
+}
+
+// SendPersonalized sends one copy of base per recipient, each with the recipient as its only "To"
+// address and without CC/BCC, after applying the optional personalize callback. A streamed body of
+// base (see SetBodyReader) is read once and each copy gets its own reader of it.
+func (b *smtpBatchSender) SendPersonalized(base Message, recipients []mail.Address, personalize func(Message, mail.Address) Message) (chan Message, error) {
+	c := b.config()
+	ready, err := c.setup()
+
+	if !ready {
+		return nil, err
+	}
+
+	messages, err := personalizeMessages(base, recipients, personalize)
+	if err != nil {
+		return nil, err
+	}
+	c.mt.Lock()
+	start := len(*c.messages)
+	*c.messages = append(*c.messages, messages...)
+	c.mt.Unlock()
+
+	return b.sendBatch(c, start), nil
+}
+
+// SendStatus returns the fraction of the queued messages that were sent, or 0 when the queue is empty.
+//
+// Deprecated: use Progress, which also counts the failed and cancelled messages.
+func (b *smtpBatchSender) SendStatus() (float64, error) {
+	c := b.config()
+	ready, err := c.setup()
+
+	if !ready {
+		return 0.0, err
+	}
+
+	p := b.progress(c)
+	if p.Total == 0 {
+		return 0, nil
+	}
+	return float64(p.Sent) / float64(p.Total), nil
+}
+
+// Progress counts the queued messages by the final status of their last Send. Once Pending is zero,
+// every message was dispatched, and Failed and Cancelled tell whether they all were delivered.
+func (b *smtpBatchSender) Progress() SendProgress {
+	return b.progress(b.config())
+}
+
+// progress counts the queued messages by the final status of their last Send.
+func (b *smtpBatchSender) progress(c sendConfig) SendProgress {
+	c.mt.RLock()
+	defer c.mt.RUnlock()
+	p := SendProgress{Total: len(*c.messages)}
+	for _, msg := range *c.messages {
+		switch msg.Status {
+		case Sent:
+			p.Sent++
+		case SendError:
+			p.Failed++
+		case Cancelled:
+			p.Cancelled++
+		}
+	}
+	return p
+}
+
+// sendMessage sends the queued messages starting at index start, emitting every status change on the returned channel.
+func (b *smtpBatchSender) sendMessage(start int) chan Message {
+	return b.sendBatch(b.config(), start)
+}
+
+// sendBatch sends the queued messages starting at index start, emitting every status change on the
+// returned channel.
+func (b *smtpBatchSender) sendBatch(c sendConfig, start int) chan Message {
+	ch := make(chan Message, c.channelBufferSize())
+	ctx, end := b.batches.begin(b.context())
+
+	go func() {
+		defer close(ch)
+		defer end()
+		wg := &sync.WaitGroup{}
+		limiter := newRateLimiter(c.maxMessagesPerSecond)
+		slots := make(chan struct{}, maxConcurrentSends)
+		var prepare prepareFunc = c.smtpDelivery
+		if c.batch != nil {
+			prepare = c.batch()
+		}
+
+		c.mt.RLock()
+		tm := len(*c.messages)
+		c.mt.RUnlock()
+		for i := start; i < tm; i++ {
+			c.mt.Lock()
+			m := (*c.messages)[i]
+			c.mt.Unlock()
+			m.Status = Queued
+			if ctx.Err() != nil || !emit(ctx, ch, m) {
+				b.cancelled(c, ch, i, m)
+				continue
+			}
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				b.cancelled(c, ch, i, m)
+				continue
+			}
+			wg.Add(1)
+			go func(i int, m Message) {
+				defer func() { <-slots }()
+				b.send(ctx, c, ch, i, m, wg, limiter, prepare)
+			}(i, m)
+		}
+
+		wg.Wait()
+	}()
+	return ch
+}
+
+// cancelled marks the queued message i, which was not dispatched because its batch is cancelled.
+func (b *smtpBatchSender) cancelled(c sendConfig, ch chan Message, i int, m Message) {
+	m.Status = Cancelled
+	m.DateStatus = time.Now()
+	b.record(c, i, &m)
+	tryEmit(ch, m)
+}
+
+// record stores the final status of m, the queued message i, back in the queue, since the send goroutines
+// work on a copy. Messages abandoned before reaching a final status are left untouched.
+func (b *smtpBatchSender) record(c sendConfig, i int, m *Message) {
+	if m.Status != Sent && m.Status != SendError && m.Status != Cancelled {
+		return
+	}
+	c.mt.Lock()
+	defer c.mt.Unlock()
+	(*c.messages)[i].Status = m.Status
+	(*c.messages)[i].DateStatus = m.DateStatus
+	(*c.messages)[i].Error = m.Error
+	(*c.messages)[i].Attempts = m.Attempts
+	(*c.messages)[i].ProviderMessageID = m.ProviderMessageID
+}
+
+func (b *smtpBatchSender) send(ctx context.Context, c sendConfig, ch chan Message, i int, m Message, wg *sync.WaitGroup, limiter *rate.Limiter, prepare prepareFunc) {
+	defer wg.Done()
+	defer observeOutcome(c.observer, c.provider, &m)
+	defer b.record(c, i, &m)
+	m.Status = Sending
+	if !emit(ctx, ch, m) {
+		return
+	}
+
+	list, err := m.Tolist()
+	var operation string
+	var deliver func() error
+	if err == nil {
+		operation, deliver, err = prepare(ctx, &m, list)
+	}
+	if err != nil {
+		m.Status = SendError
+		m.DateStatus = time.Now()
+		m.Error = err
+		emit(ctx, ch, m)
+		return
+	}
+
+	err = deliverWithRetry(ctx, c.backoff, ch, &m, observer.Retrying(c.observer, c.provider, operation, func() error {
+		// Wait fails once ctx is done, or at once when the delay would outlast its deadline.
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+		return deliver()
+	}))
+
+	if err != nil && ctx.Err() != nil {
+		m.Status = Cancelled
+		m.DateStatus = time.Now()
+		tryEmit(ch, m)
+		return
+	}
+	if err != nil {
+		m.Status = SendError
+		m.DateStatus = time.Now()
+		m.Error = err
+		emit(ctx, ch, m)
+		return
+	}
+
+	m.Status = Sent
+	m.DateStatus = time.Now()
+	m.Error = nil
+	emit(ctx, ch, m)
+}
@@ -0,0 +1,56 @@
+package messaging
+
+import (
+	"crypto/tls"
+	"fmt"
+	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/diegoyosiura/cloud-manager/pkg/backoff"
+	"github.com/diegoyosiura/cloud-manager/pkg/observer"
+	"net/smtp"
+	"sync"
+)
+
+// GCPManager sends messages through an SMTP relay, since Google Cloud has no email service of its own:
+// typically the Google Workspace SMTP relay (smtp-relay.gmail.com) or SendGrid (smtp.sendgrid.net, with
+// the user "apikey" and an API key as the password), both on port 587 with TLSModeStartTLS.
+type GCPManager struct {
+	Auth   *authentication.GCPAuth // GCP authentication details.
+	Client smtp.Auth
+
+	Backoff   backoff.Backoff   // Retry policy of transient failures, e.g. 4xx SMTP replies (defaults to DefaultSendBackoff, 3 attempts).
+	Observer  observer.Observer // Receives the SMTP call, retry and message metrics when set (optional).
+	TLSMode   TLSMode           // Encryption of the SMTP connection (defaults to TLSModeNone; relays on port 587 expect TLSModeStartTLS).
+	TLSConfig *tls.Config       // TLS settings of the SMTP connection (optional).
+
+	MaxMessagesPerSecond float64 // Maximum dispatch rate of a Send batch, retries included (0 means unlimited).
+	ChannelBuffer        int     // Capacity of the status channel returned by Send (0 means MaxOCIMessages).
+
+	Messages   []Message
+	MessagesMT *sync.RWMutex
+
+	smtpBatchSender // Queue and send methods shared with the other managers, bound by NewMessageManager.
+}
+
+// sendConfig returns the queue and the delivery settings used by the shared send loop.
+func (g *GCPManager) sendConfig() sendConfig {
+	return sendConfig{
+		provider:             "gcp",
+		setup:                g.setup,
+		messages:             &g.Messages,
+		mt:                   g.MessagesMT,
+		backoff:              g.Backoff,
+		observer:             g.Observer,
+		tlsMode:              g.TLSMode,
+		tlsConfig:            g.TLSConfig,
+		maxMessagesPerSecond: g.MaxMessagesPerSecond,
+		channelBuffer:        g.ChannelBuffer,
+		endpoint: func() (string, smtp.Auth) {
+			return fmt.Sprintf(`%s:%s`, g.Auth.EmailHost, g.Auth.EmailPort), g.Client
+		},
+	}
+}
+
+func (g *GCPManager) setup() (bool, error) {
+	g.Client = smtp.PlainAuth("", g.Auth.EmailUser, g.Auth.EmailPassword, g.Auth.EmailHost)
+	return true, nil
+}
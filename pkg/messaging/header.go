@@ -1,7 +1,67 @@
 package messaging
 
+import (
+	"fmt"
+	"net/textproto"
+	"strings"
+)
+
 // Header represents an additional email header.
 type Header struct {
 	Key   string
 	Value string
 }
+
+// HeaderCanonicalization selects how header names are spelled when a message is rendered.
+type HeaderCanonicalization string
+
+const (
+	// HeaderCanonicalizationStandard writes every header name in canonical form (e.g. "Content-Type",
+	// "Mime-Version"), as produced by textproto.CanonicalMIMEHeaderKey. It is the default.
+	HeaderCanonicalizationStandard HeaderCanonicalization = "standard"
+	// HeaderCanonicalizationAsIs writes custom header names exactly as given to AddHeader and the
+	// built-in ones in their conventional spelling (e.g. "MIME-Version"), for receivers that are picky about case.
+	HeaderCanonicalizationAsIs HeaderCanonicalization = "asIs"
+)
+
+// headerKey returns the header name spelled according to the message's canonicalization.
+func (m *Message) headerKey(key string) string {
+	if m.HeaderCanonicalization == HeaderCanonicalizationAsIs {
+		return key
+	}
+	return textproto.CanonicalMIMEHeaderKey(key)
+}
+
+// writeHeader writes a "Key: value" header line, spelling the key with headerKey.
+func (m *Message) writeHeader(buf *countingWriter, key, value string) {
+	buf.WriteString(m.headerKey(key) + ": " + value + "\r\n")
+}
+
+// isBccHeader reports whether the custom header would disclose the blind recipients, whatever its spelling.
+// Blind recipients are only ever given to the SMTP envelope, through Message.BCC.
+func isBccHeader(key string) bool {
+	return textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(key)) == "Bcc"
+}
+
+// checkHeaders rejects the custom headers whose name has characters other than the printable ASCII ones
+// RFC 5322 allows in field names (33 to 126, except ':'), or whose value holds a CR or LF, which would
+// inject headers of its own (e.g. a "Bcc"). "Bcc" headers are skipped, since they are never rendered.
+func checkHeaders(headers []Header) error {
+	for _, header := range headers {
+		if isBccHeader(header.Key) {
+			continue
+		}
+		if header.Key == "" {
+			return fmt.Errorf("invalid header name %q: must not be empty", header.Key)
+		}
+		for i := 0; i < len(header.Key); i++ {
+			if c := header.Key[i]; c < 33 || c > 126 || c == ':' {
+				return fmt.Errorf("invalid header name %q: character %q is not allowed", header.Key, c)
+			}
+		}
+		if strings.ContainsAny(header.Value, "\r\n") {
+			return fmt.Errorf("invalid value of header '%s': contains a line break", header.Key)
+		}
+	}
+	return nil
+}
@@ -2,9 +2,14 @@ package messaging
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/tls"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"mime"
+	"net/http"
 	"net/mail"
 	"net/smtp"
 	"os"
@@ -13,10 +18,28 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
-// Global regex for sanitizing filenames (compiled once for reuse)
-var validFilenameRegex = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+// Global regexes for sanitizing filenames (compiled once for reuse)
+var (
+	validFilenameRegex  = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+	unsafeFilenameRegex = regexp.MustCompile(`[/\\"'\x00-\x1f\x7f]+`)
+)
+
+// DefaultMaxBodyBytes is the body size limit applied when Message.MaxBodyBytes is zero.
+const DefaultMaxBodyBytes = 1024 * 1024
+
+// ErrBodyTooLarge is returned when a rendered message body exceeds its size limit.
+type ErrBodyTooLarge struct {
+	Size  int // Actual size of the body in bytes (for streamed bodies, the bytes read before giving up)
+	Limit int // Maximum size allowed in bytes
+}
+
+func (e *ErrBodyTooLarge) Error() string {
+	return fmt.Sprintf("message body too large: %d bytes exceeds the limit of %d bytes", e.Size, e.Limit)
+}
 
 // Buffer pool for optimized memory allocation when creating email content
 var bufferPool = sync.Pool{
@@ -27,21 +50,39 @@ var bufferPool = sync.Pool{
 
 // Message represents an email with metadata, recipients, and content.
 type Message struct {
-	ID              string                 // Message Identifier
-	Subject         string                 // Email subject
-	Body            string                 // Email body content
-	Error           error                  // Error content
-	Status          MessageStatus          // Current status of the email (e.g., NotSent, Sent)
-	From            mail.Address           // Sender's email address
-	MailTo          []string               // Primary recipients
-	CC              []string               // Carbon copy recipients
-	BCC             []string               // Blind carbon copy recipients
-	Reply           []string               // Reply-To addresses
-	BodyContentType string                 // MIME type of the body content (e.g., text/plain, text/html)
-	Headers         []Header               // Additional custom headers
-	Attachments     map[string]*Attachment // Attachments associated with the email
-	DateReceived    time.Time              // Timestamp when the email was created
-	DateStatus      time.Time              // Timestamp when the status was last updated
+	ID                   string                 // Message Identifier
+	Subject              string                 // Email subject
+	Body                 string                 // Email body content
+	BodyReader           io.Reader              // Streamed body content, used instead of Body when set (see SetBodyReader)
+	Error                error                  // Error content
+	Status               MessageStatus          // Current status of the email (e.g., NotSent, Sent)
+	From                 mail.Address           // Sender's email address
+	MailTo               []string               // Primary recipients
+	CC                   []string               // Carbon copy recipients
+	BCC                  []string               // Blind carbon copy recipients
+	Reply                []string               // Reply-To addresses
+	BodyContentType      string                 // MIME type of the body content (e.g., text/plain, text/html)
+	Headers              []Header               // Additional custom headers
+	Attachments          map[string]*Attachment // Attachments associated with the email, by filename with non-ASCII characters replaced
+	DateReceived         time.Time              // Timestamp when the email was created
+	DateStatus           time.Time              // Timestamp when the status was last updated
+	Attempts             int                    // Number of delivery attempts made so far
+	SanitizeHTML         bool                   // Strips dangerous markup from HTML bodies when rendering (best-effort)
+	Expiry               time.Time              // Time after which the message may be expired by the client (optional)
+	SuppressAutoReply    bool                   // Asks receiving servers not to send auto-responses (e.g. out-of-office)
+	AutoSubmitted        string                 // RFC 3834 "Auto-Submitted" value marking automated mail (see SetAutomated)
+	Precedence           string                 // "Precedence" header value (e.g. "bulk"), omitted when empty
+	MaxBodyBytes         int                    // Maximum body size in bytes (0 uses DefaultMaxBodyBytes, negative disables the check)
+	Metadata             map[string]string      // Application data carried with the message through Send(); never written to the email
+	SuppressedRecipients []string               // Recipients skipped because they are on the provider's suppression list
+	ProviderMessageID    string                 // Identifier the provider API assigned to the sent message (e.g. the SES message ID)
+	RequestDSN           bool                   // Requests delivery status notifications when the SMTP server supports DSN
+	PGPPublicKeys        [][]byte               // OpenPGP public keys the body and attachments are encrypted to (see PGPEncryptTo)
+	SniffContentType     bool                   // Detects the type of attachments without a known extension from their first 512 bytes
+
+	HeaderCanonicalization HeaderCanonicalization // Spelling of header names (defaults to HeaderCanonicalizationStandard)
+	Boundary               string                 // Multipart boundary used when the message has attachments (defaults to a random boundary; see validateBoundary)
+	FilenameSanitizer      func(string) string    // Cleans attachment filenames (defaults to sanitizeFilename; see StrictFilenameSanitizer)
 }
 
 // NewMessage initializes a new Message object with default values if not provided.
@@ -67,6 +108,35 @@ func NewMessage(from mail.Address, subject, body, bodyContentType string, mailTo
 	}
 }
 
+// Clone returns a deep copy of the message, so recipients, headers, attachments and metadata
+// can be changed on the copy without affecting the original. BodyReader is shared, not copied, so
+// only one of the two messages can render a streamed body.
+func (m *Message) Clone() Message {
+	c := *m
+	c.MailTo = append([]string(nil), m.MailTo...)
+	c.CC = append([]string(nil), m.CC...)
+	c.BCC = append([]string(nil), m.BCC...)
+	c.Reply = append([]string(nil), m.Reply...)
+	c.Headers = append([]Header(nil), m.Headers...)
+	c.SuppressedRecipients = append([]string(nil), m.SuppressedRecipients...)
+	c.PGPPublicKeys = append([][]byte(nil), m.PGPPublicKeys...)
+
+	if m.Attachments != nil {
+		c.Attachments = make(map[string]*Attachment, len(m.Attachments))
+		for k, a := range m.Attachments {
+			att := *a
+			c.Attachments[k] = &att
+		}
+	}
+	if m.Metadata != nil {
+		c.Metadata = make(map[string]string, len(m.Metadata))
+		for k, v := range m.Metadata {
+			c.Metadata[k] = v
+		}
+	}
+	return c
+}
+
 // attach adds a file to the message's attachments, optionally setting it as inline content.
 func (m *Message) attach(file string, inline bool) error {
 	// Read the file contents
@@ -76,20 +146,80 @@ func (m *Message) attach(file string, inline bool) error {
 	}
 
 	// Sanitize the filename to prevent malicious input
-	filename := sanitizeFilename(filepath.Base(file))
+	filename := m.sanitizeFilename(filepath.Base(file))
 
 	// Store the attachment
-	m.Attachments[filename] = &Attachment{
+	return m.storeAttachment(&Attachment{
 		Filename: filename,
 		Data:     data,
 		Inline:   inline,
-	}
+	})
+}
 
+// storeAttachment adds a to the attachments under the ASCII form of its filename, replacing an attachment
+// with the same filename. Another filename with the same ASCII form (e.g. "é.txt" and "è.txt") is an
+// error, since its attachment would be silently replaced.
+func (m *Message) storeAttachment(a *Attachment) error {
+	key := asciiFilename(a.Filename)
+	if existing, ok := m.Attachments[key]; ok && existing.Filename != a.Filename {
+		return fmt.Errorf("attachment '%s' conflicts with attachment '%s': both are keyed '%s'", a.Filename, existing.Filename, key)
+	}
+	m.Attachments[key] = a
 	return nil
 }
 
-// sanitizeFilename replaces invalid characters in a filename with underscores.
+// sanitizeFilename applies the message's FilenameSanitizer, or the default policy when none is set.
+func (m *Message) sanitizeFilename(filename string) string {
+	if m.FilenameSanitizer != nil {
+		return m.FilenameSanitizer(filename)
+	}
+	return sanitizeFilename(filename)
+}
+
+// sanitizeFilename replaces path separators, control characters and quotes in a filename with underscores.
+// Spaces and other punctuation are kept since the filename is quoted in the Content-Disposition header.
+// A name made only of dots ("." or "..") is replaced entirely so it cannot refer to a directory.
 func sanitizeFilename(filename string) string {
+	filename = unsafeFilenameRegex.ReplaceAllString(filename, "_")
+	if strings.Trim(filename, ".") == "" {
+		return "_"
+	}
+	return filename
+}
+
+// asciiFilename replaces the non-ASCII and control characters of a filename with underscores. It gives
+// the key of the attachment in Message.Attachments and its Content-ID, which must be ASCII.
+func asciiFilename(filename string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r >= 0x7f {
+			return '_'
+		}
+		return r
+	}, filename)
+}
+
+// contentDisposition renders the Content-Disposition header value of an attachment. Non-ASCII filenames
+// are given both RFC 2231 encoded (filename*), and RFC 2047 encoded for the clients that only read
+// filename. Control characters are replaced whatever the FilenameSanitizer, so the filename cannot
+// inject headers.
+func contentDisposition(disposition, filename string) string {
+	filename = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, filename)
+	if asciiFilename(filename) == filename {
+		return fmt.Sprintf("%s; filename=\"%s\"", disposition, strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(filename))
+	}
+	encoded := mime.BEncoding.Encode("utf-8", filename)
+	extended := mime.FormatMediaType(disposition, map[string]string{"filename": filename})
+	return fmt.Sprintf("%s; filename=\"%s\"%s", disposition, encoded, strings.TrimPrefix(extended, disposition))
+}
+
+// StrictFilenameSanitizer replaces everything outside [a-zA-Z0-9._-] with underscores.
+// Assign it to Message.FilenameSanitizer to restrict attachment names to that character set.
+func StrictFilenameSanitizer(filename string) string {
 	return validFilenameRegex.ReplaceAllString(filename, "_")
 }
 
@@ -112,7 +242,9 @@ func (m *Message) AddBcc(address mail.Address) []string {
 }
 
 // AttachBuffer adds an attachment to the message directly from a buffer.
-// It sanitizes the filename and sets the inline flag as specified.
+// It sanitizes the filename and sets the inline flag as specified. Attachments are keyed by the ASCII
+// form of their filename, so a filename differing from an attached one only by its non-ASCII characters
+// is rejected with an error.
 func (m *Message) AttachBuffer(filename string, buf []byte, inline bool) error {
 	// Check for empty buffer
 	if len(buf) == 0 {
@@ -120,10 +252,90 @@ func (m *Message) AttachBuffer(filename string, buf []byte, inline bool) error {
 	}
 
 	// Store the attachment
-	m.Attachments[sanitizeFilename(filename)] = &Attachment{
-		Filename: sanitizeFilename(filename),
+	return m.storeAttachment(&Attachment{
+		Filename: m.sanitizeFilename(filename),
 		Data:     buf,
 		Inline:   inline,
+	})
+}
+
+// SetHTMLBody sets an HTML body on the message. When sanitize is true, scripts, event handler
+// attributes and unsafe URLs are stripped from the body when the message is rendered.
+// Sanitization is best-effort and is not a replacement for a full HTML sanitizer.
+func (m *Message) SetHTMLBody(html string, sanitize bool) {
+	m.Body = html
+	m.BodyContentType = "text/html"
+	m.SanitizeHTML = sanitize
+}
+
+// renderedBody returns the body as it must be written to the message, sanitizing HTML bodies when requested.
+func (m *Message) renderedBody(body string) string {
+	if m.SanitizeHTML && strings.HasPrefix(strings.ToLower(m.BodyContentType), "text/html") {
+		return sanitizeHTML(body)
+	}
+	return body
+}
+
+// SetBodyReader sets a body that is streamed from r when the message is rendered, instead of
+// being held in Body. The body is single-use: the reader is drained by the first call to Bytes or
+// WriteTo, so render the message once and keep the bytes when they are needed again (the send loops
+// of the managers retry with the bytes they rendered). When SanitizeHTML is set the body must be
+// buffered in full to be sanitized. A streamed body is written as read, without the UTF-8
+// validation of Body.
+func (m *Message) SetBodyReader(r io.Reader, contentType string) {
+	m.BodyReader = r
+	if contentType != "" {
+		m.BodyContentType = contentType
+	}
+}
+
+// SetExpiry sets the time after which clients may consider the message expired.
+// It is rendered as an RFC 2156 "Expiry-Date" header and must be in the future.
+func (m *Message) SetExpiry(t time.Time) error {
+	if !t.After(time.Now()) {
+		return fmt.Errorf("expiry date '%s' is not in the future", t.Format(time.RFC1123Z))
+	}
+	m.Expiry = t
+	return nil
+}
+
+// Values of the RFC 3834 "Auto-Submitted" header accepted by SetAutomated.
+const (
+	AutoSubmittedGenerated = "auto-generated" // Message generated by an automatic process, such as an alert or a receipt
+	AutoSubmittedReplied   = "auto-replied"   // Automatic response to another message
+)
+
+// SetAutomated marks the message as sent by an automatic process so that receivers do not answer it
+// with vacation replies. kind is AutoSubmittedGenerated (the default when empty) or AutoSubmittedReplied;
+// bulk also sets "Precedence: bulk" for mailing-list software.
+func (m *Message) SetAutomated(kind string, bulk bool) error {
+	switch kind {
+	case "":
+		kind = AutoSubmittedGenerated
+	case AutoSubmittedGenerated, AutoSubmittedReplied:
+	default:
+		return fmt.Errorf("invalid Auto-Submitted value '%s'", kind)
+	}
+
+	m.AutoSubmitted = kind
+	if bulk {
+		m.Precedence = "bulk"
+	}
+	return nil
+}
+
+// bodyLimit returns the body size limit in bytes, or a negative value when the check is disabled.
+func (m *Message) bodyLimit() int {
+	if m.MaxBodyBytes == 0 {
+		return DefaultMaxBodyBytes
+	}
+	return m.MaxBodyBytes
+}
+
+// checkBodySize returns an *ErrBodyTooLarge if a body of the given size exceeds the message's size limit.
+func (m *Message) checkBodySize(size int) error {
+	if limit := m.bodyLimit(); limit > 0 && size > limit {
+		return &ErrBodyTooLarge{Size: size, Limit: limit}
 	}
 	return nil
 }
@@ -133,18 +345,40 @@ func (m *Message) Attach(file string) error {
 	return m.attach(file, false)
 }
 
-// Inline adds a file as an inline attachment.
+// Inline adds a file as an inline attachment, which an HTML body references as "cid:<filename>" (with
+// non-ASCII characters of the filename replaced by underscores).
 func (m *Message) Inline(file string) error {
 	return m.attach(file, true)
 }
 
-// AddHeader appends a custom header to the message.
+// AddHeader appends a custom header to the message. Names and values are checked when the message is
+// validated or rendered (see checkHeaders).
 func (m *Message) AddHeader(key, value string) Header {
 	header := Header{Key: key, Value: value}
 	m.Headers = append(m.Headers, header)
 	return header
 }
 
+// Validate checks the parts of the message that would otherwise only fail at send time:
+// the "From" address, the custom headers and the recipients, of which there must be at least one.
+func (m *Message) Validate() error {
+	if _, err := mail.ParseAddress(m.From.Address); err != nil {
+		return fmt.Errorf("invalid 'From' address: %w", err)
+	}
+	if err := checkHeaders(append(m.automationHeaders(), m.Headers...)); err != nil {
+		return err
+	}
+
+	recipients, err := m.Tolist()
+	if err != nil {
+		return err
+	}
+	if len(recipients) == 0 {
+		return fmt.Errorf("message has no recipients")
+	}
+	return nil
+}
+
 // Tolist compiles and validates all recipients from "To", "CC", and "BCC" lists.
 func (m *Message) Tolist() ([]string, error) {
 	// Combine all recipient lists
@@ -172,65 +406,186 @@ func (m *Message) Bytes() ([]byte, error) {
 	defer bufferPool.Put(buf)
 	buf.Reset()
 
+	if _, err := m.WriteTo(buf); err != nil {
+		return nil, err
+	}
+
+	// The buffer goes back to the pool, so its content is copied before the caller gets it
+	return bytes.Clone(buf.Bytes()), nil
+}
+
+// WriteTo renders the message into w, streaming the body from BodyReader when one is set.
+// It implements io.WriterTo.
+//
+// Addresses, the subject and a Body string are validated before anything is written. A streamed
+// body is only measured while it is copied, so when it exceeds the size limit the headers and part
+// of the body are already in w as WriteTo returns *ErrBodyTooLarge; that output must be discarded.
+// Bytes does so and returns no content on error.
+func (m *Message) WriteTo(w io.Writer) (int64, error) {
+	buf := &countingWriter{w: w}
+
 	// Validate the "From" address
 	if _, err := mail.ParseAddress(m.From.Address); err != nil {
-		return nil, fmt.Errorf("invalid 'From' address: %w", err)
+		return 0, fmt.Errorf("invalid 'From' address: %w", err)
+	}
+
+	// Validate the recipient headers, the "Subject" and the custom headers before anything is written
+	to, err := formatAddressList("To", m.MailTo)
+	if err != nil {
+		return 0, err
+	}
+	cc, err := formatAddressList("Cc", m.CC)
+	if err != nil {
+		return 0, err
+	}
+	replyTo, err := formatAddressList("Reply-To", m.Reply)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkUTF8("subject", m.Subject); err != nil {
+		return 0, err
+	}
+	if err := checkHeaders(append(m.automationHeaders(), m.Headers...)); err != nil {
+		return 0, err
+	}
+
+	// Render the body and reject it before building the message if it is oversized or not valid UTF-8
+	var body string
+	if m.BodyReader == nil || m.SanitizeHTML {
+		if body, err = m.readBody(); err != nil {
+			return 0, err
+		}
+		body = m.renderedBody(body)
+		if err := checkUTF8("body", body); err != nil {
+			return 0, err
+		}
+		if err := m.checkBodySize(len(body)); err != nil {
+			return 0, err
+		}
 	}
 
 	// Add "From" and "Date" headers
-	buf.WriteString(fmt.Sprintf("From: %s\r\n", m.From.String()))
-	buf.WriteString(fmt.Sprintf("Date: %s\r\n", time.Now().Format(time.RFC1123Z)))
+	m.writeHeader(buf, "From", m.From.String())
+	m.writeHeader(buf, "Date", time.Now().Format(time.RFC1123Z))
 
-	// Add "To" and "CC" headers
-	buf.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(m.MailTo, ", ")))
-	if len(m.CC) > 0 {
-		buf.WriteString(fmt.Sprintf("Cc: %s\r\n", strings.Join(m.CC, ", ")))
+	// Add "To" and "CC" headers; BCC recipients are only given to the envelope
+	m.writeHeader(buf, "To", to)
+	if cc != "" {
+		m.writeHeader(buf, "Cc", cc)
 	}
 
 	// Encode and add the "Subject" header
-	if !isUTF8(m.Subject) {
-		return nil, fmt.Errorf("subject contains non-UTF-8 characters")
-	}
 	encodedSubject := base64.StdEncoding.EncodeToString([]byte(m.Subject))
-	buf.WriteString(fmt.Sprintf("Subject: =?UTF-8?B?%s?=\r\n", encodedSubject))
+	m.writeHeader(buf, "Subject", fmt.Sprintf("=?UTF-8?B?%s?=", encodedSubject))
 
 	// Add "Reply-To" header if applicable
-	if len(m.Reply) > 0 {
-		buf.WriteString(fmt.Sprintf("Reply-To: %s\r\n", strings.Join(m.Reply, ", ")))
+	if replyTo != "" {
+		m.writeHeader(buf, "Reply-To", replyTo)
 	}
 
-	// Add MIME version and custom headers
-	buf.WriteString("MIME-Version: 1.0\r\n")
+	// Add expiry and auto-response headers if applicable
+	for _, header := range m.automationHeaders() {
+		m.writeHeader(buf, header.Key, header.Value)
+	}
+
+	// Add MIME version and custom headers, dropping any "Bcc" header that would expose the blind recipients
+	m.writeHeader(buf, "MIME-Version", "1.0")
 	for _, header := range m.Headers {
-		buf.WriteString(fmt.Sprintf("%s: %s\r\n", header.Key, header.Value))
+		if isBccHeader(header.Key) {
+			continue
+		}
+		m.writeHeader(buf, header.Key, header.Value)
 	}
 
-	// Handle body and attachments
+	// Handle body and attachments, encrypting them when PGP keys are configured
+	if len(m.PGPPublicKeys) > 0 {
+		if err := m.writeEncrypted(buf, body); err != nil {
+			return buf.n, err
+		}
+		return buf.n, buf.err
+	}
+	if err := m.writeContent(buf, body); err != nil {
+		return buf.n, err
+	}
+
+	return buf.n, buf.err
+}
+
+// automationHeaders returns the headers rendering Expiry, SuppressAutoReply, AutoSubmitted and Precedence,
+// which are set only when the matching field is.
+func (m *Message) automationHeaders() []Header {
+	var headers []Header
+	if !m.Expiry.IsZero() {
+		headers = append(headers, Header{Key: "Expiry-Date", Value: m.Expiry.Format(time.RFC1123Z)})
+	}
+	if m.SuppressAutoReply {
+		headers = append(headers, Header{Key: "X-Auto-Response-Suppress", Value: "All"})
+	}
+	if m.AutoSubmitted != "" {
+		headers = append(headers, Header{Key: "Auto-Submitted", Value: m.AutoSubmitted})
+	}
+	if m.Precedence != "" {
+		headers = append(headers, Header{Key: "Precedence", Value: m.Precedence})
+	}
+	return headers
+}
+
+// writeContent writes the MIME entity holding the body and the attachments, starting with its Content-Type header.
+func (m *Message) writeContent(buf *countingWriter, body string) error {
 	if len(m.Attachments) > 0 {
+		// Encode the attachments first, so the boundary can be checked against their content
+		encoded := make(map[string][]byte, len(m.Attachments))
+		parts := [][]byte{[]byte(body)}
+		for name, att := range m.Attachments {
+			encoded[name] = make([]byte, base64.StdEncoding.EncodedLen(len(att.Data)))
+			base64.StdEncoding.Encode(encoded[name], att.Data)
+			parts = append(parts, encoded[name])
+		}
+
 		// Add multipart boundary for attachments
-		boundary := "f46d043c813270fc6b04c2d223da"
-		buf.WriteString(fmt.Sprintf("Content-Type: multipart/mixed; boundary=%s\r\n\r\n", boundary))
+		boundary := m.Boundary
+		if boundary == "" {
+			var err error
+			if boundary, err = randomBoundary(parts...); err != nil {
+				return err
+			}
+		} else if err := validateBoundary(boundary, parts...); err != nil {
+			return err
+		}
+		// Inline attachments are referenced from the body by Content-ID, which needs multipart/related
+		subtype := "mixed"
+		for _, att := range m.Attachments {
+			if att.Inline {
+				subtype = "related"
+				break
+			}
+		}
+		m.writeHeader(buf, "Content-Type", fmt.Sprintf("multipart/%s; boundary=\"%s\"", subtype, boundary))
+		buf.WriteString("\r\n")
 
 		// Add body content
 		buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
-		buf.WriteString(fmt.Sprintf("Content-Type: %s; charset=utf-8\r\n\r\n", m.BodyContentType))
-		buf.WriteString(m.Body + "\r\n")
+		m.writeHeader(buf, "Content-Type", fmt.Sprintf("%s; charset=utf-8", m.BodyContentType))
+		buf.WriteString("\r\n")
+		if err := m.writeBody(buf, body); err != nil {
+			return err
+		}
 
 		// Add attachments
-		for _, att := range m.Attachments {
+		for name, att := range m.Attachments {
 			buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
-			mimeType := mime.TypeByExtension(filepath.Ext(att.Filename))
-			if mimeType == "" {
-				mimeType = "application/octet-stream"
+			m.writeHeader(buf, "Content-Type", m.attachmentContentType(att))
+			if att.Inline {
+				m.writeHeader(buf, "Content-Disposition", contentDisposition("inline", att.Filename))
+				m.writeHeader(buf, "Content-ID", fmt.Sprintf("<%s>", asciiFilename(name)))
+			} else {
+				m.writeHeader(buf, "Content-Disposition", contentDisposition("attachment", att.Filename))
 			}
-			buf.WriteString(fmt.Sprintf("Content-Type: %s\r\n", mimeType))
-			buf.WriteString(fmt.Sprintf("Content-Disposition: %s; filename=\"%s\"\r\n", "attachment", att.Filename))
-			buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
-
-			// Encode and add attachment content
-			encoded := make([]byte, base64.StdEncoding.EncodedLen(len(att.Data)))
-			base64.StdEncoding.Encode(encoded, att.Data)
-			buf.Write(encoded)
+			m.writeHeader(buf, "Content-Transfer-Encoding", "base64")
+			buf.WriteString("\r\n")
+
+			// Add encoded attachment content
+			buf.Write(encoded[name])
 			buf.WriteString("\r\n")
 		}
 
@@ -238,15 +593,137 @@ func (m *Message) Bytes() ([]byte, error) {
 		buf.WriteString(fmt.Sprintf("--%s--\r\n", boundary))
 	} else {
 		// Add plain body content
-		buf.WriteString(fmt.Sprintf("Content-Type: %s; charset=utf-8\r\n\r\n", m.BodyContentType))
-		buf.WriteString(m.Body + "\r\n")
+		m.writeHeader(buf, "Content-Type", fmt.Sprintf("%s; charset=utf-8", m.BodyContentType))
+		buf.WriteString("\r\n")
+		if err := m.writeBody(buf, body); err != nil {
+			return err
+		}
+	}
+
+	return buf.err
+}
+
+// randomBoundary returns a random multipart boundary that occurs in none of parts. A body streamed from
+// BodyReader cannot be checked, but a collision with 240 random bits is not a practical concern.
+func randomBoundary(parts ...[]byte) (string, error) {
+	for {
+		var b [30]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return "", fmt.Errorf("failed to generate multipart boundary: %w", err)
+		}
+		boundary := hex.EncodeToString(b[:])
+
+		collides := false
+		for _, part := range parts {
+			if bytes.Contains(part, []byte(boundary)) {
+				collides = true
+				break
+			}
+		}
+		if !collides {
+			return boundary, nil
+		}
+	}
+}
+
+// validateBoundary checks that a caller-supplied boundary has 1 to 70 of the characters RFC 2046 allows,
+// does not end with a space and occurs in none of parts.
+func validateBoundary(boundary string, parts ...[]byte) error {
+	if len(boundary) > 70 || strings.HasSuffix(boundary, " ") {
+		return fmt.Errorf("invalid multipart boundary %q: must have 1 to 70 characters and not end with a space", boundary)
+	}
+	for _, c := range boundary {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || strings.ContainsRune("'()+_,-./:=? ", c)) {
+			return fmt.Errorf("invalid multipart boundary %q: character %q is not allowed", boundary, c)
+		}
 	}
+	for _, part := range parts {
+		if bytes.Contains(part, []byte(boundary)) {
+			return fmt.Errorf("multipart boundary %q occurs in the message content", boundary)
+		}
+	}
+	return nil
+}
 
-	return buf.Bytes(), nil
+// attachmentContentType returns the MIME type of the attachment from its filename extension. When the
+// extension yields no type and SniffContentType is set, the type is detected from the content instead.
+// It falls back to "application/octet-stream".
+func (m *Message) attachmentContentType(att *Attachment) string {
+	if mimeType := mime.TypeByExtension(filepath.Ext(att.Filename)); mimeType != "" {
+		return mimeType
+	}
+	if m.SniffContentType {
+		// DetectContentType considers at most the first 512 bytes and returns "application/octet-stream" when unsure.
+		return http.DetectContentType(att.Data)
+	}
+	return "application/octet-stream"
+}
+
+// readBody returns the body as a string, draining BodyReader when one is set.
+func (m *Message) readBody() (string, error) {
+	if m.BodyReader == nil {
+		return m.Body, nil
+	}
+	data, err := io.ReadAll(m.BodyReader)
+	if err != nil {
+		return "", fmt.Errorf("failed to read message body: %w", err)
+	}
+	return string(data), nil
+}
+
+// writeBody writes the already rendered body, or streams BodyReader when the body was not buffered.
+func (m *Message) writeBody(buf *countingWriter, body string) error {
+	if m.BodyReader == nil || m.SanitizeHTML {
+		buf.WriteString(body + "\r\n")
+		return buf.err
+	}
+
+	limit := m.bodyLimit()
+	reader := m.BodyReader
+	if limit > 0 {
+		reader = io.LimitReader(reader, int64(limit)+1)
+	}
+
+	n, err := io.Copy(buf, reader)
+	if err != nil {
+		return fmt.Errorf("failed to stream message body: %w", err)
+	}
+	if err := m.checkBodySize(int(n)); err != nil {
+		return err
+	}
+	buf.WriteString("\r\n")
+	return buf.err
+}
+
+// countingWriter wraps an io.Writer, counting the bytes written and keeping the first error.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+func (c *countingWriter) WriteString(s string) {
+	_, _ = c.Write([]byte(s))
 }
 
 // Send transmits the email message using the specified SMTP server.
 func Send(addr string, auth smtp.Auth, m *Message) error {
+	return SendTLS(addr, auth, TLSModeNone, nil, m)
+}
+
+// SendTLS transmits the email message using the specified SMTP server, encrypting the connection as
+// required by mode. config is optional; the certificate is verified against the host of addr by default.
+func SendTLS(addr string, auth smtp.Auth, mode TLSMode, config *tls.Config, m *Message) error {
 	data, err := m.Bytes()
 	if err != nil {
 		return err
@@ -255,15 +732,42 @@ func Send(addr string, auth smtp.Auth, m *Message) error {
 	if err != nil {
 		return err
 	}
-	return smtp.SendMail(addr, auth, m.From.Address, recipients, data)
+	return sendMail(addr, auth, mode, config, m, recipients, data)
+}
+
+// formatAddressList validates the addresses of a header and renders them canonicalized by mail.ParseAddress,
+// rejecting any address holding control characters that could inject headers.
+func formatAddressList(header string, addresses []string) (string, error) {
+	formatted := make([]string, 0, len(addresses))
+	for _, a := range addresses {
+		if strings.ContainsFunc(a, unicode.IsControl) {
+			return "", fmt.Errorf("invalid '%s' address %q: contains control characters", header, a)
+		}
+		address, err := mail.ParseAddress(a)
+		if err != nil {
+			return "", fmt.Errorf("invalid '%s' address %q: %w", header, a, err)
+		}
+		if address.Name == "" {
+			formatted = append(formatted, address.Address)
+		} else {
+			formatted = append(formatted, address.String())
+		}
+	}
+	return strings.Join(formatted, ", "), nil
 }
 
-// isUTF8 checks if the given string contains only valid UTF-8 characters.
-func isUTF8(s string) bool {
-	for _, r := range s {
-		if r == '�' {
-			return false
+// checkUTF8 returns an error locating the first invalid UTF-8 sequence of the named field, if any.
+// A U+FFFD replacement character in the text is valid UTF-8 and accepted.
+func checkUTF8(field, s string) error {
+	if utf8.ValidString(s) {
+		return nil
+	}
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			return fmt.Errorf("%s is not valid UTF-8: invalid byte 0x%02x at offset %d", field, s[i], i)
 		}
+		i += size
 	}
-	return true
+	return fmt.Errorf("%s is not valid UTF-8", field)
 }
@@ -1,18 +1,43 @@
 package messaging
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/diegoyosiura/cloud-manager/pkg/backoff"
+	"github.com/diegoyosiura/cloud-manager/pkg/observer"
+	"io"
+	"net/mail"
+	"strings"
 	"sync"
+	"time"
 )
 
 type MessageManager interface {
 	AddMessage(m Message)
 	AddMessages(m []Message)
+	AddMessagesValidated(m []Message) (accepted int, rejected map[int]error)
 	setup() (bool, error)
 	CancelSend() (bool, error)
 	Send() (chan Message, bool, error)
+	SendPersonalized(base Message, recipients []mail.Address, personalize func(Message, mail.Address) Message) (chan Message, error)
 	SendStatus() (float64, error)
+	Progress() SendProgress
+}
+
+// SendProgress counts the queued messages of a manager by the outcome of their last Send.
+type SendProgress struct {
+	Total     int // Queued messages.
+	Sent      int // Messages delivered.
+	Failed    int // Messages that ended with SendError.
+	Cancelled int // Messages not sent because their batch was cancelled.
+}
+
+// Pending returns the number of messages that were not sent yet or are still being sent.
+func (p SendProgress) Pending() int {
+	return p.Total - p.Sent - p.Failed - p.Cancelled
 }
 
 func NewMessageManager(authConfig *authentication.AuthConfig) (MessageManager, error) {
@@ -29,17 +54,203 @@ func NewMessageManager(authConfig *authentication.AuthConfig) (MessageManager, e
 		if !ok {
 			return nil, fmt.Errorf("invalid OCI authentication config")
 		}
-		return &OciManager{Auth: ociConfig, MessagesMT: &sync.RWMutex{}}, nil
+		return boundManager(&OciManager{Auth: ociConfig, MessagesMT: &sync.RWMutex{}}), nil
 	case "aws":
 		// Returns an AWS-specific manager implementation.
 		awsConfig, ok := authConfig.Config.(*authentication.AWSAuth)
 		if !ok {
 			return nil, fmt.Errorf("invalid AWS authentication config")
 		}
-		return &AWSManager{Auth: awsConfig, MessagesMT: &sync.RWMutex{}}, nil
+		return boundManager(&AWSManager{Auth: awsConfig, MessagesMT: &sync.RWMutex{}}), nil
+	case "azure":
+		// Returns an Azure Communication Services Email manager implementation.
+		azureConfig, ok := authConfig.Config.(*authentication.AzureAuth)
+		if !ok {
+			return nil, fmt.Errorf("invalid Azure authentication config")
+		}
+		return boundManager(&AzureManager{Auth: azureConfig, MessagesMT: &sync.RWMutex{}}), nil
+	case "gcp":
+		// Returns a GCP (SMTP relay) manager implementation.
+		gcpConfig, ok := authConfig.Config.(*authentication.GCPAuth)
+		if !ok {
+			return nil, fmt.Errorf("invalid GCP authentication config")
+		}
+		return boundManager(&GCPManager{Auth: gcpConfig, MessagesMT: &sync.RWMutex{}}), nil
 
 	default:
 		// Returns an error if the cloud provider is unsupported.
 		return nil, fmt.Errorf("unsupported provider: %s", authConfig.ProviderName)
 	}
 }
+
+// DefaultSendBackoff is the retry policy of the managers whose Backoff is not set: 3 attempts, 1s apart
+// doubling up to 30s, with 20% jitter. Set Backoff to backoff.ConstantBackoff{Attempts: 1} to disable retries.
+var DefaultSendBackoff backoff.Backoff = backoff.ExponentialBackoff{Initial: time.Second, Max: 30 * time.Second, Multiplier: 2, Jitter: 0.2, Attempts: 3}
+
+// deliverWithRetry runs deliver under the policy b, or DefaultSendBackoff when nil, retrying transient
+// failures (see isTransientSend). Every attempt is counted in m.Attempts, and a Retrying update of m is
+// emitted on ch before every retry. It returns the error of the last attempt, or ctx.Err() when the
+// batch is cancelled while waiting.
+func deliverWithRetry(ctx context.Context, b backoff.Backoff, ch chan Message, m *Message, deliver func() error) error {
+	if b == nil {
+		b = DefaultSendBackoff
+	}
+	return backoff.RetryNotify(ctx, b, isTransientSend, func() error {
+		m.Attempts++
+		return deliver()
+	}, func(err error, _ int, _ time.Duration) {
+		m.Status = Retrying
+		m.DateStatus = time.Now()
+		m.Error = err
+		emit(ctx, ch, *m)
+	})
+}
+
+// emit delivers a status update of m on ch, giving up when ctx is done so that the send goroutines
+// never block forever on a consumer that stopped reading. It reports whether the update was delivered.
+func emit(ctx context.Context, ch chan Message, m Message) bool {
+	select {
+	case ch <- m:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// tryEmit delivers a status update of m on ch only if the channel has room. It is used once the batch
+// is cancelled, when the consumer may no longer be reading.
+func tryEmit(ch chan Message, m Message) {
+	select {
+	case ch <- m:
+	default:
+	}
+}
+
+// batches tracks the running Send batches of a manager so that CancelSend can cancel them and wait
+// until they stop. The zero value is ready to use.
+type batches struct {
+	mu      sync.Mutex
+	cancels map[int]context.CancelFunc
+	next    int
+	wg      sync.WaitGroup
+}
+
+// begin registers a new batch, returning its context, derived from parent, and the function the batch
+// must call when it ends.
+func (b *batches) begin(parent context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cancels == nil {
+		b.cancels = map[int]context.CancelFunc{}
+	}
+	id := b.next
+	b.next++
+	b.cancels[id] = cancel
+	b.wg.Add(1)
+
+	return ctx, func() {
+		b.mu.Lock()
+		delete(b.cancels, id)
+		b.mu.Unlock()
+		cancel()
+		b.wg.Done()
+	}
+}
+
+// cancelAll cancels every running batch and waits until they all ended.
+func (b *batches) cancelAll() {
+	b.mu.Lock()
+	for _, cancel := range b.cancels {
+		cancel()
+	}
+	b.mu.Unlock()
+	b.wg.Wait()
+}
+
+// observeOutcome counts m in MessagesSent or MessagesFailed according to its final status. It is deferred
+// by the send goroutines; messages abandoned because the batch context is done are not counted.
+func observeOutcome(o observer.Observer, provider string, m *Message) {
+	switch m.Status {
+	case Sent:
+		observer.OrNop(o).IncCounter(observer.MessagesSent, map[string]string{"provider": provider})
+	case SendError:
+		observer.OrNop(o).IncCounter(observer.MessagesFailed, map[string]string{"provider": provider})
+	}
+}
+
+// personalizeMessages clones base once per recipient, addressing each clone to that recipient only
+// and applying the optional personalize callback. A BodyReader of base is read once, and every clone
+// gets its own reader of the buffered body.
+func personalizeMessages(base Message, recipients []mail.Address, personalize func(Message, mail.Address) Message) ([]Message, error) {
+	var body []byte
+	if base.BodyReader != nil {
+		data, err := io.ReadAll(base.BodyReader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message body: %w", err)
+		}
+		body = data
+	}
+
+	messages := make([]Message, 0, len(recipients))
+	for _, recipient := range recipients {
+		m := base.Clone()
+		if base.BodyReader != nil {
+			m.BodyReader = bytes.NewReader(body)
+		}
+		m.MailTo = []string{recipient.String()}
+		m.CC = nil
+		m.BCC = nil
+		if personalize != nil {
+			m = personalize(m, recipient)
+		}
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+// validateMessages splits the messages into the valid ones and the validation errors of the others,
+// keyed by their index in m. The rejected map is nil when every message is valid.
+func validateMessages(m []Message) ([]Message, map[int]error) {
+	valid := make([]Message, 0, len(m))
+	var rejected map[int]error
+	for i := range m {
+		if err := m[i].Validate(); err != nil {
+			if rejected == nil {
+				rejected = map[int]error{}
+			}
+			rejected[i] = err
+			continue
+		}
+		valid = append(valid, m[i])
+	}
+	return valid, rejected
+}
+
+// CollectResults drains the channel returned by Send or SendPersonalized and returns the final state
+// of every message (Sent or SendError). The failures are joined into the returned error, each one
+// prefixed with the message ID (or its recipients when the ID is empty), so errors.Is and errors.As
+// reach every underlying cause while the messages still carry their individual errors.
+func CollectResults(ch chan Message) ([]Message, error) {
+	var results []Message
+	var errs []error
+	for m := range ch {
+		if m.Status != Sent && m.Status != SendError {
+			continue
+		}
+		results = append(results, m)
+		if m.Status == SendError {
+			errs = append(errs, fmt.Errorf("message %s: %w", m.label(), m.Error))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// label identifies the message in aggregated errors.
+func (m *Message) label() string {
+	if m.ID != "" {
+		return m.ID
+	}
+	return "to " + strings.Join(m.MailTo, ", ")
+}
@@ -0,0 +1,553 @@
+package messaging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/diegoyosiura/cloud-manager/pkg/backoff"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Test personalized batch sending
+// Verifies that one message is produced per recipient with its own "To" header and personalization applied.
+func TestSendPersonalized(t *testing.T) {
+	base := generateSampleMessage()
+	recipients := []mail.Address{
+		{Name: "Alice", Address: "alice@example.com"},
+		{Name: "Bob", Address: "bob@example.com"},
+	}
+	personalize := func(m Message, to mail.Address) Message {
+		m.Body = "Hello " + to.Name
+		return m
+	}
+
+	messages, err := personalizeMessages(base, recipients, personalize)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(messages) != len(recipients) {
+		t.Fatalf("expected %d messages, got %d", len(recipients), len(messages))
+	}
+
+	for i, m := range messages {
+		data, err := m.Bytes()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Contains(data, []byte("To: "+recipients[i].String()+"\r\n")) {
+			t.Errorf("message %d is missing the individual 'To' header for %s", i, recipients[i].Address)
+		}
+		if bytes.Contains(data, []byte("Cc: ")) {
+			t.Errorf("message %d must not carry the base CC recipients", i)
+		}
+		if !bytes.Contains(data, []byte("Hello "+recipients[i].Name)) {
+			t.Errorf("message %d was not personalized", i)
+		}
+	}
+	if len(base.MailTo) != 1 || base.MailTo[0] != "to@example.com" {
+		t.Errorf("base message was modified: %v", base.MailTo)
+	}
+
+	// Sending emits the events of each personalized copy. An invalid sender makes them fail before any network access.
+	base.From = mail.Address{Address: "invalid"}
+	manager := boundManager(&AWSManager{Auth: &authentication.AWSAuth{}, MessagesMT: &sync.RWMutex{}})
+	ch, err := manager.SendPersonalized(base, recipients, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	seen := map[string]bool{}
+	for event := range ch {
+		if len(event.MailTo) != 1 {
+			t.Errorf("expected exactly one recipient per message, got %v", event.MailTo)
+			continue
+		}
+		seen[event.MailTo[0]] = true
+	}
+	if len(seen) != len(recipients) {
+		t.Errorf("expected events for %d recipients, got %v", len(recipients), seen)
+	}
+}
+
+// Test personalizing a message with a streamed body
+// Verifies that every personalized copy renders the whole body instead of sharing one drained reader.
+func TestSendPersonalized_BodyReader(t *testing.T) {
+	base := generateSampleMessage()
+	base.SetBodyReader(strings.NewReader("Streamed report body"), "text/plain")
+	recipients := []mail.Address{{Address: "alice@example.com"}, {Address: "bob@example.com"}}
+
+	messages, err := personalizeMessages(base, recipients, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, m := range messages {
+		data, err := m.Bytes()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Contains(data, []byte("Streamed report body")) {
+			t.Errorf("message %d is missing the streamed body", i)
+		}
+	}
+}
+
+// Test aggregating the results of a bulk send
+// Verifies that only final states are returned and that the joined error unwraps to every failure.
+func TestCollectResults(t *testing.T) {
+	errRejected := errors.New("recipient rejected")
+	errTimeout := errors.New("connection timed out")
+
+	ch := make(chan Message, 8)
+	ch <- Message{ID: "msg-1", Status: Queued}
+	ch <- Message{ID: "msg-1", Status: Sent}
+	ch <- Message{ID: "msg-2", Status: SendError, Error: errRejected}
+	ch <- Message{MailTo: []string{"bob@example.com"}, Status: SendError, Error: errTimeout}
+	close(ch)
+
+	results, err := CollectResults(ch)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 final results, got %d", len(results))
+	}
+	if !errors.Is(err, errRejected) || !errors.Is(err, errTimeout) {
+		t.Errorf("expected the joined error to wrap every failure, got %v", err)
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok || len(joined.Unwrap()) != 2 {
+		t.Fatalf("expected a join of 2 errors, got %v", err)
+	}
+	if got := joined.Unwrap()[0].Error(); got != "message msg-2: recipient rejected" {
+		t.Errorf("unexpected error context: %s", got)
+	}
+	if got := joined.Unwrap()[1].Error(); got != "message to bob@example.com: connection timed out" {
+		t.Errorf("unexpected error context: %s", got)
+	}
+}
+
+// TestAddMessagesValidated verifies that only valid messages are enqueued and each rejection is reported by index.
+func TestAddMessagesValidated(t *testing.T) {
+	valid := generateSampleMessage()
+
+	badFrom := generateSampleMessage()
+	badFrom.From = mail.Address{Address: "not-an-address"}
+
+	noRecipients := generateSampleMessage()
+	noRecipients.MailTo, noRecipients.CC, noRecipients.BCC = nil, nil, nil
+
+	badRecipient := generateSampleMessage()
+	badRecipient.CC = []string{"broken@"}
+
+	manager := boundManager(&OciManager{Auth: &authentication.OCIAuth{}, MessagesMT: &sync.RWMutex{}})
+	accepted, rejected := manager.AddMessagesValidated([]Message{valid, badFrom, noRecipients, valid, badRecipient})
+
+	if accepted != 2 || len(manager.Messages) != 2 {
+		t.Errorf("expected 2 accepted and enqueued messages, got %d accepted and %d enqueued", accepted, len(manager.Messages))
+	}
+	if len(rejected) != 3 {
+		t.Fatalf("expected 3 rejected messages, got %v", rejected)
+	}
+	for _, i := range []int{1, 2, 4} {
+		if rejected[i] == nil {
+			t.Errorf("expected message %d to be rejected, got %v", i, rejected)
+		}
+	}
+
+	if _, rejected := manager.AddMessagesValidated([]Message{valid}); rejected != nil {
+		t.Errorf("expected no rejections, got %v", rejected)
+	}
+}
+
+// Test sending a large batch through a large status channel drained slowly
+// Verifies that ChannelBuffer sizes the channel and that every message completes without deadlocking.
+func TestChannelBuffer(t *testing.T) {
+	host, port := fakeSMTPRelay(t, nil)
+	manager := boundManager(&OciManager{Auth: &authentication.OCIAuth{EmailHost: host, EmailPort: port}, MessagesMT: &sync.RWMutex{}, ChannelBuffer: 64})
+
+	const total = 40
+	for i := 0; i < total; i++ {
+		manager.AddMessage(generateSampleMessage())
+	}
+
+	ch, _, err := manager.Send()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cap(ch) != 64 {
+		t.Errorf("expected a channel buffer of 64, got %d", cap(ch))
+	}
+
+	done := make(chan int)
+	go func() {
+		sent := 0
+		for m := range ch {
+			time.Sleep(time.Millisecond)
+			if m.Status == Sent {
+				sent++
+			}
+		}
+		done <- sent
+	}()
+
+	select {
+	case sent := <-done:
+		if sent != total {
+			t.Errorf("expected %d messages sent, got %d", total, sent)
+		}
+	case <-time.After(20 * time.Second):
+		t.Fatal("the batch did not complete: the send goroutines are blocked")
+	}
+}
+
+// Test a consumer that stops reading the status channel
+// Verifies that cancelling the context of the batch releases the send goroutines, which close the channel.
+func TestWithContextStopsBlockedSends(t *testing.T) {
+	host, port := fakeSMTPRelay(t, nil)
+	manager := boundManager(&AWSManager{Auth: &authentication.AWSAuth{EmailHost: host, EmailPort: port}, MessagesMT: &sync.RWMutex{}, ChannelBuffer: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	manager.WithContext(ctx)
+	for i := 0; i < 20; i++ {
+		manager.AddMessage(generateSampleMessage())
+	}
+
+	ch, _, err := manager.Send()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-ch // The consumer reads a single update, then stops.
+	cancel()
+
+	// Without a reader, the full channel only lets the goroutines return through the cancelled context.
+	ended := make(chan struct{})
+	go func() {
+		manager.batches.wg.Wait()
+		close(ended)
+	}()
+	select {
+	case <-ended:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the batch to end once the context is cancelled")
+	}
+
+	closed := make(chan int)
+	go func() {
+		pending := 0
+		for range ch {
+			pending++
+		}
+		closed <- pending
+	}()
+	select {
+	case pending := <-closed:
+		if pending > cap(ch) {
+			t.Errorf("expected at most the %d buffered updates after cancelling, got %d", cap(ch), pending)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the channel to close once the context is cancelled")
+	}
+}
+
+// Test cancelling a rate-limited batch
+// Verifies that CancelSend returns once the batch stopped, marking the messages not sent as Cancelled.
+func TestCancelSend(t *testing.T) {
+	host, port := fakeSMTPRelay(t, nil)
+	manager := boundManager(&OciManager{Auth: &authentication.OCIAuth{EmailHost: host, EmailPort: port}, MessagesMT: &sync.RWMutex{}, ChannelBuffer: 64, MaxMessagesPerSecond: 2})
+
+	const total = 10
+	for i := 0; i < total; i++ {
+		m := generateSampleMessage()
+		m.ID = fmt.Sprint(i)
+		manager.AddMessage(m)
+	}
+
+	ch, _, err := manager.Send()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Cancel once the first message was sent; the rate limit holds the others back.
+	final := map[string]MessageStatus{}
+	for m := range ch {
+		if m.Status == Sent || m.Status == SendError || m.Status == Cancelled {
+			final[m.ID] = m.Status
+		}
+		if m.Status == Sent {
+			break
+		}
+	}
+
+	start := time.Now()
+	if ok, err := manager.CancelSend(); !ok || err != nil {
+		t.Fatalf("expected the batch to be cancelled, got %v, %v", ok, err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected CancelSend to return promptly, took %v", elapsed)
+	}
+
+	for m := range ch {
+		if m.Status == Sent || m.Status == SendError || m.Status == Cancelled {
+			final[m.ID] = m.Status
+		}
+	}
+	sent, cancelled := 0, 0
+	for _, status := range final {
+		switch status {
+		case Sent:
+			sent++
+		case Cancelled:
+			cancelled++
+		default:
+			t.Errorf("unexpected final status %v", status)
+		}
+	}
+	if sent == 0 || sent == total || sent+cancelled != total {
+		t.Errorf("expected the first messages sent and the others cancelled, got %d sent and %d cancelled", sent, cancelled)
+	}
+
+	// The manager can send again after a cancellation.
+	manager.MaxMessagesPerSecond = 0
+	ch, _, err = manager.Send()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for range ch {
+	}
+}
+
+// fakeSMTPRejecting accepts any number of connections, rejecting the first ones with the given greeting
+// replies (e.g. "421 Busy") and serving the following ones with serveFakeSMTP. It returns the host and
+// port it listens on.
+func fakeSMTPRejecting(t *testing.T, replies ...string) (string, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error listening: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for i := 0; ; i++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if i < len(replies) {
+				_ = textproto.NewConn(conn).PrintfLine("%s", replies[i])
+				_ = conn.Close()
+				continue
+			}
+			go serveFakeSMTP(conn, nil, nil)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	return host, port
+}
+
+// Test sending through a server that is temporarily unavailable
+// Verifies that transient replies are retried with a Retrying update per retry, and that permanent ones are not.
+func TestSendRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		replies  []string
+		status   MessageStatus
+		attempts int
+		retries  int
+	}{
+		{name: "transient then accepted", replies: []string{"421 Busy", "451 Try again"}, status: Sent, attempts: 3, retries: 2},
+		{name: "transient until exhausted", replies: []string{"421 Busy", "421 Busy", "421 Busy"}, status: SendError, attempts: 3, retries: 2},
+		{name: "permanent", replies: []string{"554 No service"}, status: SendError, attempts: 1, retries: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port := fakeSMTPRejecting(t, tt.replies...)
+			manager := boundManager(&AWSManager{
+				Auth:       &authentication.AWSAuth{EmailHost: host, EmailPort: port},
+				MessagesMT: &sync.RWMutex{},
+				Backoff:    backoff.ConstantBackoff{Delay: time.Millisecond, Attempts: 3},
+			})
+			manager.AddMessage(generateSampleMessage())
+
+			ch, _, err := manager.Send()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var last Message
+			retries := 0
+			for m := range ch {
+				if m.Status == Retrying {
+					retries++
+					if m.Error == nil {
+						t.Error("expected the Retrying update to carry the transient error")
+					}
+				}
+				last = m
+			}
+
+			if last.Status != tt.status || last.Attempts != tt.attempts || retries != tt.retries {
+				t.Errorf("expected status %d after %d attempts and %d retries, got status %d after %d attempts and %d retries (%v)",
+					tt.status, tt.attempts, tt.retries, last.Status, last.Attempts, retries, last.Error)
+			}
+			if tt.status == Sent && last.Error != nil {
+				t.Errorf("expected the error to be cleared once sent, got %v", last.Error)
+			}
+		})
+	}
+}
+
+// Test sending through the Azure and GCP managers
+// Verifies that both deliver the queued messages to the SMTP host and port of their authentication config.
+func TestSMTPRelayManagers(t *testing.T) {
+	host, port := fakeSMTPRelay(t, nil)
+	managers := map[string]MessageManager{
+		"azure": boundManager(&AzureManager{Auth: &authentication.AzureAuth{EmailHost: host, EmailPort: port}, MessagesMT: &sync.RWMutex{}}),
+		"gcp":   boundManager(&GCPManager{Auth: &authentication.GCPAuth{EmailHost: host, EmailPort: port}, MessagesMT: &sync.RWMutex{}}),
+	}
+
+	for name, manager := range managers {
+		t.Run(name, func(t *testing.T) {
+			manager.AddMessages([]Message{generateSampleMessage(), generateSampleMessage()})
+			ch, _, err := manager.Send()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			results, err := CollectResults(ch)
+			if err != nil {
+				t.Fatalf("unexpected send error: %v", err)
+			}
+			if len(results) != 2 || results[0].Status != Sent || results[1].Status != Sent {
+				t.Errorf("expected 2 sent messages, got %+v", results)
+			}
+		})
+	}
+}
+
+// Test the provider managers against the MessageManager interface
+// Verifies that every manager still implements MessageManager with the send loop shared through smtpBatchSender.
+func TestManagersImplementMessageManager(t *testing.T) {
+	managers := map[string]MessageManager{
+		"aws":   boundManager(&AWSManager{MessagesMT: &sync.RWMutex{}}),
+		"azure": boundManager(&AzureManager{MessagesMT: &sync.RWMutex{}}),
+		"gcp":   boundManager(&GCPManager{MessagesMT: &sync.RWMutex{}}),
+		"oci":   boundManager(&OciManager{MessagesMT: &sync.RWMutex{}}),
+	}
+	for name, manager := range managers {
+		if ok, err := manager.CancelSend(); !ok || err != nil {
+			t.Errorf("%s: expected CancelSend to succeed without running batches, got %v, %v", name, ok, err)
+		}
+		manager.AddMessage(generateSampleMessage())
+		if accepted, rejected := manager.AddMessagesValidated([]Message{generateSampleMessage(), {}}); accepted != 1 || len(rejected) != 1 {
+			t.Errorf("%s: expected 1 accepted and 1 rejected message, got %d and %v", name, accepted, rejected)
+		}
+	}
+}
+
+// Test the progress of a queue
+// Verifies that SendStatus handles an empty queue and that every manager reports its progress.
+func TestProgress(t *testing.T) {
+	managers := map[string]MessageManager{
+		"aws":   boundManager(&AWSManager{Auth: &authentication.AWSAuth{}, MessagesMT: &sync.RWMutex{}}),
+		"azure": boundManager(&AzureManager{Auth: &authentication.AzureAuth{}, MessagesMT: &sync.RWMutex{}}),
+		"gcp":   boundManager(&GCPManager{Auth: &authentication.GCPAuth{}, MessagesMT: &sync.RWMutex{}}),
+		"oci":   boundManager(&OciManager{Auth: &authentication.OCIAuth{}, MessagesMT: &sync.RWMutex{}}),
+	}
+	for name, manager := range managers {
+		if status, err := manager.SendStatus(); err != nil || status != 0 {
+			t.Errorf("%s: expected a send status of 0 for an empty queue, got %v (%v)", name, status, err)
+		}
+		if p := manager.Progress(); p != (SendProgress{}) {
+			t.Errorf("%s: expected an empty progress, got %+v", name, p)
+		}
+
+		manager.AddMessages([]Message{generateSampleMessage(), generateSampleMessage()})
+		if p := manager.Progress(); p.Total != 2 || p.Pending() != 2 {
+			t.Errorf("%s: expected 2 pending messages before sending, got %+v", name, p)
+		}
+	}
+}
+
+// Test the progress of a cancelled batch
+// Verifies that messages not dispatched because the batch was cancelled are counted as Cancelled.
+func TestProgressCancelled(t *testing.T) {
+	manager := boundManager(&OciManager{Auth: &authentication.OCIAuth{}, MessagesMT: &sync.RWMutex{}})
+	manager.AddMessages([]Message{generateSampleMessage(), generateSampleMessage()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	manager.WithContext(ctx)
+	ch, _, err := manager.Send()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for range ch {
+	}
+
+	p := manager.Progress()
+	if p.Total != 2 || p.Cancelled != 2 || p.Pending() != 0 {
+		t.Errorf("expected 2 cancelled messages, got %+v", p)
+	}
+	if status, err := manager.SendStatus(); err != nil || status != 0 {
+		t.Errorf("expected a send status of 0, got %v (%v)", status, err)
+	}
+}
+
+// Test the send status once a batch completes
+// Verifies that the send goroutines update the queued messages, so SendStatus reports every message sent.
+func TestSendStatusAfterSend(t *testing.T) {
+	host, port := fakeSMTPRelay(t, nil)
+	manager := boundManager(&OciManager{Auth: &authentication.OCIAuth{EmailHost: host, EmailPort: port}, MessagesMT: &sync.RWMutex{}})
+	manager.AddMessages([]Message{generateSampleMessage(), generateSampleMessage()})
+
+	ch, _, err := manager.Send()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for range ch {
+	}
+
+	if status, err := manager.SendStatus(); err != nil || status != 1.0 {
+		t.Errorf("expected a send status of 1.0, got %v (%v)", status, err)
+	}
+	for i, m := range manager.Messages {
+		if m.Status != Sent || m.Attempts != 1 || m.DateStatus.IsZero() {
+			t.Errorf("expected message %d to be recorded as sent after 1 attempt, got status %d after %d attempts", i, m.Status, m.Attempts)
+		}
+	}
+}
+
+// Test the progress of a batch with a failed message
+// Verifies that Progress counts the sent and failed messages once the batch ends and that the error is recorded.
+func TestProgressAfterSend(t *testing.T) {
+	host, port := fakeSMTPRelay(t, nil)
+	manager := boundManager(&AWSManager{Auth: &authentication.AWSAuth{EmailHost: host, EmailPort: port}, MessagesMT: &sync.RWMutex{}})
+
+	invalid := generateSampleMessage()
+	invalid.From = mail.Address{Address: "invalid"}
+	manager.AddMessages([]Message{generateSampleMessage(), invalid})
+
+	ch, _, err := manager.Send()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for range ch {
+	}
+
+	p := manager.Progress()
+	if p.Total != 2 || p.Sent != 1 || p.Failed != 1 || p.Cancelled != 0 || p.Pending() != 0 {
+		t.Errorf("expected 1 sent and 1 failed message, got %+v", p)
+	}
+	if status, err := manager.SendStatus(); err != nil || status != 0.5 {
+		t.Errorf("expected a send status of 0.5, got %v (%v)", status, err)
+	}
+	if manager.Messages[1].Error == nil {
+		t.Error("expected the error of the failed message to be recorded in the queue")
+	}
+}
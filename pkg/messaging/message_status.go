@@ -8,4 +8,6 @@ const (
 	Sending   MessageStatus = 2
 	Sent      MessageStatus = 3
 	SendError MessageStatus = 4
+	Cancelled MessageStatus = 5 // Not sent because the batch was cancelled (see CancelSend).
+	Retrying  MessageStatus = 6 // Failed with a transient error and waiting to be sent again (see Message.Attempts).
 )
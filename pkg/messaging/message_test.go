@@ -2,8 +2,17 @@ package messaging
 
 import (
 	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/mail"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // Helper function to generate a sample message
@@ -99,6 +108,56 @@ func TestInline(t *testing.T) {
 	}
 }
 
+// Test inline attachment rendering
+// Verifies that inline attachments are rendered with the inline disposition and a Content-ID inside a
+// multipart/related message, while regular attachments keep the attachment disposition.
+func TestInlineRendering(t *testing.T) {
+	msg := generateSampleMessage()
+	msg.SetHTMLBody(`<img src="cid:image.png">`, false)
+	if err := msg.Inline("testdata/image.png"); err != nil {
+		t.Fatalf("unexpected error attaching inline file: %v", err)
+	}
+	if err := msg.AttachBuffer("report.txt", []byte("report"), false); err != nil {
+		t.Fatalf("unexpected error attaching buffer: %v", err)
+	}
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error parsing the message: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/related" {
+		t.Fatalf("expected a multipart/related message, got %q", parsed.Header.Get("Content-Type"))
+	}
+
+	dispositions := map[string]string{}
+	contentIDs := map[string]string{}
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error reading the parts: %v", err)
+		}
+		if part.FileName() != "" {
+			dispositions[part.FileName()], _, _ = mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+			contentIDs[part.FileName()] = part.Header.Get("Content-ID")
+		}
+	}
+	if dispositions["image.png"] != "inline" || contentIDs["image.png"] != "<image.png>" {
+		t.Errorf("unexpected inline image: disposition %q, Content-ID %q", dispositions["image.png"], contentIDs["image.png"])
+	}
+	if dispositions["report.txt"] != "attachment" || contentIDs["report.txt"] != "" {
+		t.Errorf("unexpected attachment: disposition %q, Content-ID %q", dispositions["report.txt"], contentIDs["report.txt"])
+	}
+}
+
 // Test adding custom headers
 // Verifies that custom headers can be added to the message.
 func TestAddHeader(t *testing.T) {
@@ -183,3 +242,629 @@ func TestBytes(t *testing.T) {
 		t.Error("missing custom header")
 	}
 }
+
+// TestBytesConcurrent verifies that the bytes returned by Bytes are not overwritten when the pooled
+// buffer is reused by messages rendered concurrently.
+func TestBytesConcurrent(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			msg := generateSampleMessage()
+			msg.Body = strings.Repeat(fmt.Sprintf("message-%d ", i), 100)
+
+			data, err := msg.Bytes()
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			// Render more messages so the pooled buffer is reused while data is still held
+			for j := 0; j < 10; j++ {
+				other := generateSampleMessage()
+				other.Body = strings.Repeat("x", 2000)
+				if _, err := other.Bytes(); err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+			}
+			if !bytes.Contains(data, []byte(msg.Body)) || bytes.Contains(data, []byte("xxx")) {
+				t.Errorf("message %d was overwritten by another message", i)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// Test sanitizing HTML bodies
+// Verifies that scripts and event handlers are stripped while safe markup is kept.
+func TestBytesSanitizeHTML(t *testing.T) {
+	msg := generateSampleMessage()
+	msg.SetHTMLBody(`<p onclick="steal()">Hello <b>World</b></p><script>alert('x')</script><a href="javascript:alert(1)">link</a>`, true)
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if bytes.Contains(data, []byte("<script")) || bytes.Contains(data, []byte("alert('x')")) {
+		t.Error("script element was not removed")
+	}
+	if bytes.Contains(data, []byte("onclick")) {
+		t.Error("onclick attribute was not removed")
+	}
+	if bytes.Contains(data, []byte("javascript:")) {
+		t.Error("javascript URL was not removed")
+	}
+	if !bytes.Contains(data, []byte("<p>Hello <b>World</b></p>")) {
+		t.Error("safe markup was not preserved")
+	}
+}
+
+// Test that plain-text bodies are never sanitized
+// Verifies that markup-looking text in a text/plain body is left untouched.
+func TestBytesSanitizeHTMLPlainText(t *testing.T) {
+	msg := generateSampleMessage()
+	msg.Body = "<script>not html</script>"
+	msg.SanitizeHTML = true
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !bytes.Contains(data, []byte("<script>not html</script>")) {
+		t.Error("plain-text body should not be modified")
+	}
+}
+
+// Test setting an expiry date
+// Verifies that the Expiry-Date header is rendered in RFC 1123Z format and past dates are rejected.
+func TestSetExpiry(t *testing.T) {
+	msg := generateSampleMessage()
+
+	if err := msg.SetExpiry(time.Now().Add(-time.Hour)); err == nil {
+		t.Error("expected error for an expiry date in the past")
+	}
+
+	expiry := time.Now().Add(24 * time.Hour)
+	if err := msg.SetExpiry(expiry); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	msg.SuppressAutoReply = true
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "Expiry-Date: " + expiry.Format(time.RFC1123Z) + "\r\n"
+	if !bytes.Contains(data, []byte(expected)) {
+		t.Errorf("missing or invalid 'Expiry-Date' header, expected '%s'", expected)
+	}
+	if !bytes.Contains(data, []byte("X-Auto-Response-Suppress: All\r\n")) {
+		t.Error("missing 'X-Auto-Response-Suppress' header")
+	}
+}
+
+// Test marking automated messages
+// Verifies the Auto-Submitted and Precedence headers and that they are absent by default.
+func TestSetAutomated(t *testing.T) {
+	msg := generateSampleMessage()
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bytes.Contains(data, []byte("Auto-Submitted:")) || bytes.Contains(data, []byte("Precedence:")) {
+		t.Error("automated headers must be absent by default")
+	}
+
+	if err := msg.SetAutomated("", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err = msg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Contains(data, []byte("Auto-Submitted: auto-generated\r\n")) {
+		t.Error("missing or invalid 'Auto-Submitted' header")
+	}
+	if !bytes.Contains(data, []byte("Precedence: bulk\r\n")) {
+		t.Error("missing or invalid 'Precedence' header")
+	}
+
+	reply := generateSampleMessage()
+	if err := reply.SetAutomated(AutoSubmittedReplied, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err = reply.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Contains(data, []byte("Auto-Submitted: auto-replied\r\n")) || bytes.Contains(data, []byte("Precedence:")) {
+		t.Error("expected 'Auto-Submitted: auto-replied' without 'Precedence'")
+	}
+
+	if err := reply.SetAutomated("no", false); err == nil {
+		t.Error("expected an error for an invalid Auto-Submitted value")
+	}
+}
+
+// Test the body size limit
+// Verifies that oversized bodies return ErrBodyTooLarge with the actual size and bodies at the limit succeed.
+func TestBytesMaxBodyBytes(t *testing.T) {
+	msg := generateSampleMessage()
+	msg.MaxBodyBytes = 1024
+
+	msg.Body = strings.Repeat("a", 1025)
+	_, err := msg.Bytes()
+	var tooLarge *ErrBodyTooLarge
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("expected ErrBodyTooLarge, got %v", err)
+	}
+	if tooLarge.Size != 1025 || tooLarge.Limit != 1024 {
+		t.Errorf("expected size 1025 and limit 1024, got %d and %d", tooLarge.Size, tooLarge.Limit)
+	}
+
+	msg.Body = strings.Repeat("a", 1024)
+	if _, err := msg.Bytes(); err != nil {
+		t.Errorf("unexpected error for body within the limit: %v", err)
+	}
+
+	// The default limit applies when MaxBodyBytes is not set.
+	msg.MaxBodyBytes = 0
+	msg.Body = strings.Repeat("a", DefaultMaxBodyBytes+1)
+	if _, err := msg.Bytes(); !errors.As(err, &tooLarge) {
+		t.Errorf("expected ErrBodyTooLarge with the default limit, got %v", err)
+	}
+}
+
+// Test streaming the body from a reader
+// Verifies that a body supplied via SetBodyReader renders exactly like the equivalent string body.
+func TestSetBodyReader(t *testing.T) {
+	body := strings.Repeat("Line of a large generated report.\r\n", 100)
+
+	stringMsg := generateSampleMessage()
+	stringMsg.Body = body
+
+	readerMsg := generateSampleMessage()
+	readerMsg.Body = ""
+	readerMsg.SetBodyReader(strings.NewReader(body), "text/plain")
+
+	var expected, got bytes.Buffer
+	if _, err := stringMsg.WriteTo(&expected); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := readerMsg.WriteTo(&got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The Date header depends on the rendering time, so it is excluded from the comparison.
+	if stripDateHeader(got.String()) != stripDateHeader(expected.String()) {
+		t.Errorf("reader body rendered differently from string body:\n%s\n---\n%s", got.String(), expected.String())
+	}
+
+	// Streamed bodies are still subject to the size limit.
+	limited := generateSampleMessage()
+	limited.MaxBodyBytes = 10
+	limited.SetBodyReader(strings.NewReader(body), "text/plain")
+	var tooLarge *ErrBodyTooLarge
+	if _, err := limited.WriteTo(&bytes.Buffer{}); !errors.As(err, &tooLarge) {
+		t.Errorf("expected ErrBodyTooLarge for streamed body, got %v", err)
+	}
+
+	// Bytes discards the partial output of an oversized streamed body.
+	limited.SetBodyReader(strings.NewReader(body), "text/plain")
+	if data, err := limited.Bytes(); !errors.As(err, &tooLarge) || data != nil {
+		t.Errorf("expected ErrBodyTooLarge and no content from Bytes, got %d bytes and %v", len(data), err)
+	}
+}
+
+// stripDateHeader removes the Date header line from a rendered message.
+func stripDateHeader(message string) string {
+	lines := strings.Split(message, "\r\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "Date: ") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\r\n")
+}
+
+// Test header canonicalization
+// Verifies that the standard mode, the default, canonicalizes every header name and the as-is mode keeps the
+// given spelling, and that a custom boundary replaces the default one.
+func TestHeaderCanonicalization(t *testing.T) {
+	msg := generateSampleMessage()
+	msg.AddHeader("content-LANGUAGE", "pt-BR")
+	msg.AddHeader("X-MAILER", "legacy")
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{"Mime-Version: 1.0\r\n", "Content-Language: pt-BR\r\n", "X-Mailer: legacy\r\n", "Content-Type: text/plain; charset=utf-8\r\n"} {
+		if !bytes.Contains(data, []byte(expected)) {
+			t.Errorf("standard mode: missing header %q", expected)
+		}
+	}
+
+	msg.HeaderCanonicalization = HeaderCanonicalizationAsIs
+	msg.Boundary = "legacy-boundary"
+	msg.Attachments["note.txt"] = &Attachment{Filename: "note.txt", Data: []byte("note")}
+
+	data, err = msg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{"MIME-Version: 1.0\r\n", "content-LANGUAGE: pt-BR\r\n", "X-MAILER: legacy\r\n", "Content-Type: multipart/mixed; boundary=\"legacy-boundary\"\r\n", "--legacy-boundary--\r\n"} {
+		if !bytes.Contains(data, []byte(expected)) {
+			t.Errorf("as-is mode: missing header %q", expected)
+		}
+	}
+	if bytes.Contains(data, []byte("Mime-Version")) || bytes.Contains(data, []byte("Content-Language")) {
+		t.Error("as-is mode must not canonicalize header names")
+	}
+}
+
+// Test custom multipart boundary
+// Verifies that a custom boundary is quoted in the Content-Type header, and that boundaries outside the RFC 2046
+// syntax or occurring in the content are rejected.
+func TestCustomBoundary(t *testing.T) {
+	msg := generateSampleMessage()
+	msg.Attachments["note.txt"] = &Attachment{Filename: "note.txt", Data: []byte("note")}
+	msg.Boundary = "legacy boundary:1"
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+	_, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || params["boundary"] != "legacy boundary:1" {
+		t.Errorf("unexpected Content-Type %q: %v", parsed.Header.Get("Content-Type"), err)
+	}
+
+	for _, boundary := range []string{strings.Repeat("b", 71), "trailing ", "semi;colon", "quote\"", "line\r\nBcc: x@example.com", "test body"} {
+		msg.Boundary = boundary
+		if _, err := msg.Bytes(); err == nil {
+			t.Errorf("expected an error for the boundary %q", boundary)
+		}
+	}
+}
+
+// Test random multipart boundary
+// Verifies that content holding the former fixed boundary no longer breaks the MIME structure, and that
+// every message gets a different boundary.
+func TestRandomBoundary(t *testing.T) {
+	const oldBoundary = "--f46d043c813270fc6b04c2d223da"
+	msg := generateSampleMessage()
+	msg.Body = "Before\r\n" + oldBoundary + "\r\nAfter"
+	msg.Attachments["raw.txt"] = &Attachment{Filename: "raw.txt", Data: []byte(oldBoundary + "--\r\n")}
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error parsing the message: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" || params["boundary"] == "f46d043c813270fc6b04c2d223da" {
+		t.Fatalf("unexpected Content-Type %q", parsed.Header.Get("Content-Type"))
+	}
+
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	var parts []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error reading the parts: %v", err)
+		}
+		content, _ := io.ReadAll(part)
+		parts = append(parts, string(content))
+	}
+	if len(parts) != 2 || !strings.Contains(parts[0], oldBoundary+"\r\nAfter") {
+		t.Fatalf("expected the body and the attachment parts, got %q", parts)
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(parts[1])); err != nil || string(decoded) != oldBoundary+"--\r\n" {
+		t.Errorf("unexpected attachment content %q (%v)", decoded, err)
+	}
+
+	other, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	otherParsed, _ := mail.ReadMessage(bytes.NewReader(other))
+	_, otherParams, _ := mime.ParseMediaType(otherParsed.Header.Get("Content-Type"))
+	if otherParams["boundary"] == params["boundary"] {
+		t.Error("expected a new boundary for every rendering")
+	}
+}
+
+// TestBccHeaderStripped verifies that a custom "Bcc" header, whatever its spelling, is never rendered,
+// while the BCC recipients are still part of the envelope.
+func TestBccHeaderStripped(t *testing.T) {
+	msg := generateSampleMessage()
+	msg.AddHeader("Bcc", "bcc@example.com")
+	msg.AddHeader(" bCC ", "hidden@example.com")
+
+	for _, canonicalization := range []HeaderCanonicalization{HeaderCanonicalizationStandard, HeaderCanonicalizationAsIs} {
+		msg.HeaderCanonicalization = canonicalization
+		data, err := msg.Bytes()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if bytes.Contains(bytes.ToLower(data), []byte("bcc")) {
+			t.Errorf("%s mode: blind recipients leaked into the message:\n%s", canonicalization, data)
+		}
+	}
+
+	recipients, err := msg.Tolist()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if recipients[len(recipients)-1] != "bcc@example.com" {
+		t.Errorf("expected the BCC recipient in the envelope, got %v", recipients)
+	}
+}
+
+// TestSanitizeFilename verifies that readable filenames survive, traversal attempts are neutralized
+// and that the strict policy can be selected per message.
+func TestSanitizeFilename(t *testing.T) {
+	tests := map[string]string{
+		"Q3 Financial Report (final).pdf": "Q3 Financial Report (final).pdf",
+		"../../etc/passwd":                ".._.._etc_passwd",
+		`..\..\windows\win.ini`:           ".._.._windows_win.ini",
+		"..":                              "_",
+		"say \"hi\"\r\n.txt":              "say _hi_.txt",
+	}
+	for input, want := range tests {
+		if got := sanitizeFilename(input); got != want {
+			t.Errorf("sanitizeFilename(%q): expected %q, got %q", input, want, got)
+		}
+	}
+
+	msg := generateSampleMessage()
+	if err := msg.AttachBuffer("Q3 Financial Report (final).pdf", []byte("data"), false); err != nil {
+		t.Fatalf("unexpected error attaching buffer: %v", err)
+	}
+	if _, ok := msg.Attachments["Q3 Financial Report (final).pdf"]; !ok {
+		t.Errorf("expected the filename to be preserved, got %v", msg.Attachments)
+	}
+	raw, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error rendering message: %v", err)
+	}
+	if !strings.Contains(string(raw), `filename="Q3 Financial Report (final).pdf"`) {
+		t.Error("expected the quoted filename in the Content-Disposition header")
+	}
+
+	strict := generateSampleMessage()
+	strict.FilenameSanitizer = StrictFilenameSanitizer
+	if err := strict.AttachBuffer("Q3 Financial Report (final).pdf", []byte("data"), false); err != nil {
+		t.Fatalf("unexpected error attaching buffer: %v", err)
+	}
+	if _, ok := strict.Attachments["Q3_Financial_Report_final_.pdf"]; !ok {
+		t.Errorf("expected the strict filename, got %v", strict.Attachments)
+	}
+}
+
+// Test header injection through recipients
+// Verifies that recipients holding control characters or malformed addresses are rejected, that the
+// addresses are canonicalized, and that BCC recipients never appear in the headers.
+func TestRecipientHeaderInjection(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(m *Message)
+	}{
+		{name: "CRLF in To", modify: func(m *Message) { m.MailTo = []string{"to@example.com\r\nBcc: victim@example.com"} }},
+		{name: "LF in Cc", modify: func(m *Message) { m.CC = []string{"cc@example.com\nX-Injected: yes"} }},
+		{name: "CR in display name", modify: func(m *Message) { m.Reply = []string{"\"Reply\r\nX-Injected: yes\" <reply@example.com>"} }},
+		{name: "malformed To", modify: func(m *Message) { m.MailTo = []string{"not an address"} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := generateSampleMessage()
+			tt.modify(&msg)
+			if _, err := msg.Bytes(); err == nil {
+				t.Error("expected the recipient to be rejected")
+			}
+		})
+	}
+
+	msg := generateSampleMessage()
+	msg.MailTo = []string{"Zoë <to@example.com>", "other@example.com"}
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Contains(data, []byte("To: =?utf-8?q?Zo=C3=AB?= <to@example.com>, other@example.com\r\n")) {
+		t.Errorf("expected the canonicalized recipients in the To header: %q", data)
+	}
+	if bytes.Contains(data, []byte("bcc@example.com")) {
+		t.Error("BCC recipients must not appear in the headers")
+	}
+	recipients, err := msg.Tolist()
+	if err != nil || recipients[len(recipients)-1] != "bcc@example.com" {
+		t.Errorf("expected the BCC recipient in the envelope, got %v (%v)", recipients, err)
+	}
+}
+
+// Test header injection through custom headers
+// Verifies that custom header names outside the RFC 5322 field-name characters and values holding line breaks,
+// including that of Precedence, are rejected by Bytes and Validate, while well-formed headers are rendered.
+func TestCustomHeaderInjection(t *testing.T) {
+	tests := []struct {
+		name       string
+		key, value string
+	}{
+		{name: "CRLF in value", key: "X-Campaign", value: "launch\r\nBcc: victim@example.com"},
+		{name: "LF in value", key: "X-Campaign", value: "launch\nX-Injected: yes"},
+		{name: "CRLF in name", key: "X-Campaign\r\nBcc", value: "victim@example.com"},
+		{name: "colon in name", key: "X-Campaign: launch\r\nX", value: "yes"},
+		{name: "space in name", key: "X Campaign", value: "launch"},
+		{name: "non-ASCII name", key: "X-Campanha-ç", value: "launch"},
+		{name: "empty name", key: "", value: "launch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := generateSampleMessage()
+			msg.AddHeader(tt.key, tt.value)
+			if data, err := msg.Bytes(); err == nil {
+				t.Errorf("expected the header to be rejected, got %q", data)
+			}
+			if err := msg.Validate(); err == nil {
+				t.Error("expected Validate to reject the header")
+			}
+		})
+	}
+
+	msg := generateSampleMessage()
+	msg.Precedence = "bulk\r\nBcc: victim@example.com"
+	if _, err := msg.Bytes(); err == nil {
+		t.Error("expected the Precedence value to be rejected")
+	}
+
+	msg = generateSampleMessage()
+	msg.AddHeader("X-Campaign", "launch; id=42")
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Contains(data, []byte("X-Campaign: launch; id=42\r\n")) {
+		t.Errorf("missing custom header: %q", data)
+	}
+}
+
+// Test UTF-8 validation of the subject and body
+// Verifies that invalid byte sequences are rejected with the failing field and offset, while a
+// legitimate U+FFFD replacement character is accepted.
+func TestBytesInvalidUTF8(t *testing.T) {
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+	}{
+		{name: "invalid subject", subject: "Caf" + string([]byte{0xc3, 0x28}), body: "ok", expected: "subject is not valid UTF-8: invalid byte 0xc3 at offset 3"},
+		{name: "truncated subject", subject: string([]byte{'a', 0xe2, 0x82}), body: "ok", expected: "subject is not valid UTF-8: invalid byte 0xe2 at offset 1"},
+		{name: "invalid body", subject: "ok", body: "Hello " + string([]byte{0xff, 0xfe}), expected: "body is not valid UTF-8: invalid byte 0xff at offset 6"},
+		{name: "replacement character", subject: "Caf\ufffd", body: "\ufffd body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := generateSampleMessage()
+			msg.Subject = tt.subject
+			msg.Body = tt.body
+
+			_, err := msg.Bytes()
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("expected error %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+// Test encoding of attachment filenames
+// Verifies that Unicode filenames are encoded in the Content-Disposition header and keyed by their ASCII
+// fallback, that two filenames with the same fallback conflict, and that newlines left by a custom
+// sanitizer cannot inject headers.
+func TestAttachmentFilenameEncoding(t *testing.T) {
+	msg := generateSampleMessage()
+	if err := msg.AttachBuffer("relatório.pdf", []byte("data"), false); err != nil {
+		t.Fatalf("unexpected error attaching buffer: %v", err)
+	}
+	att, ok := msg.Attachments["relat_rio.pdf"]
+	if !ok || att.Filename != "relatório.pdf" {
+		t.Fatalf("expected the ASCII key and the Unicode filename, got %v", msg.Attachments)
+	}
+
+	raw, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error rendering message: %v", err)
+	}
+	if bytes.Contains(raw, []byte("relatório")) {
+		t.Error("expected no raw UTF-8 in the headers")
+	}
+	for _, expected := range []string{`filename="=?utf-8?b?cmVsYXTDs3Jpby5wZGY=?="`, `filename*=utf-8''relat%C3%B3rio.pdf`} {
+		if !bytes.Contains(raw, []byte(expected)) {
+			t.Errorf("expected %s in the Content-Disposition header", expected)
+		}
+	}
+	_, params, err := mime.ParseMediaType(contentDisposition("attachment", "relatório.pdf"))
+	if err != nil || params["filename"] != "relatório.pdf" {
+		t.Errorf("expected the filename to decode back, got %v (%v)", params, err)
+	}
+
+	if err := msg.AttachBuffer("relatório.pdf", []byte("updated"), false); err != nil {
+		t.Errorf("expected the same filename to replace the attachment, got %v", err)
+	}
+	if err := msg.AttachBuffer("relatòrio.pdf", []byte("other"), false); err == nil {
+		t.Error("expected an error for a filename with the same ASCII key")
+	}
+	if string(msg.Attachments["relat_rio.pdf"].Data) != "updated" {
+		t.Error("expected the conflicting attachment to leave the stored one untouched")
+	}
+
+	injected := generateSampleMessage()
+	injected.FilenameSanitizer = func(filename string) string { return filename }
+	if err := injected.AttachBuffer("a.txt\r\nBcc: victim@example.com", []byte("data"), true); err != nil {
+		t.Fatalf("unexpected error attaching buffer: %v", err)
+	}
+	raw, err = injected.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error rendering message: %v", err)
+	}
+	if bytes.Contains(raw, []byte("\r\nBcc:")) {
+		t.Errorf("the filename injected a header: %q", raw)
+	}
+	if !bytes.Contains(raw, []byte(`filename="a.txt__Bcc: victim@example.com"`)) || !bytes.Contains(raw, []byte("<a.txt__Bcc: victim@example.com>")) {
+		t.Errorf("expected the newlines replaced in the Content-Disposition and Content-ID headers: %q", raw)
+	}
+}
+
+// Test detecting the content type of attachments without a known extension
+// Verifies that sniffing is opt-in and that the extension keeps priority over the content.
+func TestSniffContentType(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+
+	tests := []struct {
+		filename string
+		sniff    bool
+		expected string
+	}{
+		{"chart", false, "application/octet-stream"},
+		{"chart", true, "image/png"},
+		{"chart.pdf", true, "application/pdf"},
+		{"chart.unknownext", true, "image/png"},
+	}
+	for _, tt := range tests {
+		msg := generateSampleMessage()
+		msg.SniffContentType = tt.sniff
+		if err := msg.AttachBuffer(tt.filename, png, false); err != nil {
+			t.Fatalf("unexpected error attaching buffer: %v", err)
+		}
+		raw, err := msg.Bytes()
+		if err != nil {
+			t.Fatalf("unexpected error rendering message: %v", err)
+		}
+		if !strings.Contains(string(raw), "Content-Type: "+tt.expected+"\r\nContent-Disposition: attachment; filename=\""+tt.filename+"\"") {
+			t.Errorf("%s (sniff %v): expected Content-Type %s", tt.filename, tt.sniff, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/emaildataplane"
+	"net/mail"
+	"strings"
+)
+
+// ociSubmitClient is the subset of the OCI Email Delivery data-plane client used by the OciManager.
+type ociSubmitClient interface {
+	SubmitEmail(ctx context.Context, request emaildataplane.SubmitEmailRequest) (emaildataplane.SubmitEmailResponse, error)
+}
+
+// submitter returns the email data-plane client, creating it on first use.
+func (o *OciManager) submitter() (ociSubmitClient, error) {
+	o.clientsMu.Lock()
+	defer o.clientsMu.Unlock()
+	if o.submitClient == nil {
+		c, err := emaildataplane.NewEmailDPClientWithConfigurationProvider(o.Auth.GetConfigurationProvider())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OCI email data-plane client: %w", err)
+		}
+		o.submitClient = &c
+	}
+	return o.submitClient, nil
+}
+
+// submitEmailDetails builds the data-plane submission of m, validated like WriteTo validates the SMTP
+// message. Recipients listed in m.SuppressedRecipients are left out. The expiry and auto-response
+// settings are passed as header fields, as WriteTo renders them. Attachments and PGP encryption need a
+// MIME message, which the data-plane API does not accept, so such messages must be sent over SMTP.
+func (o *OciManager) submitEmailDetails(m *Message) (emaildataplane.SubmitEmailDetails, error) {
+	if len(m.Attachments) > 0 || len(m.PGPPublicKeys) > 0 {
+		return emaildataplane.SubmitEmailDetails{}, errors.New("attachments and PGP encryption are not supported by the OCI email data-plane API")
+	}
+	if _, err := mail.ParseAddress(m.From.Address); err != nil {
+		return emaildataplane.SubmitEmailDetails{}, fmt.Errorf("invalid 'From' address: %w", err)
+	}
+	if err := checkUTF8("subject", m.Subject); err != nil {
+		return emaildataplane.SubmitEmailDetails{}, err
+	}
+	if err := checkHeaders(append(m.automationHeaders(), m.Headers...)); err != nil {
+		return emaildataplane.SubmitEmailDetails{}, err
+	}
+
+	body, err := m.readBody()
+	if err != nil {
+		return emaildataplane.SubmitEmailDetails{}, err
+	}
+	body = m.renderedBody(body)
+	if err := checkUTF8("body", body); err != nil {
+		return emaildataplane.SubmitEmailDetails{}, err
+	}
+	if err := m.checkBodySize(len(body)); err != nil {
+		return emaildataplane.SubmitEmailDetails{}, err
+	}
+
+	skipped := map[string]bool{}
+	for _, r := range m.SuppressedRecipients {
+		skipped[strings.ToLower(r)] = true
+	}
+	recipients := func(list []string) ([]emaildataplane.EmailAddress, error) {
+		var r []emaildataplane.EmailAddress
+		for _, recipient := range list {
+			address, err := mail.ParseAddress(recipient)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address '%s': %w", recipient, err)
+			}
+			if !skipped[strings.ToLower(address.Address)] {
+				r = append(r, ociEmailAddress(address))
+			}
+		}
+		return r, nil
+	}
+
+	sender := ociEmailAddress(&m.From)
+	details := emaildataplane.SubmitEmailDetails{
+		Sender: &emaildataplane.Sender{
+			SenderAddress: &sender,
+			CompartmentId: &o.Auth.CompartmentID,
+		},
+		Recipients: &emaildataplane.Recipients{},
+		Subject:    &m.Subject,
+	}
+	if details.Recipients.To, err = recipients(m.MailTo); err != nil {
+		return emaildataplane.SubmitEmailDetails{}, err
+	}
+	if details.Recipients.Cc, err = recipients(m.CC); err != nil {
+		return emaildataplane.SubmitEmailDetails{}, err
+	}
+	if details.Recipients.Bcc, err = recipients(m.BCC); err != nil {
+		return emaildataplane.SubmitEmailDetails{}, err
+	}
+	if details.ReplyTo, err = recipients(m.Reply); err != nil {
+		return emaildataplane.SubmitEmailDetails{}, err
+	}
+
+	if strings.HasPrefix(strings.ToLower(m.BodyContentType), "text/html") {
+		details.BodyHtml = &body
+	} else {
+		details.BodyText = &body
+	}
+
+	for _, h := range append(m.automationHeaders(), m.Headers...) {
+		if isBccHeader(h.Key) {
+			continue
+		}
+		if details.HeaderFields == nil {
+			details.HeaderFields = map[string]string{}
+		}
+		details.HeaderFields[h.Key] = h.Value
+	}
+	return details, nil
+}
+
+// ociEmailAddress converts a parsed address to an OCI Email Delivery address, leaving out an empty name.
+func ociEmailAddress(address *mail.Address) emaildataplane.EmailAddress {
+	r := emaildataplane.EmailAddress{Email: common.String(address.Address)}
+	if address.Name != "" {
+		r.Name = common.String(address.Name)
+	}
+	return r
+}
+
+// submitEmail submits the message through the data-plane API, adding the recipients the service
+// suppressed to m.SuppressedRecipients. Service errors carry the OCI request ID, for support requests.
+func (o *OciManager) submitEmail(ctx context.Context, m *Message, details emaildataplane.SubmitEmailDetails) error {
+	client, err := o.submitter()
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.SubmitEmail(ctx, emaildataplane.SubmitEmailRequest{SubmitEmailDetails: details})
+	if err != nil {
+		if serviceErr, ok := common.IsServiceError(err); ok {
+			return fmt.Errorf("failed to submit email (opc-request-id %s): %w", serviceErr.GetOpcRequestID(), err)
+		}
+		return fmt.Errorf("failed to submit email: %w", err)
+	}
+
+	for _, r := range resp.SuppressedRecipients {
+		if r.Email != nil {
+			m.SuppressedRecipients = append(m.SuppressedRecipients, *r.Email)
+		}
+	}
+	return nil
+}
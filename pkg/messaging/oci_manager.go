@@ -2,137 +2,98 @@ package messaging
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/diegoyosiura/cloud-manager/pkg/backoff"
+	"github.com/diegoyosiura/cloud-manager/pkg/observer"
 	"net/smtp"
 	"sync"
-	"time"
 )
 
+// MaxOCIMessages is the default capacity of the status channel returned by Send (see ChannelBuffer).
 const MaxOCIMessages = 10
 
 type OciManager struct {
-	Auth        *authentication.OCIAuth // OCI authentication details.
-	sendContext context.Context
-	Client      smtp.Auth
+	Auth   *authentication.OCIAuth // OCI authentication details.
+	Client smtp.Auth
 
 	Messages   []Message
 	MessagesMT *sync.RWMutex
-}
 
-func (o *OciManager) setup() (bool, error) {
-	o.Client = smtp.PlainAuth("", string(o.Auth.EmailUser), string(o.Auth.EmailPassword), o.Auth.EmailHost)
-	return true, nil
-}
+	SkipSuppressed    bool                 // Pre-checks recipients against the suppression list and skips suppressed ones.
+	UseEmailDataPlane bool                 // Submits messages through the Email Delivery data-plane API instead of SMTP (no SMTP credentials needed).
+	Backoff           backoff.Backoff      // Retry policy of transient failures, e.g. 4xx SMTP replies (defaults to DefaultSendBackoff, 3 attempts).
+	Observer          observer.Observer    // Receives the SMTP call, retry and message metrics when set (optional).
+	TLSMode           TLSMode              // Encryption of the SMTP connection (defaults to TLSModeNone; OCI's port 587 expects TLSModeStartTLS).
+	TLSConfig         *tls.Config          // TLS settings of the SMTP connection (optional).
+	suppressionClient ociSuppressionClient // OCI email management client, created on first use.
+	submitClient      ociSubmitClient      // OCI email data-plane client, created on first use.
+	clientsMu         sync.Mutex           // Guards the creation of the clients above by concurrent sends.
 
-func (o *OciManager) AddMessage(m Message) {
-	o.MessagesMT.Lock()
-	defer o.MessagesMT.Unlock()
-	o.Messages = append(o.Messages, m)
-}
+	MaxMessagesPerSecond float64 // Maximum dispatch rate of a Send batch, retries included (0 means unlimited).
+	ChannelBuffer        int     // Capacity of the status channel returned by Send (0 means MaxOCIMessages).
 
-func (o *OciManager) AddMessages(m []Message) {
-	o.MessagesMT.Lock()
-	defer o.MessagesMT.Unlock()
-	o.Messages = append(o.Messages, m...)
+	smtpBatchSender // Queue and send methods shared with the other managers, bound by NewMessageManager.
 }
-func (o *OciManager) CancelSend() (bool, error) {
-	ready, err := o.setup()
 
-	if !ready {
-		return false, err
+// sendConfig returns the queue and the delivery settings used by the shared send loop.
+func (o *OciManager) sendConfig() sendConfig {
+	return sendConfig{
+		provider:             "oci",
+		setup:                o.setup,
+		messages:             &o.Messages,
+		mt:                   o.MessagesMT,
+		backoff:              o.Backoff,
+		observer:             o.Observer,
+		tlsMode:              o.TLSMode,
+		tlsConfig:            o.TLSConfig,
+		maxMessagesPerSecond: o.MaxMessagesPerSecond,
+		channelBuffer:        o.ChannelBuffer,
+		endpoint: func() (string, smtp.Auth) {
+			return fmt.Sprintf(`%s:%s`, o.Auth.EmailHost, o.Auth.EmailPort), o.Client
+		},
+		batch: o.batch,
 	}
-	panic("implement me")
 }
 
-func (o *OciManager) Send() (chan Message, bool, error) {
-	ready, err := o.setup()
-
-	if !ready {
-		return nil, false, err
-	}
-
-	ch := o.sendMessage()
-
-	return ch, true, nil
+func (o *OciManager) setup() (bool, error) {
+	o.Client = smtp.PlainAuth("", string(o.Auth.EmailUser), string(o.Auth.EmailPassword), o.Auth.EmailHost)
+	return true, nil
 }
 
-func (o *OciManager) SendStatus() (float64, error) {
-	ready, err := o.setup()
-
-	if !ready {
-		return 0.0, err
+// batch loads the suppression list once for the whole batch and returns how its messages are delivered.
+func (o *OciManager) batch() prepareFunc {
+	var suppressed map[string]bool
+	var suppressedErr error
+	if o.SkipSuppressed {
+		suppressed, suppressedErr = o.suppressedSet()
 	}
-
-	sent := 0.0
-	o.MessagesMT.Lock()
-	defer o.MessagesMT.Unlock()
-	for _, msg := range o.Messages {
-		if msg.Status == Sent {
-			sent++
-		}
+	return func(ctx context.Context, m *Message, list []string) (string, func() error, error) {
+		return o.prepare(ctx, m, list, suppressed, suppressedErr)
 	}
-
-	return sent / float64(len(o.Messages)), nil
-}
-
-func (o *OciManager) sendMessage() chan Message {
-	ch := make(chan Message, MaxOCIMessages)
-
-	go func() {
-		defer close(ch)
-		wg := &sync.WaitGroup{}
-
-		tm := len(o.Messages)
-		for i := 0; i < tm; i++ {
-			o.MessagesMT.Lock()
-			m := o.Messages[i]
-			o.MessagesMT.Unlock()
-			m.Status = Queued
-			ch <- m
-			wg.Add(1)
-			go o.send(ch, m, wg)
-		}
-
-		wg.Wait()
-	}()
-	return ch
 }
 
-func (o *OciManager) send(ch chan Message, m Message, wg *sync.WaitGroup) {
-	defer wg.Done()
-	m.Status = Sending
-	ch <- m
-
-	list, err := m.Tolist()
-	if err != nil {
-		m.Status = SendError
-		m.DateStatus = time.Now()
-		m.Error = err
-		ch <- m
-		return
+// prepare skips the suppressed recipients of m, then readies it for either the data-plane API or,
+// rendered, the SMTP endpoint.
+func (o *OciManager) prepare(ctx context.Context, m *Message, list []string, suppressed map[string]bool, suppressedErr error) (string, func() error, error) {
+	if suppressedErr != nil {
+		return "", nil, fmt.Errorf("failed to load suppression list: %w", suppressedErr)
 	}
 
-	data, err := m.Bytes()
-	if err != nil {
-		m.Status = SendError
-		m.DateStatus = time.Now()
-		m.Error = err
-		ch <- m
-		return
+	if suppressed != nil {
+		list, m.SuppressedRecipients = filterSuppressed(list, suppressed)
+		if len(list) == 0 {
+			return "", nil, fmt.Errorf("all recipients are on the suppression list: %v", m.SuppressedRecipients)
+		}
 	}
 
-	err = smtp.SendMail(fmt.Sprintf(`%s:%s`, o.Auth.EmailHost, o.Auth.EmailPort), o.Client, m.From.Address, list, data)
-
-	if err != nil {
-		m.Status = SendError
-		m.DateStatus = time.Now()
-		m.Error = err
-		ch <- m
-		return
+	if o.UseEmailDataPlane {
+		details, err := o.submitEmailDetails(m)
+		if err != nil {
+			return "", nil, err
+		}
+		return "SubmitEmail", func() error { return o.submitEmail(ctx, m, details) }, nil
 	}
-
-	m.Status = Sent
-	m.DateStatus = time.Now()
-	ch <- m
+	return o.sendConfig().smtpDelivery(ctx, m, list)
 }
@@ -0,0 +1,248 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/email"
+	"github.com/oracle/oci-go-sdk/v65/emaildataplane"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSuppressionClient is an in-memory ociSuppressionClient.
+type fakeSuppressionClient struct {
+	items      []email.SuppressionSummary
+	deleted    []string
+	deleteErrs map[string]error // Errors returned when deleting the suppression with the given ID.
+}
+
+func (f *fakeSuppressionClient) ListSuppressions(_ context.Context, request email.ListSuppressionsRequest) (email.ListSuppressionsResponse, error) {
+	var items []email.SuppressionSummary
+	for _, item := range f.items {
+		if request.EmailAddress == nil || *request.EmailAddress == *item.EmailAddress {
+			items = append(items, item)
+		}
+	}
+	return email.ListSuppressionsResponse{Items: items}, nil
+}
+
+func (f *fakeSuppressionClient) DeleteSuppression(_ context.Context, request email.DeleteSuppressionRequest) (email.DeleteSuppressionResponse, error) {
+	if err := f.deleteErrs[*request.SuppressionId]; err != nil {
+		return email.DeleteSuppressionResponse{}, err
+	}
+	f.deleted = append(f.deleted, *request.SuppressionId)
+	return email.DeleteSuppressionResponse{}, nil
+}
+
+// newTestOciManager returns an OciManager backed by a fake suppression list.
+func newTestOciManager() (*OciManager, *fakeSuppressionClient) {
+	client := &fakeSuppressionClient{items: []email.SuppressionSummary{
+		{Id: common.String("ocid1.suppression.1"), EmailAddress: common.String("cc@example.com"), Reason: email.SuppressionReasonHardbounce},
+		{Id: common.String("ocid1.suppression.2"), EmailAddress: common.String("bcc@example.com"), Reason: email.SuppressionReasonComplaint},
+	}}
+	manager := boundManager(&OciManager{
+		Auth:              &authentication.OCIAuth{TenancyID: "ocid1.tenancy"},
+		MessagesMT:        &sync.RWMutex{},
+		suppressionClient: client,
+	})
+	return manager, client
+}
+
+// Test listing and removing suppressions
+// Verifies that the suppression list is mapped and that removal deletes the matching suppression.
+func TestOciManagerSuppressions(t *testing.T) {
+	manager, client := newTestOciManager()
+
+	list, err := manager.ListSuppressions()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 2 || list[0].EmailAddress != "cc@example.com" || list[0].Reason != "HARDBOUNCE" {
+		t.Errorf("unexpected suppression list: %+v", list)
+	}
+
+	if err := manager.RemoveSuppression("bcc@example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(client.deleted) != 1 || client.deleted[0] != "ocid1.suppression.2" {
+		t.Errorf("expected suppression 'ocid1.suppression.2' to be deleted, got %v", client.deleted)
+	}
+
+	if err := manager.RemoveSuppression("to@example.com"); err == nil {
+		t.Error("expected error when removing an address that is not suppressed")
+	}
+}
+
+// Test removing an address with several suppressions when some deletions fail
+// Verifies that every suppression is attempted and the failures are joined with their IDs.
+func TestOciManagerRemoveSuppressionPartialFailure(t *testing.T) {
+	manager, client := newTestOciManager()
+	client.items = append(client.items,
+		email.SuppressionSummary{Id: common.String("ocid1.suppression.3"), EmailAddress: common.String("bcc@example.com")},
+		email.SuppressionSummary{Id: common.String("ocid1.suppression.4"), EmailAddress: common.String("bcc@example.com")},
+	)
+	errDenied := errors.New("not authorized")
+	errConflict := errors.New("conflict")
+	client.deleteErrs = map[string]error{"ocid1.suppression.2": errDenied, "ocid1.suppression.4": errConflict}
+
+	err := manager.RemoveSuppression("bcc@example.com")
+	if !errors.Is(err, errDenied) || !errors.Is(err, errConflict) {
+		t.Fatalf("expected the joined error to wrap every failure, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "ocid1.suppression.2") || !strings.Contains(err.Error(), "ocid1.suppression.4") {
+		t.Errorf("expected the failed suppression IDs in the error, got %v", err)
+	}
+	if len(client.deleted) != 1 || client.deleted[0] != "ocid1.suppression.3" {
+		t.Errorf("expected the remaining suppression to be deleted, got %v", client.deleted)
+	}
+}
+
+// Test pre-checking recipients against the suppression list
+// Verifies that suppressed recipients are skipped and flagged on the message.
+func TestOciManagerSkipSuppressed(t *testing.T) {
+	manager, _ := newTestOciManager()
+
+	suppressed, err := manager.suppressedSet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg := generateSampleMessage()
+	recipients, err := msg.Tolist()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	allowed, skipped := filterSuppressed(recipients, suppressed)
+	if len(allowed) != 1 || allowed[0] != "to@example.com" {
+		t.Errorf("expected only 'to@example.com' to be allowed, got %v", allowed)
+	}
+	if len(skipped) != 2 {
+		t.Errorf("expected 2 skipped recipients, got %v", skipped)
+	}
+
+	// A message whose recipients are all suppressed fails without being sent.
+	manager.SkipSuppressed = true
+	msg.MailTo = nil
+	manager.AddMessage(msg)
+
+	var last Message
+	for event := range manager.sendMessage(0) {
+		last = event
+	}
+	if last.Status != SendError || len(last.SuppressedRecipients) != 2 {
+		t.Errorf("expected SendError with 2 suppressed recipients, got status %d and %v", last.Status, last.SuppressedRecipients)
+	}
+}
+
+// fakeSubmitClient is an in-memory ociSubmitClient recording the submitted emails.
+type fakeSubmitClient struct {
+	submitted []emaildataplane.SubmitEmailDetails
+	err       error // Error returned by every submission when set.
+}
+
+func (f *fakeSubmitClient) SubmitEmail(_ context.Context, request emaildataplane.SubmitEmailRequest) (emaildataplane.SubmitEmailResponse, error) {
+	if f.err != nil {
+		return emaildataplane.SubmitEmailResponse{}, f.err
+	}
+	f.submitted = append(f.submitted, request.SubmitEmailDetails)
+	return emaildataplane.SubmitEmailResponse{}, nil
+}
+
+// fakeServiceError is a common.ServiceError as returned by the OCI SDK.
+type fakeServiceError struct{ requestID string }
+
+func (e fakeServiceError) Error() string           { return "service error" }
+func (e fakeServiceError) GetHTTPStatusCode() int  { return 400 }
+func (e fakeServiceError) GetMessage() string      { return "invalid sender" }
+func (e fakeServiceError) GetCode() string         { return "InvalidParameter" }
+func (e fakeServiceError) GetOpcRequestID() string { return e.requestID }
+
+// Test sending through the Email Delivery data-plane API
+// Verifies the submitted sender, recipients and body, and that submission failures carry the OCI request ID.
+func TestOciManagerUseEmailDataPlane(t *testing.T) {
+	manager, _ := newTestOciManager()
+	manager.Auth.CompartmentID = "ocid1.compartment"
+	manager.UseEmailDataPlane = true
+	manager.SkipSuppressed = true
+	client := &fakeSubmitClient{}
+	manager.submitClient = client
+
+	manager.AddMessage(generateSampleMessage())
+	var last Message
+	for event := range manager.sendMessage(0) {
+		last = event
+	}
+	if last.Status != Sent {
+		t.Fatalf("expected the message to be sent, got status %d (%v)", last.Status, last.Error)
+	}
+	if len(client.submitted) != 1 {
+		t.Fatalf("expected 1 submission, got %d", len(client.submitted))
+	}
+	details := client.submitted[0]
+	if *details.Sender.SenderAddress.Email != "from@email.com" || *details.Sender.SenderAddress.Name != "Test" || *details.Sender.CompartmentId != "ocid1.compartment" {
+		t.Errorf("unexpected sender: %v", details.Sender)
+	}
+	if len(details.Recipients.To) != 1 || *details.Recipients.To[0].Email != "to@example.com" {
+		t.Errorf("unexpected 'To' recipients: %v", details.Recipients.To)
+	}
+	if len(details.Recipients.Cc) != 0 || len(details.Recipients.Bcc) != 0 {
+		t.Errorf("expected the suppressed recipients to be left out, got %v and %v", details.Recipients.Cc, details.Recipients.Bcc)
+	}
+	if details.BodyText == nil || *details.BodyText != "This is a test body." || details.BodyHtml != nil {
+		t.Errorf("unexpected body: %v, %v", details.BodyText, details.BodyHtml)
+	}
+
+	client.err = fakeServiceError{requestID: "request-1"}
+	manager.Messages = nil
+	manager.AddMessage(generateSampleMessage())
+	for event := range manager.sendMessage(0) {
+		last = event
+	}
+	if last.Status != SendError || last.Error == nil || !strings.Contains(last.Error.Error(), "request-1") {
+		t.Errorf("expected SendError mentioning the request ID, got status %d (%v)", last.Status, last.Error)
+	}
+}
+
+// Test the data-plane submission of addresses and message settings
+// Verifies that addresses with apostrophes and non-ASCII local parts are submitted unchanged, that the
+// expiry and auto-response settings become header fields, and that invalid UTF-8 is rejected as over SMTP.
+func TestOciManagerSubmitEmailDetails(t *testing.T) {
+	manager, _ := newTestOciManager()
+	manager.Auth.CompartmentID = "ocid1.compartment"
+
+	msg := generateSampleMessage()
+	msg.From = mail.Address{Name: "Seán O'Brien", Address: "o'brien@example.com"}
+	msg.MailTo = []string{"María López <maría.lopez@exemplo.com>"}
+	msg.CC, msg.BCC = nil, nil
+	if err := msg.SetAutomated("", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	msg.SuppressAutoReply = true
+
+	details, err := manager.submitEmailDetails(&msg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sender := details.Sender.SenderAddress; *sender.Email != "o'brien@example.com" || *sender.Name != "Seán O'Brien" {
+		t.Errorf("unexpected sender: %s <%s>", *sender.Name, *sender.Email)
+	}
+	if to := details.Recipients.To; len(to) != 1 || *to[0].Email != "maría.lopez@exemplo.com" || *to[0].Name != "María López" {
+		t.Errorf("unexpected 'To' recipients: %v", to)
+	}
+	expected := map[string]string{"Auto-Submitted": "auto-generated", "Precedence": "bulk", "X-Auto-Response-Suppress": "All"}
+	for key, value := range expected {
+		if details.HeaderFields[key] != value {
+			t.Errorf("expected header field %s: %s, got %v", key, value, details.HeaderFields)
+		}
+	}
+
+	msg.Subject = "invalid \xff"
+	if _, err := manager.submitEmailDetails(&msg); err == nil || !strings.Contains(err.Error(), "subject") {
+		t.Errorf("expected the invalid subject to be rejected, got %v", err)
+	}
+}
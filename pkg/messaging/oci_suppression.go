@@ -0,0 +1,147 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/oracle/oci-go-sdk/v65/email"
+	"strings"
+	"time"
+)
+
+// Suppression is an email address OCI Email Delivery refuses to deliver to (e.g. after a hard bounce).
+type Suppression struct {
+	ID           string    // OCID of the suppression
+	EmailAddress string    // Suppressed email address
+	Reason       string    // Reason for the suppression (e.g. HARDBOUNCE, COMPLAINT)
+	TimeCreated  time.Time // Timestamp when the address was suppressed
+}
+
+// ociSuppressionClient is the subset of the OCI email management client used by the OciManager.
+type ociSuppressionClient interface {
+	ListSuppressions(ctx context.Context, request email.ListSuppressionsRequest) (email.ListSuppressionsResponse, error)
+	DeleteSuppression(ctx context.Context, request email.DeleteSuppressionRequest) (email.DeleteSuppressionResponse, error)
+}
+
+// suppressions returns the email management client, creating it on first use.
+func (o *OciManager) suppressions() (ociSuppressionClient, error) {
+	o.clientsMu.Lock()
+	defer o.clientsMu.Unlock()
+	if o.suppressionClient == nil {
+		c, err := email.NewEmailClientWithConfigurationProvider(o.Auth.GetConfigurationProvider())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OCI email client: %w", err)
+		}
+		o.suppressionClient = &c
+	}
+	return o.suppressionClient, nil
+}
+
+// ListSuppressions returns every suppressed address of the tenancy.
+func (o *OciManager) ListSuppressions() ([]Suppression, error) {
+	return o.listSuppressions(nil)
+}
+
+// listSuppressions lists the tenancy suppressions, optionally restricted to a single address.
+func (o *OciManager) listSuppressions(address *string) ([]Suppression, error) {
+	client, err := o.suppressions()
+	if err != nil {
+		return nil, err
+	}
+
+	// Suppressions always live in the root compartment of the tenancy.
+	rq := email.ListSuppressionsRequest{
+		CompartmentId: &o.Auth.TenancyID,
+		EmailAddress:  address,
+	}
+
+	var r []Suppression
+	for {
+		resp, err := client.ListSuppressions(context.Background(), rq)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range resp.Items {
+			s := Suppression{Reason: string(item.Reason)}
+			if item.Id != nil {
+				s.ID = *item.Id
+			}
+			if item.EmailAddress != nil {
+				s.EmailAddress = *item.EmailAddress
+			}
+			if item.TimeCreated != nil {
+				s.TimeCreated = item.TimeCreated.Time
+			}
+			r = append(r, s)
+		}
+
+		if resp.OpcNextPage == nil {
+			break
+		}
+		rq.Page = resp.OpcNextPage
+	}
+
+	return r, nil
+}
+
+// RemoveSuppression deletes the suppression of the given address so it can receive messages again.
+func (o *OciManager) RemoveSuppression(address string) error {
+	list, err := o.listSuppressions(&address)
+	if err != nil {
+		return err
+	}
+
+	client, err := o.suppressions()
+	if err != nil {
+		return err
+	}
+
+	// An address may have several suppressions; every one is attempted and the failures are joined.
+	found := false
+	var errs []error
+	for _, s := range list {
+		if !strings.EqualFold(s.EmailAddress, address) {
+			continue
+		}
+		found = true
+		id := s.ID
+		if _, err := client.DeleteSuppression(context.Background(), email.DeleteSuppressionRequest{SuppressionId: &id}); err != nil {
+			errs = append(errs, fmt.Errorf("suppression %s: %w", id, err))
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("address '%s' is not suppressed", address)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to remove suppression for '%s': %w", address, errors.Join(errs...))
+	}
+	return nil
+}
+
+// suppressedSet returns the suppressed addresses, lower-cased, for recipient pre-checks.
+func (o *OciManager) suppressedSet() (map[string]bool, error) {
+	list, err := o.ListSuppressions()
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(map[string]bool, len(list))
+	for _, s := range list {
+		set[strings.ToLower(s.EmailAddress)] = true
+	}
+	return set, nil
+}
+
+// filterSuppressed splits the recipients into the ones that can be delivered to and the suppressed ones.
+func filterSuppressed(recipients []string, suppressed map[string]bool) (allowed, skipped []string) {
+	for _, r := range recipients {
+		if suppressed[strings.ToLower(r)] {
+			skipped = append(skipped, r)
+			continue
+		}
+		allowed = append(allowed, r)
+	}
+	return allowed, skipped
+}
@@ -0,0 +1,99 @@
+package messaging
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	_ "golang.org/x/crypto/ripemd160" // Hash openpgp falls back to for keys that declare no hash preferences.
+)
+
+// PGPEncryptTo enables PGP/MIME (RFC 3156) encryption of the message. The body and attachments are
+// encrypted to every given public key (ASCII-armored or binary) when the message is rendered; the
+// envelope headers (From, To, Subject, ...) stay in clear text.
+// Every recipient needs its key in the list: a recipient without one receives a message it cannot read.
+// Calling it without keys disables encryption.
+func (m *Message) PGPEncryptTo(publicKeys ...[]byte) {
+	m.PGPPublicKeys = publicKeys
+}
+
+// pgpEntities parses the configured public keys.
+func (m *Message) pgpEntities() (openpgp.EntityList, error) {
+	var entities openpgp.EntityList
+	for i, key := range m.PGPPublicKeys {
+		var list openpgp.EntityList
+		var err error
+		if bytes.HasPrefix(bytes.TrimSpace(key), []byte("-----BEGIN")) {
+			list, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(key))
+		} else {
+			list, err = openpgp.ReadKeyRing(bytes.NewReader(key))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid PGP public key %d: %w", i, err)
+		}
+		entities = append(entities, list...)
+	}
+	return entities, nil
+}
+
+// writeEncrypted renders the body and attachments, encrypts them to the configured keys and writes
+// the resulting multipart/encrypted entity, starting with its Content-Type header.
+func (m *Message) writeEncrypted(buf *countingWriter, body string) error {
+	entities, err := m.pgpEntities()
+	if err != nil {
+		return err
+	}
+
+	// Render the clear-text entity that is encrypted
+	plain := &bytes.Buffer{}
+	if err := m.writeContent(&countingWriter{w: plain}, body); err != nil {
+		return err
+	}
+
+	encrypted := &bytes.Buffer{}
+	armored, err := armor.Encode(encrypted, "PGP MESSAGE", nil)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt message: %w", err)
+	}
+	w, err := openpgp.Encrypt(armored, entities, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt message: %w", err)
+	}
+	if _, err := w.Write(plain.Bytes()); err != nil {
+		return fmt.Errorf("failed to encrypt message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to encrypt message: %w", err)
+	}
+	if err := armored.Close(); err != nil {
+		return fmt.Errorf("failed to encrypt message: %w", err)
+	}
+
+	boundary, err := randomBoundary(encrypted.Bytes())
+	if err != nil {
+		return err
+	}
+	m.writeHeader(buf, "Content-Type", fmt.Sprintf("multipart/encrypted; protocol=\"application/pgp-encrypted\"; boundary=\"%s\"", boundary))
+	buf.WriteString("\r\n")
+
+	// Add the version identification part
+	buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
+	m.writeHeader(buf, "Content-Type", "application/pgp-encrypted")
+	m.writeHeader(buf, "Content-Description", "PGP/MIME version identification")
+	buf.WriteString("\r\n")
+	buf.WriteString("Version: 1\r\n")
+
+	// Add the encrypted content
+	buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
+	m.writeHeader(buf, "Content-Type", "application/octet-stream; name=\"encrypted.asc\"")
+	m.writeHeader(buf, "Content-Description", "OpenPGP encrypted message")
+	m.writeHeader(buf, "Content-Disposition", "inline; filename=\"encrypted.asc\"")
+	buf.WriteString("\r\n")
+	buf.Write(encrypted.Bytes())
+	buf.WriteString("\r\n")
+
+	// Close the multipart boundary
+	buf.WriteString(fmt.Sprintf("--%s--\r\n", boundary))
+
+	return buf.err
+}
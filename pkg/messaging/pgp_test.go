@@ -0,0 +1,110 @@
+package messaging
+
+import (
+	"bytes"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// Test PGP/MIME encryption
+// Verifies that the body and attachments are encrypted to the recipient key and decrypt back to the clear-text entity.
+func TestPGPEncryptTo(t *testing.T) {
+	entity, err := openpgp.NewEntity("Test", "", "to@example.com", nil)
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+	publicKey := &bytes.Buffer{}
+	armored, err := armor.Encode(publicKey, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := entity.Serialize(armored); err != nil {
+		t.Fatalf("unexpected error serializing key: %v", err)
+	}
+	_ = armored.Close()
+
+	msg := generateSampleMessage()
+	if err := msg.AttachBuffer("secret.txt", []byte("attachment content"), false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	msg.PGPEncryptTo(publicKey.Bytes())
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bytes.Contains(data, []byte("This is a test body.")) {
+		t.Fatal("the body must not be rendered in clear text")
+	}
+
+	// Parse the multipart/encrypted structure
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error parsing message: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/encrypted" || params["protocol"] != "application/pgp-encrypted" {
+		t.Fatalf("unexpected Content-Type: %s", parsed.Header.Get("Content-Type"))
+	}
+
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	version, err := reader.NextPart()
+	if err != nil {
+		t.Fatalf("unexpected error reading version part: %v", err)
+	}
+	if version.Header.Get("Content-Type") != "application/pgp-encrypted" {
+		t.Errorf("unexpected version part Content-Type: %s", version.Header.Get("Content-Type"))
+	}
+	encrypted, err := reader.NextPart()
+	if err != nil {
+		t.Fatalf("unexpected error reading encrypted part: %v", err)
+	}
+
+	// Decrypt with the private key and check the clear-text entity
+	block, err := armor.Decode(encrypted)
+	if err != nil {
+		t.Fatalf("unexpected error decoding armor: %v", err)
+	}
+	md, err := openpgp.ReadMessage(block.Body, openpgp.EntityList{entity}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+	plain, err := io.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		t.Fatalf("unexpected error reading decrypted body: %v", err)
+	}
+
+	if !strings.Contains(string(plain), "This is a test body.") {
+		t.Error("decrypted content is missing the body")
+	}
+	if !strings.Contains(string(plain), "filename=\"secret.txt\"") {
+		t.Error("decrypted content is missing the attachment")
+	}
+
+	// Without keys the message is rendered in clear text
+	msg.PGPEncryptTo()
+	data, err = msg.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Contains(data, []byte("This is a test body.")) {
+		t.Error("expected a clear-text body when no keys are configured")
+	}
+}
+
+// Test rejecting invalid PGP keys
+// Verifies that rendering fails instead of sending the message unencrypted.
+func TestPGPEncryptToInvalidKey(t *testing.T) {
+	msg := generateSampleMessage()
+	msg.PGPEncryptTo([]byte("not a key"))
+
+	if _, err := msg.Bytes(); err == nil {
+		t.Error("expected error for an invalid public key")
+	}
+}
@@ -0,0 +1,14 @@
+package messaging
+
+import (
+	"golang.org/x/time/rate"
+)
+
+// newRateLimiter returns a limiter allowing perSecond dispatches per second, without bursts, so dispatches
+// are spaced at least 1/perSecond apart. It never blocks when perSecond is not positive.
+func newRateLimiter(perSecond float64) *rate.Limiter {
+	if perSecond <= 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), 1)
+}
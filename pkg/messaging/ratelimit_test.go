@@ -0,0 +1,74 @@
+package messaging
+
+import (
+	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"golang.org/x/time/rate"
+	"net/mail"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestMaxMessagesPerSecond verifies that the limiter of a capped batch spaces dispatches 1/rate apart,
+// that an uncapped batch is never delayed, and that a capped batch is still sent in full. The spacing is
+// checked on the limiter's reservations rather than on wall-clock timings.
+func TestMaxMessagesPerSecond(t *testing.T) {
+	if limiter := newRateLimiter(0); limiter.Limit() != rate.Inf {
+		t.Errorf("expected no limit for a zero rate, got %v", limiter.Limit())
+	}
+
+	limiter := newRateLimiter(10)
+	if limiter.Burst() != 1 {
+		t.Errorf("expected bursts of a single dispatch, got %d", limiter.Burst())
+	}
+	now := time.Now()
+	for i := 0; i < 6; i++ {
+		expected := time.Duration(i) * 100 * time.Millisecond
+		if delay := limiter.ReserveN(now, 1).DelayFrom(now); delay < expected-time.Millisecond || delay > expected+time.Millisecond {
+			t.Errorf("dispatch %d: expected a delay of %v at 10 messages/s, got %v", i, expected, delay)
+		}
+	}
+
+	var mu sync.Mutex
+	accepted := 0
+	host, port := fakeSMTPRelay(t, func() {
+		mu.Lock()
+		accepted++
+		mu.Unlock()
+	})
+
+	manager := boundManager(&AWSManager{
+		Auth:                 &authentication.AWSAuth{EmailHost: host, EmailPort: port},
+		MessagesMT:           &sync.RWMutex{},
+		MaxMessagesPerSecond: 100,
+	})
+
+	const total = 6
+	for i := 0; i < total; i++ {
+		manager.AddMessage(NewMessage(mail.Address{Address: "sender@example.com"}, "Subject", "Body", "text/plain",
+			[]string{"recipient@example.com"}, nil, nil, nil))
+	}
+
+	ch, _, err := manager.Send()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sent := 0
+	for m := range ch {
+		if m.Status == SendError {
+			t.Errorf("unexpected send error: %v", m.Error)
+		}
+		if m.Status == Sent {
+			sent++
+		}
+	}
+	if sent != total {
+		t.Fatalf("expected %d messages sent, got %d", total, sent)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if accepted != total {
+		t.Errorf("expected %d messages accepted by the relay, got %d", total, accepted)
+	}
+}
@@ -0,0 +1,132 @@
+package messaging
+
+import (
+	"golang.org/x/net/html"
+	"strings"
+)
+
+// allowedHTMLTags lists the elements kept by sanitizeHTML. Anything else is dropped, keeping its text content.
+var allowedHTMLTags = map[string]bool{
+	"a": true, "abbr": true, "b": true, "blockquote": true, "body": true, "br": true, "caption": true,
+	"center": true, "code": true, "div": true, "em": true, "font": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "head": true, "hr": true, "html": true, "i": true, "img": true,
+	"li": true, "ol": true, "p": true, "pre": true, "s": true, "small": true, "span": true, "strong": true,
+	"sub": true, "sup": true, "table": true, "tbody": true, "td": true, "tfoot": true, "th": true,
+	"thead": true, "title": true, "tr": true, "u": true, "ul": true,
+}
+
+// droppedHTMLTags lists the elements removed together with everything they contain.
+var droppedHTMLTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true, "applet": true,
+	"form": true, "frame": true, "frameset": true, "noscript": true, "template": true,
+}
+
+// allowedHTMLAttributes lists the attributes kept on allowed elements.
+var allowedHTMLAttributes = map[string]bool{
+	"align": true, "alt": true, "bgcolor": true, "border": true, "cellpadding": true, "cellspacing": true,
+	"class": true, "color": true, "colspan": true, "dir": true, "face": true, "height": true, "href": true,
+	"lang": true, "rowspan": true, "size": true, "src": true, "style": true, "title": true, "valign": true,
+	"width": true,
+}
+
+// urlHTMLAttributes lists the attributes whose values are URLs and must use a safe scheme.
+var urlHTMLAttributes = map[string]bool{"href": true, "src": true}
+
+// sanitizeHTML strips dangerous markup from an HTML document using a conservative allowlist.
+// Scripts and other active elements are removed with their content, event handler attributes
+// are discarded and URLs with schemes such as "javascript:" are dropped.
+//
+// This is a best-effort filter meant to reduce the risk of forwarding hostile markup;
+// it is not a full HTML sanitizer and should not be relied upon as a security boundary.
+func sanitizeHTML(body string) string {
+	var sb strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(body))
+	skipDepth := 0
+
+	for {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			// io.EOF or a malformed document: return what was sanitized so far.
+			return sb.String()
+		}
+
+		token := tokenizer.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedHTMLTags[token.Data] {
+				if tt == html.StartTagToken {
+					skipDepth++
+				}
+				continue
+			}
+			if skipDepth > 0 || !allowedHTMLTags[token.Data] {
+				continue
+			}
+			token.Attr = sanitizeHTMLAttributes(token.Attr)
+			sb.WriteString(token.String())
+		case html.EndTagToken:
+			if droppedHTMLTags[token.Data] {
+				if skipDepth > 0 {
+					skipDepth--
+				}
+				continue
+			}
+			if skipDepth > 0 || !allowedHTMLTags[token.Data] {
+				continue
+			}
+			sb.WriteString(token.String())
+		case html.TextToken:
+			if skipDepth > 0 {
+				continue
+			}
+			sb.WriteString(token.String())
+		case html.DoctypeToken:
+			sb.WriteString(token.String())
+		}
+	}
+}
+
+// sanitizeHTMLAttributes keeps only allowlisted attributes and discards URLs with unsafe schemes.
+func sanitizeHTMLAttributes(attrs []html.Attribute) []html.Attribute {
+	var kept []html.Attribute
+	for _, attr := range attrs {
+		key := strings.ToLower(attr.Key)
+		if attr.Namespace != "" || !allowedHTMLAttributes[key] {
+			continue
+		}
+		if urlHTMLAttributes[key] && !isSafeURL(attr.Val) {
+			continue
+		}
+		if key == "style" && strings.Contains(strings.ToLower(attr.Val), "expression(") {
+			continue
+		}
+		kept = append(kept, html.Attribute{Key: key, Val: attr.Val})
+	}
+	return kept
+}
+
+// isSafeURL reports whether a URL uses a scheme that is safe to render in an email body.
+func isSafeURL(value string) bool {
+	// Remove whitespace and control characters that browsers ignore inside schemes (e.g. "java\tscript:").
+	normalized := strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(value))
+
+	colon := strings.Index(normalized, ":")
+	if colon < 0 {
+		return true // Relative URL.
+	}
+	if slash := strings.IndexAny(normalized, "/?#"); slash >= 0 && slash < colon {
+		return true // The colon belongs to the path or query, not to a scheme.
+	}
+
+	switch normalized[:colon] {
+	case "http", "https", "mailto", "cid", "tel":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,73 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sesv2"
+)
+
+// sesSendClient is the subset of the SES v2 client used by the AWSManager.
+type sesSendClient interface {
+	SendEmailWithContext(ctx aws.Context, input *sesv2.SendEmailInput, opts ...request.Option) (*sesv2.SendEmailOutput, error)
+}
+
+// sesSender returns the SES v2 client, creating it from the session of Auth on first use. Auth is
+// authenticated first, or its credentials refreshed when they are about to expire (see AWSAuth.EnsureValid).
+func (a *AWSManager) sesSender() (sesSendClient, error) {
+	if err := a.Auth.EnsureValid(); err != nil {
+		return nil, err
+	}
+	a.sesClientMu.Lock()
+	defer a.sesClientMu.Unlock()
+	if a.sesClient == nil {
+		a.sesClient = sesv2.New(a.Auth.Session)
+	}
+	return a.sesClient, nil
+}
+
+// sesSendEmailInput builds the SendEmail request of m, rendered as the raw MIME message data so that
+// attachments, inline images and PGP encryption are kept. The envelope recipients in list, BCC included,
+// are passed as the destination, since the raw message has no Bcc header.
+func sesSendEmailInput(m *Message, list []string, data []byte) *sesv2.SendEmailInput {
+	return &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(m.From.Address),
+		Destination:      &sesv2.Destination{ToAddresses: aws.StringSlice(list)},
+		Content:          &sesv2.EmailContent{Raw: &sesv2.RawMessage{Data: data}},
+	}
+}
+
+// sendSES sends input through the SES v2 API, storing the SES message ID in m.ProviderMessageID. Throttling
+// errors are reported as transient, so they are retried like 4xx SMTP replies; rejections are permanent.
+// Service errors carry the AWS request ID, for support requests.
+func (a *AWSManager) sendSES(ctx context.Context, m *Message, input *sesv2.SendEmailInput) error {
+	client, err := a.sesSender()
+	if err != nil {
+		return err
+	}
+
+	out, err := client.SendEmailWithContext(ctx, input)
+	if err != nil {
+		if failure, ok := err.(awserr.RequestFailure); ok {
+			err = fmt.Errorf("failed to send email through SES (request id %s): %w", failure.RequestID(), err)
+		} else {
+			err = fmt.Errorf("failed to send email through SES: %w", err)
+		}
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) {
+			switch awsErr.Code() {
+			case sesv2.ErrCodeTooManyRequestsException, sesv2.ErrCodeLimitExceededException:
+				return transientError{err}
+			}
+		}
+		return err
+	}
+
+	if out.MessageId != nil {
+		m.ProviderMessageID = *out.MessageId
+	}
+	return nil
+}
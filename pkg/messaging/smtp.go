@@ -0,0 +1,277 @@
+package messaging
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"golang.org/x/net/idna"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"unicode/utf8"
+)
+
+// TLSMode selects how the connection to the SMTP server is encrypted.
+type TLSMode string
+
+const (
+	// TLSModeNone upgrades the connection with STARTTLS only when the server advertises it, as smtp.SendMail
+	// does. It is the default.
+	TLSModeNone TLSMode = "none"
+	// TLSModeStartTLS requires the server to support STARTTLS and upgrades the connection before AUTH,
+	// failing otherwise (e.g. port 587).
+	TLSModeStartTLS TLSMode = "starttls"
+	// TLSModeImplicit opens the connection with TLS from the start (e.g. port 465).
+	TLSModeImplicit TLSMode = "implicit"
+)
+
+// tlsConfigFor returns a copy of config (or an empty config) verifying the certificate against host
+// unless config already names a server.
+func tlsConfigFor(config *tls.Config, host string) *tls.Config {
+	if config == nil {
+		return &tls.Config{ServerName: host}
+	}
+	config = config.Clone()
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	return config
+}
+
+// dialSMTP connects to the server at addr, with TLS from the start when mode is TLSModeImplicit, and
+// greets it. STARTTLS is then issued as required by mode, before any credentials are sent.
+func dialSMTP(addr string, mode TLSMode, config *tls.Config) (*smtp.Client, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var c *smtp.Client
+	switch mode {
+	case "", TLSModeNone, TLSModeStartTLS:
+		if c, err = smtp.Dial(addr); err != nil {
+			return nil, err
+		}
+	case TLSModeImplicit:
+		conn, err := tls.Dial("tcp", addr, tlsConfigFor(config, host))
+		if err != nil {
+			return nil, err
+		}
+		if c, err = smtp.NewClient(conn, host); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported TLS mode '%s'", mode)
+	}
+
+	if err = c.Hello("localhost"); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	if mode != TLSModeImplicit {
+		ok, _ := c.Extension("STARTTLS")
+		if !ok && mode == TLSModeStartTLS {
+			_ = c.Close()
+			return nil, fmt.Errorf("smtp: server %s does not support STARTTLS", addr)
+		}
+		if ok {
+			if err = c.StartTLS(tlsConfigFor(config, host)); err != nil {
+				_ = c.Close()
+				return nil, err
+			}
+		}
+	}
+	return c, nil
+}
+
+// sendMail connects to the server at addr and sends the rendered message data to the recipients,
+// following the same steps as smtp.SendMail (STARTTLS and AUTH when the server supports them), with
+// the encryption required by mode (see TLSMode) and the optional TLS config.
+// When m.RequestDSN is set and the server advertises the DSN extension, the MAIL FROM and
+// RCPT TO commands carry the delivery status notification parameters; otherwise they are omitted.
+// Internationalized addresses are sent as-is to servers advertising SMTPUTF8 (see asciiAddress otherwise).
+func sendMail(addr string, auth smtp.Auth, mode TLSMode, config *tls.Config, m *Message, to []string, data []byte) error {
+	from := m.From.Address
+	if err := validateSMTPLine(from); err != nil {
+		return err
+	}
+	for _, recp := range to {
+		if err := validateSMTPLine(recp); err != nil {
+			return err
+		}
+	}
+
+	c, err := dialSMTP(addr, mode, config)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err = c.Auth(auth); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Without SMTPUTF8 the envelope must be ASCII: IDN domains are sent in punycode and
+	// non-ASCII local parts cannot be delivered.
+	smtputf8, _ := c.Extension("SMTPUTF8")
+	if !smtputf8 {
+		if from, err = asciiAddress(from); err != nil {
+			return err
+		}
+		ascii := make([]string, len(to))
+		for i, recp := range to {
+			if ascii[i], err = asciiAddress(recp); err != nil {
+				return err
+			}
+		}
+		to = ascii
+	}
+
+	dsn := false
+	if m.RequestDSN {
+		dsn, _ = c.Extension("DSN")
+	}
+
+	if !dsn {
+		if err = c.Mail(from); err != nil {
+			return err
+		}
+		for _, recp := range to {
+			if err = c.Rcpt(recp); err != nil {
+				return err
+			}
+		}
+	} else {
+		// smtp.Client has no way to add extension parameters, so the envelope commands are sent directly.
+		mailCmd := fmt.Sprintf("MAIL FROM:<%s> RET=HDRS", from)
+		if m.ID != "" {
+			mailCmd += " ENVID=" + xtext(m.ID)
+		}
+		if smtputf8 {
+			mailCmd += " SMTPUTF8"
+		}
+		if err = smtpCmd(c, 250, mailCmd); err != nil {
+			return err
+		}
+		for _, recp := range to {
+			if err = smtpCmd(c, 25, fmt.Sprintf("RCPT TO:<%s> NOTIFY=SUCCESS,FAILURE,DELAY", recp)); err != nil {
+				return err
+			}
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(data); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// asciiAddress returns the address with its domain converted to punycode (IDNA), for servers
+// without SMTPUTF8. Addresses with a non-ASCII local part have no ASCII form and are rejected.
+func asciiAddress(address string) (string, error) {
+	at := strings.LastIndex(address, "@")
+	if at < 0 || isASCII(address) {
+		return address, nil
+	}
+
+	local, domain := address[:at], address[at+1:]
+	if !isASCII(local) {
+		return "", fmt.Errorf("address '%s' has a non-ASCII local part and the server does not support SMTPUTF8", address)
+	}
+	asciiDomain, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", fmt.Errorf("invalid domain in address '%s': %w", address, err)
+	}
+	return local + "@" + asciiDomain, nil
+}
+
+// isASCII reports whether s only contains ASCII characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// smtpCmd sends a raw command and checks the reply code (a prefix such as 25 accepts 250 and 251).
+func smtpCmd(c *smtp.Client, expectCode int, cmd string) error {
+	id, err := c.Text.Cmd("%s", cmd)
+	if err != nil {
+		return err
+	}
+	c.Text.StartResponse(id)
+	defer c.Text.EndResponse(id)
+	_, _, err = c.Text.ReadResponse(expectCode)
+	return err
+}
+
+// validateSMTPLine rejects envelope addresses that would inject extra SMTP commands.
+func validateSMTPLine(line string) error {
+	if strings.ContainsAny(line, "\n\r") {
+		return errors.New("smtp: A line must not contain CR or LF")
+	}
+	return nil
+}
+
+// xtext encodes s as an RFC 3461 xtext, the format required for the ENVID parameter.
+func xtext(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '!' || c > '~' || c == '+' || c == '=' {
+			_, _ = fmt.Fprintf(&b, "+%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// isTransientSMTP reports whether the server rejected the message with a transient (4xx) reply,
+// meaning the same message may be accepted if sent again later.
+func isTransientSMTP(err error) bool {
+	var protoErr *textproto.Error
+	return errors.As(err, &protoErr) && protoErr.Code >= 400 && protoErr.Code < 500
+}
+
+// transientError marks a provider API error worth retrying, such as throttling.
+type transientError struct {
+	err error
+}
+
+func (e transientError) Error() string { return e.err.Error() }
+func (e transientError) Unwrap() error { return e.err }
+
+// isTransientSend reports whether a failed delivery is worth retrying: a transient (4xx) SMTP reply, a
+// transientError, a temporary DNS failure or a network timeout. Permanent (5xx) replies and cancellations are not retried.
+func isTransientSend(err error) bool {
+	var transient transientError
+	if isTransientSMTP(err) || errors.As(err, &transient) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,364 @@
+package messaging
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer accepts a single connection, advertising the given EHLO extensions, and returns
+// the address it listens on plus a channel that receives the envelope commands once the session ends.
+func fakeSMTPServer(t *testing.T, extensions []string) (string, chan []string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error listening: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	commands := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			commands <- nil
+			return
+		}
+		commands <- serveFakeSMTP(conn, extensions, nil)
+	}()
+
+	return ln.Addr().String(), commands
+}
+
+// fakeSMTPRelay accepts any number of connections, serving each one with serveFakeSMTP and calling the
+// optional accepted callback first, and returns the host and port it listens on.
+func fakeSMTPRelay(t *testing.T, accepted func()) (string, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error listening: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if accepted != nil {
+				accepted()
+			}
+			go serveFakeSMTP(conn, nil, nil)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	return host, port
+}
+
+// serveFakeSMTP runs one SMTP session on conn, advertising the given EHLO extensions,
+// and returns the envelope commands it received. STARTTLS upgrades the session with config when set.
+func serveFakeSMTP(conn net.Conn, extensions []string, config *tls.Config) []string {
+	defer conn.Close()
+
+	var received []string
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 fake ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return received
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO":
+			reply := append([]string{"fake"}, extensions...)
+			for i, ext := range reply {
+				sep := "-"
+				if i == len(reply)-1 {
+					sep = " "
+				}
+				_ = tp.PrintfLine("250%s%s", sep, ext)
+			}
+		case "STARTTLS":
+			if config == nil {
+				_ = tp.PrintfLine("502 Not implemented")
+				continue
+			}
+			_ = tp.PrintfLine("220 Ready to start TLS")
+			tlsConn := tls.Server(conn, config)
+			if err := tlsConn.Handshake(); err != nil {
+				return received
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(conn)
+			received = append(received, line)
+		case "MAIL", "RCPT":
+			received = append(received, line)
+			_ = tp.PrintfLine("250 OK")
+		case "DATA":
+			_ = tp.PrintfLine("354 Go ahead")
+			if _, err := tp.ReadDotBytes(); err != nil {
+				return received
+			}
+			_ = tp.PrintfLine("250 Queued")
+		case "QUIT":
+			_ = tp.PrintfLine("221 Bye")
+			return received
+		default:
+			_ = tp.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+// TestSendRequestDSN verifies that the DSN parameters are sent only when requested and advertised by the server.
+func TestSendRequestDSN(t *testing.T) {
+	tests := []struct {
+		name       string
+		extensions []string
+		requestDSN bool
+		expected   []string
+	}{
+		{
+			name:       "DSN requested and supported",
+			extensions: []string{"DSN"},
+			requestDSN: true,
+			expected: []string{
+				"MAIL FROM:<sender@example.com> RET=HDRS ENVID=msg+2B1",
+				"RCPT TO:<recipient@example.com> NOTIFY=SUCCESS,FAILURE,DELAY",
+			},
+		},
+		{
+			name:       "DSN requested but not supported",
+			extensions: []string{"8BITMIME"},
+			requestDSN: true,
+			expected: []string{
+				"MAIL FROM:<sender@example.com> BODY=8BITMIME",
+				"RCPT TO:<recipient@example.com>",
+			},
+		},
+		{
+			name:       "DSN not requested",
+			extensions: []string{"DSN"},
+			requestDSN: false,
+			expected: []string{
+				"MAIL FROM:<sender@example.com>",
+				"RCPT TO:<recipient@example.com>",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, commands := fakeSMTPServer(t, tt.extensions)
+
+			m := NewMessage(mail.Address{Address: "sender@example.com"}, "Subject", "Body", "text/plain",
+				[]string{"recipient@example.com"}, nil, nil, nil)
+			m.ID = "msg+1"
+			m.RequestDSN = tt.requestDSN
+
+			if err := Send(addr, nil, &m); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			received := <-commands
+			if strings.Join(received, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("expected commands %q, got %q", tt.expected, received)
+			}
+		})
+	}
+}
+
+// TestIsTransientSMTP verifies that only 4xx replies are considered retryable.
+func TestIsTransientSMTP(t *testing.T) {
+	tests := map[error]bool{
+		&textproto.Error{Code: 421, Msg: "Service not available"}:           true,
+		&textproto.Error{Code: 451, Msg: "Local error in processing"}:       true,
+		&textproto.Error{Code: 550, Msg: "Mailbox unavailable"}:             false,
+		fmt.Errorf("wrapped: %w", &textproto.Error{Code: 452, Msg: "full"}): true,
+		errors.New("connection refused"):                                    false,
+	}
+	for err, want := range tests {
+		if got := isTransientSMTP(err); got != want {
+			t.Errorf("%v: expected %v, got %v", err, want, got)
+		}
+	}
+}
+
+// TestIsTransientSend verifies which delivery failures are retried by the send loop.
+func TestIsTransientSend(t *testing.T) {
+	tests := map[error]bool{
+		&textproto.Error{Code: 421, Msg: "Service not available"}:   true,
+		&textproto.Error{Code: 554, Msg: "Transaction failed"}:      false,
+		&net.DNSError{Err: "server misbehaving", IsTemporary: true}: true,
+		&net.DNSError{Err: "no such host", IsNotFound: true}:        false,
+		&net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}:       true,
+		fmt.Errorf("send: %w", context.Canceled):                    false,
+		errors.New("connection refused"):                            false,
+	}
+	for err, want := range tests {
+		if got := isTransientSend(err); got != want {
+			t.Errorf("%v: expected %v, got %v", err, want, got)
+		}
+	}
+}
+
+// TestSendInternationalizedAddress verifies the envelope of IDN addresses with and without SMTPUTF8 support.
+func TestSendInternationalizedAddress(t *testing.T) {
+	tests := []struct {
+		name       string
+		extensions []string
+		requestDSN bool
+		recipient  string
+		expected   []string
+		wantErr    bool
+	}{
+		{
+			name:       "SMTPUTF8 supported",
+			extensions: []string{"SMTPUTF8"},
+			recipient:  "用户@例子.公司",
+			expected: []string{
+				"MAIL FROM:<sender@example.com> SMTPUTF8",
+				"RCPT TO:<用户@例子.公司>",
+			},
+		},
+		{
+			name:       "SMTPUTF8 supported with DSN",
+			extensions: []string{"SMTPUTF8", "DSN"},
+			requestDSN: true,
+			recipient:  "user@例子.公司",
+			expected: []string{
+				"MAIL FROM:<sender@example.com> RET=HDRS SMTPUTF8",
+				"RCPT TO:<user@例子.公司> NOTIFY=SUCCESS,FAILURE,DELAY",
+			},
+		},
+		{
+			name:      "IDN domain without SMTPUTF8",
+			recipient: "user@例子.公司",
+			expected: []string{
+				"MAIL FROM:<sender@example.com>",
+				"RCPT TO:<user@xn--fsqu00a.xn--55qx5d>",
+			},
+		},
+		{
+			name:      "non-ASCII local part without SMTPUTF8",
+			recipient: "用户@例子.公司",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, commands := fakeSMTPServer(t, tt.extensions)
+
+			m := NewMessage(mail.Address{Address: "sender@example.com"}, "Subject", "Body", "text/plain",
+				[]string{tt.recipient}, nil, nil, nil)
+			m.RequestDSN = tt.requestDSN
+
+			err := Send(addr, nil, &m)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			received := <-commands
+			if strings.Join(received, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("expected commands %q, got %q", tt.expected, received)
+			}
+		})
+	}
+}
+
+// TestSendTLS verifies that STARTTLS is required by TLSModeStartTLS and that TLSModeImplicit opens the
+// connection with TLS, using the certificate of an httptest TLS server.
+func TestSendTLS(t *testing.T) {
+	certServer := httptest.NewTLSServer(http.NotFoundHandler())
+	defer certServer.Close()
+	serverConfig := &tls.Config{Certificates: certServer.TLS.Certificates}
+	clientConfig := certServer.Client().Transport.(*http.Transport).TLSClientConfig
+
+	tests := []struct {
+		name       string
+		mode       TLSMode
+		implicit   bool
+		extensions []string
+		expected   []string
+		wantErr    bool
+	}{
+		{
+			name:       "STARTTLS advertised",
+			mode:       TLSModeStartTLS,
+			extensions: []string{"STARTTLS"},
+			expected:   []string{"STARTTLS", "MAIL FROM:<sender@example.com>", "RCPT TO:<recipient@example.com>"},
+		},
+		{
+			name:    "STARTTLS required but not advertised",
+			mode:    TLSModeStartTLS,
+			wantErr: true,
+		},
+		{
+			name:     "implicit TLS",
+			mode:     TLSModeImplicit,
+			implicit: true,
+			expected: []string{"MAIL FROM:<sender@example.com>", "RCPT TO:<recipient@example.com>"},
+		},
+		{
+			name:     "none without STARTTLS",
+			mode:     TLSModeNone,
+			expected: []string{"MAIL FROM:<sender@example.com>", "RCPT TO:<recipient@example.com>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("unexpected error listening: %v", err)
+			}
+			defer func() { _ = ln.Close() }()
+			if tt.implicit {
+				ln = tls.NewListener(ln, serverConfig)
+			}
+
+			commands := make(chan []string, 1)
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					commands <- nil
+					return
+				}
+				commands <- serveFakeSMTP(conn, tt.extensions, serverConfig)
+			}()
+
+			m := NewMessage(mail.Address{Address: "sender@example.com"}, "Subject", "Body", "text/plain",
+				[]string{"recipient@example.com"}, nil, nil, nil)
+			err = SendTLS(ln.Addr().String(), nil, tt.mode, clientConfig, &m)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			received := <-commands
+			if strings.Join(received, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("expected commands %q, got %q", tt.expected, received)
+			}
+		})
+	}
+}
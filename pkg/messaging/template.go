@@ -0,0 +1,80 @@
+package messaging
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"net/mail"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Template is a message body rendered for each recipient of a campaign (see NewMessageFromTemplate).
+// HTML bodies use html/template, which escapes the substituted values according to their context;
+// any other content type uses text/template. Missing map keys are reported as render errors.
+type Template struct {
+	ContentType string // MIME type of the rendered body (e.g., text/plain, text/html)
+
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// NewTemplate parses body as a template producing content of the given type, defaulting to "text/html"
+// as NewMessage does.
+func NewTemplate(name, body, contentType string) (*Template, error) {
+	if contentType == "" {
+		contentType = "text/html"
+	}
+
+	t := &Template{ContentType: contentType}
+	var err error
+	if strings.HasPrefix(strings.ToLower(contentType), "text/html") {
+		t.html, err = htmltemplate.New(name).Option("missingkey=error").Parse(body)
+	} else {
+		t.text, err = texttemplate.New(name).Option("missingkey=error").Parse(body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template '%s': %w", name, err)
+	}
+	return t, nil
+}
+
+// Execute renders the template with data.
+func (t *Template) Execute(data interface{}) (string, error) {
+	var b strings.Builder
+	var err error
+	if t.html != nil {
+		err = t.html.Execute(&b, data)
+	} else {
+		err = t.text.Execute(&b, data)
+	}
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// NewMessageFromTemplate builds a message to the given recipients whose body is tmpl rendered with data.
+// subject is a text/template rendered with the same data. Every parse and render error is returned, so
+// a message that cannot be rendered never reaches the queue.
+func NewMessageFromTemplate(from, subject string, tmpl *Template, data interface{}, recipients ...string) (Message, error) {
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return Message{}, fmt.Errorf("invalid sender address '%s': %w", from, err)
+	}
+
+	subjectTmpl, err := texttemplate.New("subject").Option("missingkey=error").Parse(subject)
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to parse subject template: %w", err)
+	}
+	var renderedSubject strings.Builder
+	if err := subjectTmpl.Execute(&renderedSubject, data); err != nil {
+		return Message{}, fmt.Errorf("failed to render subject: %w", err)
+	}
+
+	body, err := tmpl.Execute(data)
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to render body: %w", err)
+	}
+
+	return NewMessage(*sender, renderedSubject.String(), body, tmpl.ContentType, recipients, nil, nil, nil), nil
+}
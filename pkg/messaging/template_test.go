@@ -0,0 +1,44 @@
+package messaging
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test rendering a template into the subject and body of a message
+// Verifies the substitution of {{.Name}}, the HTML escaping of text/html bodies and that render errors are returned.
+func TestNewMessageFromTemplate(t *testing.T) {
+	tmpl, err := NewTemplate("welcome", "<p>Hello {{.Name}}</p>", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m, err := NewMessageFromTemplate("Sender <sender@example.com>", "Welcome, {{.Name}}", tmpl, map[string]string{"Name": "Ana & <Bob>"}, "ana@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Subject != "Welcome, Ana & <Bob>" {
+		t.Errorf("unexpected subject: %q", m.Subject)
+	}
+	if m.Body != "<p>Hello Ana &amp; &lt;Bob&gt;</p>" || m.BodyContentType != "text/html" {
+		t.Errorf("unexpected body: %q (%s)", m.Body, m.BodyContentType)
+	}
+	if m.From.Address != "sender@example.com" || len(m.MailTo) != 1 || m.MailTo[0] != "ana@example.com" {
+		t.Errorf("unexpected addresses: %v -> %v", m.From, m.MailTo)
+	}
+
+	plain, err := NewTemplate("plain", "Hello {{.Name}}", "text/plain")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m, err := NewMessageFromTemplate("sender@example.com", "Hi", plain, struct{ Name string }{"<Bob>"}, "bob@example.com"); err != nil || m.Body != "Hello <Bob>" {
+		t.Errorf("expected the plain text body not to be escaped, got %q (%v)", m.Body, err)
+	}
+
+	if _, err := NewMessageFromTemplate("sender@example.com", "Hi {{.Missing}}", tmpl, map[string]string{"Name": "Ana"}, "ana@example.com"); err == nil || !strings.Contains(err.Error(), "subject") {
+		t.Errorf("expected a subject render error, got %v", err)
+	}
+	if _, err := NewTemplate("broken", "{{.Name", ""); err == nil {
+		t.Error("expected a parse error")
+	}
+}
@@ -0,0 +1,140 @@
+package observer
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Observer receives the metrics of the managers: counters, durations and byte counts. It is the
+// integration point for a metrics library (e.g. Prometheus counters and histograms), which this
+// module does not import. Implementations must be safe for concurrent use.
+type Observer interface {
+	// IncCounter increments the counter name with the given labels by one.
+	IncCounter(name string, labels map[string]string)
+	// ObserveDuration records the duration d in the distribution name with the given labels.
+	ObserveDuration(name string, d time.Duration, labels map[string]string)
+	// AddBytes adds n to the byte count name.
+	AddBytes(name string, n int64)
+}
+
+// Metrics reported by the managers.
+const (
+	APICalls        = "api_calls_total"        // Counter of provider API calls; labels "provider", "operation" and "outcome" ("success" or "error").
+	APICallDuration = "api_call_duration"      // Duration of provider API calls; labels "provider" and "operation".
+	Retries         = "retries_total"          // Counter of retried API calls; labels "provider" and "operation".
+	PartsUploaded   = "upload_parts_total"     // Counter of uploaded parts; label "provider".
+	BytesUploaded   = "bytes_uploaded_total"   // Bytes uploaded to the buckets.
+	BytesDownloaded = "bytes_downloaded_total" // Bytes downloaded from the buckets.
+	MessagesSent    = "messages_sent_total"    // Counter of messages sent; label "provider".
+	MessagesFailed  = "messages_failed_total"  // Counter of messages that could not be sent; label "provider".
+)
+
+// Nop is an Observer discarding every observation, used when a manager has no Observer.
+type Nop struct{}
+
+func (Nop) IncCounter(string, map[string]string)                     {}
+func (Nop) ObserveDuration(string, time.Duration, map[string]string) {}
+func (Nop) AddBytes(string, int64)                                   {}
+
+// OrNop returns o, or Nop when o is nil.
+func OrNop(o Observer) Observer {
+	if o == nil {
+		return Nop{}
+	}
+	return o
+}
+
+// Call runs an API call of the provider, counting it in APICalls by outcome and recording its
+// APICallDuration. The error of call is returned unchanged.
+func Call(o Observer, provider, operation string, call func() error) error {
+	o = OrNop(o)
+	start := time.Now()
+	err := call()
+
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	o.ObserveDuration(APICallDuration, time.Since(start), map[string]string{"provider": provider, "operation": operation})
+	o.IncCounter(APICalls, map[string]string{"provider": provider, "operation": operation, "outcome": outcome})
+	return err
+}
+
+// Retrying wraps the operation passed to backoff.Retry: every attempt is observed as an API call
+// with Call, and every attempt after the first is counted in Retries.
+func Retrying(o Observer, provider, operation string, call func() error) func() error {
+	attempts := 0
+	return func() error {
+		if attempts++; attempts > 1 {
+			OrNop(o).IncCounter(Retries, map[string]string{"provider": provider, "operation": operation})
+		}
+		return Call(o, provider, operation, call)
+	}
+}
+
+// Memory is an Observer keeping every observation in memory, for tests and debugging.
+type Memory struct {
+	mu        sync.Mutex
+	counters  map[string]int64
+	durations map[string][]time.Duration
+	bytes     map[string]int64
+}
+
+// NewMemory returns an empty Memory observer.
+func NewMemory() *Memory {
+	return &Memory{counters: map[string]int64{}, durations: map[string][]time.Duration{}, bytes: map[string]int64{}}
+}
+
+// key identifies a metric and its labels, e.g. `api_calls_total{operation="List",provider="aws"}`.
+func key(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+	pairs := make([]string, 0, len(labels))
+	for label, value := range labels {
+		pairs = append(pairs, label+`="`+value+`"`)
+	}
+	sort.Strings(pairs)
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (m *Memory) IncCounter(name string, labels map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters[key(name, labels)]++
+}
+
+func (m *Memory) ObserveDuration(name string, d time.Duration, labels map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.durations[key(name, labels)] = append(m.durations[key(name, labels)], d)
+}
+
+func (m *Memory) AddBytes(name string, n int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bytes[name] += n
+}
+
+// Counter returns the value of the counter name with exactly the given labels.
+func (m *Memory) Counter(name string, labels map[string]string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counters[key(name, labels)]
+}
+
+// Durations returns the durations recorded for name with exactly the given labels.
+func (m *Memory) Durations(name string, labels map[string]string) []time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]time.Duration(nil), m.durations[key(name, labels)]...)
+}
+
+// Bytes returns the byte count name.
+func (m *Memory) Bytes(name string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.bytes[name]
+}
@@ -0,0 +1,45 @@
+package observer
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestRetrying verifies the API calls, outcomes and retries observed across the attempts of an operation.
+func TestRetrying(t *testing.T) {
+	memory := NewMemory()
+	failures := 2
+	op := Retrying(memory, "aws", "StartInstances", func() error {
+		if failures > 0 {
+			failures--
+			return errors.New("throttled")
+		}
+		return nil
+	})
+	for op() != nil {
+	}
+
+	labels := map[string]string{"provider": "aws", "operation": "StartInstances"}
+	if got := memory.Counter(Retries, labels); got != 2 {
+		t.Errorf("expected 2 retries, got %d", got)
+	}
+	if got := memory.Counter(APICalls, map[string]string{"provider": "aws", "operation": "StartInstances", "outcome": "error"}); got != 2 {
+		t.Errorf("expected 2 failed calls, got %d", got)
+	}
+	if got := memory.Counter(APICalls, map[string]string{"provider": "aws", "operation": "StartInstances", "outcome": "success"}); got != 1 {
+		t.Errorf("expected 1 successful call, got %d", got)
+	}
+	if got := len(memory.Durations(APICallDuration, labels)); got != 3 {
+		t.Errorf("expected 3 durations, got %d", got)
+	}
+
+	// A nil observer discards the observations.
+	if err := Call(nil, "aws", "DescribeInstances", func() error { return nil }); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	memory.AddBytes(BytesUploaded, 10)
+	memory.AddBytes(BytesUploaded, 5)
+	if got := memory.Bytes(BytesUploaded); got != 15 {
+		t.Errorf("expected 15 bytes, got %d", got)
+	}
+}
@@ -2,84 +2,260 @@ package bucket
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/diegoyosiura/cloud-manager/pkg/backoff"
+	"github.com/diegoyosiura/cloud-manager/pkg/observer"
 	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"sort"
+	"sync"
 	"time"
 )
 
 type AWSManager struct {
-	Auth   *authentication.AWSAuth // AWS authentication details.
-	Client *s3.S3
+	Auth        *authentication.AWSAuth // AWS authentication details.
+	Client      *s3.S3
+	StorageTier StorageTierEnum // Storage class used for uploads (defaults to STierStandard).
+	IfNotExists bool            // Makes uploads fail with ErrObjectExists instead of overwriting an existing key (see UploadOptions).
+	VerifySize  bool            // Checks with HeadObject that uploads stored every byte sent, failing with ErrSizeMismatch otherwise.
+	Backoff     backoff.Backoff // Polling of Create when waiting for the bucket (defaults to the SDK waiter).
+
+	Observer observer.Observer // Receives the API call, part and byte metrics when set (optional).
+
+	uploads inflightUploads // Multipart uploads in progress, aborted by Shutdown.
 }
 
-func (a *AWSManager) setup() (bool, error) {
+func (a *AWSManager) setup() error {
+	if err := a.Auth.EnsureValid(); err != nil {
+		return err
+	}
 	if a.Client == nil {
 		a.Client = s3.New(a.Auth.Session, &aws.Config{Region: &a.Auth.Region})
 		if a.Client == nil {
-			return false, errors.New("failed to create AWS client")
+			return errors.New("failed to create AWS client")
 		}
 	}
 
-	return true, nil
+	return nil
+}
+func (a *AWSManager) ListBuckets() ([]string, error) {
+	if err := a.setup(); err != nil {
+		return nil, err
+	}
+
+	resp, err := a.Client.ListBuckets(&s3.ListBucketsInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	var r []string
+	for _, b := range resp.Buckets {
+		r = append(r, aws.StringValue(b.Name))
+	}
+
+	return r, nil
 }
+
 func (a *AWSManager) List(name string) (r []BucketObject, err error) {
-	successs, err := a.setup()
-	if !successs {
-		panic(err)
+	return listAllPages(a, name)
+}
+
+// ListPage returns up to limit objects of the bucket (1000 at most, and by default) starting at the
+// continuation token, which is empty for the first page, and the token of the next page, empty after the last one.
+func (a *AWSManager) ListPage(name, token string, limit int) ([]BucketObject, string, error) {
+	if err := a.setup(); err != nil {
+		return nil, "", err
 	}
 
-	bi := &s3.ListObjectsV2Input{}
-	bi.Bucket = &name
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(name)}
+	if token != "" {
+		input.ContinuationToken = aws.String(token)
+	}
+	if limit > 0 {
+		input.MaxKeys = aws.Int64(int64(limit))
+	}
+
+	var page *s3.ListObjectsV2Output
+	err := observer.Call(a.Observer, "aws", "ListObjectsV2", func() (err error) {
+		page, err = a.Client.ListObjectsV2(input)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	r := make([]BucketObject, 0, len(page.Contents))
+	for _, o := range page.Contents {
+		r = append(r, NewBucketObjectFromAWS(o))
+	}
+	if !aws.BoolValue(page.IsTruncated) {
+		return r, "", nil
+	}
+	return r, aws.StringValue(page.NextContinuationToken), nil
+}
 
-	buckets, err := a.Client.ListObjectsV2(bi)
+// ListPrefix returns the objects of the bucket whose keys start with prefix, reading every page. With a
+// delimiter such as "/", the keys containing it after the prefix are grouped into the returned common
+// prefixes (the "subfolders") instead of being listed as objects.
+func (a *AWSManager) ListPrefix(bucket, prefix, delimiter string) ([]BucketObject, []string, error) {
+	if err := a.setup(); err != nil {
+		return nil, nil, err
+	}
 
-	for _, b := range buckets.Contents {
-		r = append(r, NewBucketObjectFromAWS(b))
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(bucket)}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+	if delimiter != "" {
+		input.Delimiter = aws.String(delimiter)
+	}
+
+	var objects []BucketObject
+	var prefixes []string
+	err := observer.Call(a.Observer, "aws", "ListObjectsV2", func() error {
+		return a.Client.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, o := range page.Contents {
+				objects = append(objects, NewBucketObjectFromAWS(o))
+			}
+			for _, p := range page.CommonPrefixes {
+				prefixes = append(prefixes, aws.StringValue(p.Prefix))
+			}
+			return true
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return objects, prefixes, nil
+}
+
+// ListObjectsSince returns the objects of the bucket modified after since.
+// S3 has no server-side filter on modification time, so every page of the listing
+// is fetched and filtered on the client.
+func (a *AWSManager) ListObjectsSince(name string, since time.Time) ([]BucketObject, error) {
+	if err := a.setup(); err != nil {
+		return nil, err
+	}
+
+	var r []BucketObject
+	err := observer.Call(a.Observer, "aws", "ListObjectsV2", func() error {
+		return a.Client.ListObjectsV2Pages(&s3.ListObjectsV2Input{Bucket: aws.String(name)}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, o := range page.Contents {
+				if o.LastModified != nil && o.LastModified.After(since) {
+					r = append(r, NewBucketObjectFromAWS(o))
+				}
+			}
+			return true
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return r, nil
 }
 
+// StreamObjects lists the objects of the bucket whose keys start with prefix, page by page in a goroutine,
+// sending each one on the returned object channel as the caller receives them, so memory stays bounded.
+// The listing stops when ctx is done. Any terminal error (including the context error) is sent on the
+// error channel, then both channels are closed.
+func (a *AWSManager) StreamObjects(ctx context.Context, name, prefix string) (<-chan BucketObject, <-chan error) {
+	return streamObjects(ctx, func(send func(BucketObject) bool) error {
+		if err := a.setup(); err != nil {
+			return err
+		}
+
+		input := &s3.ListObjectsV2Input{Bucket: aws.String(name)}
+		if prefix != "" {
+			input.Prefix = aws.String(prefix)
+		}
+		return a.Client.ListObjectsV2PagesWithContext(ctx, input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, o := range page.Contents {
+				if !send(NewBucketObjectFromAWS(o)) {
+					return false
+				}
+			}
+			return true
+		})
+	})
+}
+
 func (a *AWSManager) Create(name string, waitCreate bool) error {
-	successs, err := a.setup()
-	if !successs {
-		panic(err)
+	if err := a.setup(); err != nil {
+		return err
 	}
 
 	input := &s3.CreateBucketInput{
 		Bucket: aws.String(name),
 	}
 
-	_, err = a.Client.CreateBucket(input)
+	_, err := a.Client.CreateBucket(input)
 
 	if err != nil {
 		return err
 	}
 
 	if waitCreate {
-		err = a.Client.WaitUntilBucketExists(&s3.HeadBucketInput{
+		var options []request.WaiterOption
+		if a.Backoff != nil {
+			options = append(options, request.WithWaiterDelay(a.Backoff.NextDelay))
+			if attempts := a.Backoff.MaxAttempts(); attempts > 0 {
+				options = append(options, request.WithWaiterMaxAttempts(attempts))
+			}
+		}
+		return a.Client.WaitUntilBucketExistsWithContext(aws.BackgroundContext(), &s3.HeadBucketInput{
 			Bucket: aws.String(name),
-		})
+		}, options...)
 	}
 	return nil
 }
 
+// Exists reports whether the bucket exists with HeadBucket. A 404 means the bucket does not exist; other
+// failures, such as a 403 for a bucket owned by another account, are returned as errors.
+func (a *AWSManager) Exists(name string) (bool, error) {
+	if err := a.setup(); err != nil {
+		return false, err
+	}
+
+	err := observer.Call(a.Observer, "aws", "HeadBucket", func() error {
+		_, err := a.Client.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String(name)})
+		return err
+	})
+	if err != nil {
+		var requestErr awserr.RequestFailure
+		if errors.As(err, &requestErr) && requestErr.StatusCode() == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check bucket '%s': %w", name, err)
+	}
+	return true, nil
+}
+
+// CreateIfNotExists creates the bucket like Create unless it already exists, reporting whether it was created.
+func (a *AWSManager) CreateIfNotExists(name string, waitCreate bool) (bool, error) {
+	return createIfNotExists(a, name, waitCreate)
+}
+
 func (a *AWSManager) Delete(name string) error {
-	successs, err := a.setup()
-	if !successs {
-		panic(err)
+	if err := a.setup(); err != nil {
+		return err
 	}
 
 	input := &s3.DeleteBucketInput{
 		Bucket: aws.String(name),
 	}
 
-	_, err = a.Client.DeleteBucket(input)
+	_, err := a.Client.DeleteBucket(input)
 
 	if err != nil {
 		return err
@@ -87,65 +263,168 @@ func (a *AWSManager) Delete(name string) error {
 	return nil
 }
 
+// Upload uploads the file as a multipart upload of partSize parts (10 MiB by default), up to threads
+// parts at once (4 by default). The upload is aborted when any part fails. A file shorter than a part,
+// an empty one included, is stored with a single PutObject.
 func (a *AWSManager) Upload(bucket string, objectName string, f *os.File, partSize int64, threads int) error {
-	successs, err := a.setup()
-	if !successs {
-		panic(err)
+	_, err := a.UploadWithResult(bucket, objectName, f, partSize, threads)
+	return err
+}
+
+// UploadWithResult uploads the file like Upload and returns the ETag and, for versioned buckets,
+// the version ID reported by CompleteMultipartUpload, together with the number of bytes sent.
+func (a *AWSManager) UploadWithResult(bucket string, objectName string, f *os.File, partSize int64, threads int) (UploadResult, error) {
+	return a.uploadStream(bucket, objectName, f, partSize, threads, UploadOptions{IfNotExists: a.IfNotExists})
+}
+
+// UploadWithOptions uploads the file like UploadWithResult. With opts.IfNotExists, the upload is
+// completed on the condition that the key does not exist yet, failing with ErrObjectExists otherwise;
+// the IfNotExists field of the manager applies as well.
+func (a *AWSManager) UploadWithOptions(bucket string, objectName string, f *os.File, partSize int64, threads int, opts UploadOptions) (UploadResult, error) {
+	opts.IfNotExists = opts.IfNotExists || a.IfNotExists
+	return a.uploadStream(bucket, objectName, f, partSize, threads, opts)
+}
+
+// UploadStream uploads the content read from r like Upload. Parts are read from r in order, so it
+// may be a pipe or a request body; size is the number of bytes r yields, or -1 when unknown.
+func (a *AWSManager) UploadStream(bucket, object string, r io.Reader, size int64, partSize int64, threads int) error {
+	_, err := a.uploadStream(bucket, object, expectSize(r, size), partSize, threads, UploadOptions{IfNotExists: a.IfNotExists})
+	return err
+}
+
+func (a *AWSManager) uploadStream(bucket string, objectName string, r io.Reader, partSize int64, threads int, opts UploadOptions) (UploadResult, error) {
+	if err := a.setup(); err != nil {
+		return UploadResult{}, err
 	}
 
 	if partSize < 131072 { // 128 * 1024
 		partSize = 10 * 1024 * 1024
 	}
-	if threads <= 0 { // 128 * 1024
+	if threads <= 0 {
 		threads = 4
 	}
 
+	storageClass, err := awsStorageClass(a.StorageTier)
+	if err != nil {
+		return UploadResult{}, err
+	}
+
+	// A multipart upload needs at least one part, so content shorter than a part, an empty stream
+	// included, is stored with a single PutObject instead
+	first := make([]byte, partSize)
+	n, err := io.ReadFull(r, first)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return a.putObject(bucket, objectName, storageClass, first[:n], opts)
+	}
+	if err != nil {
+		return UploadResult{}, err
+	}
+	r = io.MultiReader(bytes.NewReader(first), r)
+
 	rq := &s3.CreateMultipartUploadInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(objectName),
+		Bucket:       aws.String(bucket),
+		Key:          aws.String(objectName),
+		StorageClass: aws.String(storageClass),
 	}
 
 	initOut, err := a.Client.CreateMultipartUpload(rq)
 	if err != nil {
-		return err
+		return UploadResult{}, err
 	}
 
 	uploadID := initOut.UploadId
-	partNum := int64(1)
-	buf := make([]byte, partSize)
+	registration, err := a.uploads.register(func() {
+		_, _ = a.Client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+			Bucket: aws.String(bucket), Key: aws.String(objectName), UploadId: uploadID,
+		})
+	})
+	if err != nil {
+		_, _ = a.Client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+			Bucket: aws.String(bucket), Key: aws.String(objectName), UploadId: uploadID,
+		})
+		return UploadResult{}, err
+	}
+	defer a.uploads.deregister(registration)
 
-	var completed []*s3.CompletedPart
-	for {
-		n, readErr := f.Read(buf)
-		if n > 0 {
-			out, err := a.upload(bucket, objectName, partNum, uploadID, buf, n)
-			if err != nil {
-				_, _ = a.Client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
-					Bucket: aws.String(bucket), Key: aws.String(objectName), UploadId: uploadID,
-				})
-				return err
+	abort := func() {
+		_, _ = a.Client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+			Bucket: aws.String(bucket), Key: aws.String(objectName), UploadId: uploadID,
+		})
+	}
+
+	// Parts are read in order and uploaded by threads workers; at most threads parts wait in the
+	// queue, so about 2*threads parts are held in memory
+	type part struct {
+		number int64
+		data   []byte
+	}
+	parts := make(chan part, threads)
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		completed []*s3.CompletedPart
+		uploadErr error
+	)
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return uploadErr != nil
+	}
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range parts {
+				if failed() {
+					continue
+				}
+				out, err := a.upload(bucket, objectName, p.number, uploadID, p.data, len(p.data))
+				mu.Lock()
+				if err != nil {
+					if uploadErr == nil {
+						uploadErr = err
+					}
+				} else {
+					completed = append(completed, &s3.CompletedPart{ETag: out.ETag, PartNumber: aws.Int64(p.number)})
+				}
+				mu.Unlock()
 			}
+		}()
+	}
 
-			completed = append(completed, &s3.CompletedPart{
-				ETag: out.ETag, PartNumber: aws.Int64(partNum),
-			})
-			partNum++
+	sent := int64(0)
+	var readErr error
+	for partNum := int64(1); !failed(); partNum++ {
+		buf := make([]byte, partSize)
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			parts <- part{number: partNum, data: buf[:n]}
+			sent += int64(n)
 		}
-		if readErr == io.EOF {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
 			break
 		}
+		if err != nil {
+			readErr = err
+			break
+		}
+	}
+	close(parts)
+	wg.Wait()
+
+	if readErr != nil || uploadErr != nil {
+		abort()
 		if readErr != nil {
-			return readErr
+			return UploadResult{}, readErr
 		}
+		return UploadResult{}, uploadErr
 	}
 
+	// CompleteMultipartUpload requires the parts in ascending order
 	sort.Slice(completed, func(i, j int) bool {
-		if *completed[i].PartNumber == *completed[j].PartNumber {
-			return true
-		}
 		return *completed[i].PartNumber < *completed[j].PartNumber
 	})
-	_, err = a.Client.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+	req, out := a.Client.CompleteMultipartUploadRequest(&s3.CompleteMultipartUploadInput{
 		Bucket:   aws.String(bucket),
 		Key:      aws.String(objectName),
 		UploadId: uploadID,
@@ -153,39 +432,132 @@ func (a *AWSManager) Upload(bucket string, objectName string, f *os.File, partSi
 			Parts: completed,
 		},
 	})
-	return err
+	if opts.IfNotExists {
+		// The SDK input has no IfNoneMatch field yet, so the conditional header is added on build.
+		req.Handlers.Build.PushBack(func(r *request.Request) {
+			r.HTTPRequest.Header.Set("If-None-Match", "*")
+		})
+	}
+
+	if err = req.Send(); err != nil {
+		abort()
+		if isAWSPreconditionFailed(err) {
+			return UploadResult{}, ErrObjectExists
+		}
+		return UploadResult{}, err
+	}
+
+	result := UploadResult{ETag: aws.StringValue(out.ETag), VersionID: aws.StringValue(out.VersionId), Size: sent}
+	if a.VerifySize {
+		if err := a.verifySize(bucket, objectName, sent); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
 }
 
-func (a *AWSManager) upload(bucket, objectName string, partNum int64, uploadID *string, buf []byte, n int) (*s3.UploadPartOutput, error) {
-	out, err := a.Client.UploadPart(&s3.UploadPartInput{
-		Bucket:     aws.String(bucket),
-		Key:        aws.String(objectName),
-		PartNumber: &partNum,
-		UploadId:   uploadID,
-		Body:       bytes.NewReader(buf[:n]),
+// putObject stores data with a single PutObject, as uploadStream does for content shorter than a part.
+func (a *AWSManager) putObject(bucket, objectName, storageClass string, data []byte, opts UploadOptions) (UploadResult, error) {
+	registration, err := a.uploads.register(func() {})
+	if err != nil {
+		return UploadResult{}, err
+	}
+	defer a.uploads.deregister(registration)
+
+	req, out := a.Client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:       aws.String(bucket),
+		Key:          aws.String(objectName),
+		StorageClass: aws.String(storageClass),
+		Body:         bytes.NewReader(data),
 	})
+	if opts.IfNotExists {
+		// As for CompleteMultipartUpload, the SDK input has no IfNoneMatch field yet.
+		req.Handlers.Build.PushBack(func(r *request.Request) {
+			r.HTTPRequest.Header.Set("If-None-Match", "*")
+		})
+	}
+	err = observer.Call(a.Observer, "aws", "PutObject", req.Send)
+	if isAWSPreconditionFailed(err) {
+		return UploadResult{}, ErrObjectExists
+	}
 	if err != nil {
-		_, _ = a.Client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
-			Bucket: aws.String(bucket), Key: aws.String(objectName), UploadId: uploadID,
+		return UploadResult{}, err
+	}
+	observer.OrNop(a.Observer).AddBytes(observer.BytesUploaded, int64(len(data)))
+
+	result := UploadResult{ETag: aws.StringValue(out.ETag), VersionID: aws.StringValue(out.VersionId), Size: int64(len(data))}
+	if a.VerifySize {
+		if err := a.verifySize(bucket, objectName, result.Size); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// Shutdown stops accepting uploads and waits for the multipart uploads in progress to complete.
+// When ctx is done first, the remaining uploads are aborted so their parts are not left behind
+// as billed orphans; their Upload calls then fail and Shutdown returns an error wrapping ctx.Err().
+func (a *AWSManager) Shutdown(ctx context.Context) error {
+	return a.uploads.shutdown(ctx)
+}
+
+// verifySize compares the size of the stored object with the number of bytes sent.
+// The object is left in place on a mismatch so it can be inspected or deleted by the caller.
+func (a *AWSManager) verifySize(bucket string, objectName string, sent int64) error {
+	head, err := a.Client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(objectName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to verify size of '%s': %w", objectName, err)
+	}
+
+	if stored := aws.Int64Value(head.ContentLength); stored != sent {
+		return fmt.Errorf("%w: '%s' has %d bytes, %d were sent", ErrSizeMismatch, objectName, stored, sent)
+	}
+	return nil
+}
+
+// isAWSPreconditionFailed reports whether an S3 error was caused by a failed conditional write.
+func isAWSPreconditionFailed(err error) bool {
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) {
+		return reqErr.StatusCode() == http.StatusPreconditionFailed || reqErr.StatusCode() == http.StatusConflict
+	}
+	return false
+}
+
+func (a *AWSManager) upload(bucket, objectName string, partNum int64, uploadID *string, buf []byte, n int) (*s3.UploadPartOutput, error) {
+	var out *s3.UploadPartOutput
+	err := observer.Call(a.Observer, "aws", "UploadPart", func() (err error) {
+		out, err = a.Client.UploadPart(&s3.UploadPartInput{
+			Bucket:     aws.String(bucket),
+			Key:        aws.String(objectName),
+			PartNumber: &partNum,
+			UploadId:   uploadID,
+			Body:       bytes.NewReader(buf[:n]),
 		})
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	observer.OrNop(a.Observer).IncCounter(observer.PartsUploaded, map[string]string{"provider": "aws"})
+	observer.OrNop(a.Observer).AddBytes(observer.BytesUploaded, int64(n))
 	return out, nil
 }
 
 func (a *AWSManager) Update(bucket string, objectName string, f *os.File, partSize int64, threads int) error {
-	successs, err := a.setup()
-	if !successs {
-		panic(err)
+	if err := a.setup(); err != nil {
+		return err
 	}
 
 	return a.Upload(bucket, objectName, f, partSize, threads)
 }
 func (a *AWSManager) DownloadLink(bucketName string, objectName string, expires int64) (string, error) {
-	successs, err := a.setup()
-	if !successs {
-		panic(err)
+	if err := a.setup(); err != nil {
+		return "", err
 	}
 
 	req, _ := a.Client.GetObjectRequest(&s3.GetObjectInput{
@@ -200,10 +572,228 @@ func (a *AWSManager) DownloadLink(bucketName string, objectName string, expires
 
 	return urlStr, nil
 }
+
+// UploadLink returns a presigned URL, valid for expires minutes, that uploads the object with a PUT of
+// its content. No Content-Type is signed, so the client may send any: the Content-Type header of the PUT
+// becomes the one of the object. Browsers uploading across origins also need a CORS rule on the bucket
+// allowing PUT and the headers they send.
+func (a *AWSManager) UploadLink(bucket, object string, expires int64) (string, error) {
+	if err := a.setup(); err != nil {
+		return "", err
+	}
+
+	req, _ := a.Client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(object),
+	})
+	link, err := req.Presign(time.Duration(expires) * time.Minute)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign the upload of '%s': %w", object, err)
+	}
+	return link, nil
+}
+
+// ObjectURL returns the canonical virtual-hosted-style URL of the object
+// (https://<bucket>.s3.<region>.amazonaws.com/<key>). The URL carries no signature,
+// so it is only usable for objects that are publicly readable; use DownloadLink otherwise.
+func (a *AWSManager) ObjectURL(bucketName string, objectName string) (string, error) {
+	if bucketName == "" {
+		return "", errors.New("bucket name is required")
+	}
+	key, err := escapeObjectName(objectName)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucketName, a.Auth.Region, key), nil
+}
+
+// Download writes the content of the object to w, as stored.
+func (a *AWSManager) Download(bucketName string, objectName string, w io.Writer) error {
+	return a.DownloadWithOptions(bucketName, objectName, w, DownloadOptions{})
+}
+
+// DownloadRange writes the bytes from start to end (both inclusive) of the object to w with a ranged GET.
+// A range that goes past the end of the object is cut short by S3.
+func (a *AWSManager) DownloadRange(bucket, object string, start, end int64, w io.Writer) error {
+	if err := validateRange(start, end); err != nil {
+		return err
+	}
+	if err := a.setup(); err != nil {
+		return err
+	}
+
+	var out *s3.GetObjectOutput
+	err := observer.Call(a.Observer, "aws", "GetObject", func() (err error) {
+		out, err = a.Client.GetObject(&s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(object),
+			Range:  aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Body.Close() }()
+
+	n, err := io.Copy(w, out.Body)
+	observer.OrNop(a.Observer).AddBytes(observer.BytesDownloaded, n)
+	if err != nil {
+		return fmt.Errorf("failed to download '%s': %w", object, err)
+	}
+	return nil
+}
+
+// DownloadToFile downloads the object into the file at path with s3manager.Downloader, which fetches
+// parts of partSize bytes with up to threads ranged GETs at once (10 MiB and 4 by default, as Upload).
+// The file is removed when the download fails.
+func (a *AWSManager) DownloadToFile(bucket string, objectName string, path string, partSize int64, threads int) error {
+	if err := a.setup(); err != nil {
+		return err
+	}
+
+	if partSize < 131072 { // 128 * 1024
+		partSize = 10 * 1024 * 1024
+	}
+	if threads <= 0 {
+		threads = 4
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	downloader := s3manager.NewDownloaderWithClient(a.Client, func(d *s3manager.Downloader) {
+		d.PartSize = partSize
+		d.Concurrency = threads
+	})
+	var n int64
+	err = observer.Call(a.Observer, "aws", "Download", func() (err error) {
+		n, err = downloader.Download(f, &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(objectName),
+		})
+		return err
+	})
+	observer.OrNop(a.Observer).AddBytes(observer.BytesDownloaded, n)
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("failed to download '%s' to '%s': %w", objectName, path, err)
+	}
+	return nil
+}
+
+// DownloadWithOptions downloads the object like Download. With opts.AutoDecompress, objects whose
+// Content-Encoding is gzip are decompressed before being written to w. With opts.VerifyChecksum, the
+// stored bytes are checked against the object's x-amz-checksum-* value (requested with checksum mode)
+// or, when the object was uploaded without one, against its ETag if that is a plain MD5.
+func (a *AWSManager) DownloadWithOptions(bucketName string, objectName string, w io.Writer, opts DownloadOptions) error {
+	if err := a.setup(); err != nil {
+		return err
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectName),
+	}
+	if opts.VerifyChecksum {
+		input.ChecksumMode = aws.String(s3.ChecksumModeEnabled)
+	}
+
+	var out *s3.GetObjectOutput
+	err := observer.Call(a.Observer, "aws", "GetObject", func() (err error) {
+		out, err = a.Client.GetObject(input)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Body.Close() }()
+
+	var checksum *objectChecksum
+	if opts.VerifyChecksum {
+		checksum = awsChecksum(out.ChecksumSHA256, out.ChecksumSHA1, out.ChecksumCRC32C, out.ChecksumCRC32, out.ETag)
+		if checksum == nil {
+			return fmt.Errorf("no verifiable checksum stored for '%s'", objectName)
+		}
+	}
+	body := &countingReader{r: out.Body}
+	defer func() { observer.OrNop(a.Observer).AddBytes(observer.BytesDownloaded, body.n) }()
+	return writeObject(w, body, objectName, out.ContentEncoding, opts, checksum)
+}
+
+// StatObject returns the metadata of the object. The archival state is not filled for S3 objects.
+func (a *AWSManager) StatObject(bucketName string, objectName string) (BucketObject, error) {
+	if err := a.setup(); err != nil {
+		return BucketObject{}, err
+	}
+
+	out, err := a.Client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectName),
+	})
+	if err != nil {
+		return BucketObject{}, err
+	}
+
+	// HeadObject omits the storage class of standard objects, which NewBucketObjectFromAWS handles
+	object := NewBucketObjectFromAWS(&s3.Object{
+		Key:          aws.String(objectName),
+		LastModified: out.LastModified,
+		Size:         out.ContentLength,
+		StorageClass: out.StorageClass,
+	})
+	object.ETag = aws.StringValue(out.ETag)
+	object.ContentType = aws.StringValue(out.ContentType)
+	return object, nil
+}
+
+// Stat returns the metadata of the object like StatObject, or found=false when HeadObject answers 404.
+// Other failures, such as a 403 for a key the credentials cannot read, are returned as errors.
+func (a *AWSManager) Stat(bucket, object string) (*BucketObject, bool, error) {
+	result, err := a.StatObject(bucket, object)
+	if err != nil {
+		var requestErr awserr.RequestFailure
+		if errors.As(err, &requestErr) && requestErr.StatusCode() == http.StatusNotFound {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to stat '%s': %w", object, err)
+	}
+	return &result, true, nil
+}
+
+// ResumableDownload downloads the object into localPath, resuming a previous partial download of the
+// same version of the object (see the ".state" file kept next to localPath) with a ranged GET.
+// If the object changed since the partial download, it is downloaded again from the start.
+func (a *AWSManager) ResumableDownload(bucket, objectName, localPath string) error {
+	object, err := a.StatObject(bucket, objectName)
+	if err != nil {
+		return err
+	}
+
+	return resumeDownload(localPath, object, func(offset int64) (io.ReadCloser, error) {
+		out, err := a.Client.GetObject(&s3.GetObjectInput{
+			Bucket:  aws.String(bucket),
+			Key:     aws.String(objectName),
+			IfMatch: aws.String(object.ETag),
+			Range:   aws.String(fmt.Sprintf("bytes=%d-", offset)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return out.Body, nil
+	})
+}
+
 func (a *AWSManager) DeleteObject(bucketName string, objectName string) error {
-	successs, err := a.setup()
-	if !successs {
-		panic(err)
+	if err := a.setup(); err != nil {
+		return err
 	}
 
 	req := &s3.DeleteObjectInput{
@@ -211,10 +801,89 @@ func (a *AWSManager) DeleteObject(bucketName string, objectName string) error {
 		Key:    aws.String(objectName),
 	}
 
-	_, err = a.Client.DeleteObject(req)
+	_, err := a.Client.DeleteObject(req)
 
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// Copy copies the object into dstObject of dstBucket on the server side with CopyObject, keeping its
+// metadata. CopyObject is limited to objects of up to 5 GB.
+func (a *AWSManager) Copy(srcBucket, srcObject, dstBucket, dstObject string) error {
+	if err := a.setup(); err != nil {
+		return err
+	}
+
+	source, err := escapeObjectName(srcObject)
+	if err != nil {
+		return err
+	}
+	err = observer.Call(a.Observer, "aws", "CopyObject", func() error {
+		_, err := a.Client.CopyObject(&s3.CopyObjectInput{
+			Bucket:     aws.String(dstBucket),
+			Key:        aws.String(dstObject),
+			CopySource: aws.String(url.PathEscape(srcBucket) + "/" + source),
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy '%s' to '%s': %w", srcObject, dstObject, err)
+	}
+	return nil
+}
+
+// ChangeStorageTier moves the object to the storage class of tier. S3 cannot change the class of an
+// object in place, so the object is copied onto itself with the new class: its metadata is kept, but it
+// gets a new ETag and modification time (and a new version on versioned buckets). CopyObject is limited
+// to 5 GB, and archived objects must be restored before they can be copied.
+func (a *AWSManager) ChangeStorageTier(bucket, key string, tier StorageTierEnum) error {
+	if err := a.setup(); err != nil {
+		return err
+	}
+
+	storageClass, err := awsStorageClass(tier)
+	if err != nil {
+		return err
+	}
+	source, err := escapeObjectName(key)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.Client.CopyObject(&s3.CopyObjectInput{
+		Bucket:            aws.String(bucket),
+		Key:               aws.String(key),
+		CopySource:        aws.String(url.PathEscape(bucket) + "/" + source),
+		MetadataDirective: aws.String(s3.MetadataDirectiveCopy),
+		StorageClass:      aws.String(storageClass),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to change storage tier of '%s' to %s: %w", key, tier, err)
+	}
+	return nil
+}
+
+// SetNotifications configures the bucket to notify the target of the configured events.
+// PutBucketNotificationConfiguration replaces the whole configuration, so any notification
+// previously set on the bucket is removed.
+func (a *AWSManager) SetNotifications(bucket string, config NotificationConfig) error {
+	if err := a.setup(); err != nil {
+		return err
+	}
+
+	notification, err := awsNotificationConfiguration(config)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.Client.PutBucketNotificationConfiguration(&s3.PutBucketNotificationConfigurationInput{
+		Bucket:                    aws.String(bucket),
+		NotificationConfiguration: notification,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set notifications of bucket '%s': %w", bucket, err)
+	}
+	return nil
+}
@@ -0,0 +1,631 @@
+package bucket
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/diegoyosiura/cloud-manager/pkg/observer"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestAWSManager returns an AWSManager whose S3 client talks to the given fake endpoint.
+func newTestAWSManager(t *testing.T, endpoint string) *AWSManager {
+	sess, err := session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String(endpoint),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("test-key", "test-secret", ""),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating session: %v", err)
+	}
+
+	return &AWSManager{Auth: &authentication.AWSAuth{Session: sess, Region: "us-east-1"}}
+}
+
+// s3ObjectXML renders a single <Contents> entry of a ListObjectsV2 response.
+func s3ObjectXML(key string, modified time.Time) string {
+	return fmt.Sprintf(`<Contents><Key>%s</Key><LastModified>%s</LastModified><Size>10</Size><StorageClass>STANDARD</StorageClass></Contents>`,
+		key, modified.UTC().Format("2006-01-02T15:04:05.000Z"))
+}
+
+// TestAWSManager_ListObjectsSince verifies that every page is read and only newer objects are returned.
+func TestAWSManager_ListObjectsSince(t *testing.T) {
+	since := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		if r.URL.Query().Get("continuation-token") == "" {
+			_, _ = fmt.Fprintf(w, `<ListBucketResult><Name>bucket</Name><IsTruncated>true</IsTruncated><NextContinuationToken>page-2</NextContinuationToken>%s%s</ListBucketResult>`,
+				s3ObjectXML("old.txt", since.Add(-time.Hour)),
+				s3ObjectXML("new-1.txt", since.Add(time.Hour)))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `<ListBucketResult><Name>bucket</Name><IsTruncated>false</IsTruncated>%s%s</ListBucketResult>`,
+			s3ObjectXML("same.txt", since),
+			s3ObjectXML("new-2.txt", since.Add(48*time.Hour)))
+	}))
+	defer server.Close()
+
+	objects, err := newTestAWSManager(t, server.URL).ListObjectsSince("bucket", since)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(objects) != 2 {
+		t.Fatalf("expected 2 objects, got %d: %v", len(objects), objects)
+	}
+	if objects[0].Key != "new-1.txt" || objects[1].Key != "new-2.txt" {
+		t.Errorf("unexpected objects returned: %s, %s", objects[0].Key, objects[1].Key)
+	}
+}
+
+// TestAWSManager_ListPrefix verifies that the prefix and delimiter are sent and that the objects and
+// common prefixes of every page are returned.
+func TestAWSManager_ListPrefix(t *testing.T) {
+	modified := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("prefix") != "photos/" || r.URL.Query().Get("delimiter") != "/" {
+			t.Errorf("unexpected listing query: %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		if r.URL.Query().Get("continuation-token") == "" {
+			_, _ = fmt.Fprintf(w, `<ListBucketResult><Name>bucket</Name><IsTruncated>true</IsTruncated><NextContinuationToken>page-2</NextContinuationToken>%s`+
+				`<CommonPrefixes><Prefix>photos/2023/</Prefix></CommonPrefixes></ListBucketResult>`, s3ObjectXML("photos/cover.jpg", modified))
+			return
+		}
+		_, _ = fmt.Fprint(w, `<ListBucketResult><Name>bucket</Name><IsTruncated>false</IsTruncated>`+
+			`<CommonPrefixes><Prefix>photos/2024/</Prefix></CommonPrefixes></ListBucketResult>`)
+	}))
+	defer server.Close()
+
+	objects, prefixes, err := newTestAWSManager(t, server.URL).ListPrefix("bucket", "photos/", "/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Key != "photos/cover.jpg" {
+		t.Errorf("unexpected objects returned: %v", objects)
+	}
+	if strings.Join(prefixes, ",") != "photos/2023/,photos/2024/" {
+		t.Errorf("unexpected prefixes returned: %v", prefixes)
+	}
+}
+
+// fakeS3MultipartHandler serves the multipart upload calls and PutObject, honoring "If-None-Match: *" on
+// completion and on PutObject.
+func fakeS3MultipartHandler(existing map[string]bool) http.HandlerFunc {
+	mu := &sync.Mutex{}
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodPost && query.Has("uploads"):
+			_, _ = fmt.Fprint(w, `<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+		case r.Method == http.MethodPut && query.Has("partNumber"):
+			w.Header().Set("ETag", `"etag-`+query.Get("partNumber")+`"`)
+		case r.Method == http.MethodPut:
+			if r.Header.Get("If-None-Match") == "*" && existing[r.URL.Path] {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			existing[r.URL.Path] = true
+			w.Header().Set("ETag", `"final"`)
+			w.Header().Set("x-amz-version-id", "version-1")
+		case r.Method == http.MethodPost && query.Has("uploadId"):
+			if r.Header.Get("If-None-Match") == "*" && existing[r.URL.Path] {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				_, _ = fmt.Fprint(w, `<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>`)
+				return
+			}
+			existing[r.URL.Path] = true
+			w.Header().Set("x-amz-version-id", "version-1")
+			_, _ = fmt.Fprint(w, `<CompleteMultipartUploadResult><ETag>"final"</ETag></CompleteMultipartUploadResult>`)
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}
+}
+
+// TestAWSManager_Upload_IfNotExists verifies that a second conditional upload to the same key fails with ErrObjectExists.
+func TestAWSManager_Upload_IfNotExists(t *testing.T) {
+	server := httptest.NewServer(fakeS3MultipartHandler(map[string]bool{}))
+	defer server.Close()
+
+	manager := newTestAWSManager(t, server.URL)
+	manager.IfNotExists = true
+
+	upload := func() error {
+		f, err := os.CreateTemp(t.TempDir(), "upload")
+		if err != nil {
+			t.Fatalf("unexpected error creating file: %v", err)
+		}
+		defer func() { _ = f.Close() }()
+		if _, err := f.WriteString("content"); err != nil {
+			t.Fatalf("unexpected error writing file: %v", err)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatalf("unexpected error seeking file: %v", err)
+		}
+		return manager.Upload("bucket", "object.txt", f, 0, 1)
+	}
+
+	if err := upload(); err != nil {
+		t.Fatalf("unexpected error on first upload: %v", err)
+	}
+	if err := upload(); !errors.Is(err, ErrObjectExists) {
+		t.Fatalf("expected ErrObjectExists on second upload, got %v", err)
+	}
+}
+
+// TestAWSManager_Upload_Parallel verifies that parts are uploaded concurrently, completed in ascending
+// order, and that a failed part aborts the multipart upload.
+func TestAWSManager_Upload_Parallel(t *testing.T) {
+	var mu sync.Mutex
+	var inFlight, maxInFlight int
+	var completed []int
+	aborted := false
+	failPart := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodPost && query.Has("uploads"):
+			_, _ = fmt.Fprint(w, `<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+		case r.Method == http.MethodPut && query.Has("partNumber"):
+			mu.Lock()
+			inFlight++
+			maxInFlight = max(maxInFlight, inFlight)
+			fail := query.Get("partNumber") == failPart
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			if fail {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = fmt.Fprint(w, `<Error><Code>InvalidPart</Code><Message>Part rejected</Message></Error>`)
+				return
+			}
+			w.Header().Set("ETag", `"etag-`+query.Get("partNumber")+`"`)
+		case r.Method == http.MethodPost && query.Has("uploadId"):
+			var body struct {
+				Parts []struct {
+					PartNumber int `xml:"PartNumber"`
+				} `xml:"Part"`
+			}
+			_ = xml.NewDecoder(r.Body).Decode(&body)
+			mu.Lock()
+			for _, part := range body.Parts {
+				completed = append(completed, part.PartNumber)
+			}
+			mu.Unlock()
+			_, _ = fmt.Fprint(w, `<CompleteMultipartUploadResult><ETag>"final"</ETag></CompleteMultipartUploadResult>`)
+		case r.Method == http.MethodDelete:
+			mu.Lock()
+			aborted = true
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	defer server.Close()
+
+	f, err := os.CreateTemp(t.TempDir(), "upload")
+	if err != nil {
+		t.Fatalf("unexpected error creating file: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.Write(make([]byte, 5*131072)); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+
+	manager := newTestAWSManager(t, server.URL)
+	upload := func() (UploadResult, error) {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatalf("unexpected error seeking file: %v", err)
+		}
+		return manager.UploadWithResult("bucket", "object.bin", f, 131072, 3)
+	}
+
+	result, err := upload()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Size != 5*131072 {
+		t.Errorf("unexpected upload result: %+v", result)
+	}
+	if maxInFlight < 2 || maxInFlight > 3 {
+		t.Errorf("expected 2 to 3 parts in flight, got %d", maxInFlight)
+	}
+	if fmt.Sprint(completed) != "[1 2 3 4 5]" {
+		t.Errorf("expected the parts completed in order, got %v", completed)
+	}
+
+	failPart = "2"
+	if _, err := upload(); err == nil {
+		t.Fatal("expected the failed part to fail the upload")
+	}
+	if !aborted {
+		t.Error("expected the multipart upload to be aborted")
+	}
+}
+
+// TestAWSManager_Upload_VerifySize verifies that an object stored with fewer bytes than were sent fails with ErrSizeMismatch.
+func TestAWSManager_Upload_VerifySize(t *testing.T) {
+	var mu sync.Mutex
+	var received, lost int64
+	multipart := fakeS3MultipartHandler(map[string]bool{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodHead:
+			mu.Lock()
+			w.Header().Set("Content-Length", fmt.Sprint(received-lost))
+			mu.Unlock()
+		case r.Method == http.MethodPut && r.URL.Query().Has("partNumber"):
+			n, _ := io.Copy(io.Discard, r.Body)
+			mu.Lock()
+			received += n
+			mu.Unlock()
+			multipart(w, r)
+		default:
+			multipart(w, r)
+		}
+	}))
+	defer server.Close()
+
+	manager := newTestAWSManager(t, server.URL)
+	manager.VerifySize = true
+
+	f, err := os.CreateTemp(t.TempDir(), "upload")
+	if err != nil {
+		t.Fatalf("unexpected error creating file: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.Write(make([]byte, 300*1024)); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+
+	upload := func() error {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatalf("unexpected error seeking file: %v", err)
+		}
+		mu.Lock()
+		received = 0
+		mu.Unlock()
+		return manager.Upload("bucket", "object.bin", f, 131072, 1)
+	}
+
+	if err := upload(); err != nil {
+		t.Fatalf("unexpected error on complete upload: %v", err)
+	}
+
+	// The store keeps one byte less than it received, as if a part had been truncated.
+	mu.Lock()
+	lost = 1
+	mu.Unlock()
+	if err := upload(); !errors.Is(err, ErrSizeMismatch) {
+		t.Fatalf("expected ErrSizeMismatch, got %v", err)
+	}
+}
+
+// TestAWSManager_UploadWithResult verifies that the ETag and version ID of the completed upload are returned.
+func TestAWSManager_UploadWithResult(t *testing.T) {
+	server := httptest.NewServer(fakeS3MultipartHandler(map[string]bool{}))
+	defer server.Close()
+
+	f, err := os.CreateTemp(t.TempDir(), "upload")
+	if err != nil {
+		t.Fatalf("unexpected error creating file: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.WriteString("content"); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("unexpected error seeking file: %v", err)
+	}
+
+	result, err := newTestAWSManager(t, server.URL).UploadWithResult("bucket", "object.txt", f, 0, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ETag != `"final"` || result.VersionID != "version-1" || result.Size != int64(len("content")) {
+		t.Errorf("unexpected upload result: %+v", result)
+	}
+}
+
+// TestAWSManager_UploadStream verifies that content read from a bytes.Reader is uploaded in parts, and that
+// a stream shorter than the announced size aborts the multipart upload with ErrSizeMismatch.
+func TestAWSManager_UploadStream(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		parts   = map[string][]byte{}
+		aborted bool
+	)
+	multipart := fakeS3MultipartHandler(map[string]bool{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		switch {
+		case r.Method == http.MethodPut && r.URL.Query().Has("partNumber"):
+			body, _ := io.ReadAll(r.Body)
+			parts[r.URL.Query().Get("partNumber")] = body
+		case r.Method == http.MethodDelete:
+			aborted = true
+		}
+		mu.Unlock()
+		multipart(w, r)
+	}))
+	defer server.Close()
+
+	content := bytes.Repeat([]byte("0123456789"), (2*131072+10)/10)
+	manager := newTestAWSManager(t, server.URL)
+	if err := manager.UploadStream("bucket", "object.bin", bytes.NewReader(content), int64(len(content)), 131072, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := append(append(append([]byte{}, parts["1"]...), parts["2"]...), parts["3"]...)
+	if len(parts) != 3 || !bytes.Equal(got, content) {
+		t.Errorf("unexpected parts: %d parts, %d bytes", len(parts), len(got))
+	}
+	if aborted {
+		t.Error("expected the complete upload not to be aborted")
+	}
+
+	err := manager.UploadStream("bucket", "object.bin", bytes.NewReader(content), int64(len(content))+1, 131072, 2)
+	if !errors.Is(err, ErrSizeMismatch) {
+		t.Fatalf("expected ErrSizeMismatch, got %v", err)
+	}
+	if !aborted {
+		t.Error("expected the short upload to be aborted")
+	}
+}
+
+// TestAWSManager_UploadStream_Empty verifies that an empty stream is stored with a single conditional
+// PutObject, since a multipart upload cannot be completed without parts.
+func TestAWSManager_UploadStream_Empty(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+		body     []byte
+	)
+	multipart := fakeS3MultipartHandler(map[string]bool{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.RawQuery+" "+r.Header.Get("If-None-Match"))
+		body, _ = io.ReadAll(r.Body)
+		mu.Unlock()
+		multipart(w, r)
+	}))
+	defer server.Close()
+
+	manager := newTestAWSManager(t, server.URL)
+	manager.IfNotExists = true
+	if err := manager.UploadStream("bucket", "empty.txt", bytes.NewReader(nil), 0, 0, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requests) != 1 || requests[0] != "PUT  *" || len(body) != 0 {
+		t.Errorf("expected a single conditional PutObject of no bytes, got %q (%d bytes)", requests, len(body))
+	}
+
+	if err := manager.UploadStream("bucket", "empty.txt", bytes.NewReader(nil), 0, 0, 1); !errors.Is(err, ErrObjectExists) {
+		t.Errorf("expected ErrObjectExists on the second upload, got %v", err)
+	}
+}
+
+// TestAWSManager_Observer verifies the observations emitted for a listing and a multipart upload.
+func TestAWSManager_Observer(t *testing.T) {
+	multipart := fakeS3MultipartHandler(map[string]bool{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2" {
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = fmt.Fprintf(w, `<ListBucketResult><Name>bucket</Name><IsTruncated>false</IsTruncated>%s</ListBucketResult>`,
+				s3ObjectXML("object.txt", time.Now()))
+			return
+		}
+		multipart(w, r)
+	}))
+	defer server.Close()
+
+	memory := observer.NewMemory()
+	manager := newTestAWSManager(t, server.URL)
+	manager.Observer = memory
+
+	if _, err := manager.ListObjectsSince("bucket", time.Time{}); err != nil {
+		t.Fatalf("unexpected error listing: %v", err)
+	}
+	if got := memory.Counter(observer.APICalls, map[string]string{"provider": "aws", "operation": "ListObjectsV2", "outcome": "success"}); got != 1 {
+		t.Errorf("expected 1 successful ListObjectsV2 call, got %d", got)
+	}
+
+	f, err := os.CreateTemp(t.TempDir(), "upload")
+	if err != nil {
+		t.Fatalf("unexpected error creating file: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.Write(make([]byte, 300*1024)); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("unexpected error seeking file: %v", err)
+	}
+	if err := manager.Upload("bucket", "object.bin", f, 131072, 1); err != nil {
+		t.Fatalf("unexpected error uploading: %v", err)
+	}
+
+	if got := memory.Counter(observer.PartsUploaded, map[string]string{"provider": "aws"}); got != 3 {
+		t.Errorf("expected 3 parts uploaded, got %d", got)
+	}
+	if got := memory.Counter(observer.APICalls, map[string]string{"provider": "aws", "operation": "UploadPart", "outcome": "success"}); got != 3 {
+		t.Errorf("expected 3 successful UploadPart calls, got %d", got)
+	}
+	if got := memory.Bytes(observer.BytesUploaded); got != 300*1024 {
+		t.Errorf("expected %d bytes uploaded, got %d", 300*1024, got)
+	}
+}
+
+// TestAWSManager_DownloadWithOptions_VerifyChecksum verifies that corrupted content is detected through
+// the ETag of single-part objects and the x-amz-checksum-sha256 of multipart ones.
+func TestAWSManager_DownloadWithOptions_VerifyChecksum(t *testing.T) {
+	content := []byte("original content")
+	md5Sum := md5.Sum(content)
+	sha256Sum := sha256.Sum256(content)
+
+	var checksumMode string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checksumMode = r.Header.Get("x-amz-checksum-mode")
+		switch r.URL.Path {
+		case "/bucket/single.txt":
+			w.Header().Set("ETag", fmt.Sprintf(`"%x"`, md5Sum))
+			_, _ = w.Write(content)
+		case "/bucket/corrupted.txt":
+			w.Header().Set("ETag", fmt.Sprintf(`"%x"`, md5Sum))
+			_, _ = w.Write([]byte("corrupted content"))
+		case "/bucket/multipart.bin":
+			w.Header().Set("ETag", `"0123456789abcdef0123456789abcdef-2"`)
+			w.Header().Set("x-amz-checksum-sha256", base64.StdEncoding.EncodeToString(sha256Sum[:]))
+			_, _ = w.Write([]byte("corrupted content"))
+		case "/bucket/unverifiable.bin":
+			w.Header().Set("ETag", `"0123456789abcdef0123456789abcdef-2"`)
+			_, _ = w.Write(content)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	manager := newTestAWSManager(t, server.URL)
+	opts := DownloadOptions{VerifyChecksum: true}
+
+	var out bytes.Buffer
+	if err := manager.DownloadWithOptions("bucket", "single.txt", &out, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != string(content) {
+		t.Errorf("expected %q, got %q", content, out.String())
+	}
+	if checksumMode != "ENABLED" {
+		t.Errorf("expected the checksum mode to be enabled, got '%s'", checksumMode)
+	}
+
+	if err := manager.DownloadWithOptions("bucket", "corrupted.txt", io.Discard, opts); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch for a corrupted single-part object, got %v", err)
+	}
+	if err := manager.DownloadWithOptions("bucket", "multipart.bin", io.Discard, opts); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch for a corrupted multipart object, got %v", err)
+	}
+	if err := manager.DownloadWithOptions("bucket", "unverifiable.bin", io.Discard, opts); err == nil || errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected an error for an object without a verifiable checksum, got %v", err)
+	}
+
+	// Without verification, the corrupted content is written as received.
+	if err := manager.Download("bucket", "corrupted.txt", io.Discard); err != nil {
+		t.Errorf("unexpected error without verification: %v", err)
+	}
+}
+
+// TestAWSManager_ChangeStorageTier verifies that the object is copied onto itself with the new storage class.
+func TestAWSManager_ChangeStorageTier(t *testing.T) {
+	var method, path, source, storageClass, directive string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.EscapedPath()
+		source = r.Header.Get("x-amz-copy-source")
+		storageClass = r.Header.Get("x-amz-storage-class")
+		directive = r.Header.Get("x-amz-metadata-directive")
+		_, _ = fmt.Fprint(w, `<CopyObjectResult><ETag>"etag"</ETag><LastModified>2024-01-01T00:00:00.000Z</LastModified></CopyObjectResult>`)
+	}))
+	defer server.Close()
+
+	manager := newTestAWSManager(t, server.URL)
+	if err := manager.ChangeStorageTier("bucket", "logs/2024 01.txt", STierLowAccess); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if method != http.MethodPut || path != "/bucket/logs/2024%2001.txt" {
+		t.Errorf("expected a PUT onto the object itself, got %s %s", method, path)
+	}
+	if source != "bucket/logs/2024%2001.txt" || storageClass != "STANDARD_IA" || directive != "COPY" {
+		t.Errorf("unexpected copy source %q, storage class %q or metadata directive %q", source, storageClass, directive)
+	}
+
+	if err := manager.ChangeStorageTier("bucket", "logs/2024 01.txt", "COLD"); err == nil {
+		t.Error("expected an error for an unsupported tier")
+	}
+}
+
+// TestAWSManager_Copy verifies that the copy source is escaped segment by segment, so keys with spaces
+// and non-ASCII characters reach S3 intact.
+func TestAWSManager_Copy(t *testing.T) {
+	var method, path, source string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.EscapedPath()
+		source = r.Header.Get("x-amz-copy-source")
+		_, _ = fmt.Fprint(w, `<CopyObjectResult><ETag>"etag"</ETag><LastModified>2024-01-01T00:00:00.000Z</LastModified></CopyObjectResult>`)
+	}))
+	defer server.Close()
+
+	manager := newTestAWSManager(t, server.URL)
+	if err := manager.Copy("source bucket", "relatórios/año 2024.pdf", "backup", "copies/año 2024.pdf"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if method != http.MethodPut || path != "/backup/copies/a%C3%B1o%202024.pdf" {
+		t.Errorf("expected a PUT of the destination key, got %s %s", method, path)
+	}
+	if source != "source%20bucket/relat%C3%B3rios/a%C3%B1o%202024.pdf" {
+		t.Errorf("unexpected copy source %q", source)
+	}
+
+	if err := manager.Copy("bucket", "", "backup", "copy"); err == nil {
+		t.Error("expected an error without a source object")
+	}
+}
+
+// TestAWSManager_UploadLink verifies that the presigned URL uploads the object with a PUT of its content.
+func TestAWSManager_UploadLink(t *testing.T) {
+	var method, path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.EscapedPath(), string(data)
+	}))
+	defer server.Close()
+
+	link, err := newTestAWSManager(t, server.URL).UploadLink("bucket", "dir/a b.txt", 15)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("unexpected error parsing the URL: %v", err)
+	}
+	if query := parsed.Query(); query.Get("X-Amz-Expires") != "900" || query.Get("X-Amz-Signature") == "" {
+		t.Errorf("unexpected presigned query: %v", query)
+	}
+
+	req, _ := http.NewRequest(http.MethodPut, link, strings.NewReader("content"))
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error uploading: %v", err)
+	}
+	_ = resp.Body.Close()
+	if method != http.MethodPut || path != "/bucket/dir/a%20b.txt" || body != "content" {
+		t.Errorf("expected a PUT of the content to the object, got %s %s %q", method, path, body)
+	}
+}
@@ -0,0 +1,1042 @@
+package bucket
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/diegoyosiura/cloud-manager/pkg/backoff"
+	"github.com/diegoyosiura/cloud-manager/pkg/observer"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// azureStorageAPIVersion is the Blob service version sent with the requests and signed into the SAS tokens.
+const azureStorageAPIVersion = "2021-08-06"
+
+// azureEventGridAPIVersion is the Microsoft.EventGrid API version used by SetNotifications.
+const azureEventGridAPIVersion = "2022-06-15"
+
+// azureStorageScope is the token scope of the Blob service requests.
+const azureStorageScope = "https://storage.azure.com/.default"
+
+// azureStorageError is an error response of the Blob service or of the Resource Manager.
+type azureStorageError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *azureStorageError) Error() string {
+	return fmt.Sprintf("azure request failed with status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// AzureBlobProperties are the properties of a blob, as listed by the Blob service.
+type AzureBlobProperties struct {
+	LastModified  string `xml:"Last-Modified"` // RFC 1123 time of the last change.
+	Etag          string `xml:"Etag"`
+	ContentLength int64  `xml:"Content-Length"`
+	AccessTier    string `xml:"AccessTier"` // "Hot", "Cool", "Cold" or "Archive".
+	ContentType   string `xml:"Content-Type"`
+}
+
+// AzureBlob is a blob of a container listing.
+type AzureBlob struct {
+	Name       string              `xml:"Name"`
+	Properties AzureBlobProperties `xml:"Properties"`
+}
+
+// AzureUserDelegationKey is a key obtained with the Microsoft Entra credential to sign user-delegation SAS tokens.
+type AzureUserDelegationKey struct {
+	SignedOid     string `xml:"SignedOid"`
+	SignedTid     string `xml:"SignedTid"`
+	SignedStart   string `xml:"SignedStart"`
+	SignedExpiry  string `xml:"SignedExpiry"`
+	SignedService string `xml:"SignedService"`
+	SignedVersion string `xml:"SignedVersion"`
+	Value         string `xml:"Value"` // Base64 encoded signing key.
+}
+
+// AzureStorageClient calls the Blob service REST API of one storage account, and the Event Grid
+// operations of the Resource Manager for its notifications.
+//
+// The client only covers the calls the manager makes, authenticated with the azcore credential
+// the module already depends on, rather than adding the azblob SDK. The SAS tokens it signs are
+// tested against a vector produced by the sas package of azblob.
+type AzureStorageClient struct {
+	Credential         azcore.TokenCredential // Credential used to obtain the Storage and Resource Manager tokens.
+	Account            string                 // Storage account whose containers are managed.
+	Endpoint           string                 // Blob service endpoint (defaults to https://<account>.blob.core.windows.net).
+	ManagementEndpoint string                 // Resource Manager endpoint (defaults to https://management.azure.com).
+	HTTPClient         *http.Client           // HTTP client used for the requests (defaults to http.DefaultClient).
+}
+
+// url returns the URL of the blob of the container on the Blob service endpoint. An empty container
+// addresses the account and an empty blob the container.
+func (c *AzureStorageClient) url(container, blob string, query url.Values) (string, error) {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = "https://" + c.Account + ".blob.core.windows.net"
+	}
+	target := strings.TrimRight(endpoint, "/") + "/" + url.PathEscape(container)
+	if blob != "" {
+		key, err := escapeObjectName(blob)
+		if err != nil {
+			return "", err
+		}
+		target += "/" + key
+	}
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	return target, nil
+}
+
+// send authorizes req with a token of scope and sends it, converting error responses into
+// *azureStorageError. The caller closes the body of the returned response.
+func (c *AzureStorageClient) send(req *http.Request, scope string) (*http.Response, error) {
+	token, err := c.Credential.GetToken(req.Context(), policy.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Azure token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	if scope == azureStorageScope {
+		req.Header.Set("x-ms-version", azureStorageAPIVersion)
+		req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer func() { _ = resp.Body.Close() }()
+		// The Blob service answers in XML and the Resource Manager in JSON; HEAD responses have
+		// no body, only the error code header
+		respErr := &azureStorageError{StatusCode: resp.StatusCode}
+		data, _ := io.ReadAll(resp.Body)
+		var xmlBody struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		var jsonBody struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if xml.Unmarshal(data, &xmlBody) == nil {
+			respErr.Code, respErr.Message = xmlBody.Code, xmlBody.Message
+		} else if json.Unmarshal(data, &jsonBody) == nil {
+			respErr.Code, respErr.Message = jsonBody.Error.Code, jsonBody.Error.Message
+		}
+		if respErr.Code == "" {
+			respErr.Code = resp.Header.Get("x-ms-error-code")
+		}
+		return nil, respErr
+	}
+	return resp, nil
+}
+
+// do sends a Blob service request with body, when not nil, and decodes the XML response into out,
+// when not nil. The headers of the response are returned.
+func (c *AzureStorageClient) do(ctx context.Context, method, container, blob string, query url.Values, header http.Header, body []byte, out interface{}) (http.Header, error) {
+	target, err := c.url(container, blob, query)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := c.send(req, azureStorageScope)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp.Header, nil
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(out); err != nil {
+		return nil, fmt.Errorf("failed to decode Azure response: %w", err)
+	}
+	return resp.Header, nil
+}
+
+// ListContainers returns the names of the containers of the account.
+func (c *AzureStorageClient) ListContainers(ctx context.Context) ([]string, error) {
+	query := url.Values{"comp": {"list"}}
+	var r []string
+	for {
+		var page struct {
+			Containers []struct {
+				Name string `xml:"Name"`
+			} `xml:"Containers>Container"`
+			NextMarker string `xml:"NextMarker"`
+		}
+		if _, err := c.do(ctx, http.MethodGet, "", "", query, nil, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, container := range page.Containers {
+			r = append(r, container.Name)
+		}
+		if page.NextMarker == "" {
+			return r, nil
+		}
+		query.Set("marker", page.NextMarker)
+	}
+}
+
+// ListBlobsPage returns one page of up to limit blobs of the container (the service default when not
+// positive) starting at the marker, and the marker of the next page, empty after the last one.
+func (c *AzureStorageClient) ListBlobsPage(ctx context.Context, container, marker string, limit int) ([]AzureBlob, string, error) {
+	query := url.Values{"restype": {"container"}, "comp": {"list"}}
+	if marker != "" {
+		query.Set("marker", marker)
+	}
+	if limit > 0 {
+		query.Set("maxresults", strconv.Itoa(limit))
+	}
+	var page struct {
+		Blobs      []AzureBlob `xml:"Blobs>Blob"`
+		NextMarker string      `xml:"NextMarker"`
+	}
+	if _, err := c.do(ctx, http.MethodGet, container, "", query, nil, nil, &page); err != nil {
+		return nil, "", err
+	}
+	return page.Blobs, page.NextMarker, nil
+}
+
+// ListBlobsPages lists the blobs of the container whose names start with prefix, calling fn for
+// every page until it returns false.
+func (c *AzureStorageClient) ListBlobsPages(ctx context.Context, container, prefix string, fn func(blobs []AzureBlob) bool) error {
+	query := url.Values{"restype": {"container"}, "comp": {"list"}}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	for {
+		var page struct {
+			Blobs      []AzureBlob `xml:"Blobs>Blob"`
+			NextMarker string      `xml:"NextMarker"`
+		}
+		if _, err := c.do(ctx, http.MethodGet, container, "", query, nil, nil, &page); err != nil {
+			return err
+		}
+		if !fn(page.Blobs) || page.NextMarker == "" {
+			return nil
+		}
+		query.Set("marker", page.NextMarker)
+	}
+}
+
+// ListBlobsHierarchy lists every blob of the container whose name starts with prefix. With a delimiter,
+// the names containing it after the prefix are returned as prefixes instead of blobs.
+func (c *AzureStorageClient) ListBlobsHierarchy(ctx context.Context, container, prefix, delimiter string) ([]AzureBlob, []string, error) {
+	query := url.Values{"restype": {"container"}, "comp": {"list"}}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
+
+	var blobs []AzureBlob
+	var prefixes []string
+	for {
+		var page struct {
+			Blobs    []AzureBlob `xml:"Blobs>Blob"`
+			Prefixes []struct {
+				Name string `xml:"Name"`
+			} `xml:"Blobs>BlobPrefix"`
+			NextMarker string `xml:"NextMarker"`
+		}
+		if _, err := c.do(ctx, http.MethodGet, container, "", query, nil, nil, &page); err != nil {
+			return nil, nil, err
+		}
+		blobs = append(blobs, page.Blobs...)
+		for _, p := range page.Prefixes {
+			prefixes = append(prefixes, p.Name)
+		}
+		if page.NextMarker == "" {
+			return blobs, prefixes, nil
+		}
+		query.Set("marker", page.NextMarker)
+	}
+}
+
+// GetBlobProperties returns the properties of a blob.
+func (c *AzureStorageClient) GetBlobProperties(ctx context.Context, container, blob string) (AzureBlob, error) {
+	header, err := c.do(ctx, http.MethodHead, container, blob, nil, nil, nil, nil)
+	if err != nil {
+		return AzureBlob{}, err
+	}
+	size, _ := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	return AzureBlob{Name: blob, Properties: AzureBlobProperties{
+		LastModified:  header.Get("Last-Modified"),
+		Etag:          header.Get("ETag"),
+		ContentLength: size,
+		AccessTier:    header.Get("x-ms-access-tier"),
+		ContentType:   header.Get("Content-Type"),
+	}}, nil
+}
+
+// ReadBlob returns the content of a blob. When end is not negative, only the bytes from offset to end
+// (inclusive) are returned. A non-empty etag makes the read fail if the blob was replaced since.
+func (c *AzureStorageClient) ReadBlob(ctx context.Context, container, blob string, offset, end int64, etag string) (io.ReadCloser, error) {
+	target, err := c.url(container, blob, nil)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if end >= 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, end))
+	}
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+
+	resp, err := c.send(req, azureStorageScope)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// PutBlock stages a block of a block blob, committed later by PutBlockList.
+func (c *AzureStorageClient) PutBlock(ctx context.Context, container, blob, blockID string, data []byte) error {
+	_, err := c.do(ctx, http.MethodPut, container, blob, url.Values{"comp": {"block"}, "blockid": {blockID}}, nil, data, nil)
+	return err
+}
+
+// PutBlockList commits the staged blocks, in order, as the content of the blob. header holds the
+// access tier and the preconditions of the write. The headers of the response are returned.
+func (c *AzureStorageClient) PutBlockList(ctx context.Context, container, blob string, blockIDs []string, header http.Header) (http.Header, error) {
+	data, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"BlockList"`
+		Latest  []string `xml:"Latest"`
+	}{Latest: blockIDs})
+	if err != nil {
+		return nil, err
+	}
+	return c.do(ctx, http.MethodPut, container, blob, url.Values{"comp": {"blocklist"}}, header, append([]byte(xml.Header), data...), nil)
+}
+
+// GetUserDelegationKey returns a key valid from start to expiry (at most 7 days later) to sign user-delegation SAS tokens.
+func (c *AzureStorageClient) GetUserDelegationKey(ctx context.Context, start, expiry time.Time) (AzureUserDelegationKey, error) {
+	data, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"KeyInfo"`
+		Start   string   `xml:"Start"`
+		Expiry  string   `xml:"Expiry"`
+	}{Start: start.UTC().Format(time.RFC3339), Expiry: expiry.UTC().Format(time.RFC3339)})
+	if err != nil {
+		return AzureUserDelegationKey{}, err
+	}
+
+	var key AzureUserDelegationKey
+	_, err = c.do(ctx, http.MethodPost, "", "", url.Values{"restype": {"service"}, "comp": {"userdelegationkey"}}, nil, append([]byte(xml.Header), data...), &key)
+	return key, err
+}
+
+// PutEventSubscription creates or replaces the Event Grid subscription name on the Azure resource scope
+// (e.g. a storage account ID). The subscription is provisioned asynchronously after the call returns.
+func (c *AzureStorageClient) PutEventSubscription(ctx context.Context, scope, name string, subscription interface{}) error {
+	data, err := json.Marshal(subscription)
+	if err != nil {
+		return err
+	}
+
+	endpoint := c.ManagementEndpoint
+	if endpoint == "" {
+		endpoint = "https://management.azure.com"
+	}
+	target := strings.TrimRight(endpoint, "/") + scope + "/providers/Microsoft.EventGrid/eventSubscriptions/" + url.PathEscape(name) +
+		"?api-version=" + azureEventGridAPIVersion
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.send(req, "https://management.azure.com/.default")
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// AzureManager manages the containers of an Azure Storage account as buckets through the Blob service
+// REST API, authenticated with the credential of Auth.
+type AzureManager struct {
+	Auth          *authentication.AzureAuth // Azure authentication details.
+	Client        *AzureStorageClient       // Storage client, created from Auth on first use.
+	ResourceGroup string                    // Resource group of the storage account, required by SetNotifications.
+	StorageTier   StorageTierEnum           // Access tier of uploaded blobs (defaults to the account's default tier).
+	IfNotExists   bool                      // Makes uploads fail with ErrObjectExists instead of overwriting an existing blob (see UploadOptions).
+	Backoff       backoff.Backoff           // Polling of Copy while the blob is copied (defaults to every second, without limit).
+
+	Observer observer.Observer // Receives the API call, part and byte metrics when set (optional).
+
+	uploads inflightUploads // Uploads in progress, cancelled by Shutdown.
+}
+
+// setup creates the storage client from the authenticated credentials when it is not set.
+func (a *AzureManager) setup() error {
+	if a.Client != nil {
+		return nil
+	}
+	if a.Auth == nil || a.Auth.TokenCredential() == nil {
+		return errors.New("azure credentials are not initialized; authenticate first")
+	}
+	if a.Auth.StorageAccount == "" {
+		return errors.New("azure storage account is not set")
+	}
+	a.Client = &AzureStorageClient{Credential: a.Auth.TokenCredential(), Account: a.Auth.StorageAccount}
+	return nil
+}
+
+func (a *AzureManager) ListBuckets() ([]string, error) {
+	if err := a.setup(); err != nil {
+		return nil, err
+	}
+	return a.Client.ListContainers(context.Background())
+}
+
+func (a *AzureManager) List(name string) (r []BucketObject, err error) {
+	return listAllPages(a, name)
+}
+
+// ListPage returns up to limit blobs of the container (5000 at most, and by default) starting at the
+// marker, which is empty for the first page, and the marker of the next page, empty after the last one.
+func (a *AzureManager) ListPage(name, token string, limit int) ([]BucketObject, string, error) {
+	if err := a.setup(); err != nil {
+		return nil, "", err
+	}
+
+	var blobs []AzureBlob
+	var next string
+	err := observer.Call(a.Observer, "azure", "ListBlobs", func() (err error) {
+		blobs, next, err = a.Client.ListBlobsPage(context.Background(), name, token, limit)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	r := make([]BucketObject, 0, len(blobs))
+	for _, b := range blobs {
+		r = append(r, NewBucketObjectFromAzure(b))
+	}
+	return r, next, nil
+}
+
+// ListObjectsSince returns the blobs of the container modified after since. The Blob service has no
+// filter on modification time, so every page of the listing is fetched and filtered on the client.
+func (a *AzureManager) ListObjectsSince(name string, since time.Time) ([]BucketObject, error) {
+	return a.listObjects(name, func(o BucketObject) bool { return o.LastModified.After(since) })
+}
+
+// listObjects returns the blobs of the container accepted by keep.
+func (a *AzureManager) listObjects(name string, keep func(BucketObject) bool) ([]BucketObject, error) {
+	if err := a.setup(); err != nil {
+		return nil, err
+	}
+
+	var r []BucketObject
+	err := observer.Call(a.Observer, "azure", "ListBlobs", func() error {
+		return a.Client.ListBlobsPages(context.Background(), name, "", func(blobs []AzureBlob) bool {
+			for _, b := range blobs {
+				if object := NewBucketObjectFromAzure(b); keep(object) {
+					r = append(r, object)
+				}
+			}
+			return true
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// StreamObjects lists the blobs of the container whose names start with prefix, page by page in a goroutine,
+// sending each one on the returned object channel as the caller receives them, so memory stays bounded.
+func (a *AzureManager) StreamObjects(ctx context.Context, name, prefix string) (<-chan BucketObject, <-chan error) {
+	return streamObjects(ctx, func(send func(BucketObject) bool) error {
+		if err := a.setup(); err != nil {
+			return err
+		}
+		return a.Client.ListBlobsPages(ctx, name, prefix, func(blobs []AzureBlob) bool {
+			for _, b := range blobs {
+				if !send(NewBucketObjectFromAzure(b)) {
+					return false
+				}
+			}
+			return true
+		})
+	})
+}
+
+// ListPrefix returns the blobs of the container whose names start with prefix. With a delimiter such as
+// "/", the names containing it after the prefix are grouped into the returned prefixes (the "subfolders").
+func (a *AzureManager) ListPrefix(bucket, prefix, delimiter string) ([]BucketObject, []string, error) {
+	if err := a.setup(); err != nil {
+		return nil, nil, err
+	}
+
+	var blobs []AzureBlob
+	var prefixes []string
+	err := observer.Call(a.Observer, "azure", "ListBlobs", func() (err error) {
+		blobs, prefixes, err = a.Client.ListBlobsHierarchy(context.Background(), bucket, prefix, delimiter)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var objects []BucketObject
+	for _, b := range blobs {
+		objects = append(objects, NewBucketObjectFromAzure(b))
+	}
+	return objects, prefixes, nil
+}
+
+// Create creates the container. Containers are ready as soon as the call returns, so waitCreate has no effect.
+func (a *AzureManager) Create(name string, waitCreate bool) error {
+	if err := a.setup(); err != nil {
+		return err
+	}
+	_, err := a.Client.do(context.Background(), http.MethodPut, name, "", url.Values{"restype": {"container"}}, nil, nil, nil)
+	return err
+}
+
+// Exists reports whether the container exists. A 404 means the container does not exist; other
+// failures, such as a 403, are returned as errors.
+func (a *AzureManager) Exists(name string) (bool, error) {
+	if err := a.setup(); err != nil {
+		return false, err
+	}
+
+	_, err := a.Client.do(context.Background(), http.MethodHead, name, "", url.Values{"restype": {"container"}}, nil, nil, nil)
+	if err != nil {
+		var storageErr *azureStorageError
+		if errors.As(err, &storageErr) && storageErr.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check container '%s': %w", name, err)
+	}
+	return true, nil
+}
+
+// CreateIfNotExists creates the container like Create unless it already exists, reporting whether it was created.
+func (a *AzureManager) CreateIfNotExists(name string, waitCreate bool) (bool, error) {
+	return createIfNotExists(a, name, waitCreate)
+}
+
+// Delete deletes the container and its blobs. The name stays unavailable for a while after the call
+// returns, as the service removes the container in the background.
+func (a *AzureManager) Delete(name string) error {
+	if err := a.setup(); err != nil {
+		return err
+	}
+	_, err := a.Client.do(context.Background(), http.MethodDelete, name, "", url.Values{"restype": {"container"}}, nil, nil, nil)
+	return err
+}
+
+func (a *AzureManager) Upload(bucket string, objectName string, f *os.File, partSize int64, threads int) error {
+	_, err := a.UploadWithResult(bucket, objectName, f, partSize, threads)
+	return err
+}
+
+// UploadWithResult uploads the file as a block blob, staging blocks of partSize bytes up to threads at
+// once (10 MiB and 4 by default) and committing them with the manager's access tier. Blocks of a
+// failed upload are never committed and the service discards them. The VersionID of the result is
+// only set for accounts with blob versioning.
+func (a *AzureManager) UploadWithResult(bucket string, objectName string, f *os.File, partSize int64, threads int) (UploadResult, error) {
+	return a.uploadStream(bucket, objectName, f, partSize, threads, UploadOptions{IfNotExists: a.IfNotExists})
+}
+
+// UploadWithOptions uploads the file like UploadWithResult. With opts.IfNotExists, the block list is
+// committed on the condition that the blob does not exist yet, failing with ErrObjectExists otherwise;
+// the IfNotExists field of the manager applies as well.
+func (a *AzureManager) UploadWithOptions(bucket string, objectName string, f *os.File, partSize int64, threads int, opts UploadOptions) (UploadResult, error) {
+	opts.IfNotExists = opts.IfNotExists || a.IfNotExists
+	return a.uploadStream(bucket, objectName, f, partSize, threads, opts)
+}
+
+// UploadStream uploads the content read from r like Upload; size is the number of bytes r yields,
+// or -1 when unknown.
+func (a *AzureManager) UploadStream(bucket, object string, r io.Reader, size int64, partSize int64, threads int) error {
+	_, err := a.uploadStream(bucket, object, expectSize(r, size), partSize, threads, UploadOptions{IfNotExists: a.IfNotExists})
+	return err
+}
+
+func (a *AzureManager) uploadStream(bucket string, objectName string, r io.Reader, partSize int64, threads int, opts UploadOptions) (UploadResult, error) {
+	if err := a.setup(); err != nil {
+		return UploadResult{}, err
+	}
+
+	if partSize < 131072 { // 128 * 1024
+		partSize = 10 * 1024 * 1024
+	}
+	if threads <= 0 {
+		threads = 4
+	}
+
+	header := http.Header{}
+	if a.StorageTier != "" {
+		tier, err := azureAccessTier(a.StorageTier)
+		if err != nil {
+			return UploadResult{}, err
+		}
+		header.Set("x-ms-access-tier", tier)
+	}
+	if opts.IfNotExists {
+		header.Set("If-None-Match", "*")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	registration, err := a.uploads.register(cancel)
+	if err != nil {
+		return UploadResult{}, err
+	}
+	defer a.uploads.deregister(registration)
+
+	var (
+		blockIDs []string
+		size     int64
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	failed := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr
+	}
+	slots := make(chan struct{}, threads)
+	for failed() == nil {
+		buf := make([]byte, partSize)
+		n, readErr := io.ReadFull(r, buf)
+		last := readErr == io.EOF || readErr == io.ErrUnexpectedEOF
+		if readErr != nil && !last {
+			cancel()
+			wg.Wait()
+			return UploadResult{}, readErr
+		}
+		if n == 0 {
+			// Committing an empty block list stores an empty blob
+			break
+		}
+
+		// Block IDs must all have the same length
+		blockID := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("block-%08d", len(blockIDs))))
+		blockIDs = append(blockIDs, blockID)
+		size += int64(n)
+
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(data []byte) {
+			defer func() { <-slots; wg.Done() }()
+			err := observer.Call(a.Observer, "azure", "PutBlock", func() error {
+				return a.Client.PutBlock(ctx, bucket, objectName, blockID, data)
+			})
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				cancel()
+				return
+			}
+			observer.OrNop(a.Observer).IncCounter(observer.PartsUploaded, map[string]string{"provider": "azure"})
+			observer.OrNop(a.Observer).AddBytes(observer.BytesUploaded, int64(len(data)))
+		}(buf[:n])
+
+		if last {
+			break
+		}
+	}
+	wg.Wait()
+	if err := failed(); err != nil {
+		return UploadResult{}, fmt.Errorf("failed to upload '%s': %w", objectName, err)
+	}
+
+	var committed http.Header
+	err = observer.Call(a.Observer, "azure", "PutBlockList", func() (err error) {
+		committed, err = a.Client.PutBlockList(ctx, bucket, objectName, blockIDs, header)
+		return err
+	})
+	if err != nil {
+		return UploadResult{}, azureUploadError(err)
+	}
+	return UploadResult{ETag: committed.Get("ETag"), VersionID: committed.Get("x-ms-version-id"), Size: size}, nil
+}
+
+// azureUploadError reports the failed precondition of conditional uploads as ErrObjectExists.
+func azureUploadError(err error) error {
+	var respErr *azureStorageError
+	if errors.As(err, &respErr) && (respErr.StatusCode == http.StatusPreconditionFailed || respErr.Code == "BlobAlreadyExists") {
+		return ErrObjectExists
+	}
+	return err
+}
+
+// Shutdown stops accepting uploads and waits for the in-flight ones to finish. If ctx is done
+// first, the uploads still running are cancelled and ctx.Err() is returned.
+func (a *AzureManager) Shutdown(ctx context.Context) error {
+	return a.uploads.shutdown(ctx)
+}
+
+// DownloadLink returns a URL of the blob carrying a read-only user-delegation SAS token valid for
+// expires minutes (at most 7 days, the lifetime of a user delegation key).
+func (a *AzureManager) DownloadLink(bucketName string, objectName string, expires int64) (string, error) {
+	return a.sasLink(bucketName, objectName, "r", expires)
+}
+
+// UploadLink returns a URL of the blob carrying a create/write user-delegation SAS token valid for
+// expires minutes (at most 7 days), that uploads the blob with a PUT of its content. The PUT must send
+// the "x-ms-blob-type: BlockBlob" header, and its Content-Type header becomes the one of the blob.
+// Browsers uploading across origins also need a CORS rule on the account allowing PUT and these headers.
+func (a *AzureManager) UploadLink(bucket, object string, expires int64) (string, error) {
+	return a.sasLink(bucket, object, "cw", expires)
+}
+
+// sasLink returns a URL of the blob carrying a user-delegation SAS token with the permissions, valid
+// for expires minutes.
+func (a *AzureManager) sasLink(bucketName, objectName, permissions string, expires int64) (string, error) {
+	if expires <= 0 || expires > 7*24*60 {
+		return "", fmt.Errorf("SAS expiration must be between 1 minute and 7 days, got %d minutes", expires)
+	}
+	if err := a.setup(); err != nil {
+		return "", err
+	}
+
+	// The start is set in the past to tolerate clock skew between the client and the service
+	start := time.Now().UTC().Add(-5 * time.Minute).Truncate(time.Second)
+	expiry := start.Add(5*time.Minute + time.Duration(expires)*time.Minute)
+	key, err := a.Client.GetUserDelegationKey(context.Background(), start, expiry)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user delegation key: %w", err)
+	}
+
+	link, err := a.Client.url(bucketName, objectName, nil)
+	if err != nil {
+		return "", err
+	}
+	sas, err := azureUserDelegationSAS(a.Client.Account, bucketName, objectName, permissions, key, start, expiry)
+	if err != nil {
+		return "", err
+	}
+	return link + "?" + sas, nil
+}
+
+// azureUserDelegationSAS returns the query of a SAS token of the blob with the permissions (e.g. "r"
+// to read) valid from start to expiry, signed with the user delegation key.
+func azureUserDelegationSAS(account, container, blob, permissions string, key AzureUserDelegationKey, start, expiry time.Time) (string, error) {
+	secret, err := base64.StdEncoding.DecodeString(key.Value)
+	if err != nil {
+		return "", fmt.Errorf("invalid user delegation key: %w", err)
+	}
+
+	query := url.Values{
+		"sp":    {permissions},
+		"st":    {start.UTC().Format(time.RFC3339)},
+		"se":    {expiry.UTC().Format(time.RFC3339)},
+		"skoid": {key.SignedOid},
+		"sktid": {key.SignedTid},
+		"skt":   {key.SignedStart},
+		"ske":   {key.SignedExpiry},
+		"sks":   {key.SignedService},
+		"skv":   {key.SignedVersion},
+		"spr":   {"https"},
+		"sv":    {azureStorageAPIVersion},
+		"sr":    {"b"},
+	}
+	// Fields of the string-to-sign left empty: authorized and unauthorized user object IDs,
+	// correlation ID, IP range, snapshot time, encryption scope and the response header overrides
+	stringToSign := strings.Join([]string{
+		query.Get("sp"), query.Get("st"), query.Get("se"), "/blob/" + account + "/" + container + "/" + blob,
+		query.Get("skoid"), query.Get("sktid"), query.Get("skt"), query.Get("ske"), query.Get("sks"), query.Get("skv"),
+		"", "", "", "", query.Get("spr"), query.Get("sv"), query.Get("sr"), "", "",
+		"", "", "", "", "",
+	}, "\n")
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(stringToSign))
+	query.Set("sig", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	return query.Encode(), nil
+}
+
+// ObjectURL returns the canonical URL of the blob (https://<account>.blob.core.windows.net/<container>/<name>).
+// The URL carries no SAS token, so it is only usable for containers with public read access; use
+// DownloadLink otherwise.
+func (a *AzureManager) ObjectURL(bucketName string, objectName string) (string, error) {
+	if bucketName == "" {
+		return "", errors.New("bucket name is required")
+	}
+	if err := a.setup(); err != nil {
+		return "", err
+	}
+	return a.Client.url(bucketName, objectName, nil)
+}
+
+// Download writes the content of the blob to w, as stored.
+func (a *AzureManager) Download(bucketName string, objectName string, w io.Writer) error {
+	if err := a.setup(); err != nil {
+		return err
+	}
+
+	var content io.ReadCloser
+	err := observer.Call(a.Observer, "azure", "GetBlob", func() (err error) {
+		content, err = a.Client.ReadBlob(context.Background(), bucketName, objectName, 0, -1, "")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = content.Close() }()
+
+	n, err := io.Copy(w, content)
+	observer.OrNop(a.Observer).AddBytes(observer.BytesDownloaded, n)
+	if err != nil {
+		return fmt.Errorf("failed to download '%s': %w", objectName, err)
+	}
+	return nil
+}
+
+// DownloadRange writes the bytes from start to end (both inclusive) of the blob to w with a ranged GET.
+// A range that goes past the end of the blob is cut short by Azure.
+func (a *AzureManager) DownloadRange(bucket, object string, start, end int64, w io.Writer) error {
+	if err := validateRange(start, end); err != nil {
+		return err
+	}
+	if err := a.setup(); err != nil {
+		return err
+	}
+
+	var content io.ReadCloser
+	err := observer.Call(a.Observer, "azure", "GetBlob", func() (err error) {
+		content, err = a.Client.ReadBlob(context.Background(), bucket, object, start, end, "")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = content.Close() }()
+
+	n, err := io.Copy(w, content)
+	observer.OrNop(a.Observer).AddBytes(observer.BytesDownloaded, n)
+	if err != nil {
+		return fmt.Errorf("failed to download '%s': %w", object, err)
+	}
+	return nil
+}
+
+// DownloadToFile downloads the blob into the file at path with ranged reads of partSize bytes, up to
+// threads at once (10 MiB and 4 by default, as Upload). Every part must match the ETag of the blob
+// seen when the download started. Archived blobs must be rehydrated first (see ChangeStorageTier).
+// The file is removed when the download fails.
+func (a *AzureManager) DownloadToFile(bucket string, objectName string, path string, partSize int64, threads int) error {
+	if partSize < 131072 { // 128 * 1024
+		partSize = 10 * 1024 * 1024
+	}
+	if threads <= 0 {
+		threads = 4
+	}
+
+	object, err := a.StatObject(bucket, objectName)
+	if err != nil {
+		return err
+	}
+	if object.StorageClass == STierTierArchive {
+		return fmt.Errorf("object '%s' is archived and must be rehydrated before download", objectName)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = downloadParts(f, object.Size, partSize, threads, func(offset, end int64) (io.ReadCloser, error) {
+		var content io.ReadCloser
+		err := observer.Call(a.Observer, "azure", "GetBlob", func() (err error) {
+			content, err = a.Client.ReadBlob(context.Background(), bucket, objectName, offset, end, object.ETag)
+			return err
+		})
+		return content, err
+	})
+	if err == nil {
+		observer.OrNop(a.Observer).AddBytes(observer.BytesDownloaded, object.Size)
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("failed to download '%s' to '%s': %w", objectName, path, err)
+	}
+	return nil
+}
+
+// StatObject returns the properties of the blob.
+func (a *AzureManager) StatObject(bucketName string, objectName string) (BucketObject, error) {
+	if err := a.setup(); err != nil {
+		return BucketObject{}, err
+	}
+
+	b, err := a.Client.GetBlobProperties(context.Background(), bucketName, objectName)
+	if err != nil {
+		return BucketObject{}, err
+	}
+	object := NewBucketObjectFromAzure(b)
+	object.ETag = b.Properties.Etag
+	return object, nil
+}
+
+// Stat returns the properties of the blob like StatObject, or found=false when the Blob service answers 404.
+// Other failures, such as a 403, are returned as errors.
+func (a *AzureManager) Stat(bucket, object string) (*BucketObject, bool, error) {
+	result, err := a.StatObject(bucket, object)
+	if err != nil {
+		var storageErr *azureStorageError
+		if errors.As(err, &storageErr) && storageErr.StatusCode == http.StatusNotFound {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to stat '%s': %w", object, err)
+	}
+	return &result, true, nil
+}
+
+func (a *AzureManager) Update(bucket string, objectName string, f *os.File, partSize int64, threads int) error {
+	return a.Upload(bucket, objectName, f, partSize, threads)
+}
+
+func (a *AzureManager) DeleteObject(bucketName string, objectName string) error {
+	if err := a.setup(); err != nil {
+		return err
+	}
+	_, err := a.Client.do(context.Background(), http.MethodDelete, bucketName, objectName, nil, nil, nil, nil)
+	return err
+}
+
+// Copy copies the blob into dstObject of the dstBucket container on the server side with Copy Blob.
+// The copy runs asynchronously, so Copy polls the destination with Backoff until it ends. The source
+// must be in the same storage account, as it is authorized with the credentials of the manager.
+func (a *AzureManager) Copy(srcBucket, srcObject, dstBucket, dstObject string) error {
+	if err := a.setup(); err != nil {
+		return err
+	}
+
+	source, err := a.Client.url(srcBucket, srcObject, nil)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("x-ms-copy-source", source)
+
+	var status http.Header
+	err = observer.Call(a.Observer, "azure", "CopyBlob", func() (err error) {
+		status, err = a.Client.do(context.Background(), http.MethodPut, dstBucket, dstObject, nil, header, nil, nil)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy '%s' to '%s': %w", srcObject, dstObject, err)
+	}
+
+	b := a.Backoff
+	if b == nil {
+		b = backoff.ConstantBackoff{Delay: time.Second}
+	}
+	err = backoff.Retry(b, func(err error) bool { return errors.Is(err, errCopyInProgress) }, func() (err error) {
+		if status == nil {
+			if status, err = a.Client.do(context.Background(), http.MethodHead, dstBucket, dstObject, nil, nil, nil, nil); err != nil {
+				return err
+			}
+		}
+		switch copyStatus := status.Get("x-ms-copy-status"); copyStatus {
+		case "success":
+			return nil
+		case "pending":
+			status = nil
+			return errCopyInProgress
+		default:
+			return fmt.Errorf("copy ended with status %s: %s", copyStatus, status.Get("x-ms-copy-status-description"))
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy '%s' to '%s': %w", srcObject, dstObject, err)
+	}
+	return nil
+}
+
+// ChangeStorageTier sets the access tier of the blob. Moving a blob out of the archive tier starts its
+// rehydration, which takes hours; the blob stays archived until it completes.
+func (a *AzureManager) ChangeStorageTier(bucket, key string, tier StorageTierEnum) error {
+	if err := a.setup(); err != nil {
+		return err
+	}
+
+	accessTier, err := azureAccessTier(tier)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("x-ms-access-tier", accessTier)
+	if _, err := a.Client.do(context.Background(), http.MethodPut, bucket, key, url.Values{"comp": {"tier"}}, header, nil, nil); err != nil {
+		return fmt.Errorf("failed to change the storage tier of '%s': %w", key, err)
+	}
+	return nil
+}
+
+// SetNotifications delivers the container's blob events through an Event Grid subscription on the
+// storage account, which needs the manager's ResourceGroup. Calls with the same config.ID replace
+// the subscription.
+func (a *AzureManager) SetNotifications(bucket string, config NotificationConfig) error {
+	if err := a.setup(); err != nil {
+		return err
+	}
+	if a.Auth == nil || a.ResourceGroup == "" {
+		return errors.New("the subscription and resource group of the storage account are required for notifications")
+	}
+
+	name, subscription, err := azureEventSubscription(bucket, config)
+	if err != nil {
+		return err
+	}
+	scope := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Storage/storageAccounts/%s",
+		url.PathEscape(a.Auth.SubscriptionID), url.PathEscape(a.ResourceGroup), url.PathEscape(a.Client.Account))
+	return a.Client.PutEventSubscription(context.Background(), scope, name, subscription)
+}
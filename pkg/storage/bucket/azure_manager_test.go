@@ -0,0 +1,333 @@
+package bucket
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAzureCredential is an azcore.TokenCredential returning a token named after the requested scope.
+type fakeAzureCredential struct{}
+
+func (fakeAzureCredential) GetToken(_ context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token " + strings.Join(options.Scopes, ","), ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// newTestAzureManager returns an AzureManager whose storage client talks to the given fake endpoint.
+func newTestAzureManager(endpoint string) *AzureManager {
+	return &AzureManager{Client: &AzureStorageClient{Credential: fakeAzureCredential{}, Account: "account", Endpoint: endpoint, ManagementEndpoint: endpoint}}
+}
+
+// fakeBlobUploadHandler serves block uploads, storing the staged blocks and committing them by block
+// list. Commits with "If-None-Match: *" fail with BlobAlreadyExists once the blob exists.
+func fakeBlobUploadHandler(t *testing.T, commits *[]http.Header) http.HandlerFunc {
+	var mu sync.Mutex
+	blocks := map[string][]byte{}
+	var stored []byte
+	exists := false
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token "+azureStorageScope || r.Header.Get("x-ms-version") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodPut || r.URL.Path != "/container/dir/object.bin" {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Query().Get("comp") {
+		case "block":
+			data, _ := io.ReadAll(r.Body)
+			blocks[r.URL.Query().Get("blockid")] = data
+			w.WriteHeader(http.StatusCreated)
+		case "blocklist":
+			*commits = append(*commits, r.Header.Clone())
+			if r.Header.Get("If-None-Match") == "*" && exists {
+				w.Header().Set("x-ms-error-code", "BlobAlreadyExists")
+				w.WriteHeader(http.StatusConflict)
+				_, _ = fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><Error><Code>BlobAlreadyExists</Code><Message>The specified blob already exists.</Message></Error>`)
+				return
+			}
+			var list struct {
+				Latest []string `xml:"Latest"`
+			}
+			if err := xml.NewDecoder(r.Body).Decode(&list); err != nil {
+				t.Errorf("unexpected block list: %v", err)
+			}
+			stored = nil
+			for _, id := range list.Latest {
+				stored = append(stored, blocks[id]...)
+			}
+			exists = true
+			w.Header().Set("ETag", fmt.Sprintf(`"0x%d"`, len(stored)))
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}
+}
+
+// TestAzureManager_Upload verifies that the blocks are committed in order with the access tier, and that
+// a conditional upload of an existing blob fails with ErrObjectExists.
+func TestAzureManager_Upload(t *testing.T) {
+	var commits []http.Header
+	server := httptest.NewServer(fakeBlobUploadHandler(t, &commits))
+	defer server.Close()
+
+	f, err := os.CreateTemp(t.TempDir(), "upload")
+	if err != nil {
+		t.Fatalf("unexpected error creating file: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	content := downloadContent()
+	if _, err := f.Write(content); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+
+	manager := newTestAzureManager(server.URL)
+	manager.StorageTier = STierLowAccess
+	manager.IfNotExists = true
+	upload := func() (UploadResult, error) {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatalf("unexpected error seeking file: %v", err)
+		}
+		return manager.UploadWithResult("container", "dir/object.bin", f, 131072, 2)
+	}
+
+	result, err := upload()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Size != int64(len(content)) || result.ETag != fmt.Sprintf(`"0x%d"`, len(content)) {
+		t.Errorf("unexpected upload result: %+v", result)
+	}
+	if len(commits) != 1 || commits[0].Get("x-ms-access-tier") != "Cool" {
+		t.Fatalf("expected one commit in the Cool tier, got %v", commits)
+	}
+
+	if _, err := upload(); err != ErrObjectExists {
+		t.Errorf("expected ErrObjectExists on second upload, got %v", err)
+	}
+}
+
+// TestAzureManager_List verifies that every page is read and the access tiers are mapped.
+func TestAzureManager_List(t *testing.T) {
+	since := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	blob := func(name, tier string, modified time.Time) string {
+		return fmt.Sprintf(`<Blob><Name>%s</Name><Properties><Last-Modified>%s</Last-Modified><Content-Length>10</Content-Length><AccessTier>%s</AccessTier></Properties></Blob>`,
+			name, modified.Format(http.TimeFormat), tier)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/container" || r.URL.Query().Get("restype") != "container" || r.URL.Query().Get("comp") != "list" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("marker") == "" {
+			_, _ = fmt.Fprintf(w, `<EnumerationResults><Blobs>%s</Blobs><NextMarker>page-2</NextMarker></EnumerationResults>`, blob("old.txt", "Hot", since.Add(-time.Hour)))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `<EnumerationResults><Blobs>%s%s</Blobs><NextMarker/></EnumerationResults>`,
+			blob("new.txt", "Cold", since.Add(time.Hour)), blob("cold.txt", "Archive", since.Add(2*time.Hour)))
+	}))
+	defer server.Close()
+
+	manager := newTestAzureManager(server.URL)
+	objects, err := manager.List("container")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objects) != 3 || objects[0].StorageClass != STierStandard || objects[1].StorageClass != STierLowAccess || objects[2].StorageClass != STierTierArchive {
+		t.Fatalf("unexpected objects: %+v", objects)
+	}
+	if objects[1].Size != 10 || !objects[1].LastModified.Equal(since.Add(time.Hour)) {
+		t.Errorf("unexpected object: %+v", objects[1])
+	}
+
+	objects, err = manager.ListObjectsSince("container", since)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objects) != 2 || objects[0].Key != "new.txt" || objects[1].Key != "cold.txt" {
+		t.Errorf("expected only the newer objects, got %+v", objects)
+	}
+}
+
+// TestAzureManager_DownloadLink verifies the expiration and the signature of the user-delegation SAS token.
+func TestAzureManager_DownloadLink(t *testing.T) {
+	secret := []byte("user-delegation-secret")
+	var keyInfo struct {
+		Start  string `xml:"Start"`
+		Expiry string `xml:"Expiry"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Query().Get("comp") != "userdelegationkey" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = xml.NewDecoder(r.Body).Decode(&keyInfo)
+		_, _ = fmt.Fprintf(w, `<UserDelegationKey><SignedOid>oid</SignedOid><SignedTid>tid</SignedTid><SignedStart>%s</SignedStart><SignedExpiry>%s</SignedExpiry><SignedService>b</SignedService><SignedVersion>%s</SignedVersion><Value>%s</Value></UserDelegationKey>`,
+			keyInfo.Start, keyInfo.Expiry, azureStorageAPIVersion, base64.StdEncoding.EncodeToString(secret))
+	}))
+	defer server.Close()
+
+	manager := newTestAzureManager(server.URL)
+	link, err := manager.DownloadLink("container", "dir/a b.txt", 15)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("unexpected error parsing the URL: %v", err)
+	}
+	if parsed.EscapedPath() != "/container/dir/a%20b.txt" {
+		t.Errorf("unexpected URL: %s", link)
+	}
+
+	query := parsed.Query()
+	start, _ := time.Parse(time.RFC3339, query.Get("st"))
+	expiry, _ := time.Parse(time.RFC3339, query.Get("se"))
+	if expiry.Sub(start) != 20*time.Minute || query.Get("se") != keyInfo.Expiry || query.Get("sp") != "r" || query.Get("sr") != "b" {
+		t.Errorf("unexpected query: %v", query)
+	}
+
+	stringToSign := strings.Join([]string{
+		"r", query.Get("st"), query.Get("se"), "/blob/account/container/dir/a b.txt",
+		"oid", "tid", keyInfo.Start, keyInfo.Expiry, "b", azureStorageAPIVersion,
+		"", "", "", "", "https", azureStorageAPIVersion, "b", "", "", "", "", "", "", "",
+	}, "\n")
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(stringToSign))
+	if query.Get("sig") != base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("invalid signature %q", query.Get("sig"))
+	}
+
+	if _, err := manager.DownloadLink("container", "object.txt", 8*24*60); err == nil {
+		t.Error("expected an error for an expiration over 7 days")
+	}
+}
+
+// TestAzureUserDelegationSAS verifies the SAS token against a known vector, signed by the sas package of
+// the azblob SDK with the same inputs.
+func TestAzureUserDelegationSAS(t *testing.T) {
+	key := AzureUserDelegationKey{
+		SignedOid:     "11111111-2222-3333-4444-555555555555",
+		SignedTid:     "66666666-7777-8888-9999-000000000000",
+		SignedStart:   "2024-01-02T03:00:00Z",
+		SignedExpiry:  "2024-01-03T03:00:00Z",
+		SignedService: "b",
+		SignedVersion: "2021-08-06",
+		Value:         "c2VjcmV0LWtleS1mb3ItdGVzdHMtb25seS0xMjM0NTY=",
+	}
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	sas, err := azureUserDelegationSAS("myaccount", "mycontainer", "dir/file name.txt", "r", key, start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "se=2024-01-02T04%3A04%3A05Z&sig=5TErh8Act%2F7Fty8GyxU8CK6pzXbp%2B7eKc66MXUS1w90%3D&ske=2024-01-03T03%3A00%3A00Z" +
+		"&skoid=11111111-2222-3333-4444-555555555555&sks=b&skt=2024-01-02T03%3A00%3A00Z&sktid=66666666-7777-8888-9999-000000000000" +
+		"&skv=2021-08-06&sp=r&spr=https&sr=b&st=2024-01-02T03%3A04%3A05Z&sv=2021-08-06"
+	if sas != expected {
+		t.Errorf("unexpected SAS token:\n got %s\nwant %s", sas, expected)
+	}
+
+	key.Value = "not base64"
+	if _, err := azureUserDelegationSAS("myaccount", "mycontainer", "blob", "r", key, start, start.Add(time.Hour)); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+}
+
+// TestAzureManager_DownloadToFile verifies the ranged download of a blob, pinned to its ETag.
+func TestAzureManager_DownloadToFile(t *testing.T) {
+	content := downloadContent()
+	var gets int
+	ranged := fakeRangeHandler(content, `"0x8D"`, &gets)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/container/dir/object.bin" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodHead {
+			w.Header().Set("x-ms-access-tier", "Hot")
+		} else if r.Header.Get("If-Match") != `"0x8D"` {
+			t.Errorf("expected the ranged reads to be pinned to the ETag, got %q", r.Header.Get("If-Match"))
+		}
+		ranged(w, r)
+	}))
+	defer server.Close()
+
+	manager := newTestAzureManager(server.URL)
+	path := t.TempDir() + "/object.bin"
+	if err := manager.DownloadToFile("container", "dir/object.bin", path, 131072, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(data, content) {
+		t.Errorf("unexpected file content (%d bytes, %v)", len(data), err)
+	}
+	if gets != 3 {
+		t.Errorf("expected 3 ranged reads, got %d", gets)
+	}
+}
+
+// TestAzureManager_SetNotifications verifies the Event Grid subscription created on the storage account.
+func TestAzureManager_SetNotifications(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token https://management.azure.com/.default" ||
+			r.URL.Path != "/subscriptions/sub/resourceGroups/group/providers/Microsoft.Storage/storageAccounts/account/providers/Microsoft.EventGrid/eventSubscriptions/uploads" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	manager := newTestAzureManager(server.URL)
+	manager.Auth = &authentication.AzureAuth{SubscriptionID: "sub"}
+	manager.ResourceGroup = "group"
+	err := manager.SetNotifications("container", NotificationConfig{
+		ID:     "uploads",
+		Events: []NotificationEvent{EventObjectCreated},
+		Target: "/subscriptions/sub/resourceGroups/group/providers/Microsoft.Storage/storageAccounts/queues/queueServices/default/queues/events",
+		Prefix: "in/",
+		Suffix: ".csv",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, _ := json.Marshal(body)
+	for _, expected := range []string{
+		`"endpointType":"StorageQueue"`,
+		`"queueName":"events"`,
+		`"resourceId":"/subscriptions/sub/resourceGroups/group/providers/Microsoft.Storage/storageAccounts/queues"`,
+		`"includedEventTypes":["Microsoft.Storage.BlobCreated"]`,
+		`"subjectBeginsWith":"/blobServices/default/containers/container/blobs/in/"`,
+		`"subjectEndsWith":".csv"`,
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("expected %s in the subscription, got %s", expected, data)
+		}
+	}
+}
@@ -1,19 +1,82 @@
 package bucket
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"io"
+	"net/url"
 	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
+// ErrObjectExists is returned by conditional uploads when the target object already exists.
+var ErrObjectExists = errors.New("object already exists")
+
+// ErrSizeMismatch is returned by verified uploads when the stored object size differs from the bytes sent,
+// and by UploadStream when the stream does not yield the announced size.
+var ErrSizeMismatch = errors.New("stored object size does not match the uploaded size")
+
+// ErrChecksumMismatch is returned by verified downloads when the content received does not match the stored checksum.
+var ErrChecksumMismatch = errors.New("downloaded content does not match the stored checksum")
+
+// errCopyInProgress is returned while polling a copy that has not ended, to keep polling it.
+var errCopyInProgress = errors.New("copy in progress")
+
+// UploadResult describes the object stored by an upload.
+type UploadResult struct {
+	ETag      string // Entity tag of the object, as returned by the provider (S3 ETags keep their quotes).
+	VersionID string // Version of the object, empty when the bucket is not versioned.
+	Size      int64  // Number of bytes uploaded.
+}
+
+// DownloadOptions controls how a download writes the object content.
+type DownloadOptions struct {
+	AutoDecompress bool // Decompresses objects stored with "Content-Encoding: gzip"; other objects are written as stored.
+	VerifyChecksum bool // Verifies the bytes received against the checksum stored by the provider (see ErrChecksumMismatch).
+}
+
+// UploadOptions controls how an upload stores the object.
+type UploadOptions struct {
+	IfNotExists bool // Fails with ErrObjectExists instead of overwriting an existing object, so retried uploads are safe.
+}
+
+// BucketManager manages the buckets and objects of one provider.
+//
+// The threads argument of Upload, UploadWithResult, UploadStream, Update and DownloadToFile bounds the
+// parts transferred at once, 4 when not positive. It is a limit, not a guarantee: GCP resumable uploads
+// must send their chunks in order, so the GCP uploads ignore it and send one chunk at a time.
 type BucketManager interface {
+	ListBuckets() ([]string, error)
 	List(name string) (r []BucketObject, err error)
+	ListPage(name, token string, limit int) ([]BucketObject, string, error)
+	ListPrefix(bucket, prefix, delimiter string) ([]BucketObject, []string, error)
+	ListObjectsSince(name string, since time.Time) ([]BucketObject, error)
+	StreamObjects(ctx context.Context, name, prefix string) (<-chan BucketObject, <-chan error)
 	Create(name string, waitCreate bool) error
 	Delete(name string) error
+	Exists(name string) (bool, error)
+	CreateIfNotExists(name string, waitCreate bool) (created bool, err error)
 	Upload(bucket string, objectName string, f *os.File, partSize int64, threads int) error
+	UploadWithResult(bucket string, objectName string, f *os.File, partSize int64, threads int) (UploadResult, error)
+	UploadStream(bucket, object string, r io.Reader, size int64, partSize int64, threads int) error
 	DownloadLink(bucketName string, objectName string, expires int64) (string, error)
+	UploadLink(bucket, object string, expires int64) (string, error)
+	Download(bucketName string, objectName string, w io.Writer) error
+	DownloadToFile(bucket string, objectName string, path string, partSize int64, threads int) error
+	DownloadRange(bucket, object string, start, end int64, w io.Writer) error
+	Stat(bucket, object string) (*BucketObject, bool, error)
+	ObjectURL(bucketName string, objectName string) (string, error)
 	Update(bucket string, objectName string, f *os.File, partSize int64, threads int) error
 	DeleteObject(bucketName string, objectName string) error
+	Copy(srcBucket, srcObject, dstBucket, dstObject string) error
+	ChangeStorageTier(bucket, key string, tier StorageTierEnum) error
+	SetNotifications(bucket string, config NotificationConfig) error
+	Shutdown(ctx context.Context) error
 }
 
 // NewBucketManager
@@ -39,9 +102,99 @@ func NewBucketManager(authConfig *authentication.AuthConfig) (BucketManager, err
 			return nil, fmt.Errorf("invalid OCI authentication config")
 		}
 		return &AWSManager{Auth: awsConfig}, nil
+	case "gcp":
+		// Returns a GCP-specific manager implementation.
+		gcpConfig, ok := authConfig.Config.(*authentication.GCPAuth)
+		if !ok {
+			return nil, fmt.Errorf("invalid GCP authentication config")
+		}
+		return &GCPManager{Auth: gcpConfig}, nil
+	case "azure":
+		// Returns an Azure-specific manager implementation.
+		azureConfig, ok := authConfig.Config.(*authentication.AzureAuth)
+		if !ok {
+			return nil, fmt.Errorf("invalid Azure authentication config")
+		}
+		return &AzureManager{Auth: azureConfig}, nil
 
 	default:
 		// Returns an error if the cloud provider is unsupported.
 		return nil, fmt.Errorf("unsupported provider: %s", authConfig.ProviderName)
 	}
 }
+
+// NewBucketManagerForCompartment behaves like NewBucketManager but pins the OCI manager to the given
+// namespace and compartment, leaving the auth configuration untouched. For providers without these
+// concepts (e.g. AWS) the extra arguments are ignored.
+func NewBucketManagerForCompartment(authConfig *authentication.AuthConfig, namespace, compartmentID string) (BucketManager, error) {
+	manager, err := NewBucketManager(authConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if ociManager, ok := manager.(*OCIManager); ok {
+		ociManager.Namespace = namespace
+		ociManager.CompartmentID = compartmentID
+	}
+
+	return manager, nil
+}
+
+// createIfNotExists creates the bucket with m.Create unless m.Exists finds it, reporting whether it was created.
+func createIfNotExists(m BucketManager, name string, waitCreate bool) (bool, error) {
+	exists, err := m.Exists(name)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+	if err := m.Create(name, waitCreate); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// escapeObjectName validates the object name and escapes each path segment for use in a URL,
+// keeping the "/" separators so folder-style keys remain readable.
+func escapeObjectName(objectName string) (string, error) {
+	if objectName == "" {
+		return "", fmt.Errorf("object name is required")
+	}
+	if !utf8.ValidString(objectName) {
+		return "", fmt.Errorf("object name '%s' is not valid UTF-8", objectName)
+	}
+	if strings.IndexFunc(objectName, unicode.IsControl) >= 0 {
+		return "", fmt.Errorf("object name '%s' contains control characters", objectName)
+	}
+
+	segments := strings.Split(objectName, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// expectSize wraps r to fail with ErrSizeMismatch once it yields more or fewer bytes than size, so
+// the upload reading it is aborted instead of storing a truncated object. A negative size leaves r as is.
+func expectSize(r io.Reader, size int64) io.Reader {
+	if size < 0 {
+		return r
+	}
+	return &sizedReader{r: r, size: size}
+}
+
+type sizedReader struct {
+	r    io.Reader
+	size int64
+	n    int64
+}
+
+func (s *sizedReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	s.n += int64(n)
+	if s.n > s.size || (err == io.EOF && s.n < s.size) {
+		return n, fmt.Errorf("%w: stream yielded %d bytes, %d were expected", ErrSizeMismatch, s.n, s.size)
+	}
+	return n, err
+}
@@ -0,0 +1,268 @@
+package bucket
+
+import (
+	"fmt"
+	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestOCIAuthConfig returns an already authenticated OCI configuration so the factories skip network calls.
+func newTestOCIAuthConfig() *authentication.AuthConfig {
+	return &authentication.AuthConfig{
+		ProviderName: "oci",
+		Config: &authentication.OCIAuth{
+			Namespace:     "auth-namespace",
+			CompartmentID: "auth-compartment",
+			Authenticated: true,
+		},
+	}
+}
+
+// TestNewBucketManagerForCompartment verifies that the explicit namespace and compartment reach the OCI operations.
+func TestNewBucketManagerForCompartment(t *testing.T) {
+	authConfig := newTestOCIAuthConfig()
+
+	manager, err := NewBucketManagerForCompartment(authConfig, "my-namespace", "my-compartment")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ociManager, ok := manager.(*OCIManager)
+	if !ok {
+		t.Fatalf("expected *OCIManager, got %T", manager)
+	}
+
+	if got := *ociManager.namespace(); got != "my-namespace" {
+		t.Errorf("expected namespace 'my-namespace', got '%s'", got)
+	}
+	if got := *ociManager.compartmentID(); got != "my-compartment" {
+		t.Errorf("expected compartment 'my-compartment', got '%s'", got)
+	}
+
+	// The auth configuration must not be mutated.
+	ociAuth := authConfig.Config.(*authentication.OCIAuth)
+	if ociAuth.Namespace != "auth-namespace" || ociAuth.CompartmentID != "auth-compartment" {
+		t.Errorf("auth configuration was mutated: %s/%s", ociAuth.Namespace, ociAuth.CompartmentID)
+	}
+}
+
+// TestNewBucketManager_FallsBackToAuth verifies that the plain constructor keeps using the auth values.
+func TestNewBucketManager_FallsBackToAuth(t *testing.T) {
+	manager, err := NewBucketManager(newTestOCIAuthConfig())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ociManager := manager.(*OCIManager)
+	if got := *ociManager.namespace(); got != "auth-namespace" {
+		t.Errorf("expected namespace 'auth-namespace', got '%s'", got)
+	}
+	if got := *ociManager.compartmentID(); got != "auth-compartment" {
+		t.Errorf("expected compartment 'auth-compartment', got '%s'", got)
+	}
+}
+
+// TestObjectURL verifies the canonical object URLs built for each provider, including keys that need escaping.
+func TestObjectURL(t *testing.T) {
+	awsManager := &AWSManager{Auth: &authentication.AWSAuth{Region: "us-east-1"}}
+	ociManager := &OCIManager{Auth: &authentication.OCIAuth{Region: "us-ashburn-1", Namespace: "my-namespace"}}
+
+	tests := []struct {
+		name     string
+		manager  BucketManager
+		object   string
+		expected string
+	}{
+		{
+			name:     "AWS simple key",
+			manager:  awsManager,
+			object:   "reports/2024.csv",
+			expected: "https://my-bucket.s3.us-east-1.amazonaws.com/reports/2024.csv",
+		},
+		{
+			name:     "AWS key with spaces",
+			manager:  awsManager,
+			object:   "my reports/annual report.pdf",
+			expected: "https://my-bucket.s3.us-east-1.amazonaws.com/my%20reports/annual%20report.pdf",
+		},
+		{
+			name:     "OCI simple key",
+			manager:  ociManager,
+			object:   "reports/2024.csv",
+			expected: "https://objectstorage.us-ashburn-1.oraclecloud.com/n/my-namespace/b/my-bucket/o/reports/2024.csv",
+		},
+		{
+			name:     "OCI key with spaces",
+			manager:  ociManager,
+			object:   "annual report.pdf",
+			expected: "https://objectstorage.us-ashburn-1.oraclecloud.com/n/my-namespace/b/my-bucket/o/annual%20report.pdf",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.manager.ObjectURL("my-bucket", tt.object)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected '%s', got '%s'", tt.expected, got)
+			}
+		})
+	}
+
+	if _, err := awsManager.ObjectURL("my-bucket", "bad\nkey"); err == nil {
+		t.Error("expected error for a key with control characters")
+	}
+}
+
+// fakeStatHandler answers the metadata requests of every provider: "present.txt" exists, "missing.txt"
+// does not (404) and "secret.txt" cannot be read (403).
+func fakeStatHandler(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "missing.txt"):
+		w.WriteHeader(http.StatusNotFound)
+	case strings.HasSuffix(r.URL.Path, "secret.txt"):
+		w.WriteHeader(http.StatusForbidden)
+	case r.Method == http.MethodGet: // Cloud Storage reads the metadata with a GET of the object resource
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"name":"present.txt","size":"42","contentType":"text/plain","updated":"2024-05-01T10:00:00Z"}`)
+	default:
+		w.Header().Set("Content-Length", "42")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Last-Modified", "Wed, 01 May 2024 10:00:00 GMT")
+		w.Header().Set("ETag", `"v1"`)
+	}
+}
+
+// TestStat verifies that every provider returns the object metadata, found=false for a missing object,
+// and an error when access is denied.
+func TestStat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(fakeStatHandler))
+	defer server.Close()
+
+	managers := map[string]BucketManager{
+		"aws":   newTestAWSManager(t, server.URL),
+		"oci":   newTestOCIManager(t, server.URL),
+		"gcp":   newTestGCPManager(server.URL),
+		"azure": newTestAzureManager(server.URL),
+	}
+	for name, manager := range managers {
+		object, found, err := manager.Stat("bucket", "present.txt")
+		if err != nil || !found {
+			t.Fatalf("%s: expected the object to be found, got %v, %v", name, found, err)
+		}
+		if object.Size != 42 || object.ContentType != "text/plain" || !object.LastModified.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+			t.Errorf("%s: unexpected metadata %+v", name, object)
+		}
+
+		object, found, err = manager.Stat("bucket", "missing.txt")
+		if err != nil || found || object != nil {
+			t.Errorf("%s: expected found=false without an error, got %v, %v, %v", name, object, found, err)
+		}
+
+		if _, found, err = manager.Stat("bucket", "secret.txt"); err == nil || found {
+			t.Errorf("%s: expected an error when access is denied, got %v, %v", name, found, err)
+		}
+	}
+}
+
+// TestSetupFailure verifies that the AWS and OCI managers return the error of a failed setup instead of
+// panicking: the AWS credentials are incomplete, and the OCI namespace cannot be resolved.
+func TestSetupFailure(t *testing.T) {
+	oci := newTestOCIManager(t, "http://127.0.0.1:0")
+	oci.Auth = &authentication.OCIAuth{}
+	managers := map[string]BucketManager{
+		"aws": &AWSManager{Auth: &authentication.AWSAuth{}},
+		"oci": oci,
+	}
+
+	for name, manager := range managers {
+		calls := map[string]func() error{
+			"ListBuckets":       func() error { _, err := manager.ListBuckets(); return err },
+			"Create":            func() error { return manager.Create("bucket", false) },
+			"Delete":            func() error { return manager.Delete("bucket") },
+			"DownloadLink":      func() error { _, err := manager.DownloadLink("bucket", "a.txt", 60); return err },
+			"Download":          func() error { return manager.Download("bucket", "a.txt", &strings.Builder{}) },
+			"DownloadRange":     func() error { return manager.DownloadRange("bucket", "a.txt", 0, 9, &strings.Builder{}) },
+			"Stat":              func() error { _, _, err := manager.Stat("bucket", "a.txt"); return err },
+			"DeleteObject":      func() error { return manager.DeleteObject("bucket", "a.txt") },
+			"Copy":              func() error { return manager.Copy("bucket", "a.txt", "backup", "a.txt") },
+			"ChangeStorageTier": func() error { return manager.ChangeStorageTier("bucket", "a.txt", STierLowAccess) },
+		}
+		for call, fn := range calls {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Errorf("%s %s: expected an error, got a panic: %v", name, call, r)
+					}
+				}()
+				if err := fn(); err == nil {
+					t.Errorf("%s %s: expected the setup error", name, call)
+				}
+			}()
+		}
+	}
+}
+
+// fakeBucketHandler answers the bucket requests of every provider: "present" exists, "missing" does
+// not (404) and "forbidden" cannot be read (403). Creations are counted in creates.
+func fakeBucketHandler(creates *int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			*creates++
+			_, _ = fmt.Fprint(w, `{"name":"missing"}`)
+			return
+		}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/missing"):
+			w.WriteHeader(http.StatusNotFound)
+		case strings.HasSuffix(r.URL.Path, "/forbidden"):
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			_, _ = fmt.Fprint(w, `{"name":"present"}`)
+		}
+	}
+}
+
+// TestExists verifies that every provider maps a 404 to false and a 403 to an error, and that
+// CreateIfNotExists only creates missing buckets.
+func TestExists(t *testing.T) {
+	var creates int
+	server := httptest.NewServer(fakeBucketHandler(&creates))
+	defer server.Close()
+
+	managers := map[string]BucketManager{
+		"aws":   newTestAWSManager(t, server.URL),
+		"oci":   newTestOCIManager(t, server.URL),
+		"gcp":   newTestGCPManager(server.URL),
+		"azure": newTestAzureManager(server.URL),
+	}
+	for name, manager := range managers {
+		if exists, err := manager.Exists("present"); err != nil || !exists {
+			t.Errorf("%s: expected the bucket to exist, got %v, %v", name, exists, err)
+		}
+		if exists, err := manager.Exists("missing"); err != nil || exists {
+			t.Errorf("%s: expected false without an error for a missing bucket, got %v, %v", name, exists, err)
+		}
+		if _, err := manager.Exists("forbidden"); err == nil {
+			t.Errorf("%s: expected an error when access is denied", name)
+		}
+
+		creates = 0
+		if created, err := manager.CreateIfNotExists("present", false); err != nil || created || creates != 0 {
+			t.Errorf("%s: expected the existing bucket to be kept, got %v, %v and %d creations", name, created, err, creates)
+		}
+		if created, err := manager.CreateIfNotExists("missing", false); err != nil || !created || creates != 1 {
+			t.Errorf("%s: expected the missing bucket to be created, got %v, %v and %d creations", name, created, err, creates)
+		}
+		if _, err := manager.CreateIfNotExists("forbidden", false); err == nil || creates != 1 {
+			t.Errorf("%s: expected an error without a creation when access is denied, got %v", name, err)
+		}
+	}
+}
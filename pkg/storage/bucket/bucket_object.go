@@ -1,37 +1,55 @@
 package bucket
 
 import (
+	"fmt"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/oracle/oci-go-sdk/v65/objectstorage"
+	"net/http"
 	"time"
 )
 
+// ArchivalStateEnum is the restore state of an object in the archive tier.
+type ArchivalStateEnum string
+
+const (
+	ArchivalStateArchived  ArchivalStateEnum = "ARCHIVED"  // Must be restored before it can be downloaded.
+	ArchivalStateRestoring ArchivalStateEnum = "RESTORING" // A restore was requested and is in progress.
+	ArchivalStateRestored  ArchivalStateEnum = "RESTORED"  // Can be downloaded until it is archived again.
+)
+
 type BucketObject struct {
 	Key          string
 	LastModified time.Time
 	Size         int64
 	StorageClass StorageTierEnum
+
+	// ContentType is the media type of the object, only filled by StatObject and Stat.
+	ContentType string
+	// ETag is the entity tag of the object, only filled by StatObject.
+	ETag string
+	// ArchivalState is only filled by StatObject, and left empty for objects outside the archive tier.
+	ArchivalState ArchivalStateEnum
+	// RestoreEstimate is the estimated time a restore takes to complete, set while the object is not restored.
+	RestoreEstimate time.Duration
+	// RestoredUntil is when a restored object returns to the archived state.
+	RestoredUntil time.Time
+}
+
+// ErrObjectArchived is returned when downloading an archived object that has not been restored.
+type ErrObjectArchived struct {
+	Object string            // Name of the object
+	State  ArchivalStateEnum // Restore state of the object (archived or restoring)
+}
+
+func (e *ErrObjectArchived) Error() string {
+	return fmt.Sprintf("object '%s' is archived (%s) and must be restored before download", e.Object, e.State)
 }
 
+// NewBucketObjectFromAWS converts an S3 object. Classes read without a restore (the infrequent access and
+// Glacier Instant Retrieval ones) map to STierLowAccess, and objects without a storage class, which S3
+// omits for standard objects, to STierStandard.
 func NewBucketObjectFromAWS(o *s3.Object) BucketObject {
-	var tier StorageTierEnum
-	switch *o.StorageClass {
-	case "STANDARD":
-	case "STANDARD_IA":
-	case "EXPRESS_ONEZONE":
-		tier = STierStandard
-		break
-	case "REDUCED_REDUNDANCY":
-	case "INTELLIGENT_TIERING":
-		tier = STierLowAccess
-		break
-	case "DEEP_ARCHIVE":
-	case "GLACIER":
-	case "GLACIER_IR":
-	case "ONEZONE_IA":
-		tier = STierTierArchive
-		break
-	}
+	tier := tierFromAWS(o.StorageClass)
 	lastModified := time.Now()
 	key := ""
 	size := int64(0)
@@ -55,18 +73,7 @@ func NewBucketObjectFromAWS(o *s3.Object) BucketObject {
 }
 
 func NewBucketObjectFromOCI(o objectstorage.ObjectSummary) BucketObject {
-	var tier StorageTierEnum
-	switch o.StorageTier {
-	case objectstorage.StorageTierStandard:
-		tier = STierStandard
-		break
-	case objectstorage.StorageTierInfrequentAccess:
-		tier = STierLowAccess
-		break
-	case objectstorage.StorageTierArchive:
-		tier = STierTierArchive
-		break
-	}
+	tier := tierFromOCI(o.StorageTier)
 	lastModified := time.Now()
 	key := ""
 	size := int64(0)
@@ -88,3 +95,86 @@ func NewBucketObjectFromOCI(o objectstorage.ObjectSummary) BucketObject {
 		StorageClass: tier,
 	}
 }
+
+// tierFromAWS translates an S3 storage class into a StorageTierEnum.
+func tierFromAWS(storageClass *string) StorageTierEnum {
+	if storageClass == nil {
+		return STierStandard
+	}
+	switch *storageClass {
+	case s3.ObjectStorageClassStandard, s3.ObjectStorageClassReducedRedundancy, s3.ObjectStorageClassExpressOnezone,
+		s3.ObjectStorageClassOutposts, s3.ObjectStorageClassSnow:
+		return STierStandard
+	case s3.ObjectStorageClassStandardIa, s3.ObjectStorageClassOnezoneIa, s3.ObjectStorageClassIntelligentTiering,
+		s3.ObjectStorageClassGlacierIr:
+		return STierLowAccess
+	case s3.ObjectStorageClassGlacier, s3.ObjectStorageClassDeepArchive:
+		return STierTierArchive
+	}
+	return ""
+}
+
+// tierFromOCI translates an OCI Object Storage tier into a StorageTierEnum.
+func tierFromOCI(tier objectstorage.StorageTierEnum) StorageTierEnum {
+	switch tier {
+	case objectstorage.StorageTierStandard:
+		return STierStandard
+	case objectstorage.StorageTierInfrequentAccess:
+		return STierLowAccess
+	case objectstorage.StorageTierArchive:
+		return STierTierArchive
+	}
+	return ""
+}
+
+// NewBucketObjectFromGCP converts a Cloud Storage object. NEARLINE and COLDLINE objects, which are read
+// without a restore, map to STierLowAccess.
+func NewBucketObjectFromGCP(o GCPObject) BucketObject {
+	var tier StorageTierEnum
+	switch o.StorageClass {
+	case "STANDARD", "MULTI_REGIONAL", "REGIONAL", "DURABLE_REDUCED_AVAILABILITY":
+		tier = STierStandard
+	case "NEARLINE", "COLDLINE":
+		tier = STierLowAccess
+	case "ARCHIVE":
+		tier = STierTierArchive
+	}
+	lastModified := o.Updated
+	if lastModified.IsZero() {
+		lastModified = time.Now()
+	}
+
+	return BucketObject{
+		Key:          o.Name,
+		LastModified: lastModified,
+		Size:         o.Size,
+		StorageClass: tier,
+		ContentType:  o.ContentType,
+	}
+}
+
+// NewBucketObjectFromAzure converts a blob of a container listing. Cool and Cold blobs, which are read
+// without a rehydration, map to STierLowAccess.
+func NewBucketObjectFromAzure(b AzureBlob) BucketObject {
+	var tier StorageTierEnum
+	switch b.Properties.AccessTier {
+	case "Hot", "Premium":
+		tier = STierStandard
+	case "Cool", "Cold":
+		tier = STierLowAccess
+	case "Archive":
+		tier = STierTierArchive
+	}
+	lastModified, err := http.ParseTime(b.Properties.LastModified)
+	if err != nil {
+		lastModified = time.Now()
+	}
+
+	return BucketObject{
+		Key:          b.Name,
+		LastModified: lastModified,
+		Size:         b.Properties.ContentLength,
+		StorageClass: tier,
+		ContentType:  b.Properties.ContentType,
+	}
+}
@@ -0,0 +1,49 @@
+package bucket
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"testing"
+)
+
+// TestNewBucketObjectFromAWS verifies the tier of every S3 storage class.
+func TestNewBucketObjectFromAWS(t *testing.T) {
+	tests := []struct {
+		storageClass *string
+		tier         StorageTierEnum
+	}{
+		{storageClass: nil, tier: STierStandard},
+		{storageClass: aws.String("STANDARD"), tier: STierStandard},
+		{storageClass: aws.String("REDUCED_REDUNDANCY"), tier: STierStandard},
+		{storageClass: aws.String("EXPRESS_ONEZONE"), tier: STierStandard},
+		{storageClass: aws.String("OUTPOSTS"), tier: STierStandard},
+		{storageClass: aws.String("SNOW"), tier: STierStandard},
+		{storageClass: aws.String("STANDARD_IA"), tier: STierLowAccess},
+		{storageClass: aws.String("ONEZONE_IA"), tier: STierLowAccess},
+		{storageClass: aws.String("INTELLIGENT_TIERING"), tier: STierLowAccess},
+		{storageClass: aws.String("GLACIER_IR"), tier: STierLowAccess},
+		{storageClass: aws.String("GLACIER"), tier: STierTierArchive},
+		{storageClass: aws.String("DEEP_ARCHIVE"), tier: STierTierArchive},
+		{storageClass: aws.String("UNKNOWN"), tier: ""},
+	}
+
+	// Every class known to the SDK must be covered
+	covered := map[string]bool{}
+	for _, tt := range tests {
+		covered[aws.StringValue(tt.storageClass)] = true
+	}
+	for _, storageClass := range s3.ObjectStorageClass_Values() {
+		if !covered[storageClass] {
+			t.Errorf("storage class %s is not covered", storageClass)
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(aws.StringValue(tt.storageClass), func(t *testing.T) {
+			object := NewBucketObjectFromAWS(&s3.Object{Key: aws.String("key"), StorageClass: tt.storageClass})
+			if object.StorageClass != tt.tier {
+				t.Errorf("expected tier %q, got %q", tt.tier, object.StorageClass)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package bucket
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+	"sync"
+)
+
+// objectChecksum is a checksum stored by the provider that the downloaded bytes are verified against.
+type objectChecksum struct {
+	algorithm string    // Name of the algorithm, used in error messages.
+	hash      hash.Hash // Hash fed with the bytes as stored, before any decompression.
+	expected  []byte
+}
+
+// base64Checksum returns the checksum for a base64 header value, or nil when the value is missing,
+// is malformed or is a composite multipart checksum (e.g. "<base64>-3"), which cannot be computed from the content.
+func base64Checksum(algorithm string, h hash.Hash, value *string) *objectChecksum {
+	if value == nil || *value == "" || strings.Contains(*value, "-") {
+		return nil
+	}
+	expected, err := base64.StdEncoding.DecodeString(*value)
+	if err != nil || len(expected) != h.Size() {
+		return nil
+	}
+	return &objectChecksum{algorithm: algorithm, hash: h, expected: expected}
+}
+
+// etagChecksum returns the MD5 checksum carried by an S3 ETag, or nil for multipart and encrypted
+// objects whose ETag is not the MD5 of the content.
+func etagChecksum(etag *string) *objectChecksum {
+	if etag == nil {
+		return nil
+	}
+	expected, err := hex.DecodeString(strings.Trim(*etag, `"`))
+	if err != nil || len(expected) != md5.Size {
+		return nil
+	}
+	return &objectChecksum{algorithm: "MD5", hash: md5.New(), expected: expected}
+}
+
+// firstChecksum returns the first available checksum, in order of preference.
+func firstChecksum(checksums ...*objectChecksum) *objectChecksum {
+	for _, checksum := range checksums {
+		if checksum != nil {
+			return checksum
+		}
+	}
+	return nil
+}
+
+// ociChecksum picks the checksum to verify an OCI download against: the Content-MD5 of single-part
+// objects, otherwise the CRC32C that OCI stores for every object, or an upload-time SHA digest.
+func ociChecksum(contentMD5, crc32c, sha256Value, sha384Value *string) *objectChecksum {
+	return firstChecksum(
+		base64Checksum("MD5", md5.New(), contentMD5),
+		base64Checksum("SHA256", sha256.New(), sha256Value),
+		base64Checksum("SHA384", sha512.New384(), sha384Value),
+		base64Checksum("CRC32C", crc32.New(crc32.MakeTable(crc32.Castagnoli)), crc32c),
+	)
+}
+
+// awsChecksum picks the checksum to verify an S3 download against: a full-object x-amz-checksum-* value
+// when the object was uploaded with one, otherwise the ETag when it is a plain MD5.
+func awsChecksum(sha256Value, sha1Value, crc32cValue, crc32Value, etag *string) *objectChecksum {
+	return firstChecksum(
+		base64Checksum("SHA256", sha256.New(), sha256Value),
+		base64Checksum("SHA1", sha1.New(), sha1Value),
+		base64Checksum("CRC32C", crc32.New(crc32.MakeTable(crc32.Castagnoli)), crc32cValue),
+		base64Checksum("CRC32", crc32.NewIEEE(), crc32Value),
+		etagChecksum(etag),
+	)
+}
+
+// writeObject copies the object content to w. With opts.AutoDecompress, content whose encoding is gzip
+// is decompressed first. When checksum is set, the bytes received are verified against it once the
+// whole object has been read; w has then already received the content, even on ErrChecksumMismatch.
+func writeObject(w io.Writer, content io.Reader, objectName string, contentEncoding *string, opts DownloadOptions, checksum *objectChecksum) error {
+	raw := content
+	if checksum != nil {
+		raw = io.TeeReader(content, checksum.hash)
+	}
+
+	reader := raw
+	if opts.AutoDecompress && contentEncoding != nil && strings.EqualFold(*contentEncoding, "gzip") {
+		gz, err := gzip.NewReader(raw)
+		if err != nil {
+			return fmt.Errorf("failed to decompress '%s': %w", objectName, err)
+		}
+		defer func() { _ = gz.Close() }()
+		reader = gz
+	}
+
+	if _, err := io.Copy(w, reader); err != nil {
+		return fmt.Errorf("failed to download '%s': %w", objectName, err)
+	}
+	if checksum == nil {
+		return nil
+	}
+
+	// The gzip reader may stop before the end of the stored bytes, which must all be hashed.
+	if _, err := io.Copy(io.Discard, raw); err != nil {
+		return fmt.Errorf("failed to download '%s': %w", objectName, err)
+	}
+	if actual := checksum.hash.Sum(nil); !bytes.Equal(actual, checksum.expected) {
+		return fmt.Errorf("%w: %s of '%s' is %s, expected %s", ErrChecksumMismatch, checksum.algorithm, objectName,
+			base64.StdEncoding.EncodeToString(actual), base64.StdEncoding.EncodeToString(checksum.expected))
+	}
+	return nil
+}
+
+// validateRange checks the bytes from start to end (both inclusive) requested from an object by DownloadRange.
+func validateRange(start, end int64) error {
+	if start < 0 || end < 0 {
+		return fmt.Errorf("invalid range %d-%d: negative offsets and suffix ranges are not supported", start, end)
+	}
+	if start > end {
+		return fmt.Errorf("invalid range %d-%d: start is after end", start, end)
+	}
+	return nil
+}
+
+// downloadParts writes the size bytes of an object to f with ranged GETs of partSize bytes, running up to
+// threads of them at once. get returns the bytes from offset to end, both inclusive. The first failure
+// stops the parts not started yet and is returned once the running ones have ended.
+func downloadParts(f io.WriterAt, size, partSize int64, threads int, get func(offset, end int64) (io.ReadCloser, error)) error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	slots := make(chan struct{}, threads)
+	for offset := int64(0); offset < size; offset += partSize {
+		slots <- struct{}{}
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+
+		wg.Add(1)
+		go func(offset, end int64) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := downloadPart(f, offset, end, get); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(offset, min(offset+partSize, size)-1)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// downloadPart writes the bytes from offset to end (inclusive) of an object at the same offset of f.
+func downloadPart(f io.WriterAt, offset, end int64, get func(offset, end int64) (io.ReadCloser, error)) error {
+	content, err := get(offset, end)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = content.Close() }()
+
+	n, err := io.Copy(io.NewOffsetWriter(f, offset), io.LimitReader(content, end-offset+1))
+	if err != nil {
+		return err
+	}
+	if n != end-offset+1 {
+		return fmt.Errorf("part at byte %d ended after %d of %d bytes", offset, n, end-offset+1)
+	}
+	return nil
+}
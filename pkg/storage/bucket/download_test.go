@@ -0,0 +1,152 @@
+package bucket
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// fakeRangeHandler serves HEAD and ranged GET requests ("bytes=start-end") for a single object, failing
+// the GETs whose If-Match differs from the ETag when one is sent, and counts the GETs served.
+func fakeRangeHandler(content []byte, etag string, gets *int) http.HandlerFunc {
+	var mu sync.Mutex
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		if r.Method == http.MethodHead {
+			w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+			return
+		}
+
+		mu.Lock()
+		*gets++
+		mu.Unlock()
+		if match := r.Header.Get("If-Match"); match != "" && match != etag {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		var start, end int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err != nil || start > end {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		end = min(end, len(content)-1)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(content)))
+		w.Header().Set("Content-Length", fmt.Sprint(end-start+1))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write(content[start : end+1])
+	}
+}
+
+// downloadContent returns 300 KiB of varying bytes, spanning three parts of the minimum part size.
+func downloadContent() []byte {
+	content := make([]byte, 300*1024)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	return content
+}
+
+// TestAWSManager_DownloadToFile verifies that the object is downloaded in parts into the file.
+func TestAWSManager_DownloadToFile(t *testing.T) {
+	content := downloadContent()
+	var gets int
+	server := httptest.NewServer(fakeRangeHandler(content, `"v1"`, &gets))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "object.bin")
+	if err := newTestAWSManager(t, server.URL).DownloadToFile("bucket", "object.bin", path, 131072, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(data, content) {
+		t.Errorf("unexpected file content (%d bytes, %v)", len(data), err)
+	}
+	if gets != 3 {
+		t.Errorf("expected 3 ranged GETs, got %d", gets)
+	}
+}
+
+// TestOCIManager_DownloadToFile verifies the ranged parts of OCI, and that the file is removed when a
+// part fails.
+func TestOCIManager_DownloadToFile(t *testing.T) {
+	content := downloadContent()
+	var gets int
+	handler := fakeRangeHandler(content, "v1", &gets)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	manager := newTestOCIManager(t, server.URL)
+	path := filepath.Join(t.TempDir(), "object.bin")
+	if err := manager.DownloadToFile("bucket", "object.bin", path, 131072, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(data, content) {
+		t.Errorf("unexpected file content (%d bytes, %v)", len(data), err)
+	}
+	if gets != 3 {
+		t.Errorf("expected 3 ranged GETs, got %d", gets)
+	}
+
+	// The object changes between the HEAD and the GETs.
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			handler(w, r)
+			return
+		}
+		r.Header.Set("If-Match", "v0")
+		handler(w, r)
+	})
+	if err := manager.DownloadToFile("bucket", "object.bin", path, 131072, 2); err == nil {
+		t.Fatal("expected an error when a part fails")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the file to be removed, got %v", err)
+	}
+}
+
+// TestDownloadRange verifies that AWS and OCI write only the requested bytes, clamp ranges past the end
+// of the object, and reject negative and reversed ranges without a request.
+func TestDownloadRange(t *testing.T) {
+	content := downloadContent()
+	var gets int
+	server := httptest.NewServer(fakeRangeHandler(content, "v1", &gets))
+	defer server.Close()
+
+	managers := map[string]BucketManager{
+		"aws": newTestAWSManager(t, server.URL),
+		"oci": newTestOCIManager(t, server.URL),
+	}
+	for name, manager := range managers {
+		var header bytes.Buffer
+		if err := manager.DownloadRange("bucket", "object.bin", 10, 19, &header); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if !bytes.Equal(header.Bytes(), content[10:20]) {
+			t.Errorf("%s: expected bytes 10-19, got %v", name, header.Bytes())
+		}
+
+		var tail bytes.Buffer
+		if err := manager.DownloadRange("bucket", "object.bin", int64(len(content)-5), int64(len(content)+100), &tail); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if !bytes.Equal(tail.Bytes(), content[len(content)-5:]) {
+			t.Errorf("%s: expected the last 5 bytes, got %d bytes", name, tail.Len())
+		}
+
+		gets = 0
+		for _, r := range [][2]int64{{20, 10}, {-5, 10}, {0, -1}} {
+			if err := manager.DownloadRange("bucket", "object.bin", r[0], r[1], &bytes.Buffer{}); err == nil {
+				t.Errorf("%s: expected an error for the range %d-%d", name, r[0], r[1])
+			}
+		}
+		if gets != 0 {
+			t.Errorf("%s: expected invalid ranges to be rejected before any request, got %d GETs", name, gets)
+		}
+	}
+}
@@ -0,0 +1,962 @@
+package bucket
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/diegoyosiura/cloud-manager/pkg/observer"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gcpChunkSize is the granularity of the chunks of a resumable upload: every chunk but the last
+// must be a multiple of it.
+const gcpChunkSize = 256 * 1024
+
+// gcpResponseError is an error response of the Cloud Storage JSON API.
+type gcpResponseError struct {
+	StatusCode int
+	Reason     string
+	Message    string
+}
+
+func (e *gcpResponseError) Error() string {
+	return fmt.Sprintf("gcp request failed with status %d: %s: %s", e.StatusCode, e.Reason, e.Message)
+}
+
+// GCPTokenSource provides the OAuth access tokens authorizing the Cloud Storage requests.
+// It is implemented by authentication.GCPCredential.
+type GCPTokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// GCPObject is an object resource of the Cloud Storage JSON API.
+type GCPObject struct {
+	Name         string    `json:"name,omitempty"`
+	Size         int64     `json:"size,string,omitempty"`
+	StorageClass string    `json:"storageClass,omitempty"` // "STANDARD", "NEARLINE", "COLDLINE" or "ARCHIVE".
+	ContentType  string    `json:"contentType,omitempty"`
+	Updated      time.Time `json:"updated"`
+	Etag         string    `json:"etag,omitempty"`
+	Generation   int64     `json:"generation,string,omitempty"` // Version of the object's content.
+}
+
+// GCPStorageClient calls the Cloud Storage JSON API for one project. It is written against the REST API
+// rather than the cloud.google.com/go/storage module, which would bring in its gRPC transport for the
+// bucket, object, resumable upload and notification calls made here, and it authorizes its requests with
+// the GCPCredential of GCPAuth, which also holds the service account key the manager signs URLs with.
+type GCPStorageClient struct {
+	Credential GCPTokenSource // Source of the access tokens.
+	ProjectID  string         // Project whose buckets are managed.
+	Endpoint   string         // API endpoint (defaults to https://storage.googleapis.com).
+	HTTPClient *http.Client   // HTTP client used for the requests (defaults to http.DefaultClient).
+}
+
+// url returns the URL of path (e.g. "storage/v1/b/name") on the endpoint.
+func (c *GCPStorageClient) url(path string, query url.Values) string {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = "https://storage.googleapis.com"
+	}
+	target := strings.TrimRight(endpoint, "/") + "/" + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	return target
+}
+
+// send authorizes and sends req, converting error responses into *gcpResponseError. The caller
+// closes the body of the returned response.
+func (c *GCPStorageClient) send(req *http.Request) (*http.Response, error) {
+	token, err := c.Credential.Token(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GCP token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer func() { _ = resp.Body.Close() }()
+		var body struct {
+			Error struct {
+				Message string `json:"message"`
+				Errors  []struct {
+					Reason string `json:"reason"`
+				} `json:"errors"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		respErr := &gcpResponseError{StatusCode: resp.StatusCode, Message: body.Error.Message}
+		if len(body.Error.Errors) > 0 {
+			respErr.Reason = body.Error.Errors[0].Reason
+		}
+		return nil, respErr
+	}
+	return resp, nil
+}
+
+// do sends a request with the JSON encoding of in as body, when not nil, and decodes the JSON
+// response into out, when not nil. path is relative to the endpoint.
+func (c *GCPStorageClient) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url(path, query), body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode GCP response: %w", err)
+	}
+	return nil
+}
+
+// gcpObjectPath returns the JSON API path of an object. Object names are escaped as a single
+// segment, "/" included, as the API requires.
+func gcpObjectPath(bucket, objectName string) string {
+	return "storage/v1/b/" + url.PathEscape(bucket) + "/o/" + url.PathEscape(objectName)
+}
+
+// ListBuckets returns the names of the buckets of the project.
+func (c *GCPStorageClient) ListBuckets(ctx context.Context) ([]string, error) {
+	query := url.Values{"project": {c.ProjectID}}
+	var r []string
+	for {
+		var page struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := c.do(ctx, http.MethodGet, "storage/v1/b", query, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			r = append(r, item.Name)
+		}
+		if page.NextPageToken == "" {
+			return r, nil
+		}
+		query.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// ListObjectsPage returns one page of up to limit objects of the bucket (the API default when not positive)
+// starting at the page token, and the token of the next page, empty after the last one.
+func (c *GCPStorageClient) ListObjectsPage(ctx context.Context, bucket, token string, limit int) ([]GCPObject, string, error) {
+	query := url.Values{}
+	if token != "" {
+		query.Set("pageToken", token)
+	}
+	if limit > 0 {
+		query.Set("maxResults", strconv.Itoa(limit))
+	}
+	var page struct {
+		Items         []GCPObject `json:"items"`
+		NextPageToken string      `json:"nextPageToken"`
+	}
+	if err := c.do(ctx, http.MethodGet, "storage/v1/b/"+url.PathEscape(bucket)+"/o", query, nil, &page); err != nil {
+		return nil, "", err
+	}
+	return page.Items, page.NextPageToken, nil
+}
+
+// ListObjectsPages lists the objects of the bucket whose names start with prefix, calling fn for
+// every page until it returns false.
+func (c *GCPStorageClient) ListObjectsPages(ctx context.Context, bucket, prefix string, fn func(objects []GCPObject) bool) error {
+	query := url.Values{}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	for {
+		var page struct {
+			Items         []GCPObject `json:"items"`
+			NextPageToken string      `json:"nextPageToken"`
+		}
+		if err := c.do(ctx, http.MethodGet, "storage/v1/b/"+url.PathEscape(bucket)+"/o", query, nil, &page); err != nil {
+			return err
+		}
+		if !fn(page.Items) || page.NextPageToken == "" {
+			return nil
+		}
+		query.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// ListObjectsDelimited lists every object of the bucket whose name starts with prefix. With a delimiter,
+// the names containing it after the prefix are returned as prefixes instead of objects.
+func (c *GCPStorageClient) ListObjectsDelimited(ctx context.Context, bucket, prefix, delimiter string) ([]GCPObject, []string, error) {
+	query := url.Values{}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
+
+	var objects []GCPObject
+	var prefixes []string
+	for {
+		var page struct {
+			Items         []GCPObject `json:"items"`
+			Prefixes      []string    `json:"prefixes"`
+			NextPageToken string      `json:"nextPageToken"`
+		}
+		if err := c.do(ctx, http.MethodGet, "storage/v1/b/"+url.PathEscape(bucket)+"/o", query, nil, &page); err != nil {
+			return nil, nil, err
+		}
+		objects = append(objects, page.Items...)
+		prefixes = append(prefixes, page.Prefixes...)
+		if page.NextPageToken == "" {
+			return objects, prefixes, nil
+		}
+		query.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// GetObject returns the metadata of an object.
+func (c *GCPStorageClient) GetObject(ctx context.Context, bucket, objectName string) (GCPObject, error) {
+	var object GCPObject
+	err := c.do(ctx, http.MethodGet, gcpObjectPath(bucket, objectName), nil, nil, &object)
+	return object, err
+}
+
+// RewriteObject copies an object into dstObject of dstBucket with the metadata overrides (e.g.
+// "storageClass"), repeating the rewrite call until Cloud Storage reports it done.
+func (c *GCPStorageClient) RewriteObject(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject string, metadata map[string]string) error {
+	path := gcpObjectPath(srcBucket, srcObject) + "/rewriteTo/b/" + url.PathEscape(dstBucket) + "/o/" + url.PathEscape(dstObject)
+	query := url.Values{}
+	for {
+		var result struct {
+			Done         bool   `json:"done"`
+			RewriteToken string `json:"rewriteToken"`
+		}
+		if err := c.do(ctx, http.MethodPost, path, query, metadata, &result); err != nil {
+			return err
+		}
+		if result.Done {
+			return nil
+		}
+		query.Set("rewriteToken", result.RewriteToken)
+	}
+}
+
+// ReadObject returns the content of an object. When end is not negative, only the bytes from offset
+// to end (inclusive) are returned. A generation other than zero makes the read fail if the object
+// was replaced since.
+func (c *GCPStorageClient) ReadObject(ctx context.Context, bucket, objectName string, offset, end, generation int64) (io.ReadCloser, error) {
+	query := url.Values{"alt": {"media"}}
+	if generation != 0 {
+		query.Set("ifGenerationMatch", strconv.FormatInt(generation, 10))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url(gcpObjectPath(bucket, objectName), query), nil)
+	if err != nil {
+		return nil, err
+	}
+	if end >= 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, end))
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// StartResumableUpload starts a resumable upload of the object described by metadata (e.g. "name" and
+// "storageClass"), returning the session URI its chunks are sent to. query holds the upload
+// preconditions (e.g. ifGenerationMatch).
+func (c *GCPStorageClient) StartResumableUpload(ctx context.Context, bucket string, metadata map[string]string, query url.Values) (string, error) {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return "", err
+	}
+	query.Set("uploadType", "resumable")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("upload/storage/v1/b/"+url.PathEscape(bucket)+"/o", query), bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.send(req)
+	if err != nil {
+		return "", err
+	}
+	_ = resp.Body.Close()
+
+	session := resp.Header.Get("Location")
+	if session == "" {
+		return "", errors.New("gcp resumable upload started without a session URI")
+	}
+	return session, nil
+}
+
+// UploadChunk sends the chunk of a resumable upload starting at offset. The stored object is returned
+// once the last chunk is accepted; intermediate chunks return nil.
+func (c *GCPStorageClient) UploadChunk(ctx context.Context, session string, chunk []byte, offset int64, last bool) (*GCPObject, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, session, bytes.NewReader(chunk))
+	if err != nil {
+		return nil, err
+	}
+
+	total := "*"
+	if last {
+		total = strconv.FormatInt(offset+int64(len(chunk)), 10)
+	}
+	if len(chunk) == 0 {
+		req.Header.Set("Content-Range", "bytes */"+total)
+	} else {
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%s", offset, offset+int64(len(chunk))-1, total))
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// 308 ("Resume Incomplete") acknowledges an intermediate chunk
+	if !last {
+		if resp.StatusCode != http.StatusPermanentRedirect {
+			return nil, fmt.Errorf("unexpected status %d for the chunk at byte %d", resp.StatusCode, offset)
+		}
+		return nil, nil
+	}
+
+	var object GCPObject
+	if err := json.NewDecoder(resp.Body).Decode(&object); err != nil {
+		return nil, fmt.Errorf("failed to decode GCP response: %w", err)
+	}
+	return &object, nil
+}
+
+// CancelResumableUpload discards a resumable upload and the chunks already sent.
+func (c *GCPStorageClient) CancelResumableUpload(ctx context.Context, session string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, session, nil)
+	if err != nil {
+		return
+	}
+	// The API answers 499 once the upload is cancelled
+	if resp, err := c.send(req); err == nil {
+		_ = resp.Body.Close()
+	}
+}
+
+// GCPManager manages Cloud Storage buckets through the JSON API, authenticated with the service account of Auth.
+type GCPManager struct {
+	Auth        *authentication.GCPAuth // GCP authentication details.
+	Client      *GCPStorageClient       // Cloud Storage client, created from Auth on first use.
+	Location    string                  // Location of new buckets (defaults to the US multi-region).
+	StorageTier StorageTierEnum         // Storage class used for new buckets and uploads (defaults to STierStandard).
+	IfNotExists bool                    // Makes uploads fail with ErrObjectExists instead of overwriting an existing object (see UploadOptions).
+
+	Observer observer.Observer // Receives the API call, part and byte metrics when set (optional).
+
+	mu      sync.Mutex      // Guards the creation of Client by setup, which concurrent calls may race on.
+	uploads inflightUploads // Resumable uploads in progress, cancelled by Shutdown.
+}
+
+// setup creates the storage client from the authenticated credentials when it is not set.
+func (g *GCPManager) setup() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.Client != nil {
+		return nil
+	}
+	if g.Auth == nil || g.Auth.Credential == nil {
+		return errors.New("gcp credentials are not initialized; authenticate first")
+	}
+	g.Client = &GCPStorageClient{Credential: g.Auth.Credential, ProjectID: g.Auth.ProjectID}
+	return nil
+}
+
+func (g *GCPManager) ListBuckets() ([]string, error) {
+	if err := g.setup(); err != nil {
+		return nil, err
+	}
+	return g.Client.ListBuckets(context.Background())
+}
+
+func (g *GCPManager) List(name string) (r []BucketObject, err error) {
+	return listAllPages(g, name)
+}
+
+// ListPage returns up to limit objects of the bucket (1000 at most, and by default) starting at the page
+// token, which is empty for the first page, and the token of the next page, empty after the last one.
+func (g *GCPManager) ListPage(name, token string, limit int) ([]BucketObject, string, error) {
+	if err := g.setup(); err != nil {
+		return nil, "", err
+	}
+
+	var objects []GCPObject
+	var next string
+	err := observer.Call(g.Observer, "gcp", "ListObjects", func() (err error) {
+		objects, next, err = g.Client.ListObjectsPage(context.Background(), name, token, limit)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	r := make([]BucketObject, 0, len(objects))
+	for _, o := range objects {
+		r = append(r, NewBucketObjectFromGCP(o))
+	}
+	return r, next, nil
+}
+
+// ListObjectsSince returns the objects of the bucket modified after since. The JSON API has no
+// filter on modification time, so every page of the listing is fetched and filtered on the client.
+func (g *GCPManager) ListObjectsSince(name string, since time.Time) ([]BucketObject, error) {
+	return g.listObjects(name, func(o BucketObject) bool { return o.LastModified.After(since) })
+}
+
+// listObjects returns the objects of the bucket accepted by keep.
+func (g *GCPManager) listObjects(name string, keep func(BucketObject) bool) ([]BucketObject, error) {
+	if err := g.setup(); err != nil {
+		return nil, err
+	}
+
+	var r []BucketObject
+	err := observer.Call(g.Observer, "gcp", "ListObjects", func() error {
+		return g.Client.ListObjectsPages(context.Background(), name, "", func(objects []GCPObject) bool {
+			for _, o := range objects {
+				if object := NewBucketObjectFromGCP(o); keep(object) {
+					r = append(r, object)
+				}
+			}
+			return true
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// StreamObjects lists the objects of the bucket whose names start with prefix, page by page in a goroutine,
+// sending each one on the returned object channel as the caller receives them, so memory stays bounded.
+func (g *GCPManager) StreamObjects(ctx context.Context, name, prefix string) (<-chan BucketObject, <-chan error) {
+	return streamObjects(ctx, func(send func(BucketObject) bool) error {
+		if err := g.setup(); err != nil {
+			return err
+		}
+		return g.Client.ListObjectsPages(ctx, name, prefix, func(objects []GCPObject) bool {
+			for _, o := range objects {
+				if !send(NewBucketObjectFromGCP(o)) {
+					return false
+				}
+			}
+			return true
+		})
+	})
+}
+
+// ListPrefix returns the objects of the bucket whose names start with prefix. With a delimiter such as
+// "/", the names containing it after the prefix are grouped into the returned prefixes (the "subfolders").
+func (g *GCPManager) ListPrefix(bucket, prefix, delimiter string) ([]BucketObject, []string, error) {
+	if err := g.setup(); err != nil {
+		return nil, nil, err
+	}
+
+	var items []GCPObject
+	var prefixes []string
+	err := observer.Call(g.Observer, "gcp", "ListObjects", func() (err error) {
+		items, prefixes, err = g.Client.ListObjectsDelimited(context.Background(), bucket, prefix, delimiter)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var objects []BucketObject
+	for _, o := range items {
+		objects = append(objects, NewBucketObjectFromGCP(o))
+	}
+	return objects, prefixes, nil
+}
+
+// Create creates the bucket in the manager's location with its storage class. Buckets are ready as
+// soon as the call returns, so waitCreate has no effect.
+func (g *GCPManager) Create(name string, waitCreate bool) error {
+	if err := g.setup(); err != nil {
+		return err
+	}
+
+	storageClass, err := gcpStorageClass(g.StorageTier)
+	if err != nil {
+		return err
+	}
+	bucket := map[string]string{"name": name, "storageClass": storageClass}
+	if g.Location != "" {
+		bucket["location"] = g.Location
+	}
+	return g.Client.do(context.Background(), http.MethodPost, "storage/v1/b", url.Values{"project": {g.Client.ProjectID}}, bucket, nil)
+}
+
+// Exists reports whether the bucket exists. A 404 means the bucket does not exist; other failures,
+// such as a 403, are returned as errors.
+func (g *GCPManager) Exists(name string) (bool, error) {
+	if err := g.setup(); err != nil {
+		return false, err
+	}
+
+	err := g.Client.do(context.Background(), http.MethodGet, "storage/v1/b/"+url.PathEscape(name), nil, nil, nil)
+	if err != nil {
+		var responseErr *gcpResponseError
+		if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check bucket '%s': %w", name, err)
+	}
+	return true, nil
+}
+
+// CreateIfNotExists creates the bucket like Create unless it already exists, reporting whether it was created.
+func (g *GCPManager) CreateIfNotExists(name string, waitCreate bool) (bool, error) {
+	return createIfNotExists(g, name, waitCreate)
+}
+
+func (g *GCPManager) Delete(name string) error {
+	if err := g.setup(); err != nil {
+		return err
+	}
+	return g.Client.do(context.Background(), http.MethodDelete, "storage/v1/b/"+url.PathEscape(name), nil, nil, nil)
+}
+
+func (g *GCPManager) Upload(bucket string, objectName string, f *os.File, partSize int64, threads int) error {
+	_, err := g.UploadWithResult(bucket, objectName, f, partSize, threads)
+	return err
+}
+
+// UploadWithResult uploads the file with a resumable upload in chunks of partSize bytes, rounded down to a
+// multiple of 256 KiB. Chunks must be sent in order, so threads has no effect. The VersionID of the
+// result is the generation of the object.
+func (g *GCPManager) UploadWithResult(bucket string, objectName string, f *os.File, partSize int64, threads int) (UploadResult, error) {
+	return g.uploadStream(bucket, objectName, f, partSize, UploadOptions{IfNotExists: g.IfNotExists})
+}
+
+// UploadWithOptions uploads the file like UploadWithResult. With opts.IfNotExists, the object is
+// written on the condition that it does not exist yet, failing with ErrObjectExists otherwise;
+// the IfNotExists field of the manager applies as well.
+func (g *GCPManager) UploadWithOptions(bucket string, objectName string, f *os.File, partSize int64, threads int, opts UploadOptions) (UploadResult, error) {
+	opts.IfNotExists = opts.IfNotExists || g.IfNotExists
+	return g.uploadStream(bucket, objectName, f, partSize, opts)
+}
+
+// UploadStream uploads the content read from r like Upload, one chunk at a time whatever threads is;
+// size is the number of bytes r yields, or -1 when unknown.
+func (g *GCPManager) UploadStream(bucket, object string, r io.Reader, size int64, partSize int64, threads int) error {
+	_, err := g.uploadStream(bucket, object, expectSize(r, size), partSize, UploadOptions{IfNotExists: g.IfNotExists})
+	return err
+}
+
+func (g *GCPManager) uploadStream(bucket string, objectName string, r io.Reader, partSize int64, opts UploadOptions) (UploadResult, error) {
+	if err := g.setup(); err != nil {
+		return UploadResult{}, err
+	}
+
+	if partSize < 131072 { // 128 * 1024
+		partSize = 10 * 1024 * 1024
+	}
+	partSize = max(partSize-partSize%gcpChunkSize, gcpChunkSize)
+
+	storageClass, err := gcpStorageClass(g.StorageTier)
+	if err != nil {
+		return UploadResult{}, err
+	}
+	query := url.Values{}
+	if opts.IfNotExists {
+		query.Set("ifGenerationMatch", "0")
+	}
+
+	ctx := context.Background()
+	session, err := g.Client.StartResumableUpload(ctx, bucket, map[string]string{"name": objectName, "storageClass": storageClass}, query)
+	if err != nil {
+		return UploadResult{}, gcpUploadError(err)
+	}
+	registration, err := g.uploads.register(func() { g.Client.CancelResumableUpload(context.Background(), session) })
+	if err != nil {
+		g.Client.CancelResumableUpload(ctx, session)
+		return UploadResult{}, err
+	}
+	defer g.uploads.deregister(registration)
+
+	buf := make([]byte, partSize)
+	offset := int64(0)
+	for {
+		n, readErr := io.ReadFull(r, buf)
+		last := readErr == io.EOF || readErr == io.ErrUnexpectedEOF
+		if readErr != nil && !last {
+			g.Client.CancelResumableUpload(ctx, session)
+			return UploadResult{}, readErr
+		}
+
+		var object *GCPObject
+		err := observer.Call(g.Observer, "gcp", "UploadChunk", func() (err error) {
+			object, err = g.Client.UploadChunk(ctx, session, buf[:n], offset, last)
+			return err
+		})
+		if err != nil {
+			g.Client.CancelResumableUpload(ctx, session)
+			return UploadResult{}, gcpUploadError(err)
+		}
+		offset += int64(n)
+		if n > 0 {
+			observer.OrNop(g.Observer).IncCounter(observer.PartsUploaded, map[string]string{"provider": "gcp"})
+			observer.OrNop(g.Observer).AddBytes(observer.BytesUploaded, int64(n))
+		}
+
+		if last {
+			return UploadResult{ETag: object.Etag, VersionID: strconv.FormatInt(object.Generation, 10), Size: offset}, nil
+		}
+	}
+}
+
+// gcpUploadError reports the failed precondition of conditional uploads as ErrObjectExists.
+func gcpUploadError(err error) error {
+	var respErr *gcpResponseError
+	if errors.As(err, &respErr) && respErr.StatusCode == http.StatusPreconditionFailed {
+		return ErrObjectExists
+	}
+	return err
+}
+
+// Shutdown stops accepting uploads and waits for the in-flight ones to finish. If ctx is done
+// first, the resumable uploads still running are cancelled and ctx.Err() is returned.
+func (g *GCPManager) Shutdown(ctx context.Context) error {
+	return g.uploads.shutdown(ctx)
+}
+
+// DownloadLink returns a V4 signed URL of the object valid for expires minutes (at most 7 days),
+// signed with the private key of the service account.
+func (g *GCPManager) DownloadLink(bucketName string, objectName string, expires int64) (string, error) {
+	credential, err := g.signingCredential(expires)
+	if err != nil {
+		return "", err
+	}
+	return gcpSignedURL(credential, http.MethodGet, "storage.googleapis.com", bucketName, objectName, time.Duration(expires)*time.Minute, time.Now())
+}
+
+// UploadLink returns a V4 signed URL, valid for expires minutes (at most 7 days), that uploads the object
+// with a PUT of its content. No Content-Type is signed, so the client may send any: the Content-Type
+// header of the PUT becomes the one of the object. Browsers uploading across origins also need a CORS
+// configuration on the bucket allowing PUT.
+func (g *GCPManager) UploadLink(bucket, object string, expires int64) (string, error) {
+	credential, err := g.signingCredential(expires)
+	if err != nil {
+		return "", err
+	}
+	return gcpSignedURL(credential, http.MethodPut, "storage.googleapis.com", bucket, object, time.Duration(expires)*time.Minute, time.Now())
+}
+
+// signingCredential returns the credential that signs the URLs, checking that it has a private key and
+// that expires (in minutes) is within the 7 days allowed by V4 signatures.
+func (g *GCPManager) signingCredential(expires int64) (*authentication.GCPCredential, error) {
+	if g.Auth == nil || g.Auth.Credential == nil {
+		return nil, errors.New("gcp credentials are not initialized; authenticate first")
+	}
+	if g.Auth.Credential.PrivateKey == nil {
+		return nil, errors.New("signed URLs require a service account key; Application Default Credentials without a key cannot sign them")
+	}
+	if expires <= 0 || expires > 7*24*60 {
+		return nil, fmt.Errorf("signed URL expiration must be between 1 minute and 7 days, got %d minutes", expires)
+	}
+	return g.Auth.Credential, nil
+}
+
+// gcpSignedURL builds a URL of the object for the HTTP method, signed with the GOOG4-RSA-SHA256 algorithm.
+// It is tested against the V4 signing conformance tests of the Cloud Storage client libraries.
+func gcpSignedURL(credential *authentication.GCPCredential, method, host, bucketName, objectName string, expires time.Duration, now time.Time) (string, error) {
+	if bucketName == "" {
+		return "", errors.New("bucket name is required")
+	}
+	if _, err := escapeObjectName(objectName); err != nil {
+		return "", err
+	}
+
+	now = now.UTC()
+	timestamp := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/auto/storage/goog4_request"
+	// The canonical path escapes every character but the unreserved ones and the slashes
+	segments := strings.Split(bucketName+"/"+objectName, "/")
+	for i, segment := range segments {
+		segments[i] = strings.ReplaceAll(url.QueryEscape(segment), "+", "%20")
+	}
+	path := "/" + strings.Join(segments, "/")
+
+	query := map[string]string{
+		"X-Goog-Algorithm":     "GOOG4-RSA-SHA256",
+		"X-Goog-Credential":    credential.ClientEmail + "/" + scope,
+		"X-Goog-Date":          timestamp,
+		"X-Goog-Expires":       strconv.FormatInt(int64(expires/time.Second), 10),
+		"X-Goog-SignedHeaders": "host",
+	}
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+"="+strings.ReplaceAll(url.QueryEscape(query[name]), "+", "%20"))
+	}
+	canonicalQuery := strings.Join(pairs, "&")
+
+	canonicalRequest := strings.Join([]string{
+		method, path, canonicalQuery, "host:" + host + "\n", "host", "UNSIGNED-PAYLOAD",
+	}, "\n")
+	requestDigest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"GOOG4-RSA-SHA256", timestamp, scope, hex.EncodeToString(requestDigest[:])}, "\n")
+
+	digest := sha256.Sum256([]byte(stringToSign))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, credential.PrivateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign URL of '%s': %w", objectName, err)
+	}
+	return "https://" + host + path + "?" + canonicalQuery + "&X-Goog-Signature=" + hex.EncodeToString(signature), nil
+}
+
+// ObjectURL returns the canonical URL of the object (https://storage.googleapis.com/<bucket>/<name>).
+// The URL carries no signature, so it is only usable for objects that are publicly readable; use
+// DownloadLink otherwise.
+func (g *GCPManager) ObjectURL(bucketName string, objectName string) (string, error) {
+	if bucketName == "" {
+		return "", errors.New("bucket name is required")
+	}
+	key, err := escapeObjectName(objectName)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("https://storage.googleapis.com/%s/%s", url.PathEscape(bucketName), key), nil
+}
+
+// Download writes the content of the object to w, as stored.
+func (g *GCPManager) Download(bucketName string, objectName string, w io.Writer) error {
+	if err := g.setup(); err != nil {
+		return err
+	}
+
+	var content io.ReadCloser
+	err := observer.Call(g.Observer, "gcp", "ReadObject", func() (err error) {
+		content, err = g.Client.ReadObject(context.Background(), bucketName, objectName, 0, -1, 0)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = content.Close() }()
+
+	n, err := io.Copy(w, content)
+	observer.OrNop(g.Observer).AddBytes(observer.BytesDownloaded, n)
+	if err != nil {
+		return fmt.Errorf("failed to download '%s': %w", objectName, err)
+	}
+	return nil
+}
+
+// DownloadRange writes the bytes from start to end (both inclusive) of the object to w with a ranged read.
+// A range that goes past the end of the object is cut short by Cloud Storage.
+func (g *GCPManager) DownloadRange(bucket, object string, start, end int64, w io.Writer) error {
+	if err := validateRange(start, end); err != nil {
+		return err
+	}
+	if err := g.setup(); err != nil {
+		return err
+	}
+
+	var content io.ReadCloser
+	err := observer.Call(g.Observer, "gcp", "ReadObject", func() (err error) {
+		content, err = g.Client.ReadObject(context.Background(), bucket, object, start, end, 0)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = content.Close() }()
+
+	n, err := io.Copy(w, content)
+	observer.OrNop(g.Observer).AddBytes(observer.BytesDownloaded, n)
+	if err != nil {
+		return fmt.Errorf("failed to download '%s': %w", object, err)
+	}
+	return nil
+}
+
+// DownloadToFile downloads the object into the file at path with ranged reads of partSize bytes, up to
+// threads at once (10 MiB and 4 by default, as Upload). Every part must come from the generation of the
+// object seen when the download started. The file is removed when the download fails.
+func (g *GCPManager) DownloadToFile(bucket string, objectName string, path string, partSize int64, threads int) error {
+	if partSize < 131072 { // 128 * 1024
+		partSize = 10 * 1024 * 1024
+	}
+	if threads <= 0 {
+		threads = 4
+	}
+
+	object, err := g.StatObject(bucket, objectName)
+	if err != nil {
+		return err
+	}
+	generation, _ := strconv.ParseInt(object.ETag, 10, 64)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = downloadParts(f, object.Size, partSize, threads, func(offset, end int64) (io.ReadCloser, error) {
+		var content io.ReadCloser
+		err := observer.Call(g.Observer, "gcp", "ReadObject", func() (err error) {
+			content, err = g.Client.ReadObject(context.Background(), bucket, objectName, offset, end, generation)
+			return err
+		})
+		return content, err
+	})
+	if err == nil {
+		observer.OrNop(g.Observer).AddBytes(observer.BytesDownloaded, object.Size)
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("failed to download '%s' to '%s': %w", objectName, path, err)
+	}
+	return nil
+}
+
+// StatObject returns the metadata of the object. The ETag of the result is the generation of the
+// object, which identifies its content (the etag of the JSON API also changes with its metadata).
+func (g *GCPManager) StatObject(bucketName string, objectName string) (BucketObject, error) {
+	if err := g.setup(); err != nil {
+		return BucketObject{}, err
+	}
+
+	o, err := g.Client.GetObject(context.Background(), bucketName, objectName)
+	if err != nil {
+		return BucketObject{}, err
+	}
+	object := NewBucketObjectFromGCP(o)
+	object.ETag = strconv.FormatInt(o.Generation, 10)
+	return object, nil
+}
+
+// Stat returns the metadata of the object like StatObject, or found=false when Cloud Storage answers 404.
+// Other failures, such as a 403, are returned as errors.
+func (g *GCPManager) Stat(bucket, object string) (*BucketObject, bool, error) {
+	result, err := g.StatObject(bucket, object)
+	if err != nil {
+		var responseErr *gcpResponseError
+		if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to stat '%s': %w", object, err)
+	}
+	return &result, true, nil
+}
+
+func (g *GCPManager) Update(bucket string, objectName string, f *os.File, partSize int64, threads int) error {
+	return g.Upload(bucket, objectName, f, partSize, threads)
+}
+
+func (g *GCPManager) DeleteObject(bucketName string, objectName string) error {
+	if err := g.setup(); err != nil {
+		return err
+	}
+	return g.Client.do(context.Background(), http.MethodDelete, gcpObjectPath(bucketName, objectName), nil, nil, nil)
+}
+
+// ChangeStorageTier moves the object to the storage class of tier by rewriting it onto itself, which
+// takes several calls for large objects.
+func (g *GCPManager) ChangeStorageTier(bucket, key string, tier StorageTierEnum) error {
+	if err := g.setup(); err != nil {
+		return err
+	}
+
+	storageClass, err := gcpStorageClass(tier)
+	if err != nil {
+		return err
+	}
+
+	if err := g.Client.RewriteObject(context.Background(), bucket, key, bucket, key, map[string]string{"storageClass": storageClass}); err != nil {
+		return fmt.Errorf("failed to change the storage tier of '%s': %w", key, err)
+	}
+	return nil
+}
+
+// Copy copies the object into dstObject of dstBucket on the server side by rewriting it, which takes
+// several calls for large objects.
+func (g *GCPManager) Copy(srcBucket, srcObject, dstBucket, dstObject string) error {
+	if err := g.setup(); err != nil {
+		return err
+	}
+
+	err := observer.Call(g.Observer, "gcp", "RewriteObject", func() error {
+		return g.Client.RewriteObject(context.Background(), srcBucket, srcObject, dstBucket, dstObject, map[string]string{})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy '%s' to '%s': %w", srcObject, dstObject, err)
+	}
+	return nil
+}
+
+// SetNotifications delivers the bucket's object events to the Pub/Sub topic of config.Target
+// ("projects/<project>/topics/<topic>"). Each call adds a notification configuration; Suffix is
+// not supported by Cloud Storage and is ignored.
+func (g *GCPManager) SetNotifications(bucket string, config NotificationConfig) error {
+	if err := g.setup(); err != nil {
+		return err
+	}
+
+	notification, err := gcpNotificationConfig(config)
+	if err != nil {
+		return err
+	}
+	return g.Client.do(context.Background(), http.MethodPost, "storage/v1/b/"+url.PathEscape(bucket)+"/notificationConfigs", nil, notification, nil)
+}
@@ -0,0 +1,243 @@
+package bucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/oracle/oci-go-sdk/v65/events"
+	"strings"
+)
+
+// NotificationEvent is a provider-neutral bucket event that can trigger a notification.
+type NotificationEvent string
+
+const (
+	EventObjectCreated NotificationEvent = "object-created"
+	EventObjectDeleted NotificationEvent = "object-deleted"
+)
+
+// NotificationConfig describes which bucket events are delivered to a target.
+//
+// Target is an SNS topic, SQS queue or Lambda function ARN for AWS, a stream (ocid1.stream...),
+// notification topic (ocid1.onstopic...) or function (ocid1.fnfunc...) OCID for OCI, a Pub/Sub
+// topic ("projects/<project>/topics/<topic>") for GCP, and a webhook URL or the resource ID of a storage
+// queue, event hub, or Service Bus queue or topic for Azure.
+type NotificationConfig struct {
+	ID     string              // Name of the configuration (AWS), display name of the Events rule (OCI) or name of the Event Grid subscription (Azure).
+	Events []NotificationEvent // Events delivered to the target; at least one is required.
+	Target string              // Destination of the notifications.
+	Prefix string              // Only notify for object names starting with Prefix (AWS, GCP and Azure only).
+	Suffix string              // Only notify for object names ending with Suffix (AWS and Azure only).
+}
+
+// validate checks the fields shared by every provider.
+func (c NotificationConfig) validate() error {
+	if c.Target == "" {
+		return fmt.Errorf("notification target is required")
+	}
+	if len(c.Events) == 0 {
+		return fmt.Errorf("at least one notification event is required")
+	}
+	return nil
+}
+
+// awsNotificationConfiguration translates the config into the S3 notification configuration,
+// choosing the topic, queue or Lambda configuration from the service of the target ARN.
+func awsNotificationConfiguration(config NotificationConfig) (*s3.NotificationConfiguration, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	var s3Events []*string
+	for _, event := range config.Events {
+		switch event {
+		case EventObjectCreated:
+			s3Events = append(s3Events, aws.String(s3.EventS3ObjectCreated))
+		case EventObjectDeleted:
+			s3Events = append(s3Events, aws.String(s3.EventS3ObjectRemoved))
+		default:
+			return nil, fmt.Errorf("unsupported notification event: %s", event)
+		}
+	}
+
+	var filter *s3.NotificationConfigurationFilter
+	if config.Prefix != "" || config.Suffix != "" {
+		filter = &s3.NotificationConfigurationFilter{Key: &s3.KeyFilter{}}
+		if config.Prefix != "" {
+			filter.Key.FilterRules = append(filter.Key.FilterRules, &s3.FilterRule{Name: aws.String(s3.FilterRuleNamePrefix), Value: aws.String(config.Prefix)})
+		}
+		if config.Suffix != "" {
+			filter.Key.FilterRules = append(filter.Key.FilterRules, &s3.FilterRule{Name: aws.String(s3.FilterRuleNameSuffix), Value: aws.String(config.Suffix)})
+		}
+	}
+
+	var id *string
+	if config.ID != "" {
+		id = aws.String(config.ID)
+	}
+
+	target, err := arn.Parse(config.Target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification target '%s': %w", config.Target, err)
+	}
+
+	result := &s3.NotificationConfiguration{}
+	switch target.Service {
+	case "sns":
+		result.TopicConfigurations = []*s3.TopicConfiguration{{Id: id, Events: s3Events, Filter: filter, TopicArn: aws.String(config.Target)}}
+	case "sqs":
+		result.QueueConfigurations = []*s3.QueueConfiguration{{Id: id, Events: s3Events, Filter: filter, QueueArn: aws.String(config.Target)}}
+	case "lambda":
+		result.LambdaFunctionConfigurations = []*s3.LambdaFunctionConfiguration{{Id: id, Events: s3Events, Filter: filter, LambdaFunctionArn: aws.String(config.Target)}}
+	default:
+		return nil, fmt.Errorf("unsupported notification target service '%s'", target.Service)
+	}
+	return result, nil
+}
+
+// gcpNotificationConfig builds the Cloud Storage notification configuration publishing the object
+// events to the Pub/Sub topic of the target.
+func gcpNotificationConfig(config NotificationConfig) (map[string]interface{}, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(strings.TrimPrefix(config.Target, "//pubsub.googleapis.com/"), "projects/") {
+		return nil, fmt.Errorf("notification target '%s' is not a Pub/Sub topic", config.Target)
+	}
+
+	var eventTypes []string
+	for _, event := range config.Events {
+		switch event {
+		case EventObjectCreated:
+			eventTypes = append(eventTypes, "OBJECT_FINALIZE")
+		case EventObjectDeleted:
+			eventTypes = append(eventTypes, "OBJECT_DELETE")
+		default:
+			return nil, fmt.Errorf("unsupported notification event: %s", event)
+		}
+	}
+
+	notification := map[string]interface{}{
+		"topic":          "//pubsub.googleapis.com/" + strings.TrimPrefix(config.Target, "//pubsub.googleapis.com/"),
+		"payload_format": "JSON_API_V1",
+		"event_types":    eventTypes,
+	}
+	if config.Prefix != "" {
+		notification["object_name_prefix"] = config.Prefix
+	}
+	return notification, nil
+}
+
+// azureEventSubscription builds the Event Grid subscription delivering the container's blob events to
+// the target, returning its name with it. Storage queues are given by their resource ID
+// (".../storageAccounts/<account>/queueServices/default/queues/<queue>").
+func azureEventSubscription(container string, config NotificationConfig) (string, map[string]interface{}, error) {
+	if err := config.validate(); err != nil {
+		return "", nil, err
+	}
+
+	var eventTypes []string
+	for _, event := range config.Events {
+		switch event {
+		case EventObjectCreated:
+			eventTypes = append(eventTypes, "Microsoft.Storage.BlobCreated")
+		case EventObjectDeleted:
+			eventTypes = append(eventTypes, "Microsoft.Storage.BlobDeleted")
+		default:
+			return "", nil, fmt.Errorf("unsupported notification event: %s", event)
+		}
+	}
+
+	target := strings.ToLower(config.Target)
+	var destination map[string]interface{}
+	switch {
+	case strings.HasPrefix(target, "https://"):
+		destination = map[string]interface{}{"endpointType": "WebHook", "properties": map[string]interface{}{"endpointUrl": config.Target}}
+	case strings.Contains(target, "/providers/microsoft.storage/storageaccounts/") && strings.Contains(target, "/queueservices/default/queues/"):
+		i := strings.Index(target, "/queueservices/default/queues/")
+		destination = map[string]interface{}{"endpointType": "StorageQueue", "properties": map[string]interface{}{
+			"resourceId": config.Target[:i],
+			"queueName":  config.Target[i+len("/queueservices/default/queues/"):],
+		}}
+	case strings.Contains(target, "/providers/microsoft.eventhub/namespaces/") && strings.Contains(target, "/eventhubs/"):
+		destination = map[string]interface{}{"endpointType": "EventHub", "properties": map[string]interface{}{"resourceId": config.Target}}
+	case strings.Contains(target, "/providers/microsoft.servicebus/namespaces/") && strings.Contains(target, "/queues/"):
+		destination = map[string]interface{}{"endpointType": "ServiceBusQueue", "properties": map[string]interface{}{"resourceId": config.Target}}
+	case strings.Contains(target, "/providers/microsoft.servicebus/namespaces/") && strings.Contains(target, "/topics/"):
+		destination = map[string]interface{}{"endpointType": "ServiceBusTopic", "properties": map[string]interface{}{"resourceId": config.Target}}
+	default:
+		return "", nil, fmt.Errorf("unsupported notification target '%s'", config.Target)
+	}
+
+	filter := map[string]interface{}{
+		"includedEventTypes": eventTypes,
+		"subjectBeginsWith":  "/blobServices/default/containers/" + container + "/blobs/" + config.Prefix,
+	}
+	if config.Suffix != "" {
+		filter["subjectEndsWith"] = config.Suffix
+	}
+
+	name := config.ID
+	if name == "" {
+		name = fmt.Sprintf("%s-notifications", container)
+	}
+	return name, map[string]interface{}{"properties": map[string]interface{}{"destination": destination, "filter": filter}}, nil
+}
+
+// ociEventsRuleDetails builds the Events rule that forwards the bucket's object events to the target.
+func ociEventsRuleDetails(bucket string, compartmentID *string, config NotificationConfig) (events.CreateRuleDetails, error) {
+	if err := config.validate(); err != nil {
+		return events.CreateRuleDetails{}, err
+	}
+
+	var eventTypes []string
+	for _, event := range config.Events {
+		switch event {
+		case EventObjectCreated:
+			eventTypes = append(eventTypes, "com.oraclecloud.objectstorage.createobject")
+		case EventObjectDeleted:
+			eventTypes = append(eventTypes, "com.oraclecloud.objectstorage.deleteobject")
+		default:
+			return events.CreateRuleDetails{}, fmt.Errorf("unsupported notification event: %s", event)
+		}
+	}
+
+	condition, err := json.Marshal(map[string]interface{}{
+		"eventType": eventTypes,
+		"data":      map[string]interface{}{"additionalDetails": map[string]interface{}{"bucketName": bucket}},
+	})
+	if err != nil {
+		return events.CreateRuleDetails{}, err
+	}
+
+	enabled := true
+	var action events.ActionDetails
+	switch {
+	case strings.HasPrefix(config.Target, "ocid1.stream."):
+		action = events.CreateStreamingServiceActionDetails{IsEnabled: &enabled, StreamId: &config.Target}
+	case strings.HasPrefix(config.Target, "ocid1.onstopic."):
+		action = events.CreateNotificationServiceActionDetails{IsEnabled: &enabled, TopicId: &config.Target}
+	case strings.HasPrefix(config.Target, "ocid1.fnfunc."):
+		action = events.CreateFaaSActionDetails{IsEnabled: &enabled, FunctionId: &config.Target}
+	default:
+		return events.CreateRuleDetails{}, fmt.Errorf("unsupported notification target '%s'", config.Target)
+	}
+
+	name := config.ID
+	if name == "" {
+		name = fmt.Sprintf("%s-notifications", bucket)
+	}
+	conditionStr := string(condition)
+	description := fmt.Sprintf("Object events of bucket %s", bucket)
+
+	return events.CreateRuleDetails{
+		DisplayName:   &name,
+		Description:   &description,
+		IsEnabled:     &enabled,
+		Condition:     &conditionStr,
+		CompartmentId: compartmentID,
+		Actions:       &events.ActionDetailsList{Actions: []events.ActionDetails{action}},
+	}, nil
+}
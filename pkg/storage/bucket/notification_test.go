@@ -0,0 +1,100 @@
+package bucket
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestAWSManager_SetNotifications verifies the PutBucketNotificationConfiguration request built from the neutral config.
+func TestAWSManager_SetNotifications(t *testing.T) {
+	var body []byte
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/uploads" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		query = r.URL.RawQuery
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	manager := newTestAWSManager(t, server.URL)
+
+	err := manager.SetNotifications("uploads", NotificationConfig{
+		ID:     "process-images",
+		Events: []NotificationEvent{EventObjectCreated, EventObjectDeleted},
+		Target: "arn:aws:sqs:us-east-1:123456789012:images",
+		Prefix: "images/",
+		Suffix: ".png",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if query != "notification=" && query != "notification" {
+		t.Errorf("expected the notification subresource, got query %q", query)
+	}
+
+	var got struct {
+		Queues []struct {
+			ID     string   `xml:"Id"`
+			Queue  string   `xml:"Queue"`
+			Events []string `xml:"Event"`
+			Rules  []struct {
+				Name  string `xml:"Name"`
+				Value string `xml:"Value"`
+			} `xml:"Filter>S3Key>FilterRule"`
+		} `xml:"QueueConfiguration"`
+		Topics  []struct{} `xml:"TopicConfiguration"`
+		Lambdas []struct{} `xml:"CloudFunctionConfiguration"`
+	}
+	if err := xml.Unmarshal(body, &got); err != nil {
+		t.Fatalf("invalid request body %s: %v", body, err)
+	}
+
+	if len(got.Queues) != 1 || len(got.Topics) != 0 || len(got.Lambdas) != 0 {
+		t.Fatalf("expected a single queue configuration, got %s", body)
+	}
+	queue := got.Queues[0]
+	if queue.ID != "process-images" || queue.Queue != "arn:aws:sqs:us-east-1:123456789012:images" {
+		t.Errorf("unexpected queue configuration: %+v", queue)
+	}
+	if len(queue.Events) != 2 || queue.Events[0] != "s3:ObjectCreated:*" || queue.Events[1] != "s3:ObjectRemoved:*" {
+		t.Errorf("unexpected events: %v", queue.Events)
+	}
+	if len(queue.Rules) != 2 || queue.Rules[0].Name != "prefix" || queue.Rules[0].Value != "images/" ||
+		queue.Rules[1].Name != "suffix" || queue.Rules[1].Value != ".png" {
+		t.Errorf("unexpected filter rules: %+v", queue.Rules)
+	}
+}
+
+// TestAwsNotificationConfiguration verifies the configuration chosen for each target service and the invalid configs.
+func TestAwsNotificationConfiguration(t *testing.T) {
+	events := []NotificationEvent{EventObjectCreated}
+
+	topic, err := awsNotificationConfiguration(NotificationConfig{Events: events, Target: "arn:aws:sns:us-east-1:123456789012:uploads"})
+	if err != nil || len(topic.TopicConfigurations) != 1 || topic.TopicConfigurations[0].Filter != nil {
+		t.Errorf("expected an unfiltered topic configuration, got %+v (%v)", topic, err)
+	}
+
+	lambda, err := awsNotificationConfiguration(NotificationConfig{Events: events, Target: "arn:aws:lambda:us-east-1:123456789012:function:thumbs"})
+	if err != nil || len(lambda.LambdaFunctionConfigurations) != 1 {
+		t.Errorf("expected a Lambda configuration, got %+v (%v)", lambda, err)
+	}
+
+	invalid := []NotificationConfig{
+		{Events: events},
+		{Target: "arn:aws:sns:us-east-1:123456789012:uploads"},
+		{Events: []NotificationEvent{"object-restored"}, Target: "arn:aws:sns:us-east-1:123456789012:uploads"},
+		{Events: events, Target: "arn:aws:s3:::other-bucket"},
+		{Events: events, Target: "uploads-topic"},
+	}
+	for _, config := range invalid {
+		if _, err := awsNotificationConfiguration(config); err == nil {
+			t.Errorf("expected an error for %+v", config)
+		}
+	}
+}
@@ -0,0 +1,675 @@
+package bucket
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"github.com/diegoyosiura/cloud-manager/pkg/authentication"
+	"github.com/diegoyosiura/cloud-manager/pkg/backoff"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/objectstorage"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestOCIManager returns an OCIManager whose Object Storage client talks to the given fake endpoint.
+// Requests are signed with a throwaway key, which the fake endpoint does not verify.
+func newTestOCIManager(t *testing.T, endpoint string) *OCIManager {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	provider := common.NewRawConfigurationProvider("ocid1.tenancy", "ocid1.user", "us-ashburn-1", "aa:bb", string(privateKey), nil)
+	client, err := objectstorage.NewObjectStorageClientWithConfigurationProvider(provider)
+	if err != nil {
+		t.Fatalf("unexpected error creating client: %v", err)
+	}
+	client.Host = endpoint
+
+	return &OCIManager{
+		Auth:   &authentication.OCIAuth{Namespace: "my-namespace", CompartmentID: "ocid1.compartment", Region: "us-ashburn-1"},
+		Client: &client,
+	}
+}
+
+// TestOCIManager_UploadContext_Cancel verifies that an upload with a cancelled context fails with a
+// cancellation error without sending any request. The context is cancelled before the upload starts:
+// the SDK reads its response variables unsynchronized when a call in flight is cancelled.
+func TestOCIManager_UploadContext_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(path, make([]byte, 3*131072), 0o600); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error opening file: %v", err)
+	}
+	defer f.Close()
+
+	err = newTestOCIManager(t, server.URL).UploadContext(ctx, "my-bucket", "big.bin", f, 131072, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancellation error, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if requests != 0 {
+		t.Errorf("expected no request for a cancelled upload, got %d", requests)
+	}
+}
+
+// TestOCIManager_UploadContext_AbortOnFailure verifies that a part failing with a non-retryable error
+// makes the upload fail and aborts the multipart upload, discarding the parts already stored.
+func TestOCIManager_UploadContext_AbortOnFailure(t *testing.T) {
+	var mu sync.Mutex
+	aborted := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/u"):
+			// CreateMultipartUpload
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"uploadId":"upload-1","namespace":"my-namespace","bucket":"my-bucket","object":"big.bin"}`))
+		case r.Method == http.MethodPut:
+			// UploadPart
+			_, _ = io.Copy(io.Discard, r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":"InvalidParameter","message":"part rejected"}`))
+		case r.Method == http.MethodDelete && r.URL.Query().Get("uploadId") == "upload-1":
+			// AbortMultipartUpload
+			mu.Lock()
+			aborted = true
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(path, make([]byte, 3*131072), 0o600); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error opening file: %v", err)
+	}
+	defer f.Close()
+
+	if err := newTestOCIManager(t, server.URL).UploadContext(context.Background(), "my-bucket", "big.bin", f, 131072, 1); err == nil {
+		t.Fatal("expected the failed part to fail the upload")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !aborted {
+		t.Error("expected the multipart upload to be aborted")
+	}
+}
+
+// TestOCIManager_UploadWithResult verifies that the ETag and version ID of the committed upload are returned.
+func TestOCIManager_UploadWithResult(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/u"):
+			// CreateMultipartUpload
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"uploadId":"upload-1","namespace":"my-namespace","bucket":"my-bucket","object":"data.bin"}`))
+		case r.Method == http.MethodPut:
+			// UploadPart
+			_, _ = io.Copy(io.Discard, r.Body)
+			w.Header().Set("etag", "part-etag-"+r.URL.Query().Get("uploadPartNum"))
+		case r.Method == http.MethodPost && r.URL.Query().Get("uploadId") == "upload-1":
+			// CommitMultipartUpload
+			w.Header().Set("etag", "object-etag")
+			w.Header().Set("version-id", "version-1")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, make([]byte, 2*131072+10), 0o600); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error opening file: %v", err)
+	}
+	defer f.Close()
+
+	result, err := newTestOCIManager(t, server.URL).UploadWithResult("my-bucket", "data.bin", f, 131072, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ETag != "object-etag" || result.VersionID != "version-1" || result.Size != 2*131072+10 {
+		t.Errorf("unexpected upload result: %+v", result)
+	}
+}
+
+// TestOCIManager_UploadStream verifies that content read from a bytes.Reader is uploaded and committed
+// as a multipart upload.
+func TestOCIManager_UploadStream(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		received  int64
+		committed bool
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/u"):
+			// CreateMultipartUpload
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"uploadId":"upload-1","namespace":"my-namespace","bucket":"my-bucket","object":"data.bin"}`))
+		case r.Method == http.MethodPut:
+			// UploadPart
+			n, _ := io.Copy(io.Discard, r.Body)
+			received += n
+			w.Header().Set("etag", "part-etag-"+r.URL.Query().Get("uploadPartNum"))
+		case r.Method == http.MethodPost && r.URL.Query().Get("uploadId") == "upload-1":
+			// CommitMultipartUpload
+			committed = true
+			w.Header().Set("etag", "object-etag")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	content := make([]byte, 2*131072+10)
+	err := newTestOCIManager(t, server.URL).UploadStream("my-bucket", "data.bin", bytes.NewReader(content), int64(len(content)), 131072, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !committed || received != int64(len(content)) {
+		t.Errorf("expected %d bytes committed, got %d (committed %v)", len(content), received, committed)
+	}
+}
+
+// TestOCIManager_UploadWithOptions_IfNotExists verifies that a second upload to the same object with
+// IfNotExists fails with ErrObjectExists, leaving the committed object untouched.
+func TestOCIManager_UploadWithOptions_IfNotExists(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		commits int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPost && r.Header.Get("if-none-match") != "*" {
+			t.Errorf("expected an if-none-match header on %s %s", r.Method, r.URL.Path)
+		}
+		exists := commits > 0
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/u") && exists,
+			r.Method == http.MethodPost && r.URL.Query().Get("uploadId") == "upload-1" && exists:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusPreconditionFailed)
+			_, _ = w.Write([]byte(`{"code":"IfNoneMatchFailed","message":"The object already exists"}`))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/u"):
+			// CreateMultipartUpload
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"uploadId":"upload-1","namespace":"my-namespace","bucket":"my-bucket","object":"data.bin"}`))
+		case r.Method == http.MethodPut:
+			// UploadPart
+			_, _ = io.Copy(io.Discard, r.Body)
+			w.Header().Set("etag", "part-etag-"+r.URL.Query().Get("uploadPartNum"))
+		case r.Method == http.MethodPost && r.URL.Query().Get("uploadId") == "upload-1":
+			// CommitMultipartUpload
+			commits++
+			w.Header().Set("etag", "object-etag")
+		case r.Method == http.MethodDelete:
+			// AbortMultipartUpload
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, make([]byte, 2*131072+10), 0o600); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+	manager := newTestOCIManager(t, server.URL)
+	upload := func() error {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("unexpected error opening file: %v", err)
+		}
+		defer f.Close()
+		_, err = manager.UploadWithOptions("my-bucket", "data.bin", f, 131072, 1, UploadOptions{IfNotExists: true})
+		return err
+	}
+
+	if err := upload(); err != nil {
+		t.Fatalf("unexpected error on first upload: %v", err)
+	}
+	if err := upload(); !errors.Is(err, ErrObjectExists) {
+		t.Fatalf("expected ErrObjectExists on second upload, got %v", err)
+	}
+	if commits != 1 {
+		t.Errorf("expected a single commit, got %d", commits)
+	}
+}
+
+// TestOCIManager_Download_Archived verifies that downloading an archived object fails with ErrObjectArchived,
+// that StatObject reports the archive tier and state, and that the object downloads once restored.
+func TestOCIManager_Download_Archived(t *testing.T) {
+	var mu sync.Mutex
+	state := "Archived"
+	restoreRequested := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodHead:
+			w.Header().Set("storage-tier", "Archive")
+			w.Header().Set("archival-state", state)
+			if state == "Restored" {
+				w.Header().Set("time-of-archival", "2030-01-02T03:04:05Z")
+			}
+		case r.Method == http.MethodGet && state != "Restored":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"code":"NotRestored","message":"The object 'cold.bin' is not restored"}`))
+		case r.Method == http.MethodGet:
+			_, _ = w.Write([]byte("cold data"))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/actions/restoreObjects"):
+			body, _ := io.ReadAll(r.Body)
+			restoreRequested = strings.Contains(string(body), `"objectName":"cold.bin"`)
+			state = "Restoring"
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	defer server.Close()
+
+	manager := newTestOCIManager(t, server.URL)
+
+	object, err := manager.StatObject("my-bucket", "cold.bin")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if object.StorageClass != STierTierArchive || object.ArchivalState != ArchivalStateArchived || object.RestoreEstimate <= 0 {
+		t.Errorf("expected an archived object with a restore estimate, got %+v", object)
+	}
+
+	var archivedErr *ErrObjectArchived
+	err = manager.Download("my-bucket", "cold.bin", io.Discard)
+	if !errors.As(err, &archivedErr) || archivedErr.State != ArchivalStateArchived {
+		t.Fatalf("expected ErrObjectArchived in the archived state, got %v", err)
+	}
+
+	if err := manager.RestoreObject("my-bucket", "cold.bin", 0); err != nil {
+		t.Fatalf("unexpected error restoring: %v", err)
+	}
+	if !restoreRequested {
+		t.Error("expected a restore request for cold.bin")
+	}
+	err = manager.Download("my-bucket", "cold.bin", io.Discard)
+	if !errors.As(err, &archivedErr) || archivedErr.State != ArchivalStateRestoring {
+		t.Fatalf("expected ErrObjectArchived in the restoring state, got %v", err)
+	}
+
+	mu.Lock()
+	state = "Restored"
+	mu.Unlock()
+
+	object, err = manager.StatObject("my-bucket", "cold.bin")
+	if err != nil || object.ArchivalState != ArchivalStateRestored || object.RestoredUntil.IsZero() {
+		t.Errorf("expected a restored object with its archival time, got %+v (%v)", object, err)
+	}
+
+	var content strings.Builder
+	if err := manager.Download("my-bucket", "cold.bin", &content); err != nil || content.String() != "cold data" {
+		t.Errorf("expected the restored content, got %q (%v)", content.String(), err)
+	}
+}
+
+// TestOCIManager_DownloadWithOptions_AutoDecompress verifies that gzip-encoded objects are decompressed
+// only when AutoDecompress is set, based on the Content-Encoding header rather than the object name.
+func TestOCIManager_DownloadWithOptions_AutoDecompress(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write([]byte("original content")); err != nil {
+		t.Fatalf("unexpected error compressing: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("unexpected error compressing: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/o/data.json"):
+			w.Header().Set("Content-Encoding", "gzip")
+			_, _ = w.Write(compressed.Bytes())
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/o/archive.gz"):
+			// A gzip file stored as-is, without Content-Encoding, must never be decompressed.
+			_, _ = w.Write(compressed.Bytes())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	manager := newTestOCIManager(t, server.URL)
+
+	var raw bytes.Buffer
+	if err := manager.Download("my-bucket", "data.json", &raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(raw.Bytes(), compressed.Bytes()) {
+		t.Errorf("expected the raw gzip bytes by default, got %q", raw.Bytes())
+	}
+
+	var decompressed bytes.Buffer
+	if err := manager.DownloadWithOptions("my-bucket", "data.json", &decompressed, DownloadOptions{AutoDecompress: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decompressed.String() != "original content" {
+		t.Errorf("expected the decompressed content, got %q", decompressed.Bytes())
+	}
+
+	var file bytes.Buffer
+	if err := manager.DownloadWithOptions("my-bucket", "archive.gz", &file, DownloadOptions{AutoDecompress: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(file.Bytes(), compressed.Bytes()) {
+		t.Errorf("expected the stored bytes for an object without Content-Encoding, got %q", file.Bytes())
+	}
+}
+
+// TestOCIManager_DownloadWithOptions_VerifyChecksum verifies that corrupted content is detected through
+// the Content-MD5 of single-part objects and the CRC32C of multipart ones.
+func TestOCIManager_DownloadWithOptions_VerifyChecksum(t *testing.T) {
+	content := []byte("original content")
+	md5Sum := md5.Sum(content)
+	crc := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	_, _ = crc.Write(content)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/o/single.txt"):
+			w.Header().Set("Content-MD5", base64.StdEncoding.EncodeToString(md5Sum[:]))
+			_, _ = w.Write(content)
+		case strings.HasSuffix(r.URL.Path, "/o/corrupted.txt"):
+			w.Header().Set("Content-MD5", base64.StdEncoding.EncodeToString(md5Sum[:]))
+			_, _ = w.Write([]byte("corrupted content"))
+		case strings.HasSuffix(r.URL.Path, "/o/multipart.bin"):
+			w.Header().Set("opc-multipart-md5", "bm90LWEtcGxhaW4tbWQ1-2")
+			w.Header().Set("opc-content-crc32c", base64.StdEncoding.EncodeToString(crc.Sum(nil)))
+			_, _ = w.Write([]byte("corrupted content"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	manager := newTestOCIManager(t, server.URL)
+	opts := DownloadOptions{VerifyChecksum: true}
+
+	var out bytes.Buffer
+	if err := manager.DownloadWithOptions("my-bucket", "single.txt", &out, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != string(content) {
+		t.Errorf("expected %q, got %q", content, out.String())
+	}
+
+	if err := manager.DownloadWithOptions("my-bucket", "corrupted.txt", io.Discard, opts); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch for a corrupted single-part object, got %v", err)
+	}
+	if err := manager.DownloadWithOptions("my-bucket", "multipart.bin", io.Discard, opts); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch for a corrupted multipart object, got %v", err)
+	}
+}
+
+// TestOCIManager_ChangeStorageTier verifies the UpdateObjectStorageTier request sent for the object.
+func TestOCIManager_ChangeStorageTier(t *testing.T) {
+	var method, path string
+	var details objectstorage.UpdateObjectStorageTierDetails
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&details)
+	}))
+	defer server.Close()
+
+	manager := newTestOCIManager(t, server.URL)
+	if err := manager.ChangeStorageTier("bucket", "logs/app.log", STierTierArchive); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if method != http.MethodPost || path != "/n/my-namespace/b/bucket/actions/updateObjectStorageTier" {
+		t.Errorf("unexpected request %s %s", method, path)
+	}
+	if details.ObjectName == nil || *details.ObjectName != "logs/app.log" || details.StorageTier != objectstorage.StorageTierArchive {
+		t.Errorf("unexpected request details: %+v", details)
+	}
+
+	if err := manager.ChangeStorageTier("bucket", "logs/app.log", "COLD"); err == nil {
+		t.Error("expected an error for an unsupported tier")
+	}
+}
+
+// TestOCIManager_Copy verifies the copy request, including a source name with spaces and non-ASCII
+// characters, and that Copy waits for the work request to end.
+func TestOCIManager_Copy(t *testing.T) {
+	var details objectstorage.CopyObjectDetails
+	var path string
+	var polls int
+	status := objectstorage.WorkRequestStatusCompleted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet {
+			polls++
+			current := objectstorage.WorkRequestStatusInProgress
+			if polls > 2 {
+				current = status
+			}
+			_ = json.NewEncoder(w).Encode(objectstorage.WorkRequest{Status: current})
+			return
+		}
+		path = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&details)
+		w.Header().Set("opc-work-request-id", "ocid1.workrequest")
+	}))
+	defer server.Close()
+
+	manager := newTestOCIManager(t, server.URL)
+	manager.Backoff = backoff.ConstantBackoff{}
+	manager.CopyRegion = "sa-saopaulo-1"
+	if err := manager.Copy("bucket", "relatórios/año 2024.pdf", "backup", "copies/año 2024.pdf"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != "/n/my-namespace/b/bucket/actions/copyObject" {
+		t.Errorf("unexpected request path %s", path)
+	}
+	if *details.SourceObjectName != "relatórios/año 2024.pdf" || *details.DestinationObjectName != "copies/año 2024.pdf" ||
+		*details.DestinationBucket != "backup" || *details.DestinationNamespace != "my-namespace" || *details.DestinationRegion != "sa-saopaulo-1" {
+		t.Errorf("unexpected copy details: %+v", details)
+	}
+	if polls != 3 {
+		t.Errorf("expected the work request to be polled until it completed, got %d polls", polls)
+	}
+
+	polls, status = 0, objectstorage.WorkRequestStatusFailed
+	manager.CopyRegion = ""
+	if err := manager.Copy("bucket", "a.txt", "backup", "a.txt"); err == nil || !strings.Contains(err.Error(), "FAILED") {
+		t.Errorf("expected the failed work request to be reported, got %v", err)
+	}
+	if *details.DestinationRegion != "us-ashburn-1" {
+		t.Errorf("expected the region of the manager by default, got %s", *details.DestinationRegion)
+	}
+}
+
+// TestOCIManager_Create_Wait verifies that Create polls the bucket with HeadBucket until it exists, and
+// fails with a timeout when the bucket never becomes ready.
+func TestOCIManager_Create_Wait(t *testing.T) {
+	var mu sync.Mutex
+	heads, readyAfter := 0, 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodHead {
+			_, _ = w.Write([]byte(`{"name":"bucket"}`))
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		heads++
+		if readyAfter < 0 || heads <= readyAfter {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	manager := newTestOCIManager(t, server.URL)
+	manager.Backoff = backoff.ConstantBackoff{Delay: time.Millisecond}
+	if err := manager.Create("bucket", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mu.Lock()
+	if heads != 3 {
+		t.Errorf("expected 3 HeadBucket calls, got %d", heads)
+	}
+	readyAfter = -1
+	mu.Unlock()
+	// The timeout expires while waiting for the next poll, which Create must not wait for.
+	manager.Backoff = backoff.ConstantBackoff{Delay: time.Minute}
+	manager.CreateTimeout = 200 * time.Millisecond
+	start := time.Now()
+	err := manager.Create("bucket", true)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected Create to stop at the timeout, took %s", elapsed)
+	}
+}
+
+// TestOCIManager_Create_Forbidden verifies that Create stops polling at the first HeadBucket error other
+// than a 404 or a transient failure, instead of retrying it until the timeout.
+func TestOCIManager_Create_Forbidden(t *testing.T) {
+	var mu sync.Mutex
+	heads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodHead {
+			_, _ = w.Write([]byte(`{"name":"bucket"}`))
+			return
+		}
+		mu.Lock()
+		heads++
+		mu.Unlock()
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	manager := newTestOCIManager(t, server.URL)
+	manager.Backoff = backoff.ConstantBackoff{Delay: time.Millisecond}
+	err := manager.Create("bucket", true)
+	if serviceErr, ok := common.IsServiceError(err); !ok || serviceErr.GetHTTPStatusCode() != http.StatusForbidden {
+		t.Fatalf("expected the 403 of HeadBucket, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if heads != 1 {
+		t.Errorf("expected a single HeadBucket call, got %d", heads)
+	}
+}
+
+// TestOCIManager_ListPrefix verifies that the prefix and delimiter are sent and that the objects and
+// prefixes of every page are returned.
+func TestOCIManager_ListPrefix(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("prefix") != "photos/" || query.Get("delimiter") != "/" {
+			t.Errorf("unexpected listing query: %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if query.Get("start") == "" {
+			_, _ = w.Write([]byte(`{"objects":[{"name":"photos/cover.jpg","size":10}],"prefixes":["photos/2023/"],"nextStartWith":"photos/2024"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"objects":[],"prefixes":["photos/2024/"]}`))
+	}))
+	defer server.Close()
+
+	objects, prefixes, err := newTestOCIManager(t, server.URL).ListPrefix("bucket", "photos/", "/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Key != "photos/cover.jpg" || objects[0].Size != 10 {
+		t.Errorf("unexpected objects returned: %v", objects)
+	}
+	if strings.Join(prefixes, ",") != "photos/2023/,photos/2024/" {
+		t.Errorf("unexpected prefixes returned: %v", prefixes)
+	}
+}
+
+// TestOCIManager_UploadLink verifies that an object-write pre-authenticated request is created and that
+// its full URL is returned.
+func TestOCIManager_UploadLink(t *testing.T) {
+	var path string
+	var details objectstorage.CreatePreauthenticatedRequestDetails
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.Method + " " + r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&details)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"par-1","name":"temp-link","accessUri":"/p/token/n/my-namespace/b/bucket/o/dir/a.txt",` +
+			`"accessType":"ObjectWrite","timeExpires":"2030-01-01T00:00:00Z","timeCreated":"2024-01-01T00:00:00Z"}`))
+	}))
+	defer server.Close()
+
+	link, err := newTestOCIManager(t, server.URL).UploadLink("bucket", "dir/a.txt", 15)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != "POST /n/my-namespace/b/bucket/p" {
+		t.Errorf("unexpected request %s", path)
+	}
+	if details.AccessType != objectstorage.CreatePreauthenticatedRequestDetailsAccessTypeObjectwrite || *details.ObjectName != "dir/a.txt" {
+		t.Errorf("unexpected request details: %+v", details)
+	}
+	if link != "https://objectstorage.us-ashburn-1.oraclecloud.com/p/token/n/my-namespace/b/bucket/o/dir/a.txt" {
+		t.Errorf("unexpected link %s", link)
+	}
+}
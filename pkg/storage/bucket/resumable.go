@@ -0,0 +1,84 @@
+package bucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// downloadState is the content of the state file kept next to a partial download. It records the
+// version of the object the bytes already written belong to.
+type downloadState struct {
+	ETag string `json:"etag"`
+	Size int64  `json:"size"`
+}
+
+// downloadStatePath returns the path of the state file of a download to localPath.
+func downloadStatePath(localPath string) string {
+	return localPath + ".state"
+}
+
+// resumeDownload completes the download of object into localPath. The bytes already in the file are kept
+// when the state file shows they belong to the same version (ETag and size) of the object; otherwise, or
+// without a state file, the download restarts from zero. get returns the content of the object from
+// offset to its end, and must fail if the object no longer has the ETag of object.
+// The state file is removed once the file is complete.
+func resumeDownload(localPath string, object BucketObject, get func(offset int64) (io.ReadCloser, error)) error {
+	statePath := downloadStatePath(localPath)
+
+	var have int64
+	if data, err := os.ReadFile(statePath); err == nil && object.ETag != "" {
+		var state downloadState
+		if json.Unmarshal(data, &state) == nil && state.ETag == object.ETag && state.Size == object.Size {
+			if info, err := os.Stat(localPath); err == nil && info.Size() <= object.Size {
+				have = info.Size()
+			}
+		}
+	}
+
+	if have == 0 {
+		data, err := json.Marshal(downloadState{ETag: object.ETag, Size: object.Size})
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(statePath, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write download state of '%s': %w", object.Key, err)
+		}
+	}
+
+	f, err := os.OpenFile(localPath, os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	// Drop whatever does not belong to the version being downloaded, then append after the kept bytes
+	if err := f.Truncate(have); err != nil {
+		return err
+	}
+	if _, err := f.Seek(have, io.SeekStart); err != nil {
+		return err
+	}
+
+	if have < object.Size {
+		content, err := get(have)
+		if err != nil {
+			return fmt.Errorf("failed to download '%s' from byte %d: %w", object.Key, have, err)
+		}
+		n, err := io.Copy(f, content)
+		_ = content.Close()
+		have += n
+		if err != nil {
+			return fmt.Errorf("download of '%s' interrupted at byte %d: %w", object.Key, have, err)
+		}
+	}
+	if have != object.Size {
+		return fmt.Errorf("download of '%s' ended at byte %d of %d", object.Key, have, object.Size)
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Remove(statePath)
+}
@@ -0,0 +1,124 @@
+package bucket
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeResumableHandler serves HEAD and ranged GET requests for a single object, checking If-Match
+// against the current ETag, and records the Range of every GET.
+func fakeResumableHandler(content, etag string, ranges *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		if r.Method == http.MethodHead {
+			w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+			return
+		}
+
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		if r.Header.Get("If-Match") != etag {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		var offset int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &offset); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(content)-1, len(content)))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = fmt.Fprint(w, content[offset:])
+	}
+}
+
+// writePartialDownload simulates an interrupted download of the object with the given ETag and size.
+func writePartialDownload(t *testing.T, localPath, partial, etag string, size int) {
+	if err := os.WriteFile(localPath, []byte(partial), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	state := fmt.Sprintf(`{"etag":%q,"size":%d}`, etag, size)
+	if err := os.WriteFile(downloadStatePath(localPath), []byte(state), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// checkDownloaded verifies the content of the downloaded file and that its state file was removed.
+func checkDownloaded(t *testing.T, localPath, expected string) {
+	data, err := os.ReadFile(localPath)
+	if err != nil || string(data) != expected {
+		t.Errorf("expected %q, got %q (%v)", expected, data, err)
+	}
+	if _, err := os.Stat(downloadStatePath(localPath)); !os.IsNotExist(err) {
+		t.Errorf("expected the state file to be removed, got %v", err)
+	}
+}
+
+// TestAWSManager_ResumableDownload verifies that a partial download is completed with a ranged GET.
+func TestAWSManager_ResumableDownload(t *testing.T) {
+	var ranges []string
+	server := httptest.NewServer(fakeResumableHandler("hello world", `"v1"`, &ranges))
+	defer server.Close()
+
+	localPath := filepath.Join(t.TempDir(), "object.txt")
+	writePartialDownload(t, localPath, "hello ", `"v1"`, 11)
+
+	if err := newTestAWSManager(t, server.URL).ResumableDownload("bucket", "object.txt", localPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkDownloaded(t, localPath, "hello world")
+	if strings.Join(ranges, ",") != "bytes=6-" {
+		t.Errorf("expected a single GET of the missing bytes, got %v", ranges)
+	}
+}
+
+// TestAWSManager_ResumableDownload_Changed verifies that the object is downloaded again from the start
+// when its ETag changed since the partial download.
+func TestAWSManager_ResumableDownload_Changed(t *testing.T) {
+	var ranges []string
+	server := httptest.NewServer(fakeResumableHandler("brand new content", `"v2"`, &ranges))
+	defer server.Close()
+
+	localPath := filepath.Join(t.TempDir(), "object.txt")
+	writePartialDownload(t, localPath, "hello ", `"v1"`, 11)
+
+	if err := newTestAWSManager(t, server.URL).ResumableDownload("bucket", "object.txt", localPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkDownloaded(t, localPath, "brand new content")
+	if strings.Join(ranges, ",") != "bytes=0-" {
+		t.Errorf("expected a full re-download, got %v", ranges)
+	}
+}
+
+// TestOCIManager_ResumableDownload verifies the ranged GET of OCI, and that a file without a state file
+// is downloaded from the start.
+func TestOCIManager_ResumableDownload(t *testing.T) {
+	var ranges []string
+	server := httptest.NewServer(fakeResumableHandler("hello world", "v1", &ranges))
+	defer server.Close()
+
+	manager := newTestOCIManager(t, server.URL)
+	localPath := filepath.Join(t.TempDir(), "object.txt")
+	writePartialDownload(t, localPath, "hello ", "v1", 11)
+	if err := manager.ResumableDownload("bucket", "object.txt", localPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkDownloaded(t, localPath, "hello world")
+
+	// Without a state file, the bytes on disk cannot be trusted
+	if err := os.WriteFile(localPath, []byte("stale bytes from elsewhere"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := manager.ResumableDownload("bucket", "object.txt", localPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkDownloaded(t, localPath, "hello world")
+	if strings.Join(ranges, ",") != "bytes=6-,bytes=0-" {
+		t.Errorf("unexpected ranges requested: %v", ranges)
+	}
+}
@@ -0,0 +1,84 @@
+package bucket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrShutdown is returned by uploads started after the manager's Shutdown was called.
+var ErrShutdown = errors.New("bucket manager is shut down")
+
+// inflightUploads tracks the multipart uploads in progress so that Shutdown can wait for them
+// and abort those still running when its context is done. The zero value is ready to use.
+type inflightUploads struct {
+	mu     sync.Mutex
+	closed bool
+	nextID int
+	aborts map[int]func() // Abort function of every in-flight upload, by registration ID.
+	idle   chan struct{}  // Closed when the last upload deregisters after shutdown started.
+}
+
+// register adds an in-flight upload and returns the ID to deregister it with.
+// It fails with ErrShutdown once shutdown has started.
+func (u *inflightUploads) register(abort func()) (int, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.closed {
+		return 0, ErrShutdown
+	}
+	if u.aborts == nil {
+		u.aborts = map[int]func(){}
+	}
+	u.nextID++
+	u.aborts[u.nextID] = abort
+	return u.nextID, nil
+}
+
+// deregister removes a finished upload, waking up a pending shutdown when it was the last one.
+func (u *inflightUploads) deregister(id int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	delete(u.aborts, id)
+	if len(u.aborts) == 0 && u.idle != nil {
+		close(u.idle)
+		u.idle = nil
+	}
+}
+
+// shutdown rejects new uploads and waits for the in-flight ones to finish. If ctx is done first,
+// every upload still running is aborted and ctx.Err() is returned.
+func (u *inflightUploads) shutdown(ctx context.Context) error {
+	u.mu.Lock()
+	u.closed = true
+	if len(u.aborts) == 0 {
+		u.mu.Unlock()
+		return nil
+	}
+	if u.idle == nil {
+		u.idle = make(chan struct{})
+	}
+	idle := u.idle
+	u.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+	}
+
+	u.mu.Lock()
+	aborts := make([]func(), 0, len(u.aborts))
+	for _, abort := range u.aborts {
+		aborts = append(aborts, abort)
+	}
+	u.mu.Unlock()
+
+	for _, abort := range aborts {
+		abort()
+	}
+	return fmt.Errorf("aborted %d in-flight uploads: %w", len(aborts), ctx.Err())
+}
@@ -0,0 +1,113 @@
+package bucket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestAWSManager_Shutdown verifies that Shutdown aborts an upload still in flight when its deadline
+// expires and that uploads started afterwards are rejected.
+func TestAWSManager_Shutdown(t *testing.T) {
+	partReceived := make(chan struct{})
+	release := make(chan struct{})
+	var received sync.Once
+	var mu sync.Mutex
+	aborted := ""
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodPost && query.Has("uploads"):
+			_, _ = fmt.Fprint(w, `<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+		case r.Method == http.MethodPut && query.Has("partNumber"):
+			// Hold the parts until the test releases them, keeping the upload in flight.
+			received.Do(func() { close(partReceived) })
+			<-release
+			w.Header().Set("ETag", `"etag-1"`)
+		case r.Method == http.MethodDelete:
+			mu.Lock()
+			aborted = query.Get("uploadId")
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && query.Has("uploadId"):
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `<Error><Code>NoSuchUpload</Code><Message>The specified upload does not exist</Message></Error>`)
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	defer server.Close()
+
+	manager := newTestAWSManager(t, server.URL)
+
+	f, err := os.CreateTemp(t.TempDir(), "upload")
+	if err != nil {
+		t.Fatalf("unexpected error creating file: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	// Two parts, so the content is sent as a multipart upload rather than with a single PutObject.
+	if _, err := f.Write(make([]byte, 2*131072)); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("unexpected error seeking file: %v", err)
+	}
+
+	uploadErr := make(chan error, 1)
+	go func() { uploadErr <- manager.Upload("bucket", "object.txt", f, 131072, 1) }()
+	<-partReceived
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := manager.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected Shutdown to report the expired deadline, got %v", err)
+	}
+
+	mu.Lock()
+	if aborted != "upload-1" {
+		t.Errorf("expected the in-flight upload to be aborted, got '%s'", aborted)
+	}
+	mu.Unlock()
+
+	close(release)
+	if err := <-uploadErr; err == nil {
+		t.Error("expected the aborted upload to fail")
+	}
+
+	if err := manager.Upload("bucket", "other.txt", f, 0, 1); !errors.Is(err, ErrShutdown) {
+		t.Errorf("expected ErrShutdown for an upload started after Shutdown, got %v", err)
+	}
+}
+
+// TestInflightUploads_Shutdown verifies that Shutdown waits for uploads that finish before the deadline without aborting them.
+func TestInflightUploads_Shutdown(t *testing.T) {
+	var uploads inflightUploads
+	if err := uploads.shutdown(context.Background()); err != nil {
+		t.Fatalf("expected an idle shutdown to succeed, got %v", err)
+	}
+
+	uploads = inflightUploads{}
+	abortCalled := false
+	id, err := uploads.register(func() { abortCalled = true })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		uploads.deregister(id)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := uploads.shutdown(ctx); err != nil || abortCalled {
+		t.Errorf("expected Shutdown to wait for the upload without aborting it, got %v (aborted: %v)", err, abortCalled)
+	}
+}
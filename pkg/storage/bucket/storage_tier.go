@@ -1,5 +1,11 @@
 package bucket
 
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/oracle/oci-go-sdk/v65/objectstorage"
+)
+
 type StorageTierEnum string
 
 const (
@@ -7,3 +13,63 @@ const (
 	STierLowAccess   StorageTierEnum = "LOW"
 	STierTierArchive StorageTierEnum = "ARCHIVE"
 )
+
+// ociStorageTier translates a StorageTierEnum into the OCI Object Storage tier.
+// An empty tier maps to the standard tier.
+func ociStorageTier(tier StorageTierEnum) (objectstorage.StorageTierEnum, error) {
+	switch tier {
+	case STierStandard, "":
+		return objectstorage.StorageTierStandard, nil
+	case STierLowAccess:
+		return objectstorage.StorageTierInfrequentAccess, nil
+	case STierTierArchive:
+		return objectstorage.StorageTierArchive, nil
+	default:
+		return "", fmt.Errorf("unsupported storage tier: %s", tier)
+	}
+}
+
+// gcpStorageClass translates a StorageTierEnum into the Cloud Storage class.
+// An empty tier maps to the standard class.
+func gcpStorageClass(tier StorageTierEnum) (string, error) {
+	switch tier {
+	case STierStandard, "":
+		return "STANDARD", nil
+	case STierLowAccess:
+		return "NEARLINE", nil
+	case STierTierArchive:
+		return "ARCHIVE", nil
+	default:
+		return "", fmt.Errorf("unsupported storage tier: %s", tier)
+	}
+}
+
+// azureAccessTier translates a StorageTierEnum into the Blob Storage access tier.
+// An empty tier maps to the hot tier.
+func azureAccessTier(tier StorageTierEnum) (string, error) {
+	switch tier {
+	case STierStandard, "":
+		return "Hot", nil
+	case STierLowAccess:
+		return "Cool", nil
+	case STierTierArchive:
+		return "Archive", nil
+	default:
+		return "", fmt.Errorf("unsupported storage tier: %s", tier)
+	}
+}
+
+// awsStorageClass translates a StorageTierEnum into the S3 storage class.
+// An empty tier maps to the standard class.
+func awsStorageClass(tier StorageTierEnum) (string, error) {
+	switch tier {
+	case STierStandard, "":
+		return s3.StorageClassStandard, nil
+	case STierLowAccess:
+		return s3.StorageClassStandardIa, nil
+	case STierTierArchive:
+		return s3.StorageClassGlacier, nil
+	default:
+		return "", fmt.Errorf("unsupported storage tier: %s", tier)
+	}
+}
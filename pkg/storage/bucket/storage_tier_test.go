@@ -0,0 +1,43 @@
+package bucket
+
+import (
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/oracle/oci-go-sdk/v65/objectstorage"
+	"testing"
+)
+
+// TestStorageTierMapping verifies the translation of every StorageTierEnum into the provider values.
+func TestStorageTierMapping(t *testing.T) {
+	tests := []struct {
+		tier    StorageTierEnum
+		oci     objectstorage.StorageTierEnum
+		aws     string
+		wantErr bool
+	}{
+		{tier: STierStandard, oci: objectstorage.StorageTierStandard, aws: s3.StorageClassStandard},
+		{tier: STierLowAccess, oci: objectstorage.StorageTierInfrequentAccess, aws: s3.StorageClassStandardIa},
+		{tier: STierTierArchive, oci: objectstorage.StorageTierArchive, aws: s3.StorageClassGlacier},
+		{tier: "", oci: objectstorage.StorageTierStandard, aws: s3.StorageClassStandard},
+		{tier: "UNKNOWN", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.tier), func(t *testing.T) {
+			oci, err := ociStorageTier(tt.tier)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ociStorageTier(%q) error = %v, wantErr %v", tt.tier, err, tt.wantErr)
+			}
+			if oci != tt.oci {
+				t.Errorf("ociStorageTier(%q) = %q, expected %q", tt.tier, oci, tt.oci)
+			}
+
+			aws, err := awsStorageClass(tt.tier)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("awsStorageClass(%q) error = %v, wantErr %v", tt.tier, err, tt.wantErr)
+			}
+			if aws != tt.aws {
+				t.Errorf("awsStorageClass(%q) = %q, expected %q", tt.tier, aws, tt.aws)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package bucket
+
+import (
+	"context"
+)
+
+// streamObjects runs list in a goroutine and returns the channels StreamObjects hands to its caller.
+// list pushes each object with send, which blocks until the object is received and returns false once
+// ctx is done, in which case list should stop. The terminal error of list, or the context error when
+// the listing was cancelled, is sent on the error channel before both channels are closed.
+func streamObjects(ctx context.Context, list func(send func(BucketObject) bool) error) (<-chan BucketObject, <-chan error) {
+	objects := make(chan BucketObject)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(objects)
+		defer close(errs)
+
+		send := func(obj BucketObject) bool {
+			select {
+			case objects <- obj:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		err := list(send)
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return objects, errs
+}
+
+// listAllPages drains the pages of the bucket listing of m, as returned by ListPage with the provider's
+// default page size.
+func listAllPages(m BucketManager, name string) ([]BucketObject, error) {
+	var r []BucketObject
+	token := ""
+	for {
+		page, next, err := m.ListPage(name, token, 0)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, page...)
+		if next == "" {
+			return r, nil
+		}
+		token = next
+	}
+}
@@ -0,0 +1,190 @@
+package bucket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeS3ListHandler serves a ListObjectsV2 listing of pages pages with two objects each.
+func fakeS3ListHandler(t *testing.T, pages int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if prefix := r.URL.Query().Get("prefix"); prefix != "logs/" {
+			t.Errorf("expected the prefix 'logs/', got %q", prefix)
+		}
+
+		page := 1
+		if token := r.URL.Query().Get("continuation-token"); token != "" {
+			_, _ = fmt.Sscanf(token, "page-%d", &page)
+		}
+		next := ""
+		if page < pages {
+			next = fmt.Sprintf("<IsTruncated>true</IsTruncated><NextContinuationToken>page-%d</NextContinuationToken>", page+1)
+		}
+
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = fmt.Fprintf(w, `<ListBucketResult><Name>bucket</Name>%s%s%s</ListBucketResult>`, next,
+			s3ObjectXML(fmt.Sprintf("logs/%d-1.txt", page), time.Now()),
+			s3ObjectXML(fmt.Sprintf("logs/%d-2.txt", page), time.Now()))
+	}
+}
+
+// TestAWSManager_StreamObjects verifies that the objects of every page are streamed in order and that both channels close.
+func TestAWSManager_StreamObjects(t *testing.T) {
+	server := httptest.NewServer(fakeS3ListHandler(t, 3))
+	defer server.Close()
+
+	objects, errs := newTestAWSManager(t, server.URL).StreamObjects(context.Background(), "bucket", "logs/")
+
+	var keys []string
+	for obj := range objects {
+		keys = append(keys, obj.Key)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"logs/1-1.txt", "logs/1-2.txt", "logs/2-1.txt", "logs/2-2.txt", "logs/3-1.txt", "logs/3-2.txt"}
+	if fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, keys)
+	}
+	if _, open := <-errs; open {
+		t.Error("expected the error channel to be closed")
+	}
+}
+
+// TestAWSManager_StreamObjects_Cancel verifies that cancelling the context stops the listing,
+// reports the cancellation and closes both channels.
+func TestAWSManager_StreamObjects_Cancel(t *testing.T) {
+	server := httptest.NewServer(fakeS3ListHandler(t, 1000))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	objects, errs := newTestAWSManager(t, server.URL).StreamObjects(ctx, "bucket", "logs/")
+
+	if _, ok := <-objects; !ok {
+		t.Fatal("expected a first object before cancelling")
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		for range objects {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the object channel to close after cancellation")
+	}
+
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// TestOCIManager_StreamObjects verifies that the listing follows nextStartWith and filters by prefix.
+func TestOCIManager_StreamObjects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("prefix") != "logs/" {
+			t.Errorf("expected the prefix 'logs/', got %q", r.URL.Query().Get("prefix"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("start") == "" {
+			_, _ = w.Write([]byte(`{"objects":[{"name":"logs/a.txt","size":1},{"name":"logs/b.txt","size":2}],"nextStartWith":"logs/c.txt"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"objects":[{"name":"logs/c.txt","size":3}]}`))
+	}))
+	defer server.Close()
+
+	objects, errs := newTestOCIManager(t, server.URL).StreamObjects(context.Background(), "bucket", "logs/")
+
+	var keys []string
+	for obj := range objects {
+		keys = append(keys, obj.Key)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(keys) != "[logs/a.txt logs/b.txt logs/c.txt]" {
+		t.Errorf("unexpected objects streamed: %v", keys)
+	}
+}
+
+// TestAWSManager_ListPage verifies the page size and continuation tokens of ListPage, that List drains
+// every page and that listing errors are returned.
+func TestAWSManager_ListPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("max-keys") != "" && r.URL.Query().Get("max-keys") != "2" {
+			t.Errorf("unexpected page size %q", r.URL.Query().Get("max-keys"))
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		switch r.URL.Query().Get("continuation-token") {
+		case "":
+			_, _ = fmt.Fprintf(w, `<ListBucketResult><Name>bucket</Name><IsTruncated>true</IsTruncated><NextContinuationToken>page-2</NextContinuationToken>%s%s</ListBucketResult>`,
+				s3ObjectXML("a.txt", time.Now()), s3ObjectXML("b.txt", time.Now()))
+		case "page-2":
+			_, _ = fmt.Fprintf(w, `<ListBucketResult><Name>bucket</Name><IsTruncated>false</IsTruncated>%s</ListBucketResult>`, s3ObjectXML("c.txt", time.Now()))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`<Error><Code>InvalidArgument</Code><Message>The continuation token provided is incorrect</Message></Error>`))
+		}
+	}))
+	defer server.Close()
+
+	manager := newTestAWSManager(t, server.URL)
+	page, next, err := manager.ListPage("bucket", "", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page) != 2 || page[0].Key != "a.txt" || next != "page-2" {
+		t.Errorf("unexpected first page %v (next %q)", page, next)
+	}
+	page, next, err = manager.ListPage("bucket", next, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page) != 1 || page[0].Key != "c.txt" || next != "" {
+		t.Errorf("unexpected last page %v (next %q)", page, next)
+	}
+
+	objects, err := manager.List("bucket")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objects) != 3 {
+		t.Errorf("expected the 3 objects of both pages, got %v", objects)
+	}
+
+	if _, _, err := manager.ListPage("bucket", "invalid", 2); err == nil {
+		t.Error("expected the listing error to be returned")
+	}
+}
+
+// TestOCIManager_List verifies that List follows nextStartWith until the last page.
+func TestOCIManager_List(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("start") == "" {
+			_, _ = w.Write([]byte(`{"objects":[{"name":"a.txt","size":1},{"name":"b.txt","size":2}],"nextStartWith":"c.txt"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"objects":[{"name":"c.txt","size":3}]}`))
+	}))
+	defer server.Close()
+
+	objects, err := newTestOCIManager(t, server.URL).List("bucket")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objects) != 3 || objects[2].Key != "c.txt" || objects[2].Size != 3 {
+		t.Errorf("unexpected objects: %+v", objects)
+	}
+}